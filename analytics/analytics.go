@@ -0,0 +1,332 @@
+// Package analytics 汇总收听历史：收听时长、节目和分类的占比、连续收听天数、收听时段热力图、完成率和收听最多的单集
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// CompletedRatio 播放进度达到时长的该比例时视为听完
+const CompletedRatio = 0.9
+
+// Uncategorized 找不到分类的节目归入该分类
+var Uncategorized = Category{Id: "", Name: "未分类"}
+
+// Play 收听历史中的一集，同一单集只有最近一次收听
+type Play struct {
+	Eid          string
+	Title        string
+	Pid          string
+	PodcastTitle string
+	PlayedAt     time.Time
+	Duration     int // 单集时长，单位为秒
+	Progress     int // 播放进度，单位为秒
+}
+
+// Seconds 估算的收听时长，即不超过单集时长的播放进度。收听历史中没有每次收听的时长，重复收听和拖动进度都无法计入
+func (p Play) Seconds() int {
+	if p.Duration > 0 {
+		return max(min(p.Progress, p.Duration), 0)
+	}
+
+	return max(p.Progress, 0)
+}
+
+// Completion 播放进度占单集时长的比例
+func (p Play) Completion() float64 {
+	if p.Duration <= 0 {
+		return 0
+	}
+
+	return math.Min(float64(p.Seconds())/float64(p.Duration), 1)
+}
+
+type Category struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Options 统计的时间范围为 [From, To)，日期和时段按 Location 计算
+type Options struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Top      int // 收听最多的节目和单集的数量
+}
+
+type Report struct {
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Timezone     string          `json:"timezone"`
+	TotalSeconds int             `json:"totalSeconds"` // 统计范围内估算的收听时长，见 Play.Seconds
+	TotalHours   float64         `json:"totalHours"`
+	EpisodeCount int             `json:"episodeCount"`
+	PodcastCount int             `json:"podcastCount"`
+	Completion   Completion      `json:"completion"`
+	Streak       Streak          `json:"streak"`
+	Heatmap      [7][24]int      `json:"heatmap"` // 按星期（周一为 0）和小时统计的收听时长，单位为秒
+	Podcasts     []*PodcastStat  `json:"podcasts"`
+	Categories   []*CategoryStat `json:"categories"`
+	TopEpisodes  []*EpisodeStat  `json:"topEpisodes"`
+	Listened     *Listened       `json:"listened"` // 上游记录的收听时长，查询失败时为 nil
+}
+
+// Listened 上游记录的实际收听时长，上游只提供累计、最近 7 天和最近 30 天的数据，不受统计范围影响
+type Listened struct {
+	TotalSeconds          int                `json:"totalSeconds"`
+	LastSevenDaysSeconds  int                `json:"lastSevenDaysSeconds"`
+	LastThirtyDaysSeconds int                `json:"lastThirtyDaysSeconds"`
+	Podcasts              []*ListenedPodcast `json:"podcasts"` // 按累计收听时长倒序
+}
+
+type ListenedPodcast struct {
+	Pid                   string `json:"pid"`
+	Title                 string `json:"title"`
+	Seconds               int    `json:"seconds"`               // 累计收听时长
+	LastThirtyDaysSeconds int    `json:"lastThirtyDaysSeconds"` // 最近 30 天的收听时长
+}
+
+type Completion struct {
+	Completed int     `json:"completed"` // 听完的单集数
+	Rate      float64 `json:"rate"`      // 听完的单集占比
+	Average   float64 `json:"average"`   // 平均播放进度占比
+}
+
+type Streak struct {
+	ActiveDays  int    `json:"activeDays"`  // 有收听的天数
+	Longest     int    `json:"longest"`     // 最长连续收听天数
+	LongestFrom string `json:"longestFrom"` // 最长连续收听的开始日期
+	LongestTo   string `json:"longestTo"`   // 最长连续收听的结束日期
+	Current     int    `json:"current"`     // 截至统计范围最后一天（或其前一天）的连续收听天数
+}
+
+type PodcastStat struct {
+	Pid      string   `json:"pid"`
+	Title    string   `json:"title"`
+	Category Category `json:"category"`
+	Episodes int      `json:"episodes"`
+	Seconds  int      `json:"seconds"`
+	Share    float64  `json:"share"` // 收听时长占比
+}
+
+type CategoryStat struct {
+	Category
+	Podcasts int     `json:"podcasts"`
+	Episodes int     `json:"episodes"`
+	Seconds  int     `json:"seconds"`
+	Share    float64 `json:"share"`
+}
+
+type EpisodeStat struct {
+	Eid          string  `json:"eid"`
+	Title        string  `json:"title"`
+	Pid          string  `json:"pid"`
+	PodcastTitle string  `json:"podcastTitle"`
+	PlayedAt     string  `json:"playedAt"`
+	Duration     int     `json:"duration"`
+	Seconds      int     `json:"seconds"`
+	Completion   float64 `json:"completion"`
+}
+
+const dateLayout = "2006-01-02"
+
+// Summarize 统计 [From, To) 内的收听，categories 为节目所属的分类，key 为 pid
+func Summarize(plays []Play, categories map[string]Category, opts Options) *Report {
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	r := &Report{
+		From:        opts.From.In(location).Format(dateLayout),
+		To:          opts.To.In(location).Add(-time.Nanosecond).Format(dateLayout),
+		Timezone:    location.String(),
+		Podcasts:    []*PodcastStat{},
+		Categories:  []*CategoryStat{},
+		TopEpisodes: []*EpisodeStat{},
+	}
+
+	podcasts := map[string]*PodcastStat{}
+	days := map[string]bool{}
+	completion := 0.0
+
+	var episodes []*EpisodeStat
+
+	for _, play := range plays {
+		if play.PlayedAt.Before(opts.From) || !play.PlayedAt.Before(opts.To) {
+			continue
+		}
+
+		at := play.PlayedAt.In(location)
+		seconds := play.Seconds()
+
+		r.EpisodeCount++
+		r.TotalSeconds += seconds
+		// 单集的收听时长全部计入最近一次收听的时段，听了多次或跨天收听时热力图和连续收听天数只是近似值
+		r.Heatmap[(int(at.Weekday())+6)%7][at.Hour()] += seconds
+		days[at.Format(dateLayout)] = true
+
+		completion += play.Completion()
+		if play.Completion() >= CompletedRatio {
+			r.Completion.Completed++
+		}
+
+		podcast, found := podcasts[play.Pid]
+		if !found {
+			category, found := categories[play.Pid]
+			if !found {
+				category = Uncategorized
+			}
+
+			podcast = &PodcastStat{Pid: play.Pid, Title: play.PodcastTitle, Category: category}
+			podcasts[play.Pid] = podcast
+		}
+
+		podcast.Episodes++
+		podcast.Seconds += seconds
+
+		episodes = append(episodes, &EpisodeStat{
+			Eid:          play.Eid,
+			Title:        play.Title,
+			Pid:          play.Pid,
+			PodcastTitle: play.PodcastTitle,
+			PlayedAt:     at.Format(time.RFC3339),
+			Duration:     play.Duration,
+			Seconds:      seconds,
+			Completion:   round(play.Completion()),
+		})
+	}
+
+	r.TotalHours = math.Round(float64(r.TotalSeconds)/360) / 10
+	r.PodcastCount = len(podcasts)
+
+	if r.EpisodeCount > 0 {
+		r.Completion.Rate = round(float64(r.Completion.Completed) / float64(r.EpisodeCount))
+		r.Completion.Average = round(completion / float64(r.EpisodeCount))
+	}
+
+	r.Streak = streak(days, opts.To.In(location).Add(-time.Nanosecond), location)
+
+	categoryStats := map[string]*CategoryStat{}
+	for _, podcast := range podcasts {
+		podcast.Share = share(podcast.Seconds, r.TotalSeconds)
+		r.Podcasts = append(r.Podcasts, podcast)
+
+		category, found := categoryStats[podcast.Category.Id]
+		if !found {
+			category = &CategoryStat{Category: podcast.Category}
+			categoryStats[podcast.Category.Id] = category
+			r.Categories = append(r.Categories, category)
+		}
+
+		category.Podcasts++
+		category.Episodes += podcast.Episodes
+		category.Seconds += podcast.Seconds
+	}
+
+	for _, category := range r.Categories {
+		category.Share = share(category.Seconds, r.TotalSeconds)
+	}
+
+	sort.Slice(r.Podcasts, func(i, j int) bool {
+		a, b := r.Podcasts[i], r.Podcasts[j]
+		if a.Seconds != b.Seconds {
+			return a.Seconds > b.Seconds
+		}
+
+		return a.Pid < b.Pid
+	})
+
+	sort.Slice(r.Categories, func(i, j int) bool {
+		a, b := r.Categories[i], r.Categories[j]
+		if a.Seconds != b.Seconds {
+			return a.Seconds > b.Seconds
+		}
+
+		return a.Id < b.Id
+	})
+
+	sort.Slice(episodes, func(i, j int) bool {
+		a, b := episodes[i], episodes[j]
+		if a.Seconds != b.Seconds {
+			return a.Seconds > b.Seconds
+		}
+
+		if a.Completion != b.Completion {
+			return a.Completion > b.Completion
+		}
+
+		return a.Eid < b.Eid
+	})
+
+	if opts.Top > 0 {
+		r.Podcasts = r.Podcasts[:min(opts.Top, len(r.Podcasts))]
+		episodes = episodes[:min(opts.Top, len(episodes))]
+	}
+
+	if episodes != nil {
+		r.TopEpisodes = episodes
+	}
+
+	return r
+}
+
+// streak 根据有收听的日期计算连续收听天数，last 为统计范围的最后一天
+func streak(days map[string]bool, last time.Time, location *time.Location) Streak {
+	s := Streak{ActiveDays: len(days)}
+
+	dates := make([]string, 0, len(days))
+	for day := range days {
+		dates = append(dates, day)
+	}
+
+	sort.Strings(dates)
+
+	length := 0
+	var start, previous time.Time
+
+	for _, date := range dates {
+		day, _ := time.ParseInLocation(dateLayout, date, location)
+
+		if length > 0 && previous.AddDate(0, 0, 1).Equal(day) {
+			length++
+		} else {
+			length, start = 1, day
+		}
+
+		if length > s.Longest {
+			s.Longest = length
+			s.LongestFrom = start.Format(dateLayout)
+			s.LongestTo = day.Format(dateLayout)
+		}
+
+		previous = day
+	}
+
+	// 最后一天还没有收听时，从前一天开始计算
+	day := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, location)
+	if !days[day.Format(dateLayout)] {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	for days[day.Format(dateLayout)] {
+		s.Current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return s
+}
+
+func share(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return round(float64(part) / float64(total))
+}
+
+// round 保留 3 位小数
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
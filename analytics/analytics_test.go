@@ -0,0 +1,212 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var shanghai = time.FixedZone("Asia/Shanghai", 8*3600)
+
+// day 2024 年 5 月的某天在 shanghai 的零点
+func day(d int) time.Time {
+	return time.Date(2024, time.May, d, 0, 0, 0, 0, shanghai)
+}
+
+func TestStreak(t *testing.T) {
+	tests := []struct {
+		name string
+		days []string
+		last time.Time
+		want Streak
+	}{
+		{
+			name: "没有收听",
+			last: day(3),
+			want: Streak{},
+		},
+		{
+			name: "最后一天有收听",
+			days: []string{"2024-05-01", "2024-05-02", "2024-05-03"},
+			last: day(3).Add(20 * time.Hour),
+			want: Streak{ActiveDays: 3, Longest: 3, LongestFrom: "2024-05-01", LongestTo: "2024-05-03", Current: 3},
+		},
+		{
+			name: "最后一天没有收听时从前一天计算",
+			days: []string{"2024-05-01", "2024-05-02"},
+			last: day(3),
+			want: Streak{ActiveDays: 2, Longest: 2, LongestFrom: "2024-05-01", LongestTo: "2024-05-02", Current: 2},
+		},
+		{
+			name: "最后两天都没有收听",
+			days: []string{"2024-05-01"},
+			last: day(3),
+			want: Streak{ActiveDays: 1, Longest: 1, LongestFrom: "2024-05-01", LongestTo: "2024-05-01"},
+		},
+		{
+			name: "中间有间隔",
+			days: []string{"2024-05-01", "2024-05-02", "2024-05-04", "2024-05-05", "2024-05-06", "2024-05-08"},
+			last: day(8),
+			want: Streak{ActiveDays: 6, Longest: 3, LongestFrom: "2024-05-04", LongestTo: "2024-05-06", Current: 1},
+		},
+		{
+			name: "同样长时取较早的一段",
+			days: []string{"2024-05-01", "2024-05-02", "2024-05-04", "2024-05-05"},
+			last: day(5),
+			want: Streak{ActiveDays: 4, Longest: 2, LongestFrom: "2024-05-01", LongestTo: "2024-05-02", Current: 2},
+		},
+		{
+			name: "跨月",
+			days: []string{"2024-04-29", "2024-04-30", "2024-05-01"},
+			last: day(1),
+			want: Streak{ActiveDays: 3, Longest: 3, LongestFrom: "2024-04-29", LongestTo: "2024-05-01", Current: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := map[string]bool{}
+			for _, d := range tt.days {
+				days[d] = true
+			}
+
+			if got := streak(days, tt.last, shanghai); got != tt.want {
+				t.Errorf("streak() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlay(t *testing.T) {
+	tests := []struct {
+		name       string
+		play       Play
+		seconds    int
+		completion float64
+	}{
+		{"部分收听", Play{Duration: 100, Progress: 45}, 45, 0.45},
+		{"进度超过时长", Play{Duration: 100, Progress: 130}, 100, 1},
+		{"负数的进度", Play{Duration: 100, Progress: -5}, 0, 0},
+		{"时长为 0", Play{Duration: 0, Progress: 60}, 60, 0},
+		{"时长为负数", Play{Duration: -1, Progress: 60}, 60, 0},
+		{"时长和进度都为 0", Play{}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.play.Seconds(); got != tt.seconds {
+				t.Errorf("Seconds() = %d, want %d", got, tt.seconds)
+			}
+
+			if got := tt.play.Completion(); got != tt.completion {
+				t.Errorf("Completion() = %v, want %v", got, tt.completion)
+			}
+		})
+	}
+}
+
+func TestSummarizeRange(t *testing.T) {
+	from, to := day(1), day(8)
+
+	tests := []struct {
+		name     string
+		playedAt time.Time
+		included bool
+	}{
+		{"开始时间", from, true},
+		{"开始前 1 秒", from.Add(-time.Second), false},
+		{"结束前 1 纳秒", to.Add(-time.Nanosecond), true},
+		{"结束时间", to, false},
+		{"UTC 前一天但在统计时区内", time.Date(2024, time.April, 30, 16, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plays := []Play{{Eid: "e1", Pid: "p1", PlayedAt: tt.playedAt, Duration: 100, Progress: 100}}
+
+			r := Summarize(plays, nil, Options{From: from, To: to, Location: shanghai})
+
+			if r.From != "2024-05-01" || r.To != "2024-05-07" {
+				t.Errorf("range = %s ~ %s, want 2024-05-01 ~ 2024-05-07", r.From, r.To)
+			}
+
+			if (r.EpisodeCount == 1) != tt.included {
+				t.Fatalf("EpisodeCount = %d, want included %v", r.EpisodeCount, tt.included)
+			}
+
+			if !tt.included {
+				return
+			}
+
+			// 日期和时段按统计时区计算
+			at := tt.playedAt.In(shanghai)
+			if r.Heatmap[(int(at.Weekday())+6)%7][at.Hour()] != 100 || r.Streak.ActiveDays != 1 {
+				t.Errorf("heatmap and streak not counted at %s: %+v", at, r.Streak)
+			}
+		})
+	}
+}
+
+func TestSummarizeCompletion(t *testing.T) {
+	plays := []Play{
+		{Eid: "e1", Pid: "p1", PlayedAt: day(1), Duration: 100, Progress: 90},
+		{Eid: "e2", Pid: "p1", PlayedAt: day(1), Duration: 100, Progress: 89},
+		{Eid: "e3", Pid: "p1", PlayedAt: day(1), Duration: 0, Progress: 300},
+		{Eid: "e4", Pid: "p1", PlayedAt: day(1), Duration: 100, Progress: 200},
+	}
+
+	r := Summarize(plays, nil, Options{From: day(1), To: day(2), Location: shanghai})
+
+	// 时长未知的单集不算听完，进度按 0 计入平均值，收听时长按播放进度计算
+	want := Completion{Completed: 2, Rate: 0.5, Average: round((0.9 + 0.89 + 0 + 1) / 4)}
+	if r.Completion != want || r.TotalSeconds != 90+89+300+100 {
+		t.Errorf("completion = %+v, total %d, want %+v, total %d", r.Completion, r.TotalSeconds, want, 90+89+300+100)
+	}
+}
+
+func TestSummarizeTop(t *testing.T) {
+	plays := []Play{
+		{Eid: "e1", Pid: "p1", PlayedAt: day(1), Duration: 100, Progress: 100},
+		{Eid: "e2", Pid: "p2", PlayedAt: day(1), Duration: 300, Progress: 300},
+		{Eid: "e3", Pid: "p2", PlayedAt: day(1), Duration: 200, Progress: 100},
+		{Eid: "e4", Pid: "p3", PlayedAt: day(1), Duration: 100, Progress: 100},
+	}
+
+	categories := map[string]Category{"p1": {Id: "c1", Name: "科技"}, "p2": {Id: "c2", Name: "文化"}}
+
+	tests := []struct {
+		name     string
+		top      int
+		podcasts []string
+		episodes []string
+	}{
+		{"不限制", 0, []string{"p2", "p1", "p3"}, []string{"e2", "e1", "e4", "e3"}},
+		{"截取前 2 个", 2, []string{"p2", "p1"}, []string{"e2", "e1"}},
+		{"超过总数", 10, []string{"p2", "p1", "p3"}, []string{"e2", "e1", "e4", "e3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Summarize(plays, categories, Options{From: day(1), To: day(2), Location: shanghai, Top: tt.top})
+
+			var podcasts, episodes []string
+			for _, p := range r.Podcasts {
+				podcasts = append(podcasts, p.Pid)
+			}
+
+			for _, e := range r.TopEpisodes {
+				episodes = append(episodes, e.Eid)
+			}
+
+			// 时长相同时按完成率、再按 id 排序
+			if !reflect.DeepEqual(podcasts, tt.podcasts) || !reflect.DeepEqual(episodes, tt.episodes) {
+				t.Errorf("top = %v %v, want %v %v", podcasts, episodes, tt.podcasts, tt.episodes)
+			}
+
+			// 分类和总数不受截取影响
+			if len(r.Categories) != 3 || r.PodcastCount != 3 || r.EpisodeCount != 4 {
+				t.Errorf("categories = %d, podcasts = %d, episodes = %d, want 3, 3, 4", len(r.Categories), r.PodcastCount, r.EpisodeCount)
+			}
+		})
+	}
+}
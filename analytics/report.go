@@ -0,0 +1,234 @@
+package analytics
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+)
+
+// HTML 将报告渲染为单个 HTML 页面，图表为内嵌的 SVG，不依赖外部资源
+func (r *Report) HTML(w io.Writer, title string) error {
+	return reportTemplate.Execute(w, newReportView(r, title))
+}
+
+var weekdays = []string{"一", "二", "三", "四", "五", "六", "日"}
+
+// 热力图每格的尺寸和条形图的宽度
+const (
+	cellSize  = 22
+	cellGap   = 3
+	labelSize = 28
+	barWidth  = 480
+	barHeight = 24
+)
+
+type reportView struct {
+	*Report
+	Title      string
+	Heatmap    heatmapView
+	Podcasts   []barView
+	Categories []barView
+}
+
+type heatmapView struct {
+	Width  int
+	Height int
+	Cells  []cellView
+	Days   []labelView
+	Hours  []labelView
+}
+
+type cellView struct {
+	X, Y    int
+	Fill    string
+	Tooltip string
+}
+
+type labelView struct {
+	X, Y int
+	Text string
+}
+
+type barView struct {
+	Y      int
+	Width  int
+	Label  string
+	Detail string
+}
+
+func newReportView(r *Report, title string) *reportView {
+	v := &reportView{Report: r, Title: title}
+
+	peak := 0
+	for _, hours := range r.Heatmap {
+		for _, seconds := range hours {
+			peak = max(peak, seconds)
+		}
+	}
+
+	step := cellSize + cellGap
+	v.Heatmap = heatmapView{Width: labelSize + 24*step, Height: labelSize + 7*step}
+
+	for day, hours := range r.Heatmap {
+		v.Heatmap.Days = append(v.Heatmap.Days, labelView{X: 0, Y: labelSize + day*step + cellSize*3/4, Text: "周" + weekdays[day]})
+
+		for hour, seconds := range hours {
+			v.Heatmap.Cells = append(v.Heatmap.Cells, cellView{
+				X:       labelSize + hour*step,
+				Y:       labelSize + day*step,
+				Fill:    heat(seconds, peak),
+				Tooltip: fmt.Sprintf("周%s %02d:00 %s", weekdays[day], hour, Duration(seconds)),
+			})
+		}
+	}
+
+	for hour := 0; hour < 24; hour += 3 {
+		v.Heatmap.Hours = append(v.Heatmap.Hours, labelView{X: labelSize + hour*step, Y: labelSize - 8, Text: fmt.Sprintf("%d 时", hour)})
+	}
+
+	for i, podcast := range r.Podcasts {
+		v.Podcasts = append(v.Podcasts, bar(i, podcast.Title, podcast.Seconds, podcast.Share, r.Podcasts[0].Seconds))
+	}
+
+	for i, category := range r.Categories {
+		v.Categories = append(v.Categories, bar(i, category.Name, category.Seconds, category.Share, r.Categories[0].Seconds))
+	}
+
+	return v
+}
+
+func bar(i int, label string, seconds int, share float64, longest int) barView {
+	width := 0
+	if longest > 0 {
+		width = max(seconds*barWidth/longest, 2)
+	}
+
+	// 标签区域宽 200，过长的名称截断
+	if runes := []rune(label); len(runes) > 14 {
+		label = string(runes[:13]) + "…"
+	}
+
+	return barView{
+		Y:      i * (barHeight + 8),
+		Width:  width,
+		Label:  label,
+		Detail: fmt.Sprintf("%s · %.1f%%", Duration(seconds), share*100),
+	}
+}
+
+// heat 热力图的颜色，收听越多颜色越深
+func heat(seconds, peak int) string {
+	if seconds == 0 || peak == 0 {
+		return "#eef1f5"
+	}
+
+	alpha := 0.15 + 0.85*math.Sqrt(float64(seconds)/float64(peak))
+
+	return fmt.Sprintf("rgba(37, 99, 235, %.2f)", alpha)
+}
+
+// Duration 以「x 小时 y 分钟」的形式表示时长
+func Duration(seconds int) string {
+	hours, minutes := seconds/3600, seconds%3600/60
+
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%d 小时 %d 分钟", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d 小时", hours)
+	default:
+		return fmt.Sprintf("%d 分钟", minutes)
+	}
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": Duration,
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.1f%%", f*100)
+	},
+	"barsHeight": func(bars []barView) int {
+		return len(bars) * (barHeight + 8)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; color: #1f2937; max-width: 960px; margin: 32px auto; padding: 0 16px; }
+  h1 { margin-bottom: 4px; }
+  .period { color: #6b7280; margin-top: 0; }
+  .cards { display: flex; flex-wrap: wrap; gap: 12px; margin: 24px 0; }
+  .card { flex: 1 1 160px; background: #f8fafc; border-radius: 8px; padding: 16px; }
+  .card b { display: block; font-size: 24px; margin-top: 4px; }
+  svg text { font-size: 12px; fill: #4b5563; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e5e7eb; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="period">{{.From}} 至 {{.To}}（{{.Timezone}}）</p>
+
+<div class="cards">
+  <div class="card">收听时长（估算）<b>{{.TotalHours}} 小时</b></div>
+  {{- with .Listened}}
+  <div class="card">最近 30 天收听<b>{{duration .LastThirtyDaysSeconds}}</b></div>
+  <div class="card">累计收听<b>{{duration .TotalSeconds}}</b></div>
+  {{- end}}
+  <div class="card">单集<b>{{.EpisodeCount}}</b></div>
+  <div class="card">节目<b>{{.PodcastCount}}</b></div>
+  <div class="card">听完<b>{{percent .Completion.Rate}}</b></div>
+  <div class="card">最长连续收听<b>{{.Streak.Longest}} 天</b></div>
+  <div class="card">收听天数<b>{{.Streak.ActiveDays}} 天</b></div>
+</div>
+
+<h2>收听时段</h2>
+<svg width="{{.Heatmap.Width}}" height="{{.Heatmap.Height}}" role="img" aria-label="收听时段热力图">
+  {{- range .Heatmap.Hours}}
+  <text x="{{.X}}" y="{{.Y}}">{{.Text}}</text>
+  {{- end}}
+  {{- range .Heatmap.Days}}
+  <text x="{{.X}}" y="{{.Y}}">{{.Text}}</text>
+  {{- end}}
+  {{- range .Heatmap.Cells}}
+  <rect x="{{.X}}" y="{{.Y}}" width="22" height="22" rx="4" fill="{{.Fill}}"><title>{{.Tooltip}}</title></rect>
+  {{- end}}
+</svg>
+
+{{- if .Podcasts}}
+<h2>收听最多的节目</h2>
+<svg width="960" height="{{barsHeight .Podcasts}}" role="img" aria-label="收听最多的节目">
+  {{- range .Podcasts}}
+  <text x="0" y="{{.Y}}" dy="16">{{.Label}}</text>
+  <rect x="200" y="{{.Y}}" width="{{.Width}}" height="24" rx="4" fill="#2563eb"></rect>
+  <text x="{{.Width}}" y="{{.Y}}" dx="208" dy="16">{{.Detail}}</text>
+  {{- end}}
+</svg>
+{{- end}}
+
+{{- if .Categories}}
+<h2>分类</h2>
+<svg width="960" height="{{barsHeight .Categories}}" role="img" aria-label="分类">
+  {{- range .Categories}}
+  <text x="0" y="{{.Y}}" dy="16">{{.Label}}</text>
+  <rect x="200" y="{{.Y}}" width="{{.Width}}" height="24" rx="4" fill="#10b981"></rect>
+  <text x="{{.Width}}" y="{{.Y}}" dx="208" dy="16">{{.Detail}}</text>
+  {{- end}}
+</svg>
+{{- end}}
+
+{{- if .TopEpisodes}}
+<h2>收听最多的单集</h2>
+<table>
+  <tr><th>单集</th><th>节目</th><th>收听时长</th><th>进度</th></tr>
+  {{- range .TopEpisodes}}
+  <tr><td>{{.Title}}</td><td>{{.PodcastTitle}}</td><td>{{duration .Seconds}}</td><td>{{percent .Completion}}</td></tr>
+  {{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,276 @@
+// Package charts 保存榜单的历史快照，计算排名变化、上升和下降最多的单集以及在榜时间
+package charts
+
+import (
+	"sort"
+	"time"
+)
+
+// Entry 榜单中的一个单集
+type Entry struct {
+	Rank         int    `json:"rank"`
+	Eid          string `json:"eid"`
+	Title        string `json:"title"`
+	Pid          string `json:"pid"`
+	PodcastTitle string `json:"podcastTitle"`
+	PlayCount    int    `json:"playCount,omitempty"`
+	ClapCount    int    `json:"clapCount,omitempty"`
+}
+
+// Snapshot 某一时刻的榜单，Entries 按排名排列
+type Snapshot struct {
+	Category string    `json:"category"`
+	At       time.Time `json:"at"`
+	Entries  []Entry   `json:"entries"`
+}
+
+// Sort 按时间正序排列快照
+func Sort(snapshots []Snapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].At.Before(snapshots[j].At)
+	})
+}
+
+// Between 时间在 [from, to) 之间的快照，零值表示不限制
+func Between(snapshots []Snapshot, from, to time.Time) []Snapshot {
+	result := []Snapshot{}
+	for _, s := range snapshots {
+		if (!from.IsZero() && s.At.Before(from)) || (!to.IsZero() && !s.At.Before(to)) {
+			continue
+		}
+
+		result = append(result, s)
+	}
+
+	return result
+}
+
+// Point 单集或节目在一次快照中的排名，节目为其单集中最好的排名
+type Point struct {
+	Category string    `json:"category"`
+	At       time.Time `json:"at"`
+	Rank     int       `json:"rank"`
+	Eid      string    `json:"eid"`   // 取得该排名的单集
+	Title    string    `json:"title"` // 单集标题
+	Entries  int       `json:"entries"`
+}
+
+// History 单集或节目的排名历史
+type History struct {
+	Points      []Point    `json:"points"`
+	Appearances int        `json:"appearances"` // 在榜的快照数
+	Snapshots   int        `json:"snapshots"`   // 查询范围内的快照数
+	BestRank    int        `json:"bestRank,omitempty"`
+	FirstSeen   *time.Time `json:"firstSeen,omitempty"`
+	LastSeen    *time.Time `json:"lastSeen,omitempty"`
+}
+
+// RankHistory 单集（eid 不为空时）或节目在快照中的排名，不在榜的快照没有对应的点
+func RankHistory(snapshots []Snapshot, eid, pid string) History {
+	h := History{Points: []Point{}, Snapshots: len(snapshots)}
+
+	for _, s := range snapshots {
+		point := Point{Category: s.Category, At: s.At}
+
+		for _, e := range s.Entries {
+			if (eid != "" && e.Eid != eid) || (eid == "" && e.Pid != pid) {
+				continue
+			}
+
+			if point.Entries++; point.Rank == 0 || e.Rank < point.Rank {
+				point.Rank, point.Eid, point.Title = e.Rank, e.Eid, e.Title
+			}
+		}
+
+		if point.Entries == 0 {
+			continue
+		}
+
+		h.Points = append(h.Points, point)
+		if h.BestRank == 0 || point.Rank < h.BestRank {
+			h.BestRank = point.Rank
+		}
+	}
+
+	if h.Appearances = len(h.Points); h.Appearances > 0 {
+		h.FirstSeen = &h.Points[0].At
+		h.LastSeen = &h.Points[len(h.Points)-1].At
+	}
+
+	return h
+}
+
+// Move 单集在两次快照之间的排名变化，Change 为正表示上升
+type Move struct {
+	Entry
+	Previous int `json:"previous"`
+	Change   int `json:"change"`
+}
+
+// Movers 两次快照之间的变化
+type Movers struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Risers  []Move    `json:"risers"`  // 按上升的名次倒序
+	Fallers []Move    `json:"fallers"` // 按下降的名次倒序
+	New     []Entry   `json:"new"`     // 新上榜的单集
+	Dropped []Entry   `json:"dropped"` // 跌出榜单的单集，排名为之前的排名
+}
+
+// Compare 比较两次快照，每个列表最多返回 limit 项，limit 为 0 时不限制
+func Compare(before, after Snapshot, limit int) Movers {
+	m := Movers{From: before.At, To: after.At, Risers: []Move{}, Fallers: []Move{}, New: []Entry{}, Dropped: []Entry{}}
+
+	previous := make(map[string]int, len(before.Entries))
+	for _, e := range before.Entries {
+		previous[e.Eid] = e.Rank
+	}
+
+	current := make(map[string]bool, len(after.Entries))
+	for _, e := range after.Entries {
+		current[e.Eid] = true
+
+		rank, found := previous[e.Eid]
+		switch {
+		case !found:
+			m.New = append(m.New, e)
+		case rank > e.Rank:
+			m.Risers = append(m.Risers, Move{Entry: e, Previous: rank, Change: rank - e.Rank})
+		case rank < e.Rank:
+			m.Fallers = append(m.Fallers, Move{Entry: e, Previous: rank, Change: rank - e.Rank})
+		}
+	}
+
+	for _, e := range before.Entries {
+		if !current[e.Eid] {
+			m.Dropped = append(m.Dropped, e)
+		}
+	}
+
+	sort.SliceStable(m.Risers, func(i, j int) bool {
+		return m.Risers[i].Change > m.Risers[j].Change
+	})
+
+	sort.SliceStable(m.Fallers, func(i, j int) bool {
+		return m.Fallers[i].Change < m.Fallers[j].Change
+	})
+
+	m.Risers, m.Fallers = truncate(m.Risers, limit), truncate(m.Fallers, limit)
+	m.New, m.Dropped = truncate(m.New, limit), truncate(m.Dropped, limit)
+
+	return m
+}
+
+// Before 时间不晚于 at 的最后一个快照，快照需按时间正序排列
+func Before(snapshots []Snapshot, at time.Time) (Snapshot, bool) {
+	i := sort.Search(len(snapshots), func(i int) bool {
+		return snapshots[i].At.After(at)
+	})
+
+	if i == 0 {
+		return Snapshot{}, false
+	}
+
+	return snapshots[i-1], true
+}
+
+const (
+	ByEpisode = "episode"
+	ByPodcast = "podcast"
+)
+
+// Charting 单集或节目的在榜时间
+type Charting struct {
+	Eid          string    `json:"eid,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Pid          string    `json:"pid"`
+	PodcastTitle string    `json:"podcastTitle"`
+	Appearances  int       `json:"appearances"` // 在榜的快照数
+	Streak       int       `json:"streak"`      // 最长连续在榜的快照数
+	Current      int       `json:"current"`     // 截至最后一次快照连续在榜的快照数
+	BestRank     int       `json:"bestRank"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+// Longest 按最长连续在榜的快照数倒序排列，by 为 episode 时按单集统计，为 podcast 时按节目统计，
+// 节目的多个单集同时在榜时只计算一次。快照需按时间正序排列
+func Longest(snapshots []Snapshot, by string) []Charting {
+	index := map[string]int{}
+	result := []Charting{}
+	last := map[string]int{} // 上一次在榜的快照序号
+
+	for i, s := range snapshots {
+		seen := map[string]bool{}
+
+		for _, e := range s.Entries {
+			key := e.Eid
+			if by == ByPodcast {
+				key = e.Pid
+			}
+
+			if seen[key] {
+				if c := &result[index[key]]; e.Rank < c.BestRank {
+					c.BestRank = e.Rank
+				}
+
+				continue
+			}
+
+			seen[key] = true
+
+			n, found := index[key]
+			if !found {
+				n = len(result)
+				index[key] = n
+				result = append(result, Charting{Pid: e.Pid, PodcastTitle: e.PodcastTitle, BestRank: e.Rank, FirstSeen: s.At})
+
+				if by != ByPodcast {
+					result[n].Eid, result[n].Title = e.Eid, e.Title
+				}
+			}
+
+			c := &result[n]
+			c.Appearances++
+			c.LastSeen = s.At
+			c.BestRank = min(c.BestRank, e.Rank)
+
+			if previous, found := last[key]; found && previous == i-1 {
+				c.Current++
+			} else {
+				c.Current = 1
+			}
+
+			c.Streak = max(c.Streak, c.Current)
+			last[key] = i
+		}
+	}
+
+	for key, n := range index {
+		if last[key] != len(snapshots)-1 {
+			result[n].Current = 0
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Streak != result[j].Streak {
+			return result[i].Streak > result[j].Streak
+		}
+
+		if result[i].Appearances != result[j].Appearances {
+			return result[i].Appearances > result[j].Appearances
+		}
+
+		return result[i].BestRank < result[j].BestRank
+	})
+
+	return result
+}
+
+func truncate[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+
+	return items
+}
@@ -0,0 +1,148 @@
+package charts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// snapshot 按 eids 的顺序排名的快照，单集所属的节目为 eid 的第一个字符
+func snapshot(hour int, eids ...string) Snapshot {
+	s := Snapshot{Category: "HOT", At: time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)}
+	for i, eid := range eids {
+		s.Entries = append(s.Entries, Entry{Rank: i + 1, Eid: eid, Pid: eid[:1]})
+	}
+
+	return s
+}
+
+func eids(entries []Entry) []string {
+	result := []string{}
+	for _, e := range entries {
+		result = append(result, e.Eid)
+	}
+
+	return result
+}
+
+func TestCompare(t *testing.T) {
+	before := snapshot(0, "a1", "b1", "c1", "d1")
+	after := snapshot(1, "c1", "a1", "e1", "b1")
+
+	tests := []struct {
+		name    string
+		limit   int
+		risers  []string
+		fallers []string
+		new     []string
+		dropped []string
+	}{
+		{"不限制数量", 0, []string{"c1"}, []string{"b1", "a1"}, []string{"e1"}, []string{"d1"}},
+		{"每个列表最多一项", 1, []string{"c1"}, []string{"b1"}, []string{"e1"}, []string{"d1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Compare(before, after, tt.limit)
+
+			var risers, fallers []Entry
+			for _, move := range m.Risers {
+				risers = append(risers, move.Entry)
+			}
+
+			for _, move := range m.Fallers {
+				fallers = append(fallers, move.Entry)
+			}
+
+			got := [][]string{eids(risers), eids(fallers), eids(m.New), eids(m.Dropped)}
+			if want := [][]string{tt.risers, tt.fallers, tt.new, tt.dropped}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Compare() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if m := Compare(before, after, 0); m.Risers[0].Change != 2 || m.Fallers[0].Change != -2 {
+		t.Errorf("Compare() changes = %d, %d, want 2, -2", m.Risers[0].Change, m.Fallers[0].Change)
+	}
+}
+
+func TestRankHistory(t *testing.T) {
+	snapshots := []Snapshot{snapshot(0, "a1", "a2"), snapshot(1, "b1"), snapshot(2, "a2", "b1")}
+
+	tests := []struct {
+		name        string
+		eid, pid    string
+		ranks       []int
+		bestRank    int
+		appearances int
+	}{
+		{"单集", "a2", "", []int{2, 1}, 1, 2},
+		{"节目取单集中最好的排名", "", "a", []int{1, 1}, 1, 2},
+		{"从未在榜", "z1", "", []int{}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := RankHistory(snapshots, tt.eid, tt.pid)
+
+			ranks := []int{}
+			for _, p := range h.Points {
+				ranks = append(ranks, p.Rank)
+			}
+
+			if !reflect.DeepEqual(ranks, tt.ranks) || h.BestRank != tt.bestRank || h.Appearances != tt.appearances || h.Snapshots != 3 {
+				t.Errorf("RankHistory() = %v best %d appearances %d snapshots %d, want %v best %d appearances %d snapshots 3",
+					ranks, h.BestRank, h.Appearances, h.Snapshots, tt.ranks, tt.bestRank, tt.appearances)
+			}
+		})
+	}
+}
+
+func TestLongest(t *testing.T) {
+	snapshots := []Snapshot{
+		snapshot(0, "a1", "b1"),
+		snapshot(1, "a1", "a2"),
+		snapshot(2, "b1", "a2"),
+		snapshot(3, "a2", "b1"),
+	}
+
+	type charting struct {
+		key                          string
+		appearances, streak, current int
+		bestRank                     int
+	}
+
+	tests := []struct {
+		name string
+		by   string
+		want []charting
+	}{
+		{"按单集", ByEpisode, []charting{
+			{"a2", 3, 3, 3, 1},
+			{"b1", 3, 2, 2, 1},
+			{"a1", 2, 2, 0, 1},
+		}},
+		{"按节目，同一快照中的多个单集只计算一次", ByPodcast, []charting{
+			{"a", 4, 4, 4, 1},
+			{"b", 3, 2, 2, 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []charting{}
+			for _, c := range Longest(snapshots, tt.by) {
+				key := c.Eid
+				if tt.by == ByPodcast {
+					key = c.Pid
+				}
+
+				got = append(got, charting{key, c.Appearances, c.Streak, c.Current, c.BestRank})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Longest(%q) = %+v, want %+v", tt.by, got, tt.want)
+			}
+		})
+	}
+}
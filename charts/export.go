@@ -0,0 +1,26 @@
+package charts
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSV 每个快照中的每个单集一行，时间按 loc 显示
+func CSV(w io.Writer, snapshots []Snapshot, loc *time.Location) error {
+	b := csv.NewWriter(w)
+
+	b.Write([]string{"captured_at", "category", "rank", "eid", "title", "pid", "podcast", "play_count", "clap_count"})
+	for _, s := range snapshots {
+		at := s.At.In(loc).Format(time.DateTime)
+
+		for _, e := range s.Entries {
+			b.Write([]string{at, s.Category, strconv.Itoa(e.Rank), e.Eid, e.Title, e.Pid, e.PodcastTitle, strconv.Itoa(e.PlayCount), strconv.Itoa(e.ClapCount)})
+		}
+	}
+
+	b.Flush()
+
+	return b.Error()
+}
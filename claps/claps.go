@@ -0,0 +1,190 @@
+// Package claps 将单集时间线上的精彩时间点标记汇总为热力图，检测标记集中的片段，并导出为 Podcasting 2.0 章节、WebVTT 和 SVG
+package claps
+
+import (
+	"math"
+	"sort"
+)
+
+// Bin 热力图中的一段，时间单位为秒
+type Bin struct {
+	Index     int     `json:"index"`
+	Start     int     `json:"start"`
+	End       int     `json:"end"`
+	Count     int     `json:"count"`
+	Mine      int     `json:"mine"`      // 当前用户在该段的标记数
+	Intensity float64 `json:"intensity"` // 相对于标记最多的一段，0 到 1
+}
+
+// Highlight 标记集中的片段
+type Highlight struct {
+	Start int     `json:"start"`
+	End   int     `json:"end"`
+	Peak  int     `json:"peak"`  // 标记最集中的时间
+	Count int     `json:"count"` // 片段内的标记数
+	Share float64 `json:"share"` // 占全部标记的比例
+}
+
+// Heatmap 将上游等分时间线的各段标记数合并为 bins 段，每段按中点归入新的分段。
+// mine 为当前用户标记的段的下标，bins 为 0 或超过上游的段数时使用上游的段数
+func Heatmap(counts []int, mine []int, duration, bins int) []Bin {
+	n := len(counts)
+	if n == 0 || duration <= 0 {
+		return []Bin{}
+	}
+
+	if bins <= 0 || bins > n {
+		bins = n
+	}
+
+	result := make([]Bin, bins)
+	for i := range result {
+		result[i] = Bin{Index: i, Start: i * duration / bins, End: (i + 1) * duration / bins}
+	}
+
+	// 上游第 i 段的中点位于 (i + 0.5) / n，对应新的第 (2i + 1) * bins / 2n 段
+	target := func(i int) int {
+		return (2*i + 1) * bins / (2 * n)
+	}
+
+	for i, count := range counts {
+		result[target(i)].Count += count
+	}
+
+	for _, i := range mine {
+		if i >= 0 && i < n {
+			result[target(i)].Mine++
+		}
+	}
+
+	peak := 0
+	for _, bin := range result {
+		peak = max(peak, bin.Count)
+	}
+
+	if peak > 0 {
+		for i := range result {
+			result[i].Intensity = math.Round(float64(result[i].Count)/float64(peak)*1000) / 1000
+		}
+	}
+
+	return result
+}
+
+// Detect 检测标记集中的片段，最多返回 top 个，按时间排列。
+//
+// 先按 1:2:1 对相邻三段加权平均平滑（两端之外以两端的段补齐），平滑后高于平均值加一个标准差的局部最高点作为候选，
+// 从标记最多的候选开始，向两侧扩展到低于该点一半的位置作为片段，与已有片段重叠的候选被忽略。
+// 片段的 Peak 为片段内原始标记数最多的一段的中点
+func Detect(bins []Bin, top int) []Highlight {
+	if len(bins) == 0 || top <= 0 {
+		return []Highlight{}
+	}
+
+	total := 0
+	for _, bin := range bins {
+		total += bin.Count
+	}
+
+	if total == 0 {
+		return []Highlight{}
+	}
+
+	// 两端只有一个相邻的段，以两端的段补齐，使每段都按三段计算
+	smoothed := make([]float64, len(bins))
+	for i := range bins {
+		previous, next := bins[max(i-1, 0)].Count, bins[min(i+1, len(bins)-1)].Count
+
+		smoothed[i] = float64(previous+2*bins[i].Count+next) / 4
+	}
+
+	// 标记均匀分布时没有集中的片段
+	mean, deviation := stats(smoothed)
+	if deviation == 0 {
+		return []Highlight{}
+	}
+
+	threshold := mean + deviation
+
+	var candidates []int
+	for i, value := range smoothed {
+		if value <= 0 || value < threshold {
+			continue
+		}
+
+		if (i == 0 || value >= smoothed[i-1]) && (i == len(smoothed)-1 || value > smoothed[i+1]) {
+			candidates = append(candidates, i)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return smoothed[candidates[i]] > smoothed[candidates[j]]
+	})
+
+	taken := make([]bool, len(bins))
+
+	var highlights []Highlight
+	for _, peak := range candidates {
+		if len(highlights) == top {
+			break
+		}
+
+		if taken[peak] {
+			continue
+		}
+
+		half := smoothed[peak] / 2
+
+		from, to := peak, peak
+		for from > 0 && !taken[from-1] && smoothed[from-1] >= half {
+			from--
+		}
+
+		for to < len(bins)-1 && !taken[to+1] && smoothed[to+1] >= half {
+			to++
+		}
+
+		// 平滑后的最高点可能偏离原始标记最多的段
+		count, highest := 0, from
+		for i := from; i <= to; i++ {
+			taken[i] = true
+			count += bins[i].Count
+
+			if bins[i].Count > bins[highest].Count {
+				highest = i
+			}
+		}
+
+		highlights = append(highlights, Highlight{
+			Start: bins[from].Start,
+			End:   bins[to].End,
+			Peak:  (bins[highest].Start + bins[highest].End) / 2,
+			Count: count,
+			Share: math.Round(float64(count)/float64(total)*1000) / 1000,
+		})
+	}
+
+	sort.Slice(highlights, func(i, j int) bool {
+		return highlights[i].Start < highlights[j].Start
+	})
+
+	if highlights == nil {
+		return []Highlight{}
+	}
+
+	return highlights
+}
+
+func stats(values []float64) (mean, deviation float64) {
+	for _, value := range values {
+		mean += value
+	}
+
+	mean /= float64(len(values))
+
+	for _, value := range values {
+		deviation += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(deviation / float64(len(values)))
+}
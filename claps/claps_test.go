@@ -0,0 +1,185 @@
+package claps
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeatmap(t *testing.T) {
+	tests := []struct {
+		name     string
+		counts   []int
+		mine     []int
+		duration int
+		bins     int
+		want     []Bin
+	}{
+		{
+			name:     "没有数据",
+			counts:   nil,
+			duration: 100,
+			want:     []Bin{},
+		},
+		{
+			name:     "没有时长",
+			counts:   []int{1, 2},
+			duration: 0,
+			want:     []Bin{},
+		},
+		{
+			name:     "使用上游的段数",
+			counts:   []int{1, 3},
+			mine:     []int{1, 5},
+			duration: 100,
+			want: []Bin{
+				{Index: 0, Start: 0, End: 50, Count: 1, Intensity: 0.333},
+				{Index: 1, Start: 50, End: 100, Count: 3, Mine: 1, Intensity: 1},
+			},
+		},
+		{
+			name:     "按中点合并",
+			counts:   []int{1, 2, 3, 4},
+			mine:     []int{0, 3},
+			duration: 100,
+			bins:     2,
+			want: []Bin{
+				{Index: 0, Start: 0, End: 50, Count: 3, Mine: 1, Intensity: 0.429},
+				{Index: 1, Start: 50, End: 100, Count: 7, Mine: 1, Intensity: 1},
+			},
+		},
+		{
+			name:     "段数超过上游",
+			counts:   []int{0, 0},
+			duration: 10,
+			bins:     5,
+			want: []Bin{
+				{Index: 0, Start: 0, End: 5},
+				{Index: 1, Start: 5, End: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Heatmap(tt.counts, tt.mine, tt.duration, tt.bins); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Heatmap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		counts   []int
+		duration int
+		bins     int
+		top      int
+		want     []Highlight
+	}{
+		{
+			name:     "峰值为原始标记最多的段",
+			counts:   []int{0, 0, 10, 50, 10, 0, 0, 0, 30, 0},
+			duration: 100,
+			bins:     5,
+			top:      3,
+			want:     []Highlight{{Start: 20, End: 60, Peak: 30, Count: 70, Share: 0.7}},
+		},
+		{
+			name:     "两端的段不会被高估",
+			counts:   []int{30, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			duration: 100,
+			top:      3,
+			want:     []Highlight{{Start: 0, End: 10, Peak: 5, Count: 30, Share: 1}},
+		},
+		{
+			name:     "多个片段按时间排列",
+			counts:   []int{0, 0, 30, 0, 0, 0, 0, 40, 0, 0},
+			duration: 100,
+			top:      3,
+			want: []Highlight{
+				{Start: 10, End: 40, Peak: 25, Count: 30, Share: 0.429},
+				{Start: 60, End: 90, Peak: 75, Count: 40, Share: 0.571},
+			},
+		},
+		{
+			name:     "只返回标记最多的 top 个",
+			counts:   []int{0, 0, 30, 0, 0, 0, 0, 40, 0, 0},
+			duration: 100,
+			top:      1,
+			want:     []Highlight{{Start: 60, End: 90, Peak: 75, Count: 40, Share: 0.571}},
+		},
+		{
+			name:     "均匀分布",
+			counts:   []int{5, 5, 5, 5},
+			duration: 100,
+			top:      3,
+			want:     []Highlight{},
+		},
+		{
+			name:     "没有标记",
+			counts:   []int{0, 0, 0},
+			duration: 100,
+			top:      3,
+			want:     []Highlight{},
+		},
+		{
+			name:     "top 为 0",
+			counts:   []int{0, 50, 0},
+			duration: 100,
+			top:      0,
+			want:     []Highlight{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bins := Heatmap(tt.counts, nil, tt.duration, tt.bins)
+			if got := Detect(bins, tt.top); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteVTT(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteVTT(&b, []Highlight{{Start: 65, End: 3725, Count: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "WEBVTT\n\nhighlight-1\n00:01:05.000 --> 01:02:05.000\n精彩片段 1（3 次标记）\n"
+	if b.String() != want {
+		t.Errorf("WriteVTT() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteChapters(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteChapters(&b, []Highlight{{Start: 10, End: 20, Count: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"version": "1.2.0"`, `"startTime": 10`, `"endTime": 20`, `"title": "精彩片段 1（2 次标记）"`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteChapters() = %s, missing %s", b.String(), want)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	bins := Heatmap([]int{0, 10}, nil, 100, 0)
+
+	var b bytes.Buffer
+	if err := WriteSVG(&b, bins, []Highlight{{Start: 50, End: 100, Count: 10}}, 100, 200, 40); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`width="200" height="40"`, `<rect x="100.0" y="0" width="100.0"`, `points="50.0,38.0 150.0,2.0"`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteSVG() = %s, missing %s", b.String(), want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package claps
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ChaptersVersion Podcasting 2.0 章节格式的版本
+const ChaptersVersion = "1.2.0"
+
+// Chapters Podcasting 2.0 章节文件，见 https://github.com/Podcastindex-org/podcast-namespace/blob/main/chapters/jsonChapters.md
+type Chapters struct {
+	Version  string    `json:"version"`
+	Chapters []Chapter `json:"chapters"`
+}
+
+type Chapter struct {
+	StartTime int    `json:"startTime"`
+	EndTime   int    `json:"endTime"`
+	Title     string `json:"title"`
+}
+
+// Title 片段的标题，如「精彩片段 1（42 次标记）」
+func Title(i int, h Highlight) string {
+	return fmt.Sprintf("精彩片段 %d（%d 次标记）", i+1, h.Count)
+}
+
+// ToChapters 将片段转换为章节
+func ToChapters(highlights []Highlight) Chapters {
+	chapters := Chapters{Version: ChaptersVersion, Chapters: []Chapter{}}
+	for i, h := range highlights {
+		chapters.Chapters = append(chapters.Chapters, Chapter{StartTime: h.Start, EndTime: h.End, Title: Title(i, h)})
+	}
+
+	return chapters
+}
+
+// WriteChapters 以 Podcasting 2.0 章节格式输出
+func WriteChapters(w io.Writer, highlights []Highlight) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(ToChapters(highlights))
+}
+
+// WriteVTT 以 WebVTT 输出，每个片段为一条 cue
+func WriteVTT(w io.Writer, highlights []Highlight) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprint(b, "WEBVTT\n")
+	for i, h := range highlights {
+		fmt.Fprintf(b, "\nhighlight-%d\n%s --> %s\n%s\n", i+1, timestamp(h.Start), timestamp(h.End), Title(i, h))
+	}
+
+	return b.Flush()
+}
+
+// timestamp WebVTT 的时间格式，如 01:05:30.000
+func timestamp(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d.000", seconds/3600, seconds%3600/60, seconds%60)
+}
+
+// WriteSVG 将热力图绘制为 width × height 的迷你折线图，片段以浅色背景标出
+func WriteSVG(w io.Writer, bins []Bin, highlights []Highlight, duration, width, height int) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="精彩时间点">`+"\n", width, height, width, height)
+
+	if duration > 0 {
+		for i, h := range highlights {
+			x := float64(h.Start) * float64(width) / float64(duration)
+			span := float64(h.End-h.Start) * float64(width) / float64(duration)
+
+			fmt.Fprintf(b, `  <rect x="%.1f" y="0" width="%.1f" height="%d" fill="#fde68a" fill-opacity="0.6"><title>%s</title></rect>`+"\n", x, span, height, Title(i, h))
+		}
+	}
+
+	peak := 0
+	for _, bin := range bins {
+		peak = max(peak, bin.Count)
+	}
+
+	if len(bins) > 0 {
+		// 上下各留 2 像素，避免线条被裁切
+		points := make([]string, 0, len(bins)+2)
+		points = append(points, fmt.Sprintf("0,%d", height))
+
+		for i, bin := range bins {
+			x := (float64(i) + 0.5) * float64(width) / float64(len(bins))
+			y := float64(height) - 2
+			if peak > 0 {
+				y -= float64(bin.Count) / float64(peak) * float64(height-4)
+			}
+
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+
+		points = append(points, fmt.Sprintf("%d,%d", width, height))
+
+		fmt.Fprintf(b, `  <polygon points="%s" fill="#2563eb" fill-opacity="0.15"></polygon>`+"\n", strings.Join(points, " "))
+		fmt.Fprintf(b, `  <polyline points="%s" fill="none" stroke="#2563eb" stroke-width="1.5" stroke-linejoin="round"></polyline>`+"\n", strings.Join(points[1:len(points)-1], " "))
+	}
+
+	fmt.Fprint(b, "</svg>\n")
+
+	return b.Flush()
+}
@@ -0,0 +1,168 @@
+// Package comments 保存单集的完整评论树（主评论和全部回复），支持按作者、关键词、点赞数和时间查询，以及导出为 JSON、Markdown 和 HTML
+package comments
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Author 评论的作者
+type Author struct {
+	Uid      string `json:"uid"`
+	Nickname string `json:"nickname"`
+}
+
+// Comment 一条评论，回复的 PrimaryId 为所属的主评论
+type Comment struct {
+	Id         string    `json:"id"`
+	PrimaryId  string    `json:"primaryId,omitempty"`
+	Author     Author    `json:"author"`
+	Text       string    `json:"text"`
+	LikeCount  int       `json:"likeCount"`
+	ReplyCount int       `json:"replyCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Archive 一个单集的评论存档，Comments 按抓取顺序保存，同一 id 只保存一次
+type Archive struct {
+	Eid      string    `json:"eid"`
+	Title    string    `json:"title"`
+	Comments []Comment `json:"comments"`
+
+	index map[string]int
+}
+
+// Thread 一条主评论和它的回复
+type Thread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+// Add 加入评论，已存在时更新点赞数等字段，返回是否为新的评论
+func (a *Archive) Add(c Comment) bool {
+	if a.index == nil {
+		a.index = make(map[string]int, len(a.Comments))
+		for i, comment := range a.Comments {
+			a.index[comment.Id] = i
+		}
+	}
+
+	if i, found := a.index[c.Id]; found {
+		a.Comments[i] = c
+
+		return false
+	}
+
+	a.index[c.Id] = len(a.Comments)
+	a.Comments = append(a.Comments, c)
+
+	return true
+}
+
+// Counts 主评论和回复的数量
+func (a *Archive) Counts() (primary, replies int) {
+	for _, c := range a.Comments {
+		if c.PrimaryId == "" {
+			primary++
+		} else {
+			replies++
+		}
+	}
+
+	return primary, replies
+}
+
+// Threads 按主评论的时间顺序组装评论树，回复按时间顺序排列，找不到主评论的回复被忽略
+func (a *Archive) Threads() []Thread {
+	var threads []Thread
+	index := map[string]int{}
+
+	for _, c := range sorted(a.Comments) {
+		if c.PrimaryId == "" {
+			index[c.Id] = len(threads)
+			threads = append(threads, Thread{Comment: c, Replies: []Comment{}})
+		}
+	}
+
+	for _, c := range sorted(a.Comments) {
+		if i, found := index[c.PrimaryId]; found && c.PrimaryId != "" {
+			threads[i].Replies = append(threads[i].Replies, c)
+		}
+	}
+
+	return threads
+}
+
+const (
+	SortTime  = "time"
+	SortLikes = "likes"
+)
+
+// Filter 查询条件，零值表示不限制
+type Filter struct {
+	Author   string    // 作者的 uid，或昵称中包含的文字
+	Keyword  string    // 正文中包含的文字，不区分大小写
+	MinLikes int       // 最少点赞数
+	From     time.Time // 不早于该时间
+	To       time.Time // 早于该时间
+	Primary  bool      // 只查询主评论
+	Sort     string    // time 按时间正序（默认），likes 按点赞数倒序
+}
+
+// Search 返回符合条件的评论
+func (a *Archive) Search(f Filter) []Comment {
+	keyword := strings.ToLower(f.Keyword)
+
+	result := []Comment{}
+	for _, c := range sorted(a.Comments) {
+		if f.Primary && c.PrimaryId != "" {
+			continue
+		}
+
+		if f.Author != "" && c.Author.Uid != f.Author && !strings.Contains(c.Author.Nickname, f.Author) {
+			continue
+		}
+
+		if keyword != "" && !strings.Contains(strings.ToLower(c.Text), keyword) {
+			continue
+		}
+
+		if c.LikeCount < f.MinLikes {
+			continue
+		}
+
+		if !f.From.IsZero() && c.CreatedAt.Before(f.From) {
+			continue
+		}
+
+		if !f.To.IsZero() && !c.CreatedAt.Before(f.To) {
+			continue
+		}
+
+		result = append(result, c)
+	}
+
+	if f.Sort == SortLikes {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].LikeCount > result[j].LikeCount
+		})
+	}
+
+	return result
+}
+
+// sorted 按时间正序排列的副本，时间相同时按 id
+func sorted(comments []Comment) []Comment {
+	result := append([]Comment(nil), comments...)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+
+		return result[i].Id < result[j].Id
+	})
+
+	return result
+}
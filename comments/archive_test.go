@@ -0,0 +1,90 @@
+package comments
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testArchive() *Archive {
+	at := func(minute int) time.Time {
+		return time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC)
+	}
+
+	a := &Archive{Eid: "e"}
+	for _, c := range []Comment{
+		{Id: "2", Author: Author{Uid: "u2", Nickname: "小红"}, Text: "第二条", LikeCount: 9, CreatedAt: at(2)},
+		{Id: "1", Author: Author{Uid: "u1", Nickname: "小明"}, Text: "第一条 Hello", LikeCount: 3, CreatedAt: at(1)},
+		{Id: "r2", PrimaryId: "1", Author: Author{Uid: "u2", Nickname: "小红"}, Text: "回复二", LikeCount: 1, CreatedAt: at(4)},
+		{Id: "r1", PrimaryId: "1", Author: Author{Uid: "u3", Nickname: "小刚"}, Text: "回复一", LikeCount: 5, CreatedAt: at(3)},
+		{Id: "orphan", PrimaryId: "missing", Author: Author{Uid: "u3", Nickname: "小刚"}, Text: "找不到主评论", CreatedAt: at(5)},
+	} {
+		a.Add(c)
+	}
+
+	return a
+}
+
+func ids(comments []Comment) []string {
+	result := []string{}
+	for _, c := range comments {
+		result = append(result, c.Id)
+	}
+
+	return result
+}
+
+func TestArchiveAdd(t *testing.T) {
+	a := testArchive()
+
+	if a.Add(Comment{Id: "1", LikeCount: 10}) {
+		t.Error("Add() existing comment = true, want false")
+	}
+
+	if len(a.Comments) != 5 || a.Comments[1].LikeCount != 10 {
+		t.Errorf("Comments = %+v, want 5 comments with the second one updated", a.Comments)
+	}
+
+	if primary, replies := a.Counts(); primary != 2 || replies != 3 {
+		t.Errorf("Counts() = %d, %d, want 2, 3", primary, replies)
+	}
+}
+
+func TestArchiveThreads(t *testing.T) {
+	threads := testArchive().Threads()
+
+	var got [][]string
+	for _, thread := range threads {
+		got = append(got, append([]string{thread.Id}, ids(thread.Replies)...))
+	}
+
+	if want := [][]string{{"1", "r1", "r2"}, {"2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Threads() = %v, want %v", got, want)
+	}
+}
+
+func TestArchiveSearch(t *testing.T) {
+	a := testArchive()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"默认按时间正序", Filter{}, []string{"1", "2", "r1", "r2", "orphan"}},
+		{"只查询主评论", Filter{Primary: true}, []string{"1", "2"}},
+		{"按作者 uid", Filter{Author: "u2"}, []string{"2", "r2"}},
+		{"按作者昵称", Filter{Author: "刚"}, []string{"r1", "orphan"}},
+		{"关键词不区分大小写", Filter{Keyword: "hello"}, []string{"1"}},
+		{"最少点赞数并按点赞数倒序", Filter{MinLikes: 3, Sort: SortLikes}, []string{"2", "r1", "1"}},
+		{"时间范围包含开始不包含结束", Filter{From: time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC), To: time.Date(2024, 1, 1, 0, 4, 0, 0, time.UTC)}, []string{"2", "r1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(a.Search(tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package comments
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Export 导出的评论树
+type Export struct {
+	Eid      string    `json:"eid"`
+	Title    string    `json:"title"`
+	Primary  int       `json:"primary"`
+	Replies  int       `json:"replies"`
+	Threads  []Thread  `json:"threads"`
+	Exported time.Time `json:"exported"`
+}
+
+func (a *Archive) export(now time.Time) Export {
+	primary, replies := a.Counts()
+
+	return Export{Eid: a.Eid, Title: a.Title, Primary: primary, Replies: replies, Threads: a.Threads(), Exported: now}
+}
+
+// JSON 以 JSON 导出评论树
+func (a *Archive) JSON(w io.Writer, now time.Time) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(a.export(now))
+}
+
+// Markdown 以 Markdown 导出评论树，时间按 loc 显示，回复以引用的形式列在主评论之后
+func (a *Archive) Markdown(w io.Writer, now time.Time, loc *time.Location) error {
+	b := bufio.NewWriter(w)
+	e := a.export(now)
+
+	fmt.Fprintf(b, "# %s\n\n", title(e))
+	fmt.Fprintf(b, "%d 条评论，%d 条回复，导出于 %s\n", e.Primary, e.Replies, now.In(loc).Format(time.DateTime))
+
+	for _, thread := range e.Threads {
+		fmt.Fprintf(b, "\n---\n\n%s\n\n%s\n", heading(thread.Comment, loc), paragraph(thread.Text, ""))
+
+		for _, reply := range thread.Replies {
+			fmt.Fprintf(b, "\n> %s\n>\n%s\n", heading(reply, loc), paragraph(reply.Text, "> "))
+		}
+	}
+
+	return b.Flush()
+}
+
+// HTML 将评论树渲染为单个 HTML 页面，不依赖外部资源
+func (a *Archive) HTML(w io.Writer, now time.Time, loc *time.Location) error {
+	e := a.export(now)
+
+	return exportTemplate.Execute(w, map[string]any{
+		"Title":    title(e),
+		"Export":   e,
+		"Exported": now.In(loc).Format(time.DateTime),
+		"Location": loc,
+	})
+}
+
+func title(e Export) string {
+	if e.Title != "" {
+		return e.Title + " 的评论"
+	}
+
+	return e.Eid + " 的评论"
+}
+
+// heading 作者、时间和点赞数，如 **小宇宙** · 2024-05-16 16:44:04 · 12 赞
+func heading(c Comment, loc *time.Location) string {
+	return fmt.Sprintf("**%s** · %s · %d 赞", c.Author.Nickname, c.CreatedAt.In(loc).Format(time.DateTime), c.LikeCount)
+}
+
+// paragraph 正文的每一行加上 prefix，空行保留为段落分隔
+func paragraph(text, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var exportTemplate = template.Must(template.New("comments").Funcs(template.FuncMap{
+	"datetime": func(t time.Time, loc *time.Location) string {
+		return t.In(loc).Format(time.DateTime)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; color: #1f2937; max-width: 760px; margin: 32px auto; padding: 0 16px; }
+  h1 { margin-bottom: 4px; }
+  .summary { color: #6b7280; margin-top: 0; }
+  .thread { border-bottom: 1px solid #e5e7eb; padding: 16px 0; }
+  .meta { color: #6b7280; font-size: 13px; margin-bottom: 4px; }
+  .meta b { color: #1f2937; }
+  .text { white-space: pre-wrap; line-height: 1.6; }
+  .replies { margin: 12px 0 0 16px; padding-left: 12px; border-left: 3px solid #e5e7eb; }
+  .reply { padding: 6px 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="summary">{{.Export.Primary}} 条评论，{{.Export.Replies}} 条回复，导出于 {{.Exported}}</p>
+{{- $loc := .Location}}
+{{- range .Export.Threads}}
+<div class="thread" id="{{.Id}}">
+  <div class="meta"><b>{{.Author.Nickname}}</b> · {{datetime .CreatedAt $loc}} · {{.LikeCount}} 赞</div>
+  <div class="text">{{.Text}}</div>
+  {{- if .Replies}}
+  <div class="replies">
+    {{- range .Replies}}
+    <div class="reply" id="{{.Id}}">
+      <div class="meta"><b>{{.Author.Nickname}}</b> · {{datetime .CreatedAt $loc}} · {{.LikeCount}} 赞</div>
+      <div class="text">{{.Text}}</div>
+    </div>
+    {{- end}}
+  </div>
+  {{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
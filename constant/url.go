@@ -1,7 +1,9 @@
 package constant
 
+// BaseUrl 上游接口地址，可以通过 -base-url 指向 xyz mock-upstream 等模拟的上游
+var BaseUrl = "https://api.xiaoyuzhoufm.com"
+
 const (
-	BaseUrl    = "https://api.xiaoyuzhoufm.com"
 	UpgradeUrl = "https://api.github.com/repos/ultrazg/xyz/releases/latest"
 	ReleaseUrl = "https://github.com/ultrazg/xyz/releases"
 )
@@ -0,0 +1,189 @@
+// Package directory 保存分类下的全部节目和它们所属的分类、标签，支持上游不提供的筛选和排序
+package directory
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tab 分类下的标签
+type Tab struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Category 分类和它的标签
+type Category struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Tabs []Tab  `json:"tabs"`
+}
+
+// Membership 节目出现在分类下的哪些标签中
+type Membership struct {
+	CategoryId string   `json:"categoryId"`
+	Tabs       []string `json:"tabs"`
+}
+
+// Podcast 目录中的节目
+type Podcast struct {
+	Pid           string       `json:"pid"`
+	Title         string       `json:"title"`
+	Author        string       `json:"author"`
+	Brief         string       `json:"brief,omitempty"`
+	Image         string       `json:"image,omitempty"`
+	Subscriptions int          `json:"subscriptions"`
+	Episodes      int          `json:"episodes"`
+	LatestEpisode *time.Time   `json:"latestEpisode,omitempty"` // 最新单集的发布时间
+	Categories    []Membership `json:"categories"`
+	SeenAt        time.Time    `json:"seenAt"` // 最近一次在分类中出现的时间
+}
+
+// InCategory 节目是否出现在分类中，tab 不为空时还需出现在该标签中
+func (p Podcast) InCategory(categoryId, tab string) bool {
+	for _, m := range p.Categories {
+		if m.CategoryId == categoryId && (tab == "" || slices.Contains(m.Tabs, tab)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Directory 分类和节目，Podcasts 的 key 为 pid
+type Directory struct {
+	Categories []Category          `json:"categories"`
+	Podcasts   map[string]*Podcast `json:"podcasts"`
+}
+
+// Add 加入在分类的标签中出现的节目，已存在时更新节目的信息并合并所属的分类
+func (d *Directory) Add(p Podcast, categoryId, tab string) {
+	if d.Podcasts == nil {
+		d.Podcasts = map[string]*Podcast{}
+	}
+
+	saved, found := d.Podcasts[p.Pid]
+	if found {
+		p.Categories = saved.Categories
+	}
+
+	p.Categories = slices.Clone(p.Categories)
+
+	i := slices.IndexFunc(p.Categories, func(m Membership) bool {
+		return m.CategoryId == categoryId
+	})
+
+	if i < 0 {
+		i = len(p.Categories)
+		p.Categories = append(p.Categories, Membership{CategoryId: categoryId, Tabs: []string{}})
+	}
+
+	if !slices.Contains(p.Categories[i].Tabs, tab) {
+		p.Categories[i].Tabs = append(slices.Clone(p.Categories[i].Tabs), tab)
+	}
+
+	d.Podcasts[p.Pid] = &p
+}
+
+// Count 出现在分类中的节目数
+func (d *Directory) Count(categoryId string) int {
+	n := 0
+	for _, p := range d.Podcasts {
+		if p.InCategory(categoryId, "") {
+			n++
+		}
+	}
+
+	return n
+}
+
+const (
+	SortSubscriptions = "subscriptions"
+	SortEpisodes      = "episodes"
+	SortUpdated       = "updated"
+	SortTitle         = "title"
+)
+
+// Filter 查询条件，零值表示不限制
+type Filter struct {
+	Keyword          string // 标题、作者或简介中包含的文字，不区分大小写
+	CategoryId       string
+	Tab              string // 与 CategoryId 一起使用
+	MinSubscriptions int
+	MaxSubscriptions int
+	MinEpisodes      int
+	MaxEpisodes      int
+	UpdatedAfter     time.Time // 最新单集的发布时间不早于该时间
+	UpdatedBefore    time.Time // 最新单集的发布时间早于该时间
+	Sort             string    // subscriptions 按订阅数（默认），episodes 按单集数，updated 按最新单集的发布时间，均为倒序；title 按标题正序
+	Reverse          bool      // 与默认的顺序相反
+}
+
+// Search 返回符合条件的节目
+func (d *Directory) Search(f Filter) []Podcast {
+	keyword := strings.ToLower(f.Keyword)
+
+	result := []Podcast{}
+	for _, p := range d.Podcasts {
+		if f.CategoryId != "" && !p.InCategory(f.CategoryId, f.Tab) {
+			continue
+		}
+
+		if keyword != "" && !strings.Contains(strings.ToLower(p.Title+"\n"+p.Author+"\n"+p.Brief), keyword) {
+			continue
+		}
+
+		if p.Subscriptions < f.MinSubscriptions || (f.MaxSubscriptions > 0 && p.Subscriptions > f.MaxSubscriptions) {
+			continue
+		}
+
+		if p.Episodes < f.MinEpisodes || (f.MaxEpisodes > 0 && p.Episodes > f.MaxEpisodes) {
+			continue
+		}
+
+		if !f.UpdatedAfter.IsZero() && (p.LatestEpisode == nil || p.LatestEpisode.Before(f.UpdatedAfter)) {
+			continue
+		}
+
+		if !f.UpdatedBefore.IsZero() && (p.LatestEpisode == nil || !p.LatestEpisode.Before(f.UpdatedBefore)) {
+			continue
+		}
+
+		result = append(result, *p)
+	}
+
+	// 先按 pid 排列，保证相同的值之间的顺序不变
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Pid < result[j].Pid
+	})
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if f.Reverse {
+			a, b = b, a
+		}
+
+		switch f.Sort {
+		case SortEpisodes:
+			return a.Episodes > b.Episodes
+		case SortUpdated:
+			return a.latest().After(b.latest())
+		case SortTitle:
+			return a.Title < b.Title
+		default:
+			return a.Subscriptions > b.Subscriptions
+		}
+	})
+
+	return result
+}
+
+func (p Podcast) latest() time.Time {
+	if p.LatestEpisode == nil {
+		return time.Time{}
+	}
+
+	return *p.LatestEpisode
+}
@@ -0,0 +1,426 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Params 一次查询的参数，MaxDepth、MaxCost 为 0 时不限制
+type Params struct {
+	Query         string
+	OperationName string
+	Variables     map[string]any
+	MaxDepth      int
+	MaxCost       int
+}
+
+// Result 查询结果。请求本身不合法（语法错误、校验失败、超出限制）时 Data 为 nil，不执行任何解析
+type Result struct {
+	Data       any            `json:"data,omitempty"`
+	Errors     []*Error       `json:"errors,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Error GraphQL 错误，resolver 返回的错误实现 Extensions() 时会附带到 extensions 中
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func locatedError(loc Location, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// 查询代价和深度超出限制时的错误码
+const (
+	CodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+)
+
+// errNull 值因错误而为 null，错误已记录，向上传递直到可以为 null 的位置
+var errNull = errors.New("null")
+
+// Execute 解析、校验并执行查询
+func (s *Schema) Execute(ctx context.Context, params Params) *Result {
+	doc, err := Parse(params.Query)
+	if err != nil {
+		return requestError(err)
+	}
+
+	operation, err := selectOperation(doc, params.OperationName)
+	if err != nil {
+		return requestError(err)
+	}
+
+	if operation.Type != "query" {
+		return requestError(locatedError(operation.Location, "%s operations are not supported", operation.Type))
+	}
+
+	variables := map[string]any{}
+	defined := map[string]bool{}
+
+	for _, definition := range operation.Variables {
+		t, err := s.inputType(definition.Type)
+		if err != nil {
+			return requestError(locatedError(definition.Location, "variable $%s: %s", definition.Name, err))
+		}
+
+		defined[definition.Name] = true
+
+		value, provided := params.Variables[definition.Name]
+		if !provided && definition.Default != nil {
+			if variables[definition.Name], err = coerceLiteral(t, definition.Default, nil, nil); err != nil {
+				return requestError(locatedError(definition.Location, "variable $%s: %s", definition.Name, err))
+			}
+
+			continue
+		}
+
+		if variables[definition.Name], err = coerceVariable(t, value); err != nil {
+			return requestError(locatedError(definition.Location, "variable $%s: %s", definition.Name, err))
+		}
+	}
+
+	v := &validator{schema: s, doc: doc, variables: variables, defined: defined}
+	depth, cost := v.selections(s.Query, operation.Selections, 1)
+
+	if len(v.errors) > 0 {
+		return &Result{Errors: v.errors}
+	}
+
+	extensions := map[string]any{"depth": depth, "cost": cost}
+
+	if params.MaxDepth > 0 && depth > params.MaxDepth {
+		e := &Error{Message: fmt.Sprintf("query depth %d exceeds the limit of %d", depth, params.MaxDepth), Extensions: map[string]any{"code": CodeQueryTooDeep}}
+
+		return &Result{Errors: []*Error{e}, Extensions: extensions}
+	}
+
+	if params.MaxCost > 0 && cost > params.MaxCost {
+		e := &Error{Message: fmt.Sprintf("query cost %d exceeds the limit of %d", cost, params.MaxCost), Extensions: map[string]any{"code": CodeQueryTooComplex}}
+
+		return &Result{Errors: []*Error{e}, Extensions: extensions}
+	}
+
+	e := &executor{doc: doc, variables: variables, defined: defined}
+
+	data, err := e.object(ctx, s.Query, nil, operation.Selections, nil)
+
+	result := &Result{Data: data, Errors: e.errors, Extensions: extensions}
+	if err != nil {
+		result.Data = json.RawMessage("null")
+	}
+
+	return result
+}
+
+func requestError(err error) *Result {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Message: err.Error()}
+	}
+
+	return &Result{Errors: []*Error{e}}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains multiple operations")
+		}
+
+		return doc.Operations[0], nil
+	}
+
+	for _, operation := range doc.Operations {
+		if operation.Name == name {
+			return operation, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	doc       *Document
+	variables map[string]any
+	defined   map[string]bool
+
+	mu     sync.Mutex
+	errors []*Error
+}
+
+func (e *executor) addError(err error, loc Location, path []any) {
+	gqlError := &Error{Message: err.Error(), Locations: []Location{loc}, Path: append([]any{}, path...)}
+
+	if extended, ok := err.(interface{ Extensions() map[string]any }); ok {
+		gqlError.Extensions = extended.Extensions()
+	}
+
+	e.mu.Lock()
+	e.errors = append(e.errors, gqlError)
+	e.mu.Unlock()
+}
+
+// collectedField 结果中的一个字段，同名的字段选择会合并
+type collectedField struct {
+	key        string
+	selections []*FieldSelection
+}
+
+func (e *executor) collect(selections []Selection, fields []*collectedField, index map[string]*collectedField) []*collectedField {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *FieldSelection:
+			if !e.included(s.Directives) {
+				continue
+			}
+
+			if field, found := index[s.ResponseKey()]; found {
+				field.selections = append(field.selections, s)
+
+				continue
+			}
+
+			field := &collectedField{key: s.ResponseKey(), selections: []*FieldSelection{s}}
+			index[field.key] = field
+			fields = append(fields, field)
+		case *FragmentSpread:
+			if e.included(s.Directives) {
+				fields = e.collect(e.doc.Fragments[s.Name].Selections, fields, index)
+			}
+		case *InlineFragment:
+			if e.included(s.Directives) {
+				fields = e.collect(s.Selections, fields, index)
+			}
+		}
+	}
+
+	return fields
+}
+
+func (e *executor) included(directives []*Directive) bool {
+	for _, directive := range directives {
+		condition, _ := directiveCondition(directive, e.variables, e.defined)
+
+		if directive.Name == "skip" && condition || directive.Name == "include" && !condition {
+			return false
+		}
+	}
+
+	return true
+}
+
+// object 并发解析对象的各个字段，字段的 resolver 可以在等待数据时让其它字段继续执行，以便 Loader 合并请求
+func (e *executor) object(ctx context.Context, object *Object, source any, selections []Selection, path []any) (any, error) {
+	fields := e.collect(selections, nil, map[string]*collectedField{})
+
+	values := make([]any, len(fields))
+	errs := make([]error, len(fields))
+
+	var wg sync.WaitGroup
+	for i, field := range fields {
+		wg.Add(1)
+
+		go func(i int, field *collectedField) {
+			defer wg.Done()
+
+			values[i], errs[i] = e.field(ctx, object, source, field, append(append([]any{}, path...), field.key))
+		}(i, field)
+	}
+
+	wg.Wait()
+
+	result := &orderedMap{values: make(map[string]any, len(fields))}
+	for i, field := range fields {
+		if errs[i] != nil {
+			return nil, errNull
+		}
+
+		result.set(field.key, values[i])
+	}
+
+	return result, nil
+}
+
+func (e *executor) field(ctx context.Context, object *Object, source any, collected *collectedField, path []any) (any, error) {
+	selection := collected.selections[0]
+
+	if selection.Name == "__typename" {
+		return object.Name, nil
+	}
+
+	field := object.Field(selection.Name)
+
+	args, err := coerceArguments(field, selection.Arguments, e.variables, e.defined)
+	if err != nil {
+		e.addError(err, selection.Location, path)
+
+		return absorb(field.Type, nil, errNull)
+	}
+
+	var value any
+
+	if field.Resolve != nil {
+		value, err = field.Resolve(ResolveParams{Context: ctx, Source: source, Args: args})
+	} else if m, ok := source.(map[string]any); ok {
+		value = m[field.Name]
+	}
+
+	if err != nil {
+		e.addError(err, selection.Location, path)
+
+		return absorb(field.Type, nil, errNull)
+	}
+
+	var subselections []Selection
+	for _, s := range collected.selections {
+		subselections = append(subselections, s.Selections...)
+	}
+
+	value, err = e.complete(ctx, field.Type, selection, subselections, value, path)
+
+	return absorb(field.Type, value, err)
+}
+
+// absorb 可以为 null 的位置吸收向上传递的 null
+func absorb(t Type, value any, err error) (any, error) {
+	if err == errNull {
+		if _, required := t.(*NonNull); !required {
+			return nil, nil
+		}
+	}
+
+	return value, err
+}
+
+func (e *executor) complete(ctx context.Context, t Type, selection *FieldSelection, selections []Selection, value any, path []any) (any, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		v, err := e.complete(ctx, nonNull.OfType, selection, selections, value, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if v == nil {
+			e.addError(fmt.Errorf("cannot return null for non-nullable field %s", selection.Name), selection.Location, path)
+
+			return nil, errNull
+		}
+
+		return v, nil
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	switch typ := t.(type) {
+	case *List:
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			e.addError(fmt.Errorf("expected a list for field %s, got %T", selection.Name, value), selection.Location, path)
+
+			return nil, errNull
+		}
+
+		values := make([]any, items.Len())
+		errs := make([]error, items.Len())
+
+		var wg sync.WaitGroup
+		for i := 0; i < items.Len(); i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				itemPath := append(append([]any{}, path...), i)
+				value, err := e.complete(ctx, typ.OfType, selection, selections, items.Index(i).Interface(), itemPath)
+				values[i], errs[i] = absorb(typ.OfType, value, err)
+			}(i)
+		}
+
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	case *Scalar:
+		v, err := typ.Serialize(value)
+		if err != nil {
+			e.addError(err, selection.Location, path)
+
+			return nil, errNull
+		}
+
+		return v, nil
+	case *Enum:
+		if s, ok := value.(string); ok {
+			for _, v := range typ.Values {
+				if v == s {
+					return s, nil
+				}
+			}
+		}
+
+		e.addError(fmt.Errorf("cannot represent %v as enum %s", value, typ.Name), selection.Location, path)
+
+		return nil, errNull
+	case *Object:
+		return e.object(ctx, typ, value, selections, path)
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// orderedMap 按查询中字段的顺序输出 JSON
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, value any) {
+	m.keys = append(m.keys, key)
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(v)
+	}
+
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
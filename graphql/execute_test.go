@@ -0,0 +1,335 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type podcastLoaderKey struct{}
+
+// testSchema 节目和单集的 schema，podcast 通过请求中的 Loader 加载，keys 记录每个批次加载的 pid
+func testSchema(t *testing.T) (*Schema, func() (context.Context, *[][]string)) {
+	t.Helper()
+
+	episode := &Object{Name: "Episode", Fields: []*Field{
+		{Name: "eid", Type: &NonNull{OfType: ID}},
+		{Name: "title", Type: String},
+	}}
+
+	podcast := &Object{Name: "Podcast", Fields: []*Field{
+		{Name: "pid", Type: &NonNull{OfType: ID}},
+		{Name: "title", Type: String},
+		{
+			Name: "episodes",
+			Type: &List{OfType: &NonNull{OfType: episode}},
+			Args: []*Arg{{Name: "first", Type: Int}},
+			Resolve: func(p ResolveParams) (any, error) {
+				first, _ := p.Args["first"].(int)
+				if first == 0 {
+					first = 2
+				}
+
+				pid := p.Source.(map[string]any)["pid"]
+
+				var episodes []map[string]any
+				for i := 1; i <= first; i++ {
+					episodes = append(episodes, map[string]any{"eid": fmt.Sprintf("%s-%d", pid, i), "title": fmt.Sprintf("第 %d 期", i)})
+				}
+
+				return episodes, nil
+			},
+		},
+	}}
+
+	sortEnum := &Enum{Name: "Sort", Values: []string{"HOT", "TIME"}}
+
+	query := &Object{Name: "Query", Fields: []*Field{
+		{
+			Name: "podcast",
+			Type: podcast,
+			Args: []*Arg{{Name: "pid", Type: &NonNull{OfType: ID}}},
+			Resolve: func(p ResolveParams) (any, error) {
+				loader := p.Context.Value(podcastLoaderKey{}).(*Loader[string, map[string]any])
+
+				return loader.Load(p.Context, p.Args["pid"].(string))
+			},
+		},
+		{
+			Name:     "podcasts",
+			Type:     &NonNull{OfType: &List{OfType: podcast}},
+			Args:     []*Arg{{Name: "first", Type: Int}, {Name: "ids", Type: &List{OfType: &NonNull{OfType: ID}}}},
+			ListSize: 20,
+			Resolve: func(p ResolveParams) (any, error) {
+				ids, _ := p.Args["ids"].([]any)
+
+				var podcasts []any
+				for _, id := range ids {
+					podcasts = append(podcasts, map[string]any{"pid": id, "title": "节目 " + id.(string)})
+				}
+
+				return podcasts, nil
+			},
+		},
+		{
+			Name: "sort",
+			Type: sortEnum,
+			Args: []*Arg{{Name: "by", Type: sortEnum, Default: "HOT"}},
+			Cost: -1,
+			Resolve: func(p ResolveParams) (any, error) {
+				return p.Args["by"], nil
+			},
+		},
+	}}
+
+	schema, err := NewSchema(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newContext := func() (context.Context, *[][]string) {
+		var (
+			mu      sync.Mutex
+			batches [][]string
+		)
+
+		loader := NewLoader(func(ctx context.Context, pids []string) ([]map[string]any, []error) {
+			mu.Lock()
+			batch := append([]string(nil), pids...)
+			sort.Strings(batch)
+			batches = append(batches, batch)
+			mu.Unlock()
+
+			values := make([]map[string]any, len(pids))
+			errs := make([]error, len(pids))
+			for i, pid := range pids {
+				if pid == "missing" {
+					errs[i] = fmt.Errorf("podcast %s not found", pid)
+				} else {
+					values[i] = map[string]any{"pid": pid, "title": "节目 " + pid}
+				}
+			}
+
+			return values, errs
+		}, time.Millisecond, 0)
+
+		return context.WithValue(context.Background(), podcastLoaderKey{}, loader), &batches
+	}
+
+	return schema, newContext
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestExecute(t *testing.T) {
+	schema, newContext := testSchema(t)
+
+	tests := []struct {
+		name    string
+		params  Params
+		data    string
+		errors  string
+		batches [][]string
+	}{
+		{
+			name:    "别名和相同的 key 合并为一次加载",
+			params:  Params{Query: `{ a: podcast(pid: "1") { title } b: podcast(pid: "2") { pid } c: podcast(pid: "1") { pid } }`},
+			data:    `{"a":{"title":"节目 1"},"b":{"pid":"2"},"c":{"pid":"1"}}`,
+			batches: [][]string{{"1", "2"}},
+		},
+		{
+			name: "变量和默认值",
+			params: Params{
+				Query:     `query Q($pid: ID!, $first: Int = 1) { podcast(pid: $pid) { episodes(first: $first) { eid } } }`,
+				Variables: map[string]any{"pid": "p"},
+			},
+			data:    `{"podcast":{"episodes":[{"eid":"p-1"}]}}`,
+			batches: [][]string{{"p"}},
+		},
+		{
+			name: "列表变量接受单个值",
+			params: Params{
+				Query:     `query ($ids: [ID!]) { podcasts(ids: $ids) { pid } }`,
+				Variables: map[string]any{"ids": "x"},
+			},
+			data: `{"podcasts":[{"pid":"x"}]}`,
+		},
+		{
+			name:   "片段、内联片段和同名字段合并",
+			params: Params{Query: `{ podcasts(ids: ["1"]) { ...Info ... on Podcast { pid } pid } } fragment Info on Podcast { title }`},
+			data:   `{"podcasts":[{"title":"节目 1","pid":"1"}]}`,
+		},
+		{
+			name: "skip 和 include 指令",
+			params: Params{
+				Query:     `query ($full: Boolean!) { podcasts(ids: ["1"]) { pid title @include(if: $full) episodes @skip(if: true) { eid } } }`,
+				Variables: map[string]any{"full": false},
+			},
+			data: `{"podcasts":[{"pid":"1"}]}`,
+		},
+		{
+			name:   "枚举参数的默认值和 __typename",
+			params: Params{Query: `{ a: sort b: sort(by: TIME) __typename }`},
+			data:   `{"a":"HOT","b":"TIME","__typename":"Query"}`,
+		},
+		{
+			name:    "resolver 的错误使可以为 null 的字段为 null",
+			params:  Params{Query: `{ podcast(pid: "missing") { pid } other: podcast(pid: "1") { pid } }`},
+			data:    `{"podcast":null,"other":{"pid":"1"}}`,
+			errors:  `[{"message":"podcast missing not found","locations":[{"line":1,"column":3}],"path":["podcast"]}]`,
+			batches: [][]string{{"1", "missing"}},
+		},
+		{
+			name: "按名称选择操作",
+			params: Params{
+				Query:         `query A { a: sort } query B { b: sort }`,
+				OperationName: "B",
+			},
+			data: `{"b":"HOT"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, batches := newContext()
+			result := schema.Execute(ctx, tt.params)
+
+			if got := marshal(t, result.Data); got != tt.data {
+				t.Errorf("data = %s, want %s", got, tt.data)
+			}
+
+			errors := ""
+			if len(result.Errors) > 0 {
+				errors = marshal(t, result.Errors)
+			}
+
+			if errors != tt.errors {
+				t.Errorf("errors = %s, want %s", errors, tt.errors)
+			}
+
+			if !reflect.DeepEqual(*batches, tt.batches) {
+				t.Errorf("batches = %v, want %v", *batches, tt.batches)
+			}
+		})
+	}
+}
+
+func TestExecuteRequestErrors(t *testing.T) {
+	schema, newContext := testSchema(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		operation string
+		want      []string
+	}{
+		{"语法错误", `{ podcast(pid: "1") { pid }`, nil, "", []string{"syntax error: unexpected end of document"}},
+		{"不支持 mutation", `mutation { podcast(pid: "1") { pid } }`, nil, "", []string{"mutation operations are not supported"}},
+		{"多个操作时缺少 operationName", `query A { sort } query B { sort }`, nil, "", []string{"operationName is required when the document contains multiple operations"}},
+		{"未知的操作", `query A { sort }`, nil, "C", []string{`unknown operation "C"`}},
+		{"缺少必填的变量", `query ($pid: ID!) { podcast(pid: $pid) { pid } }`, nil, "", []string{"variable $pid: expected a non-null ID"}},
+		{"变量类型错误", `query ($first: Int) { podcasts(first: $first) { pid } }`, map[string]any{"first": 1.5}, "", []string{"variable $first: expected an Int"}},
+		{"变量不能为对象类型", `query ($p: Podcast) { sort }`, nil, "", []string{"variable $p: type Podcast cannot be used as an input"}},
+		{"未知的变量类型", `query ($p: Unknown) { sort }`, nil, "", []string{"variable $p: unknown type Unknown"}},
+		{"未知的字段", `{ podcast(pid: "1") { name } }`, nil, "", []string{`cannot query field "name" on type Podcast`}},
+		{"缺少必填参数", `{ podcast { pid } }`, nil, "", []string{`argument "pid" of type ID! is required on field "podcast"`}},
+		{"未知的参数", `{ sort(order: HOT) }`, nil, "", []string{`unknown argument "order" on field "sort"`}},
+		{"参数类型错误", `{ podcasts(first: "1") { pid } }`, nil, "", []string{`argument "first": expected an Int`}},
+		{"枚举值错误", `{ sort(by: NEW) }`, nil, "", []string{`argument "by": expected a value of enum Sort`}},
+		{"未定义的变量", `{ podcast(pid: $pid) { pid } }`, nil, "", []string{`argument "pid": variable $pid is not defined`}},
+		{"对象字段缺少子字段", `{ podcast(pid: "1") }`, nil, "", []string{`field "podcast" of type Podcast must have a selection of subfields`}},
+		{"标量字段有子字段", `{ sort { name } }`, nil, "", []string{`field "sort" of type Sort must not have a selection`}},
+		{"未知的片段", `{ ...Missing }`, nil, "", []string{`unknown fragment "Missing"`}},
+		{"片段类型不匹配", `{ ...F } fragment F on Podcast { pid }`, nil, "", []string{`fragment "F" on Podcast cannot be spread within type Query`}},
+		{"片段引用自身", `{ podcasts { ...F } } fragment F on Podcast { ...F }`, nil, "", []string{`fragment "F" spreads itself`}},
+		{"未知的指令", `{ sort @defer }`, nil, "", []string{"unknown directive @defer"}},
+		{"同时返回多个校验错误", `{ a { b } sort(by: 1) }`, nil, "", []string{`cannot query field "a" on type Query`, `argument "by": expected a value of enum Sort`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, batches := newContext()
+			result := schema.Execute(ctx, Params{Query: tt.query, Variables: tt.variables, OperationName: tt.operation})
+
+			var messages []string
+			for _, e := range result.Errors {
+				messages = append(messages, e.Message)
+			}
+
+			if result.Data != nil || !reflect.DeepEqual(messages, tt.want) {
+				t.Errorf("Execute() = %v %q, want no data and %q", result.Data, messages, tt.want)
+			}
+
+			if len(*batches) > 0 {
+				t.Errorf("batches = %v, want nothing resolved", *batches)
+			}
+		})
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	schema, newContext := testSchema(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		maxDepth int
+		maxCost  int
+		depth    int
+		cost     int
+		code     string
+	}{
+		// podcast 1，episodes 1，单集的字段没有 resolver
+		{"单个对象", `{ podcast(pid: "1") { title episodes(first: 5) { title } } }`, 0, 0, 3, 2, ""},
+		// podcasts 1，first 为 3 时 episodes 按 3 次计算
+		{"first 参数作为列表的数量", `{ podcasts(first: 3) { episodes { eid } } }`, 0, 0, 3, 4, ""},
+		// 没有 first 时按 ListSize 20 估算，episodes 没有 ListSize 时按 10 估算
+		{"没有 first 时按 ListSize 估算", `{ podcasts { episodes { eid } } }`, 0, 0, 3, 21, ""},
+		{"嵌套列表", `{ podcasts(first: 2) { episodes(first: 3) { eid } } a: podcasts(first: 4) { pid } }`, 0, 0, 3, 4, ""},
+		{"Cost 为负数时代价为 0", `{ sort }`, 0, 0, 1, 0, ""},
+		{"片段计入深度和代价", `{ podcasts(first: 2) { ...F } } fragment F on Podcast { episodes { eid } }`, 0, 0, 3, 3, ""},
+		{"代价超出限制", `{ podcasts { episodes { eid } } }`, 0, 20, 3, 21, CodeQueryTooComplex},
+		{"深度超出限制", `{ podcast(pid: "1") { episodes { eid } } }`, 2, 0, 3, 2, CodeQueryTooDeep},
+		{"等于限制时可以执行", `{ podcast(pid: "1") { episodes { eid } } }`, 3, 2, 3, 2, ""},
+		{"代价的累加不会溢出", `{ a: podcasts(first: 2000000000) { episodes { eid } } b: podcasts(first: 2000000000) { episodes { eid } } }`, 0, 100, 3, maxEstimate, CodeQueryTooComplex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, batches := newContext()
+			result := schema.Execute(ctx, Params{Query: tt.query, MaxDepth: tt.maxDepth, MaxCost: tt.maxCost})
+
+			if result.Extensions["depth"] != tt.depth || result.Extensions["cost"] != tt.cost {
+				t.Errorf("extensions = %v, want depth %d cost %d", result.Extensions, tt.depth, tt.cost)
+			}
+
+			code := ""
+			if len(result.Errors) > 0 {
+				code, _ = result.Errors[0].Extensions["code"].(string)
+			}
+
+			if code != tt.code {
+				t.Errorf("errors = %s, want code %q", marshal(t, result.Errors), tt.code)
+			}
+
+			if tt.code != "" && (result.Data != nil || len(*batches) > 0) {
+				t.Errorf("Execute() resolved %v with data %v, want nothing resolved", *batches, result.Data)
+			}
+		})
+	}
+}
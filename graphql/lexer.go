@@ -0,0 +1,246 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	line  int
+	col   int
+}
+
+// lexer 将查询拆分为 token，忽略空白、逗号和注释
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+
+		l.pos++
+	}
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+
+	t := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		t.kind = tokenEOF
+
+		return t, nil
+	}
+
+	c := l.src[l.pos]
+
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		t.kind, t.value = tokenPunct, "..."
+		l.advance(3)
+	case strings.ContainsRune("!$():=@[]{}|&", rune(c)):
+		t.kind, t.value = tokenPunct, string(c)
+		l.advance(1)
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+
+		t.kind, t.value = tokenName, l.src[start:l.pos]
+	case c == '-' || isDigit(c):
+		return l.number(t)
+	case c == '"':
+		return l.string(t)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+
+		return t, fmt.Errorf("unexpected character %q", r)
+	}
+
+	return t, nil
+}
+
+func (l *lexer) number(t token) (token, error) {
+	start := l.pos
+	t.kind = tokenInt
+
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+			n++
+		}
+
+		return n
+	}
+
+	if digits() == 0 {
+		return t, fmt.Errorf("invalid number")
+	}
+
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		t.kind = tokenFloat
+		l.advance(1)
+
+		if digits() == 0 {
+			return t, fmt.Errorf("invalid number")
+		}
+	}
+
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		t.kind = tokenFloat
+		l.advance(1)
+
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+
+		if digits() == 0 {
+			return t, fmt.Errorf("invalid number")
+		}
+	}
+
+	t.value = l.src[start:l.pos]
+
+	return t, nil
+}
+
+// string 读取字符串，支持 \uXXXX 等转义和 """ 块字符串
+func (l *lexer) string(t token) (token, error) {
+	t.kind = tokenString
+
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		l.advance(3)
+
+		end := strings.Index(l.src[l.pos:], `"""`)
+		if end < 0 {
+			return t, fmt.Errorf("unterminated string")
+		}
+
+		t.value = strings.TrimSpace(l.src[l.pos : l.pos+end])
+		l.advance(end + 3)
+
+		return t, nil
+	}
+
+	l.advance(1)
+
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return t, fmt.Errorf("unterminated string")
+		}
+
+		c := l.src[l.pos]
+		if c == '"' {
+			l.advance(1)
+
+			break
+		}
+
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			b.WriteRune(r)
+			l.advance(size)
+
+			continue
+		}
+
+		if l.pos+1 >= len(l.src) {
+			return t, fmt.Errorf("unterminated string")
+		}
+
+		switch e := l.src[l.pos+1]; e {
+		case '"', '\\', '/':
+			b.WriteByte(e)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if l.pos+6 > len(l.src) {
+				return t, fmt.Errorf("invalid unicode escape")
+			}
+
+			r, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+			if err != nil {
+				return t, fmt.Errorf("invalid unicode escape")
+			}
+
+			b.WriteRune(rune(r))
+			l.advance(4)
+		default:
+			return t, fmt.Errorf("invalid escape \\%c", e)
+		}
+
+		l.advance(2)
+	}
+
+	t.value = b.String()
+
+	return t, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,75 @@
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		tokens []string
+		err    string
+	}{
+		{"忽略空白、逗号和注释", "{ a, b # 注释\n }", []string{"{", "a", "b", "}"}, ""},
+		{"标点和展开符", "...on $x: [ID!]! @skip", []string{"...", "on", "$", "x", ":", "[", "ID", "!", "]", "!", "@", "skip"}, ""},
+		{"整数和浮点数", "-1 0 1.5 2e3 1.5E-2", []string{"-1", "0", "1.5", "2e3", "1.5E-2"}, ""},
+		{"字符串转义", `"a\"b\n你"`, []string{"a\"b\n你"}, ""},
+		{"块字符串", `"""  多行` + "\n" + `文本 """`, []string{"多行\n文本"}, ""},
+		{"不完整的数字", "1.", nil, "invalid number"},
+		{"未结束的字符串", `"abc`, nil, "unterminated string"},
+		{"字符串中的换行", "\"a\nb\"", nil, "unterminated string"},
+		{"不支持的转义", `"\x"`, nil, `invalid escape \x`},
+		{"不支持的字符", "{ a ? }", []string{"{", "a"}, `unexpected character '?'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLexer(tt.src)
+
+			var tokens []string
+			err := ""
+			for {
+				token, e := l.next()
+				if e != nil {
+					err = e.Error()
+
+					break
+				}
+
+				if token.kind == tokenEOF {
+					break
+				}
+
+				tokens = append(tokens, token.value)
+			}
+
+			if !reflect.DeepEqual(tokens, tt.tokens) || err != tt.err {
+				t.Errorf("tokens = %q, %q, want %q, %q", tokens, err, tt.tokens, tt.err)
+			}
+		})
+	}
+}
+
+func TestLexerLocation(t *testing.T) {
+	l := newLexer("{\n  podcast\n}")
+
+	var locations []Location
+	for {
+		token, err := l.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token.kind == tokenEOF {
+			break
+		}
+
+		locations = append(locations, Location{token.line, token.col})
+	}
+
+	if want := []Location{{1, 1}, {2, 3}, {3, 1}}; !reflect.DeepEqual(locations, want) {
+		t.Errorf("locations = %v, want %v", locations, want)
+	}
+}
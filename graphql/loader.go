@@ -0,0 +1,110 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc 一次加载多个 key，返回的结果和错误与 keys 一一对应
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, []error)
+
+// Loader 合并同一次查询中对同一类数据的加载：Wait 时间内的 Load 调用合并为一次 BatchFunc 调用，相同的 key 只加载一次
+//
+// Loader 会缓存结果，应在每个请求中新建
+type Loader[K comparable, V any] struct {
+	batch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[K]*loaderEntry[V]
+	pending []K
+	batches int
+}
+
+type loaderEntry[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewLoader 创建 Loader，maxBatch 为单次 BatchFunc 最多的 key 数，0 为不限制
+func NewLoader[K comparable, V any](batch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{batch: batch, wait: wait, maxBatch: maxBatch, cache: map[K]*loaderEntry[V]{}}
+}
+
+// Load 加载一个 key，等待所在批次完成后返回
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+
+	entry, found := l.cache[key]
+	if !found {
+		entry = &loaderEntry[V]{done: make(chan struct{})}
+		l.cache[key] = entry
+		l.pending = append(l.pending, key)
+
+		switch {
+		case l.maxBatch > 0 && len(l.pending) >= l.maxBatch:
+			keys := l.pending
+			l.pending = nil
+
+			go l.dispatch(ctx, keys)
+		case len(l.pending) == 1:
+			time.AfterFunc(l.wait, func() {
+				l.mu.Lock()
+				keys := l.pending
+				l.pending = nil
+				l.mu.Unlock()
+
+				if len(keys) > 0 {
+					l.dispatch(ctx, keys)
+				}
+			})
+		}
+	}
+
+	l.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.value, entry.err
+	case <-ctx.Done():
+		var zero V
+
+		return zero, ctx.Err()
+	}
+}
+
+// Batches 已执行的批次数
+func (l *Loader[K, V]) Batches() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.batches
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, keys []K) {
+	l.mu.Lock()
+	l.batches++
+	l.mu.Unlock()
+
+	values, errs := l.batch(ctx, keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, key := range keys {
+		entry := l.cache[key]
+
+		if i < len(values) {
+			entry.value = values[i]
+		}
+
+		if i < len(errs) {
+			entry.err = errs[i]
+		}
+
+		close(entry.done)
+	}
+}
@@ -0,0 +1,138 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoader(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBatch int
+		keys     []int
+		batches  [][]int
+	}{
+		{"同一批次中相同的 key 只加载一次", 0, []int{1, 2, 1, 3, 2}, [][]int{{1, 2, 3}}},
+		{"超过 maxBatch 时分为多个批次", 2, []int{1, 2, 3, 4, 5}, [][]int{{1, 2}, {3, 4}, {5}}},
+		{"相同的 key 不计入 maxBatch", 2, []int{1, 1, 1, 2}, [][]int{{1, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu      sync.Mutex
+				batches [][]int
+			)
+
+			loader := NewLoader(func(ctx context.Context, keys []int) ([]int, []error) {
+				mu.Lock()
+				batch := append([]int(nil), keys...)
+				sort.Ints(batch)
+				batches = append(batches, batch)
+				mu.Unlock()
+
+				values := make([]int, len(keys))
+				for i, key := range keys {
+					values[i] = key * 10
+				}
+
+				return values, nil
+			}, 20*time.Millisecond, tt.maxBatch)
+
+			// 依次发起 Load，使 key 按顺序进入批次
+			results := make([]int, len(tt.keys))
+
+			var wg sync.WaitGroup
+			for i, key := range tt.keys {
+				wg.Add(1)
+
+				go func(i, key int) {
+					defer wg.Done()
+
+					results[i], _ = loader.Load(context.Background(), key)
+				}(i, key)
+
+				time.Sleep(time.Millisecond)
+			}
+
+			wg.Wait()
+
+			for i, key := range tt.keys {
+				if results[i] != key*10 {
+					t.Errorf("Load(%d) = %d, want %d", key, results[i], key*10)
+				}
+			}
+
+			sort.Slice(batches, func(i, j int) bool {
+				return batches[i][0] < batches[j][0]
+			})
+
+			if !reflect.DeepEqual(batches, tt.batches) || loader.Batches() != len(tt.batches) {
+				t.Errorf("batches = %v (%d), want %v", batches, loader.Batches(), tt.batches)
+			}
+		})
+	}
+}
+
+func TestLoaderCache(t *testing.T) {
+	calls := 0
+	failed := errors.New("not found")
+
+	loader := NewLoader(func(ctx context.Context, keys []string) ([]string, []error) {
+		calls++
+
+		errs := make([]error, len(keys))
+		for i, key := range keys {
+			if key == "missing" {
+				errs[i] = failed
+			}
+		}
+
+		return keys, errs
+	}, time.Millisecond, 0)
+
+	tests := []struct {
+		name  string
+		key   string
+		want  string
+		err   error
+		calls int
+	}{
+		{"第一次加载", "a", "a", nil, 1},
+		{"已加载的 key 从缓存返回", "a", "a", nil, 1},
+		{"按 key 返回错误", "missing", "missing", failed, 2},
+		{"错误同样被缓存", "missing", "missing", failed, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := loader.Load(context.Background(), tt.key)
+			if value != tt.want || !errors.Is(err, tt.err) || calls != tt.calls {
+				t.Errorf("Load(%q) = %q, %v after %d calls, want %q, %v after %d", tt.key, value, err, calls, tt.want, tt.err, tt.calls)
+			}
+		})
+	}
+}
+
+func TestLoaderContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	loader := NewLoader(func(ctx context.Context, keys []int) ([]int, []error) {
+		<-release
+
+		return keys, nil
+	}, time.Millisecond, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := loader.Load(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Load() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,643 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// Document 解析后的查询文档
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation 查询文档中的一个操作，只支持 query
+type Operation struct {
+	Type       string
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []Selection
+	Location   Location
+}
+
+type VariableDefinition struct {
+	Name     string
+	Type     typeRef
+	Default  *Value
+	Location Location
+}
+
+// typeRef 查询中声明的变量类型，如 [ID!]!
+type typeRef struct {
+	Name    string
+	List    *typeRef
+	NonNull bool
+}
+
+func (t typeRef) String() string {
+	s := t.Name
+	if t.List != nil {
+		s = "[" + t.List.String() + "]"
+	}
+
+	if t.NonNull {
+		s += "!"
+	}
+
+	return s
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+	Location      Location
+}
+
+// Selection 为 *FieldSelection、*FragmentSpread 或 *InlineFragment
+type Selection interface {
+	location() Location
+}
+
+type FieldSelection struct {
+	Alias      string
+	Name       string
+	Arguments  []*Argument
+	Directives []*Directive
+	Selections []Selection
+	Location   Location
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Location   Location
+}
+
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+	Location      Location
+}
+
+func (f *FieldSelection) location() Location { return f.Location }
+func (f *FragmentSpread) location() Location { return f.Location }
+func (f *InlineFragment) location() Location { return f.Location }
+
+// ResponseKey 字段在结果中的名称，有别名时为别名
+func (f *FieldSelection) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return f.Name
+}
+
+type Argument struct {
+	Name  string
+	Value *Value
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Location  Location
+}
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// Value 查询中的字面量或变量
+type Value struct {
+	Kind     valueKind
+	Raw      string
+	List     []*Value
+	Fields   []*ObjectField
+	Location Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
+
+// Location 在查询中的位置，从 1 开始
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+// Parse 解析查询文档
+func Parse(query string) (*Document, error) {
+	p := &parser{lexer: newLexer(query)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: map[string]*Fragment{}}
+
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: selections, Location: Location{1, 1}})
+		case p.token.kind == tokenName && p.token.value == "fragment":
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, found := doc.Fragments[fragment.Name]; found {
+				return nil, locatedError(fragment.Location, "fragment %q is defined more than once", fragment.Name)
+			}
+
+			doc.Fragments[fragment.Name] = fragment
+		case p.token.kind == tokenName && (p.token.value == "query" || p.token.value == "mutation" || p.token.value == "subscription"):
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.Operations = append(doc.Operations, operation)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document does not contain any operation")
+	}
+
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return locatedError(Location{p.lexer.line, p.lexer.col}, "syntax error: %s", err)
+	}
+
+	p.token = t
+
+	return nil
+}
+
+func (p *parser) loc() Location {
+	return Location{p.token.line, p.token.col}
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.token.kind == tokenPunct && p.token.value == punct
+}
+
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.unexpected()
+	}
+
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+
+	name := p.token.value
+
+	return name, p.advance()
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return locatedError(p.loc(), "syntax error: unexpected end of document")
+	}
+
+	return locatedError(p.loc(), "syntax error: unexpected %q", p.token.value)
+}
+
+func (p *parser) operation() (*Operation, error) {
+	operation := &Operation{Type: p.token.value, Location: p.loc()}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.token.kind == tokenName {
+		operation.Name = p.token.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(")") {
+			definition, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+
+			operation.Variables = append(operation.Variables, definition)
+		}
+
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	operation.Selections = selections
+
+	return operation, nil
+}
+
+func (p *parser) variableDefinition() (*VariableDefinition, error) {
+	definition := &VariableDefinition{Location: p.loc()}
+
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	definition.Name = name
+
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	t, err := p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+
+	definition.Type = t
+
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		value, err := p.value(true)
+		if err != nil {
+			return nil, err
+		}
+
+		definition.Default = value
+	}
+
+	return definition, nil
+}
+
+func (p *parser) typeRef() (typeRef, error) {
+	var t typeRef
+
+	if ok, err := p.skip("["); err != nil {
+		return t, err
+	} else if ok {
+		inner, err := p.typeRef()
+		if err != nil {
+			return t, err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return t, err
+		}
+
+		t.List = &inner
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return t, err
+		}
+
+		t.Name = name
+	}
+
+	ok, err := p.skip("!")
+	t.NonNull = ok
+
+	return t, err
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	fragment := &Fragment{Location: p.loc()}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "on" {
+		return nil, locatedError(fragment.Location, "syntax error: fragment cannot be named \"on\"")
+	}
+
+	fragment.Name = name
+
+	if p.token.kind != tokenName || p.token.value != "on" {
+		return nil, p.unexpected()
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if fragment.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+
+	if fragment.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return fragment, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []Selection
+	for !p.peek("}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+
+		selections = append(selections, selection)
+	}
+
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+
+	return selections, p.advance()
+}
+
+func (p *parser) selection() (Selection, error) {
+	loc := p.loc()
+
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.token.kind == tokenName && p.token.value != "on" {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+
+			directives, err := p.directives()
+			if err != nil {
+				return nil, err
+			}
+
+			return &FragmentSpread{Name: name, Directives: directives, Location: loc}, nil
+		}
+
+		fragment := &InlineFragment{Location: loc}
+
+		if p.token.kind == tokenName {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			if fragment.TypeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+
+		if fragment.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+
+		if fragment.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+
+		return fragment, nil
+	}
+
+	field := &FieldSelection{Location: loc}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = name
+
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	field.Name = name
+
+	if field.Arguments, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+
+	if field.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+
+	if p.peek("{") {
+		if field.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return field, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+
+	var arguments []*Argument
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		value, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, &Argument{Name: name, Value: value})
+	}
+
+	return arguments, p.advance()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var directives []*Directive
+
+	for p.peek("@") {
+		directive := &Directive{Location: p.loc()}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		directive.Name = name
+
+		if directive.Arguments, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+
+		directives = append(directives, directive)
+	}
+
+	return directives, nil
+}
+
+// value 读取值，constant 为 true 时不允许使用变量（如变量的默认值）
+func (p *parser) value(constant bool) (*Value, error) {
+	value := &Value{Raw: p.token.value, Location: p.loc()}
+
+	switch p.token.kind {
+	case tokenInt:
+		value.Kind = valueInt
+	case tokenFloat:
+		value.Kind = valueFloat
+	case tokenString:
+		value.Kind = valueString
+	case tokenName:
+		switch p.token.value {
+		case "true", "false":
+			value.Kind = valueBoolean
+		case "null":
+			value.Kind = valueNull
+		default:
+			value.Kind = valueEnum
+		}
+	case tokenPunct:
+		switch p.token.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+
+			value.Kind, value.Raw = valueVariable, name
+
+			return value, nil
+		case "[":
+			value.Kind = valueList
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			for !p.peek("]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+
+				value.List = append(value.List, item)
+			}
+
+			return value, p.advance()
+		case "{":
+			value.Kind = valueObject
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			for !p.peek("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+
+				value.Fields = append(value.Fields, &ObjectField{Name: name, Value: item})
+			}
+
+			return value, p.advance()
+		default:
+			return nil, p.unexpected()
+		}
+	default:
+		return nil, p.unexpected()
+	}
+
+	return value, p.advance()
+}
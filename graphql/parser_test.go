@@ -0,0 +1,138 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// outline 以紧凑的文本表示选择集，用于比较解析结果
+func outline(selections []Selection) string {
+	var parts []string
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *FieldSelection:
+			part := s.Name
+			if s.Alias != "" {
+				part = s.Alias + ":" + part
+			}
+
+			var args []string
+			for _, arg := range s.Arguments {
+				args = append(args, arg.Name)
+			}
+
+			if len(args) > 0 {
+				part += "(" + strings.Join(args, ",") + ")"
+			}
+
+			for _, directive := range s.Directives {
+				part += "@" + directive.Name
+			}
+
+			if len(s.Selections) > 0 {
+				part += "{" + outline(s.Selections) + "}"
+			}
+
+			parts = append(parts, part)
+		case *FragmentSpread:
+			parts = append(parts, "..."+s.Name)
+		case *InlineFragment:
+			parts = append(parts, "... on "+s.TypeCondition+"{"+outline(s.Selections)+"}")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		operations []string // 类型、名称和选择集
+		variables  []string // 第一个操作的变量和类型
+		fragments  []string
+	}{
+		{"简写的查询", "{ podcast(pid: \"p\") { title } }", []string{`query  podcast(pid){title}`}, nil, nil},
+		{
+			"变量、默认值、别名和指令",
+			`query Page($pid: ID!, $first: Int = 10, $ids: [ID!]) { p: podcast(pid: $pid) { title @include(if: true) } }`,
+			[]string{`query Page p:podcast(pid){title@include}`},
+			[]string{"pid ID!", "first Int", "ids [ID!]"},
+			nil,
+		},
+		{
+			"片段和内联片段",
+			"query A { ...F ... on Query { me { uid } } } fragment F on Query { podcast(pid: 1) { pid } }",
+			[]string{`query A ...F ... on Query{me{uid}}`},
+			nil,
+			[]string{"F on Query"},
+		},
+		{
+			"多个操作",
+			"query A { me { uid } } query B { me { nickname } }",
+			[]string{`query A me{uid}`, `query B me{nickname}`},
+			nil,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var operations, variables, fragments []string
+			for _, operation := range doc.Operations {
+				operations = append(operations, operation.Type+" "+operation.Name+" "+outline(operation.Selections))
+			}
+
+			for _, definition := range doc.Operations[0].Variables {
+				variables = append(variables, definition.Name+" "+definition.Type.String())
+			}
+
+			for name, fragment := range doc.Fragments {
+				fragments = append(fragments, name+" on "+fragment.TypeCondition)
+			}
+
+			if !reflect.DeepEqual(operations, tt.operations) || !reflect.DeepEqual(variables, tt.variables) || !reflect.DeepEqual(fragments, tt.fragments) {
+				t.Errorf("Parse() = %q %q %q, want %q %q %q", operations, variables, fragments, tt.operations, tt.variables, tt.fragments)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"空文档", "  # 注释", "document does not contain any operation"},
+		{"未闭合的选择集", "{ podcast", "syntax error: unexpected end of document"},
+		{"多余的符号", "{ podcast } }", `syntax error: unexpected "}"`},
+		{"变量缺少类型", "query ($pid) { me { uid } }", `syntax error: unexpected ")"`},
+		{"重复的片段", "{ ...F } fragment F on Query { me { uid } } fragment F on Query { me { uid } }", `fragment "F" is defined more than once`},
+		{"词法错误", "{ podcast(pid: \"p) }", "syntax error: unterminated string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	_, err := Parse("{\n  podcast(pid: 1) {\n    title\n  }\n  ]\n}")
+
+	e, ok := err.(*Error)
+	if !ok || !reflect.DeepEqual(e.Locations, []Location{{5, 3}}) {
+		t.Errorf("Parse() error = %#v, want a syntax error at 5:3", err)
+	}
+}
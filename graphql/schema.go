@@ -0,0 +1,403 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type 为 *Scalar、*Enum、*Object、*List 或 *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar 标量类型，Parse 校验并转换输入值，Serialize 转换输出值
+type Scalar struct {
+	Name        string
+	Description string
+	Parse       func(v any) (any, error)
+	Serialize   func(v any) (any, error)
+}
+
+// Enum 枚举类型，输入和输出均为枚举值的名称
+type Enum struct {
+	Name        string
+	Description string
+	Values      []string
+}
+
+// Object 对象类型
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+type List struct {
+	OfType Type
+}
+
+type NonNull struct {
+	OfType Type
+}
+
+func (t *Scalar) String() string  { return t.Name }
+func (t *Enum) String() string    { return t.Name }
+func (t *Object) String() string  { return t.Name }
+func (t *List) String() string    { return "[" + t.OfType.String() + "]" }
+func (t *NonNull) String() string { return t.OfType.String() + "!" }
+
+// Field 对象的字段，Resolve 为空时从上级对象（map[string]any）中读取同名字段
+//
+// Cost 为查询代价，未设置时有 Resolve 的字段为 1，否则为 0，不发起请求的 Resolve 可设为负数表示代价为 0；
+// 列表字段中子字段的代价按 first 参数或 ListSize 累乘
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Arg
+	Cost        int
+	ListSize    int
+	Resolve     ResolveFunc
+}
+
+type Arg struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     any
+}
+
+// ResolveFunc 解析字段的值，返回的值按字段的类型继续解析
+type ResolveFunc func(p ResolveParams) (any, error)
+
+type ResolveParams struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+}
+
+// Schema 只包含查询的 schema
+type Schema struct {
+	Query *Object
+	types map[string]Type
+}
+
+// Field 按名称查找字段
+func (o *Object) Field(name string) *Field {
+	for _, field := range o.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) arg(name string) *Arg {
+	for _, arg := range f.Args {
+		if arg.Name == name {
+			return arg
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) cost() int {
+	if f.Cost != 0 {
+		return max(f.Cost, 0)
+	}
+
+	if f.Resolve != nil {
+		return 1
+	}
+
+	return 0
+}
+
+var (
+	String = &Scalar{
+		Name:        "String",
+		Description: "UTF-8 字符串",
+		Parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+
+			return nil, fmt.Errorf("expected a string")
+		},
+		Serialize: func(v any) (any, error) {
+			switch value := v.(type) {
+			case string:
+				return value, nil
+			case bool, float64, int:
+				return fmt.Sprint(value), nil
+			}
+
+			return nil, fmt.Errorf("cannot represent %T as String", v)
+		},
+	}
+
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "唯一标识，如 pid、eid、uid",
+		Parse: func(v any) (any, error) {
+			switch value := v.(type) {
+			case string:
+				return value, nil
+			case float64:
+				if value == math.Trunc(value) {
+					return strconv.FormatInt(int64(value), 10), nil
+				}
+			}
+
+			return nil, fmt.Errorf("expected an ID")
+		},
+		Serialize: func(v any) (any, error) {
+			switch value := v.(type) {
+			case string:
+				return value, nil
+			case float64, int:
+				return fmt.Sprint(value), nil
+			}
+
+			return nil, fmt.Errorf("cannot represent %T as ID", v)
+		},
+	}
+
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "32 位整数",
+		Parse: func(v any) (any, error) {
+			if value, ok := v.(float64); ok && value == math.Trunc(value) && math.Abs(value) <= math.MaxInt32 {
+				return int(value), nil
+			}
+
+			if value, ok := v.(int); ok {
+				return value, nil
+			}
+
+			return nil, fmt.Errorf("expected an Int")
+		},
+		Serialize: func(v any) (any, error) {
+			switch value := v.(type) {
+			case int:
+				return value, nil
+			case float64:
+				if value == math.Trunc(value) {
+					return int64(value), nil
+				}
+			}
+
+			return nil, fmt.Errorf("cannot represent %v as Int", v)
+		},
+	}
+
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "双精度浮点数",
+		Parse: func(v any) (any, error) {
+			switch value := v.(type) {
+			case float64:
+				return value, nil
+			case int:
+				return float64(value), nil
+			}
+
+			return nil, fmt.Errorf("expected a Float")
+		},
+		Serialize: func(v any) (any, error) {
+			switch value := v.(type) {
+			case float64:
+				return value, nil
+			case int:
+				return float64(value), nil
+			}
+
+			return nil, fmt.Errorf("cannot represent %T as Float", v)
+		},
+	}
+
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true 或 false",
+		Parse: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+
+			return nil, fmt.Errorf("expected a Boolean")
+		},
+		Serialize: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+
+			return nil, fmt.Errorf("cannot represent %T as Boolean", v)
+		},
+	}
+)
+
+// NewSchema 收集 query 可达的全部类型，类型名重复时返回错误
+func NewSchema(query *Object) (*Schema, error) {
+	s := &Schema{Query: query, types: map[string]Type{}}
+
+	for _, scalar := range []*Scalar{String, ID, Int, Float, Boolean} {
+		s.types[scalar.Name] = scalar
+	}
+
+	if err := s.collect(query); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Schema) collect(t Type) error {
+	switch value := t.(type) {
+	case *List:
+		return s.collect(value.OfType)
+	case *NonNull:
+		return s.collect(value.OfType)
+	}
+
+	name := t.String()
+	if existing, found := s.types[name]; found {
+		if existing != t {
+			return fmt.Errorf("type %s is defined more than once", name)
+		}
+
+		return nil
+	}
+
+	s.types[name] = t
+
+	if object, ok := t.(*Object); ok {
+		for _, field := range object.Fields {
+			if err := s.collect(field.Type); err != nil {
+				return err
+			}
+
+			for _, arg := range field.Args {
+				if err := s.collect(arg.Type); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// SDL 以 GraphQL SDL 格式输出 schema
+func (s *Schema) SDL() string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	writeDescription := func(indent, description string) {
+		if description != "" {
+			fmt.Fprintf(&b, "%s%q\n", indent, description)
+		}
+	}
+
+	write := func(t Type) {
+		switch value := t.(type) {
+		case *Enum:
+			writeDescription("", value.Description)
+			fmt.Fprintf(&b, "enum %s {\n", value.Name)
+
+			for _, v := range value.Values {
+				fmt.Fprintf(&b, "  %s\n", v)
+			}
+
+			b.WriteString("}\n\n")
+		case *Object:
+			writeDescription("", value.Description)
+			fmt.Fprintf(&b, "type %s {\n", value.Name)
+
+			for _, field := range value.Fields {
+				writeDescription("  ", field.Description)
+				fmt.Fprintf(&b, "  %s", field.Name)
+
+				if len(field.Args) > 0 {
+					args := make([]string, len(field.Args))
+					for i, arg := range field.Args {
+						args[i] = arg.Name + ": " + arg.Type.String()
+						if arg.Default != nil {
+							args[i] += " = " + literal(arg.Type, arg.Default)
+						}
+					}
+
+					fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+				}
+
+				fmt.Fprintf(&b, ": %s\n", field.Type)
+			}
+
+			b.WriteString("}\n\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "schema {\n  query: %s\n}\n\n", s.Query.Name)
+
+	write(s.Query)
+
+	for _, name := range names {
+		if t := s.types[name]; t != s.Query {
+			write(t)
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// literal 默认值在 SDL 中的写法
+func literal(t Type, v any) string {
+	if nonNull, ok := t.(*NonNull); ok {
+		t = nonNull.OfType
+	}
+
+	if _, ok := t.(*Enum); ok {
+		return fmt.Sprint(v)
+	}
+
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// unwrap 去掉 NonNull 和 List，返回元素的类型
+func unwrap(t Type) Type {
+	for {
+		switch value := t.(type) {
+		case *NonNull:
+			t = value.OfType
+		case *List:
+			t = value.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isList(t Type) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		t = nonNull.OfType
+	}
+
+	_, ok := t.(*List)
+
+	return ok
+}
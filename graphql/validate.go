@@ -0,0 +1,393 @@
+package graphql
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// defaultListSize 计算代价时没有 first 参数的列表字段按此数量估算
+const defaultListSize = 10
+
+// maxEstimate 代价估算的上限，避免嵌套的 first 参数累乘时溢出
+const maxEstimate = math.MaxInt32
+
+func saturate(a, b int) int {
+	if a != 0 && b > maxEstimate/a {
+		return maxEstimate
+	}
+
+	return min(a*b, maxEstimate)
+}
+
+// validator 按 schema 校验查询，同时计算查询的深度和代价
+type validator struct {
+	schema    *Schema
+	doc       *Document
+	variables map[string]any
+	defined   map[string]bool
+	errors    []*Error
+	fragments []string
+}
+
+func (v *validator) addError(loc Location, format string, args ...any) {
+	v.errors = append(v.errors, locatedError(loc, format, args...))
+}
+
+// selections 返回选择集的深度和代价，multiplier 为上级列表字段累乘的元素数量
+func (v *validator) selections(object *Object, selections []Selection, multiplier int) (int, int) {
+	depth, cost := 0, 0
+
+	for _, selection := range selections {
+		var d, c int
+
+		switch s := selection.(type) {
+		case *FieldSelection:
+			d, c = v.field(object, s, multiplier)
+		case *FragmentSpread:
+			fragment, found := v.doc.Fragments[s.Name]
+			if !found {
+				v.addError(s.Location, "unknown fragment %q", s.Name)
+
+				continue
+			}
+
+			if slices.Contains(v.fragments, s.Name) {
+				v.addError(s.Location, "fragment %q spreads itself", s.Name)
+
+				continue
+			}
+
+			v.directives(s.Directives)
+
+			if fragment.TypeCondition != object.Name {
+				v.addError(s.Location, "fragment %q on %s cannot be spread within type %s", s.Name, fragment.TypeCondition, object.Name)
+
+				continue
+			}
+
+			v.fragments = append(v.fragments, s.Name)
+			d, c = v.selections(object, fragment.Selections, multiplier)
+			v.fragments = v.fragments[:len(v.fragments)-1]
+		case *InlineFragment:
+			v.directives(s.Directives)
+
+			if s.TypeCondition != "" && s.TypeCondition != object.Name {
+				v.addError(s.Location, "inline fragment on %s cannot be spread within type %s", s.TypeCondition, object.Name)
+
+				continue
+			}
+
+			d, c = v.selections(object, s.Selections, multiplier)
+		}
+
+		depth = max(depth, d)
+		cost = min(cost+c, maxEstimate)
+	}
+
+	return depth, cost
+}
+
+func (v *validator) field(object *Object, s *FieldSelection, multiplier int) (int, int) {
+	v.directives(s.Directives)
+
+	if s.Name == "__typename" {
+		if len(s.Arguments) > 0 || len(s.Selections) > 0 {
+			v.addError(s.Location, "field __typename does not accept arguments or selections")
+		}
+
+		return 1, 0
+	}
+
+	field := object.Field(s.Name)
+	if field == nil {
+		v.addError(s.Location, "cannot query field %q on type %s", s.Name, object.Name)
+
+		return 1, 0
+	}
+
+	args, err := coerceArguments(field, s.Arguments, v.variables, v.defined)
+	if err != nil {
+		v.addError(s.Location, "%s", err)
+	}
+
+	cost := saturate(multiplier, field.cost())
+
+	child, isObject := unwrap(field.Type).(*Object)
+	if !isObject {
+		if len(s.Selections) > 0 {
+			v.addError(s.Location, "field %q of type %s must not have a selection", s.Name, field.Type)
+		}
+
+		return 1, cost
+	}
+
+	if len(s.Selections) == 0 {
+		v.addError(s.Location, "field %q of type %s must have a selection of subfields", s.Name, field.Type)
+
+		return 1, cost
+	}
+
+	size := 1
+	if first, ok := args["first"].(int); ok {
+		size = first
+	} else if isList(field.Type) {
+		size = defaultListSize
+		if field.ListSize > 0 {
+			size = field.ListSize
+		}
+	}
+
+	depth, childCost := v.selections(child, s.Selections, saturate(multiplier, max(size, 1)))
+
+	return depth + 1, min(cost+childCost, maxEstimate)
+}
+
+func (v *validator) directives(directives []*Directive) {
+	for _, directive := range directives {
+		if directive.Name != "skip" && directive.Name != "include" {
+			v.addError(directive.Location, "unknown directive @%s", directive.Name)
+
+			continue
+		}
+
+		if _, err := directiveCondition(directive, v.variables, v.defined); err != nil {
+			v.addError(directive.Location, "%s", err)
+		}
+	}
+}
+
+// directiveCondition @skip(if:) 和 @include(if:) 的条件
+func directiveCondition(directive *Directive, variables map[string]any, defined map[string]bool) (bool, error) {
+	if len(directive.Arguments) != 1 || directive.Arguments[0].Name != "if" {
+		return false, fmt.Errorf("directive @%s requires exactly one argument \"if\"", directive.Name)
+	}
+
+	value, err := coerceLiteral(&NonNull{OfType: Boolean}, directive.Arguments[0].Value, variables, defined)
+	if err != nil {
+		return false, fmt.Errorf("directive @%s: %s", directive.Name, err)
+	}
+
+	return value.(bool), nil
+}
+
+// coerceArguments 按字段的参数定义转换查询中的参数，未传入的参数使用默认值
+func coerceArguments(field *Field, arguments []*Argument, variables map[string]any, defined map[string]bool) (map[string]any, error) {
+	args := map[string]any{}
+
+	for _, argument := range arguments {
+		arg := field.arg(argument.Name)
+		if arg == nil {
+			return nil, fmt.Errorf("unknown argument %q on field %q", argument.Name, field.Name)
+		}
+
+		if _, found := args[arg.Name]; found {
+			return nil, fmt.Errorf("argument %q is provided more than once", arg.Name)
+		}
+
+		value, err := coerceLiteral(arg.Type, argument.Value, variables, defined)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %s", arg.Name, err)
+		}
+
+		if value == nil && arg.Default != nil {
+			value = arg.Default
+		}
+
+		args[arg.Name] = value
+	}
+
+	for _, arg := range field.Args {
+		if _, found := args[arg.Name]; found {
+			continue
+		}
+
+		if arg.Default != nil {
+			args[arg.Name] = arg.Default
+
+			continue
+		}
+
+		if _, required := arg.Type.(*NonNull); required {
+			return nil, fmt.Errorf("argument %q of type %s is required on field %q", arg.Name, arg.Type, field.Name)
+		}
+	}
+
+	return args, nil
+}
+
+// coerceLiteral 将查询中的值转换为 t 类型的 Go 值
+func coerceLiteral(t Type, value *Value, variables map[string]any, defined map[string]bool) (any, error) {
+	if value.Kind == valueVariable {
+		if !defined[value.Raw] {
+			return nil, fmt.Errorf("variable $%s is not defined", value.Raw)
+		}
+
+		v := variables[value.Raw]
+		if _, required := t.(*NonNull); required && v == nil {
+			return nil, fmt.Errorf("expected a non-null value for $%s", value.Raw)
+		}
+
+		return v, nil
+	}
+
+	switch typ := t.(type) {
+	case *NonNull:
+		if value.Kind == valueNull {
+			return nil, fmt.Errorf("expected a non-null %s", typ.OfType)
+		}
+
+		return coerceLiteral(typ.OfType, value, variables, defined)
+	case *List:
+		if value.Kind == valueNull {
+			return nil, nil
+		}
+
+		if value.Kind != valueList {
+			item, err := coerceLiteral(typ.OfType, value, variables, defined)
+			if err != nil {
+				return nil, err
+			}
+
+			return []any{item}, nil
+		}
+
+		items := make([]any, len(value.List))
+		for i, item := range value.List {
+			v, err := coerceLiteral(typ.OfType, item, variables, defined)
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = v
+		}
+
+		return items, nil
+	case *Enum:
+		if value.Kind == valueNull {
+			return nil, nil
+		}
+
+		if value.Kind != valueEnum || !slices.Contains(typ.Values, value.Raw) {
+			return nil, fmt.Errorf("expected a value of enum %s", typ.Name)
+		}
+
+		return value.Raw, nil
+	case *Scalar:
+		var v any
+
+		switch value.Kind {
+		case valueNull:
+			return nil, nil
+		case valueInt, valueFloat:
+			f, err := strconv.ParseFloat(value.Raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", value.Raw)
+			}
+
+			if value.Kind == valueFloat && typ == Int {
+				return nil, fmt.Errorf("expected an Int")
+			}
+
+			v = f
+		case valueString:
+			v = value.Raw
+		case valueBoolean:
+			v = value.Raw == "true"
+		default:
+			return nil, fmt.Errorf("expected a value of type %s", typ.Name)
+		}
+
+		return typ.Parse(v)
+	}
+
+	return nil, fmt.Errorf("type %s cannot be used as an input", t)
+}
+
+// coerceVariable 将请求中 JSON 格式的变量转换为 t 类型的 Go 值
+func coerceVariable(t Type, v any) (any, error) {
+	switch typ := t.(type) {
+	case *NonNull:
+		if v == nil {
+			return nil, fmt.Errorf("expected a non-null %s", typ.OfType)
+		}
+
+		return coerceVariable(typ.OfType, v)
+	case *List:
+		if v == nil {
+			return nil, nil
+		}
+
+		list, ok := v.([]any)
+		if !ok {
+			item, err := coerceVariable(typ.OfType, v)
+			if err != nil {
+				return nil, err
+			}
+
+			return []any{item}, nil
+		}
+
+		items := make([]any, len(list))
+		for i, item := range list {
+			value, err := coerceVariable(typ.OfType, item)
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = value
+		}
+
+		return items, nil
+	case *Enum:
+		if v == nil {
+			return nil, nil
+		}
+
+		if s, ok := v.(string); ok && slices.Contains(typ.Values, s) {
+			return s, nil
+		}
+
+		return nil, fmt.Errorf("expected a value of enum %s", typ.Name)
+	case *Scalar:
+		if v == nil {
+			return nil, nil
+		}
+
+		return typ.Parse(v)
+	}
+
+	return nil, fmt.Errorf("type %s cannot be used as an input", t)
+}
+
+// inputType 变量声明中的类型，只能为标量、枚举及其列表
+func (s *Schema) inputType(ref typeRef) (Type, error) {
+	var t Type
+
+	if ref.List != nil {
+		inner, err := s.inputType(*ref.List)
+		if err != nil {
+			return nil, err
+		}
+
+		t = &List{OfType: inner}
+	} else {
+		named, found := s.types[ref.Name]
+		if !found {
+			return nil, fmt.Errorf("unknown type %s", ref.Name)
+		}
+
+		if _, ok := named.(*Object); ok {
+			return nil, fmt.Errorf("type %s cannot be used as an input", ref.Name)
+		}
+
+		t = named
+	}
+
+	if ref.NonNull {
+		t = &NonNull{OfType: t}
+	}
+
+	return t, nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+	"log"
+	"net/http"
+)
+
+type AccountRemoveRequestBody struct {
+	Name string `form:"name" json:"name" binding:"required"`
+}
+
+// AccountList 查询保存在服务端的账号，登录时签发的 API Key 只能看到自己的账号
+var AccountList = func(ctx *gin.Context) {
+	accounts, err := utils.ListAccounts()
+	if err != nil {
+		log.Println("/accounts", err)
+
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	list := make([]utils.AccountInfo, 0, len(accounts))
+	for _, account := range accounts {
+		if utils.CurrentApiKey(ctx).CanUseAccount(account.Name) {
+			list = append(list, account.Info())
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": gin.H{
+			"data": list,
+		},
+	})
+}
+
+// AccountRemove 删除保存在服务端的账号，登录时签发的 API Key 只能删除自己的账号
+var AccountRemove = func(ctx *gin.Context) {
+	var params AccountRemoveRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	if !utils.CurrentApiKey(ctx).CanManageAccount(params.Name) {
+		utils.ReturnError(ctx, utils.NewError(http.StatusForbidden, utils.ErrForbidden, "api key cannot remove account: "+params.Name))
+
+		return
+	}
+
+	err := utils.RemoveAccount(params.Name)
+	if errors.Is(err, utils.ErrStoreNotFound) {
+		utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, "account not found: "+params.Name))
+
+		return
+	}
+
+	if err != nil {
+		log.Println("/accounts/remove", err)
+
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": gin.H{
+			"name": params.Name,
+		},
+	})
+}
@@ -40,13 +40,9 @@ var BlockedUserLists = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/blocked-user/list", code, utils.GetMsg(code))
 
@@ -57,23 +53,14 @@ var BlockedUserLists = func(ctx *gin.Context) {
 }
 
 type BlockedUserBody struct {
-	Uid string `form:"uid"`
+	Uid string `form:"uid" binding:"required"`
 }
 
 // BlockedUserCreate 将用户加入黑名单
 var BlockedUserCreate = func(ctx *gin.Context) {
-	var params *BlockedUserBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params BlockedUserBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -110,13 +97,9 @@ var BlockedUserCreate = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, payload, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, payload, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/blocked-user/create", code, utils.GetMsg(code))
 
@@ -128,18 +111,9 @@ var BlockedUserCreate = func(ctx *gin.Context) {
 
 // BlockedUserRemove 将用户移出黑名单
 var BlockedUserRemove = func(ctx *gin.Context) {
-	var params *BlockedUserBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params BlockedUserBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -176,13 +150,9 @@ var BlockedUserRemove = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, payload, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, payload, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/blocked-user/remove", code, utils.GetMsg(code))
 
@@ -41,13 +41,9 @@ var CategoryList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/category/list-all", code, utils.GetMsg(code))
 
@@ -58,24 +54,15 @@ var CategoryList = func(ctx *gin.Context) {
 }
 
 type CategoryListTabByIdRequestBody struct {
-	CategoryId string `form:"categoryId"`
+	CategoryId string `form:"categoryId" binding:"required"`
 }
 
 // CategoryListTabById 获取分类下的标签
 var CategoryListTabById = func(ctx *gin.Context) {
 
-	var params *CategoryListTabByIdRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.CategoryId == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params CategoryListTabByIdRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -111,13 +98,9 @@ var CategoryListTabById = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/category/podcast/list-tabs", code, utils.GetMsg(code))
 
@@ -128,27 +111,18 @@ var CategoryListTabById = func(ctx *gin.Context) {
 }
 
 type CategoryPodcastListByTabRequestBody struct {
-	CategoryId     string `form:"categoryId"`
+	CategoryId     string `form:"categoryId" binding:"required"`
 	OmitSubscribed bool   `form:"omitSubscribed"`
-	Tab            string `form:"tab"`
-	LoadMoreKey    int    `form:"loadMoreKey"`
+	Tab            string `form:"tab" binding:"required"`
+	LoadMoreKey    int    `form:"loadMoreKey" binding:"min=0"`
 }
 
 // CategoryPodcastListByTab 根据标签获取分类下的节目列表
 var CategoryPodcastListByTab = func(ctx *gin.Context) {
 
-	var params *CategoryPodcastListByTabRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.CategoryId == "" || params.Tab == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params CategoryPodcastListByTabRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -194,13 +168,9 @@ var CategoryPodcastListByTab = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/category/podcast/list-by-tab", code, utils.GetMsg(code))
 
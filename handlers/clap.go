@@ -10,31 +10,22 @@ import (
 )
 
 type ClapRequestBody struct {
-	Eid      string `json:"eid" form:"eid"`
-	Duration int    `json:"duration" form:"duration"`
+	Eid      string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Duration int    `json:"duration" form:"duration" binding:"required,gt=0"`
 }
 
 // Clap 精彩时间点
 var Clap = func(ctx *gin.Context) {
 
-	var params *ClapRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params ClapRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" || params.Duration == 0 {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"eid":      params.Eid,
 		"duration": params.Duration,
@@ -67,13 +58,9 @@ var Clap = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/clap/list", code, utils.GetMsg(code))
 
@@ -84,32 +71,23 @@ var Clap = func(ctx *gin.Context) {
 }
 
 type CreateClapRequestBody struct {
-	Eid       string `json:"eid" form:"eid"`
-	Timestamp int64  `json:"timestamp" form:"timestamp"`
-	Duration  int64  `json:"duration" form:"duration"`
+	Eid       string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Timestamp int64  `json:"timestamp" form:"timestamp" binding:"required,gt=0,ltefield=Duration"`
+	Duration  int64  `json:"duration" form:"duration" binding:"required,gt=0"`
 }
 
 // CreateClap 创建高能点
 var CreateClap = func(ctx *gin.Context) {
 
-	var params *CreateClapRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params CreateClapRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" || params.Timestamp == 0 || params.Duration == 0 {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"eid":             params.Eid,
 		"timestamp":       params.Timestamp,
@@ -146,13 +124,9 @@ var CreateClap = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/clap/create", code, utils.GetMsg(code))
 
@@ -10,8 +10,8 @@ import (
 )
 
 type CommentPrimaryRequestBody struct {
-	Id          string                     `json:"id" form:"id"`
-	Order       string                     `json:"order" form:"order"`
+	Id          string                     `json:"id" form:"id" binding:"required,xyzid"`
+	Order       string                     `json:"order" form:"order" binding:"required,oneof=HOT TIME TIMESTAMP"`
 	LoadMoreKey *commentPrimaryLoadMoreKey `json:"loadMoreKey" form:"loadMoreKey"`
 }
 
@@ -25,22 +25,13 @@ type commentPrimaryLoadMoreKey struct {
 var CommentPrimary = func(ctx *gin.Context) {
 	var params CommentPrimaryRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Order == "" || params.Id == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"order": params.Order,
 		"owner": map[string]any{
@@ -84,13 +75,9 @@ var CommentPrimary = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/comment/list-primary", code, utils.GetMsg(code))
 
@@ -101,34 +88,30 @@ var CommentPrimary = func(ctx *gin.Context) {
 }
 
 type CommentThreadRequestBody struct {
-	Order            string `json:"order" form:"order"`
-	PrimaryCommentId string `json:"primaryCommentId" form:"primaryCommentId"`
+	Order            string         `json:"order" form:"order" binding:"required,oneof=SMART TIME"`
+	PrimaryCommentId string         `json:"primaryCommentId" form:"primaryCommentId" binding:"required"`
+	LoadMoreKey      map[string]any `json:"loadMoreKey" form:"loadMoreKey"` // 上一页返回的 loadMoreKey，原样传给上游
 }
 
 // CommentThread 评论回复
 var CommentThread = func(ctx *gin.Context) {
 	var params CommentThreadRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Order == "" || params.PrimaryCommentId == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"order":            params.Order,
 		"primaryCommentId": params.PrimaryCommentId,
 	}
+
+	if params.LoadMoreKey != nil {
+		p["loadMoreKey"] = params.LoadMoreKey
+	}
 	now := time.Now()
 	isoTime := now.Format("2006-01-02T15:04:05Z07:00")
 	url := constant.BaseUrl + "/v1/comment/list-thread"
@@ -157,13 +140,9 @@ var CommentThread = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/comment/list-thread", code, utils.GetMsg(code))
 
@@ -174,23 +153,14 @@ var CommentThread = func(ctx *gin.Context) {
 }
 
 type CommentCollect struct {
-	CommentId string `form:"commentId"`
+	CommentId string `form:"commentId" binding:"required"`
 }
 
 // CreateCommentCollect 收藏评论
 var CreateCommentCollect = func(ctx *gin.Context) {
-	var params *CommentCollect
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.CommentId == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params CommentCollect
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -227,13 +197,9 @@ var CreateCommentCollect = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/comment/collect/create", code, utils.GetMsg(code))
 
@@ -245,18 +211,9 @@ var CreateCommentCollect = func(ctx *gin.Context) {
 
 // RemoveCommentCollect 取消已收藏评论
 var RemoveCommentCollect = func(ctx *gin.Context) {
-	var params *CommentCollect
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.CommentId == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params CommentCollect
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -293,13 +250,9 @@ var RemoveCommentCollect = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/comment/collect/remove", code, utils.GetMsg(code))
 
@@ -340,13 +293,9 @@ var CommentCollectList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/comment/collect/list", code, utils.GetMsg(code))
 
@@ -358,23 +307,14 @@ var CommentCollectList = func(ctx *gin.Context) {
 
 type CommentLikeUpdateBody struct {
 	Liked bool   `form:"liked"`
-	Id    string `form:"id"`
+	Id    string `form:"id" binding:"required"`
 }
 
 // CommentLikeUpdate 点赞/取消点赞评论
 var CommentLikeUpdate = func(ctx *gin.Context) {
-	var params *CommentLikeUpdateBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Id == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params CommentLikeUpdateBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -417,13 +357,9 @@ var CommentLikeUpdate = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/like/update", code, utils.GetMsg(code))
 
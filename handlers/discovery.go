@@ -17,9 +17,7 @@ type DiscoveryRequestBody struct {
 var Discovery = func(ctx *gin.Context) {
 	var params DiscoveryRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -61,13 +59,9 @@ var Discovery = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/discovery-feed/list", code, utils.GetMsg(code))
 
@@ -108,13 +102,9 @@ var RefreshEpisodeRecommend = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, map[string]any{}, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, map[string]any{}, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/discovery-collection/refresh-episode-recommend", code, utils.GetMsg(code))
 
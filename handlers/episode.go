@@ -11,8 +11,8 @@ import (
 )
 
 type EpisodeListRequestBody struct {
-	Pid         string              `json:"pid" form:"pid"`
-	Order       string              `json:"order" form:"order"`
+	Pid         string              `json:"pid" form:"pid" binding:"required,xyzid"`
+	Order       string              `json:"order" form:"order" binding:"required,oneof=desc asc"`
 	LoadMoreKey *episodeLoadMoreKey `json:"loadMoreKey" form:"loadMoreKey"`
 }
 
@@ -26,22 +26,13 @@ type episodeLoadMoreKey struct {
 var EpisodeList = func(ctx *gin.Context) {
 	var params EpisodeListRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" || (params.Order != "desc" && params.Order != "asc") {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"limit": "20",
 		"pid":   params.Pid,
@@ -83,13 +74,9 @@ var EpisodeList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/episode/list", code, utils.GetMsg(code))
 
@@ -100,29 +87,20 @@ var EpisodeList = func(ctx *gin.Context) {
 }
 
 type EpisodeDetailRequestBody struct {
-	Eid string `json:"eid" form:"eid"`
+	Eid string `json:"eid" form:"eid" binding:"required,xyzid"`
 }
 
 // EpisodeDetail 查询单集的详情
 var EpisodeDetail = func(ctx *gin.Context) {
 	var params EpisodeDetailRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	now := time.Now()
 	isoTime := now.Format("2006-01-02T15:04:05Z07:00")
 	url := constant.BaseUrl + "/v1/episode/get?eid=" + params.Eid
@@ -149,13 +127,9 @@ var EpisodeDetail = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/episode/get?eid="+params.Eid, code, utils.GetMsg(code))
 
@@ -166,29 +140,20 @@ var EpisodeDetail = func(ctx *gin.Context) {
 }
 
 type PlaybackProgressRequestBody struct {
-	Eids []string `json:"eids" form:"eids"`
+	Eids []string `json:"eids" form:"eids" binding:"required,min=1,dive,xyzid"`
 }
 
 // PlaybackProgress 单集播放进度
 var PlaybackProgress = func(ctx *gin.Context) {
 	var params PlaybackProgressRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if len(params.Eids) == 0 {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"eids": params.Eids,
 	}
@@ -218,13 +183,9 @@ var PlaybackProgress = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/playback-progress/list", code, utils.GetMsg(code))
 
@@ -236,39 +197,24 @@ var PlaybackProgress = func(ctx *gin.Context) {
 
 type UpdatePlaybackProgressRequestBody struct {
 	Data []struct {
-		Pid      string `json:"pid" form:"pid"`
-		Eid      string `json:"eid" form:"eid"`
-		Progress int    `json:"progress" form:"progress"`
-		PlayedAt string `json:"playedAt" form:"playedAt"`
-	} `json:"data" form:"data"`
+		Pid      string `json:"pid" form:"pid" binding:"required,xyzid"`
+		Eid      string `json:"eid" form:"eid" binding:"required,xyzid"`
+		Progress int    `json:"progress" form:"progress" binding:"min=0"`
+		PlayedAt string `json:"playedAt" form:"playedAt" binding:"required"`
+	} `json:"data" form:"data" binding:"required,min=1,dive"`
 }
 
 // UpdatePlaybackProgress 更新单集播放进度
 var UpdatePlaybackProgress = func(ctx *gin.Context) {
 	var params UpdatePlaybackProgressRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if len(params.Data) == 0 {
-		utils.ReturnBadRequest(ctx, nil)
-		return
-	}
-
-	for _, data := range params.Data {
-		if data.Pid == "" || data.Eid == "" || data.Progress < 0 || data.PlayedAt == "" {
-			utils.ReturnBadRequest(ctx, nil)
-			return
-		}
-	}
-
 	p := map[string]any{
 		"data": params.Data,
 	}
@@ -300,13 +246,9 @@ var UpdatePlaybackProgress = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/playback-progress/update", code, utils.GetMsg(code))
 
@@ -320,22 +262,13 @@ var UpdatePlaybackProgress = func(ctx *gin.Context) {
 var Live = func(ctx *gin.Context) {
 	var params EpisodeDetailRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	now := time.Now()
 	isoTime := now.Format("2006-01-02T15:04:05Z07:00")
 	url := constant.BaseUrl + "/v1/live-stats/episode/get?eid=" + params.Eid
@@ -362,13 +295,9 @@ var Live = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/live-stats/episode/get?eid="+params.Eid, code, utils.GetMsg(code))
 
@@ -379,31 +308,22 @@ var Live = func(ctx *gin.Context) {
 }
 
 type LiveStatsReportRequestBody struct {
-	Eid string `form:"eid"`
-	Pid string `form:"pid"`
+	Eid string `form:"eid" binding:"required,xyzid"`
+	Pid string `form:"pid" binding:"required,xyzid"`
 }
 
 // LiveStatsReport 上报播放状态
 var LiveStatsReport = func(ctx *gin.Context) {
 
-	var params *LiveStatsReportRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params LiveStatsReportRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" || params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"playStats": map[string]string{
 			"action": "PLAY",
@@ -439,13 +359,9 @@ var LiveStatsReport = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/live-stats/report", code, utils.GetMsg(code))
 
@@ -456,23 +372,14 @@ var LiveStatsReport = func(ctx *gin.Context) {
 }
 
 type PlayedListBody struct {
-	Uid string `form:"uid"`
+	Uid string `form:"uid" binding:"required"`
 }
 
 // PlayedList 根据 uid 查询用户的收听历史记录
 var PlayedList = func(ctx *gin.Context) {
-	var params *PlayedListBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params PlayedListBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -509,13 +416,9 @@ var PlayedList = func(ctx *gin.Context) {
 		"Content-Type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/episode-played/list", code, utils.GetMsg(code))
 
@@ -526,23 +429,14 @@ var PlayedList = func(ctx *gin.Context) {
 }
 
 type EpisodeListByFilterRequestBody struct {
-	Pid string `form:"pid"`
+	Pid string `form:"pid" binding:"required,xyzid"`
 }
 
 // EpisodeListByFilter 节目内「最受欢迎」单集列表
 var EpisodeListByFilter = func(ctx *gin.Context) {
-	var params *EpisodeListByFilterRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params EpisodeListByFilterRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -579,13 +473,9 @@ var EpisodeListByFilter = func(ctx *gin.Context) {
 		"Content-Type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/episode/list-by-filter", code, utils.GetMsg(code))
 
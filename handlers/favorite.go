@@ -11,30 +11,21 @@ import (
 )
 
 type UpdateFavoriteRequestBody struct {
-	Eid       string `form:"eid"`
+	Eid       string `form:"eid" binding:"required,xyzid"`
 	Favorited bool   `form:"favorited"`
 }
 
 // UpdateEpisodeFavorite 更新收藏单集
 var UpdateEpisodeFavorite = func(ctx *gin.Context) {
-	var params *UpdateFavoriteRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params UpdateFavoriteRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"eid":             params.Eid,
 		"favorited":       params.Favorited,
@@ -67,13 +58,9 @@ var UpdateEpisodeFavorite = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/favorite/update", code, utils.GetMsg(code))
 
@@ -117,13 +104,9 @@ var FavoriteEpisodeList = func(ctx *gin.Context) {
 		"x-jike-device-id":            "",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/favorite/list", code, utils.GetMsg(code))
 
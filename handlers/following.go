@@ -10,29 +10,25 @@ import (
 )
 
 type FollowingBody struct {
-	Uid string `form:"uid"`
+	Uid         string         `form:"uid" binding:"required"`
+	LoadMoreKey map[string]any `json:"loadMoreKey" form:"loadMoreKey"` // 上一页返回的 loadMoreKey，原样传给上游
 }
 
 // FollowingList 查询「我」关注的人
 var FollowingList = func(ctx *gin.Context) {
-	var params *FollowingBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params FollowingBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	p := map[string]any{
 		"uid": params.Uid,
 	}
+
+	if params.LoadMoreKey != nil {
+		p["loadMoreKey"] = params.LoadMoreKey
+	}
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 	now := time.Now()
@@ -61,13 +57,9 @@ var FollowingList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/user-relation/list-following", code, utils.GetMsg(code))
 
@@ -79,24 +71,19 @@ var FollowingList = func(ctx *gin.Context) {
 
 // FollowerList 查询关注「我」的人
 var FollowerList = func(ctx *gin.Context) {
-	var params *FollowingBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params FollowingBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	p := map[string]any{
 		"uid": params.Uid,
 	}
+
+	if params.LoadMoreKey != nil {
+		p["loadMoreKey"] = params.LoadMoreKey
+	}
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 	now := time.Now()
@@ -125,13 +112,9 @@ var FollowerList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/user-relation/list-follower", code, utils.GetMsg(code))
 
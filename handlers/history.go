@@ -16,12 +16,9 @@ type EpisodePlayedHistoryListRequestBody struct {
 
 // EpisodePlayedHistoryList 收听历史
 var EpisodePlayedHistoryList = func(ctx *gin.Context) {
-	var params *EpisodePlayedHistoryListRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params EpisodePlayedHistoryListRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -60,13 +57,9 @@ var EpisodePlayedHistoryList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/episode-played/list-history", code, utils.GetMsg(code))
 
@@ -77,30 +70,21 @@ var EpisodePlayedHistoryList = func(ctx *gin.Context) {
 }
 
 type UpdateEpisodePlayedHistoryListRequestBody struct {
-	Eid string `form:"eid"`
+	Eid string `form:"eid" binding:"required,xyzid"`
 }
 
 // UpdateEpisodePlayedHistoryList 更新收听历史列表
 var UpdateEpisodePlayedHistoryList = func(ctx *gin.Context) {
 
-	var params *UpdateEpisodePlayedHistoryListRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params UpdateEpisodePlayedHistoryListRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Eid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"eid": params.Eid,
 	}
@@ -132,13 +116,9 @@ var UpdateEpisodePlayedHistoryList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/episode-played/create", code, utils.GetMsg(code))
 
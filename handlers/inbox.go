@@ -22,12 +22,9 @@ type InboxListLoadMoreKey struct {
 // InboxList 订阅更新列表
 var InboxList = func(ctx *gin.Context) {
 
-	var params *InboxListRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params InboxListRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -73,13 +70,9 @@ var InboxList = func(ctx *gin.Context) {
 		"x-jike-device-properties":    "",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/inbox/list", code, utils.GetMsg(code))
 
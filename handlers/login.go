@@ -12,24 +12,21 @@ import (
 
 type LoginOrSignUpWithSMSRequestBody struct {
 	AreaCode          string `form:"areaCode" json:"areaCode"`
-	VerifyCode        string `form:"verifyCode" json:"verifyCode"`
-	MobilePhoneNumber string `form:"mobilePhoneNumber" json:"mobilePhoneNumber"`
+	VerifyCode        string `form:"verifyCode" json:"verifyCode" binding:"required"`
+	MobilePhoneNumber string `form:"mobilePhoneNumber" json:"mobilePhoneNumber" binding:"required,numeric"`
+	Account           string `form:"account" json:"account" binding:"omitempty,max=64"`
 }
 
 // Login 登录认证
 var Login = func(ctx *gin.Context) {
 	var params LoginOrSignUpWithSMSRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
-	if params.VerifyCode == "" || params.MobilePhoneNumber == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
+	// 保存到服务端的账号只能由已有的 API Key 创建或覆盖，在请求上游之前校验，避免消耗验证码
+	if params.Account != "" && !utils.AuthorizeAccount(ctx, params.Account) {
 		return
 	}
 
@@ -60,23 +57,23 @@ var Login = func(ctx *gin.Context) {
 		"WifiConnected":   "true",
 		"OS-Version":      "17.4.1",
 	}
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/auth/loginOrSignUpWithSMS", code, utils.GetMsg(code))
 
 		return
 	}
 
+	defer res.Body.Close()
+
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		log.Println("Error reading response body:", err)
 
+		utils.ReturnError(ctx, utils.NewError(http.StatusBadGateway, utils.ErrUpstreamInvalidResponse, err.Error()))
+
 		return
 	}
 
@@ -85,16 +82,72 @@ var Login = func(ctx *gin.Context) {
 	if err != nil {
 		log.Println("Error parsing response body:", err)
 
+		utils.ReturnError(ctx, utils.NewError(http.StatusBadGateway, utils.ErrUpstreamInvalidResponse, err.Error()))
+
 		return
 	}
 
+	accessToken := res.Header.Get("x-jike-access-token")
+	refreshToken := res.Header.Get("x-jike-refresh-token")
+
+	if params.Account == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": http.StatusOK,
+			"msg":  utils.GetMsg(http.StatusOK),
+			"data": gin.H{
+				"data":                 data,
+				"x-jike-access-token":  accessToken,
+				"x-jike-refresh-token": refreshToken,
+			},
+		})
+
+		return
+	}
+
+	// 指定了 account 时 token 保存在服务端，后续请求通过 x-xyz-account 选择账号
+	uid, nickname := loginUser(data)
+
+	account, err := utils.SaveAccount(params.Account, accessToken, refreshToken, uid, nickname)
+	if err != nil {
+		log.Println("Error saving account:", err)
+
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	apiKey, err := utils.IssueAccountKey(ctx, params.Account)
+	if err != nil {
+		log.Println("Error issuing api key:", err)
+
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	result := gin.H{
+		"data":    data,
+		"account": account.Info(),
+	}
+
+	if apiKey != "" {
+		result["apiKey"] = apiKey
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
 		"msg":  utils.GetMsg(http.StatusOK),
-		"data": gin.H{
-			"data":                 data,
-			"x-jike-access-token":  res.Header.Get("x-jike-access-token"),
-			"x-jike-refresh-token": res.Header.Get("x-jike-refresh-token"),
-		},
+		"data": result,
 	})
 }
+
+// loginUser 从登录响应中读取 uid 和昵称
+func loginUser(data map[string]interface{}) (string, string) {
+	inner, _ := data["data"].(map[string]interface{})
+	user, _ := inner["user"].(map[string]interface{})
+
+	uid, _ := user["uid"].(string)
+	nickname, _ := user["nickname"].(string)
+
+	return uid, nickname
+}
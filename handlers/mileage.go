@@ -41,13 +41,9 @@ var GetMileage = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/mileage/get", code, utils.GetMsg(code))
 
@@ -63,12 +59,9 @@ type MileageBody struct {
 
 // GetMileageList 获取收听数据排行榜
 var GetMileageList = func(ctx *gin.Context) {
-	var params *MileageBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params MileageBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -112,13 +105,9 @@ var GetMileageList = func(ctx *gin.Context) {
 		"x-jike-device-properties":    "",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/mileage/list", code, utils.GetMsg(code))
 
@@ -130,43 +119,26 @@ var GetMileageList = func(ctx *gin.Context) {
 
 type UpdateMileageRequestBody struct {
 	Tracking []struct {
-		Eid                   string  `form:"eid" json:"eid"`
-		Pid                   string  `form:"pid" json:"pid"`
-		StartPlayingTimestamp float64 `form:"startPlayingTimestamp" json:"startPlayingTimestamp"`
-		EndPlayingTimestamp   float64 `form:"endPlayingTimestamp" json:"endPlayingTimestamp"`
+		Eid                   string  `form:"eid" json:"eid" binding:"required,xyzid"`
+		Pid                   string  `form:"pid" json:"pid" binding:"required,xyzid"`
+		StartPlayingTimestamp float64 `form:"startPlayingTimestamp" json:"startPlayingTimestamp" binding:"required,gt=0"`
+		EndPlayingTimestamp   float64 `form:"endPlayingTimestamp" json:"endPlayingTimestamp" binding:"required,gtefield=StartPlayingTimestamp"`
 		IsSpeaker             bool    `form:"isSpeaker" json:"isSpeaker"`
 		IsOffline             bool    `form:"isOffline" json:"isOffline"`
 		IsTrial               bool    `form:"isTrial" json:"isTrial"`
-		WithSpeed             float32 `form:"withSpeed" json:"withSpeed"`
-	} `form:"tracking" json:"tracking"`
+		WithSpeed             float32 `form:"withSpeed" json:"withSpeed" binding:"omitempty,gt=0"`
+	} `form:"tracking" json:"tracking" binding:"required,min=1,dive"`
 }
 
 // UpdateMileage 更新收听数据概览
 var UpdateMileage = func(ctx *gin.Context) {
 
-	var params *UpdateMileageRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params UpdateMileageRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
-	if len(params.Tracking) == 0 {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	for _, data := range params.Tracking {
-		if data.Eid == "" || data.Pid == "" || data.StartPlayingTimestamp == 0 || data.EndPlayingTimestamp == 0 {
-			utils.ReturnBadRequest(ctx, err)
-
-			return
-		}
-	}
-
 	p := map[string]any{
 		"tracking": params.Tracking,
 	}
@@ -203,13 +175,9 @@ var UpdateMileage = func(ctx *gin.Context) {
 		"x-jike-device-properties":    "",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/mileage/update", code, utils.GetMsg(code))
 
@@ -40,13 +40,9 @@ var UnreadCount = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/unread-count/get", code, utils.GetMsg(code))
 
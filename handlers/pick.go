@@ -10,24 +10,15 @@ import (
 )
 
 type PickBody struct {
-	Uid         string `form:"uid"`
+	Uid         string `form:"uid" binding:"required"`
 	LoadMoreKey string `form:"loadMoreKey"`
 }
 
 // PickListRecent 个人主页「用户的喜欢」部分展示片段
 var PickListRecent = func(ctx *gin.Context) {
-	var params *PickBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params PickBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -63,13 +54,9 @@ var PickListRecent = func(ctx *gin.Context) {
 		"Content-Type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/pick/list-recent", code, utils.GetMsg(code))
 
@@ -81,18 +68,9 @@ var PickListRecent = func(ctx *gin.Context) {
 
 // PickListHistory 个人主页「用户的喜欢」全部内容
 var PickListHistory = func(ctx *gin.Context) {
-	var params *PickBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params PickBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -133,13 +111,9 @@ var PickListHistory = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/pick/list-history", code, utils.GetMsg(code))
 
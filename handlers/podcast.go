@@ -11,29 +11,20 @@ import (
 )
 
 type PodcastDetailRequestBody struct {
-	Pid string `json:"pid" form:"pid"`
+	Pid string `json:"pid" form:"pid" binding:"required,xyzid"`
 }
 
 // PodcastDetail 查询节目详情
 var PodcastDetail = func(ctx *gin.Context) {
 	var params PodcastDetailRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	now := time.Now()
 	isoTime := now.Format("2006-01-02T15:04:05Z07:00")
 	url := constant.BaseUrl + "/v1/podcast/get?pid=" + params.Pid
@@ -60,13 +51,9 @@ var PodcastDetail = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/podcast/get?pid="+params.Pid, code, utils.GetMsg(code))
 
@@ -77,29 +64,20 @@ var PodcastDetail = func(ctx *gin.Context) {
 }
 
 type RelatedPodcastListRequestBody struct {
-	Pid string `json:"pid" form:"pid"`
+	Pid string `json:"pid" form:"pid" binding:"required,xyzid"`
 }
 
 // RelatedPodcastList 相关节目推荐
 var RelatedPodcastList = func(ctx *gin.Context) {
 	var params RelatedPodcastListRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"pid":      params.Pid,
 		"position": "BOTTOM",
@@ -131,13 +109,9 @@ var RelatedPodcastList = func(ctx *gin.Context) {
 		"Content-type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/related-podcast/list", code, utils.GetMsg(code))
 
@@ -148,23 +122,14 @@ var RelatedPodcastList = func(ctx *gin.Context) {
 }
 
 type OwnedPodcastsListBody struct {
-	Uid string `form:"uid"`
+	Uid string `form:"uid" binding:"required"`
 }
 
 // OwnedPodcastsList 用户创建的播客
 var OwnedPodcastsList = func(ctx *gin.Context) {
-	var params *OwnedPodcastsListBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params OwnedPodcastsListBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -200,13 +165,9 @@ var OwnedPodcastsList = func(ctx *gin.Context) {
 		"Content-type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/podcaster/owned-podcasts", code, utils.GetMsg(code))
 
@@ -217,29 +178,20 @@ var OwnedPodcastsList = func(ctx *gin.Context) {
 }
 
 type PodcastGetInfoBody struct {
-	Pid string `form:"pid"`
+	Pid string `form:"pid" binding:"required,xyzid"`
 }
 
 // PodcastGetInfo 获取节目主体信息
 var PodcastGetInfo = func(ctx *gin.Context) {
-	var params *PodcastGetInfoBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params PodcastGetInfoBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	url := constant.BaseUrl + "/v1/podcast/get-info?pid=" + params.Pid
 	headers := map[string]string{
 		"Host":                "api.xiaoyuzhoufm.com",
@@ -263,13 +215,9 @@ var PodcastGetInfo = func(ctx *gin.Context) {
 		"OS-Version":          "17.4.1",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/podcast/get-info?pid="+params.Pid, code, utils.GetMsg(code))
 
@@ -280,23 +228,14 @@ var PodcastGetInfo = func(ctx *gin.Context) {
 }
 
 type PodcastHonorListBody struct {
-	Pid string `form:"pid"`
+	Pid string `form:"pid" binding:"required,xyzid"`
 }
 
 // PodcastHonorList 获取节目荣誉墙
 var PodcastHonorList = func(ctx *gin.Context) {
-	var params *PodcastHonorListBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params PodcastHonorListBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -329,13 +268,9 @@ var PodcastHonorList = func(ctx *gin.Context) {
 		"Content-type":        "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/podcast-honor/list", code, utils.GetMsg(code))
 
@@ -346,29 +281,20 @@ var PodcastHonorList = func(ctx *gin.Context) {
 }
 
 type PodcastBulletinRequestBody struct {
-	Pid string `form:"pid"`
+	Pid string `form:"pid" binding:"required,xyzid"`
 }
 
 // PodcastBulletin 获取节目公告
 var PodcastBulletin = func(ctx *gin.Context) {
-	var params *PodcastBulletinRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params PodcastBulletinRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	now := time.Now()
 	isoTime := now.Format("2006-01-02T15:04:05Z07:00")
 	url := constant.BaseUrl + "/v1/podcast-bulletin/get-by-pid?pid=" + params.Pid
@@ -395,13 +321,9 @@ var PodcastBulletin = func(ctx *gin.Context) {
 		"WifiConnected":               "true",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/podcast-bulletin/get-by-pid?pid="+params.Pid, code, utils.GetMsg(code))
 
@@ -40,13 +40,9 @@ var UserPreferenceGet = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/user-preference/get", code, utils.GetMsg(code))
 
@@ -57,30 +53,21 @@ var UserPreferenceGet = func(ctx *gin.Context) {
 }
 
 type UserPreferenceUpdateBody struct {
-	Type string `form:"type"`
+	Type string `form:"type" binding:"required"`
 	Flag bool   `form:"flag"`
 }
 
 // UserPreferenceUpdate 更新用户偏好设置
 var UserPreferenceUpdate = func(ctx *gin.Context) {
-	var params *UserPreferenceUpdateBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params UserPreferenceUpdateBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Type == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	payload := map[string]any{
 		params.Type: params.Flag,
 	}
@@ -111,13 +98,9 @@ var UserPreferenceUpdate = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, payload, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, payload, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/user-preference/update", code, utils.GetMsg(code))
 
@@ -39,13 +39,9 @@ var Profile = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/profile/get", code, utils.GetMsg(code))
 
@@ -56,23 +52,14 @@ var Profile = func(ctx *gin.Context) {
 }
 
 type UserStatsBody struct {
-	Uid string `form:"uid"`
+	Uid string `form:"uid" binding:"required"`
 }
 
 // GetUserStats 查询用户统计数据（关注数、粉丝数、订阅数和收听时长）
 var GetUserStats = func(ctx *gin.Context) {
-	var params *UserStatsBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params UserStatsBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -104,13 +91,9 @@ var GetUserStats = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/user-stats/get?uid="+params.Uid, code, utils.GetMsg(code))
 
@@ -121,23 +104,14 @@ var GetUserStats = func(ctx *gin.Context) {
 }
 
 type GetProfileByUidBody struct {
-	Uid string `form:"uid"`
+	Uid string `form:"uid" binding:"required"`
 }
 
 // GetProfileByUid 根据 uid 查询用户信息
 var GetProfileByUid = func(ctx *gin.Context) {
-	var params *UserStatsBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params UserStatsBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -169,13 +143,9 @@ var GetProfileByUid = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/profile/get?uid="+params.Uid, code, utils.GetMsg(code))
 
@@ -11,30 +11,21 @@ import (
 )
 
 type RelationUpdateRequestBody struct {
-	Uid      string `form:"uid"`
-	Relation string `form:"relation"`
+	Uid      string `form:"uid" binding:"required"`
+	Relation string `form:"relation" binding:"required,oneof=FOLLOWING STRANGE"`
 }
 
 // RelationUpdate 关注/取关用户
 var RelationUpdate = func(ctx *gin.Context) {
-	var params *RelationUpdateRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params RelationUpdateRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Uid == "" || params.Relation == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"uid":      params.Uid,
 		"relation": params.Relation,
@@ -66,13 +57,9 @@ var RelationUpdate = func(ctx *gin.Context) {
 		"Content-Type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/user-relation/update", code, utils.GetMsg(code))
 
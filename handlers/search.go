@@ -11,9 +11,9 @@ import (
 )
 
 type SearchRequestBody struct {
-	Pid         string             `form:"pid"`
-	Type        string             `form:"type"`
-	Keyword     string             `form:"keyword"`
+	Pid         string             `form:"pid" binding:"omitempty,xyzid"`
+	Type        string             `form:"type" binding:"required,oneof=ALL PODCAST EPISODE USER"`
+	Keyword     string             `form:"keyword" binding:"required"`
 	LoadMoreKey *searchLoadMoreKey `form:"loadMoreKey"`
 }
 
@@ -24,24 +24,15 @@ type searchLoadMoreKey struct {
 
 // Search 搜索
 var Search = func(ctx *gin.Context) {
-	var params *SearchRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params SearchRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Keyword == "" || params.Type == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"limit":           "20",
 		"sourcePageName":  "4",
@@ -88,13 +79,9 @@ var Search = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/search/create", code, utils.GetMsg(code))
 
@@ -133,13 +120,9 @@ var SearchPreset = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	res, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/search/get-preset", code, utils.GetMsg(code))
 
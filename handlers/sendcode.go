@@ -10,7 +10,7 @@ import (
 )
 
 type SendCodeRequestBody struct {
-	MobilePhoneNumber string `form:"mobilePhoneNumber" json:"mobilePhoneNumber"`
+	MobilePhoneNumber string `form:"mobilePhoneNumber" json:"mobilePhoneNumber" binding:"required,numeric"`
 	AreaCode          string `form:"areaCode" json:"areaCode"`
 }
 
@@ -18,16 +18,7 @@ type SendCodeRequestBody struct {
 var SendCode = func(ctx *gin.Context) {
 	var params SendCodeRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.MobilePhoneNumber == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -63,13 +54,9 @@ var SendCode = func(ctx *gin.Context) {
 		"x-custom-xiaoyuzhou-app-dev": "",
 	}
 
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/auth/sendCode", code, utils.GetMsg(code))
 
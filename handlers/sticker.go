@@ -10,29 +10,20 @@ import (
 )
 
 type StickerListRequestBody struct {
-	Uid string `json:"uid" form:"uid"`
+	Uid string `json:"uid" form:"uid" binding:"required"`
 }
 
 // StickerList 根据 uid 查询已获得的贴纸
 var StickerList = func(ctx *gin.Context) {
 	var params StickerListRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"uid": params.Uid,
 	}
@@ -63,13 +54,9 @@ var StickerList = func(ctx *gin.Context) {
 		"Content-Type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/sticker/list", code, utils.GetMsg(code))
 
@@ -80,29 +67,20 @@ var StickerList = func(ctx *gin.Context) {
 }
 
 type StickerBoardRequestBody struct {
-	Uid string `json:"uid" form:"uid"`
+	Uid string `json:"uid" form:"uid" binding:"required"`
 }
 
 // StickerBoard 根据 uid 查询贴纸墙
 var StickerBoard = func(ctx *gin.Context) {
 	var params StickerBoardRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Uid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"uid": params.Uid,
 	}
@@ -132,13 +110,9 @@ var StickerBoard = func(ctx *gin.Context) {
 		"Content-Type":                "application/json",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/sticker/get-board", code, utils.GetMsg(code))
 
@@ -22,12 +22,9 @@ type SubscriptionLoadMoreKey struct {
 
 // Subscription 我的订阅
 var Subscription = func(ctx *gin.Context) {
-	var params *SubscriptionBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params SubscriptionBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -76,13 +73,9 @@ var Subscription = func(ctx *gin.Context) {
 
 	ctx.Header("Content-Type", "application/json; charset=utf-8")
 
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/subscription/list", code, utils.GetMsg(code))
 
@@ -126,13 +119,9 @@ var StarSubscription = func(ctx *gin.Context) {
 
 	ctx.Header("Content-Type", "application/json; charset=utf-8")
 
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/subscription-star/list", code, utils.GetMsg(code))
 
@@ -176,13 +165,9 @@ var NonStarredSubscription = func(ctx *gin.Context) {
 
 	ctx.Header("Content-Type", "application/json; charset=utf-8")
 
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/subscription/list-non-starred", code, utils.GetMsg(code))
 
@@ -193,30 +178,21 @@ var NonStarredSubscription = func(ctx *gin.Context) {
 }
 
 type UpdateStarSubscriptionRequestBody struct {
-	Pid      string `form:"pid"`
+	Pid      string `form:"pid" binding:"required,xyzid"`
 	WithStar bool   `form:"withStar"`
 }
 
 // UpdateStarSubscription 更新星标订阅列表
 var UpdateStarSubscription = func(ctx *gin.Context) {
-	var params *UpdateStarSubscriptionRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params UpdateStarSubscriptionRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"pid":      params.Pid,
 		"withStar": params.WithStar,
@@ -248,13 +224,9 @@ var UpdateStarSubscription = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	res, code, err := utils.Request(url, http.MethodPost, p, headers)
+	res, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/subscription-star/update", code, utils.GetMsg(code))
 
@@ -265,30 +237,21 @@ var UpdateStarSubscription = func(ctx *gin.Context) {
 }
 
 type SubscriptionUpdateRequestBody struct {
-	Pid  string `form:"pid"`
-	Mode string `form:"mode"`
+	Pid  string `form:"pid" binding:"required,xyzid"`
+	Mode string `form:"mode" binding:"required,oneof=ON OFF"`
 }
 
 // SubscriptionUpdate 更新订阅
 var SubscriptionUpdate = func(ctx *gin.Context) {
-	var params *SubscriptionUpdateRequestBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
+	var params SubscriptionUpdateRequestBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
 	h := ctx.Request.Header
 	XJikeAccessToken := h.Get("x-jike-access-token")
 
-	if params.Pid == "" || (params.Mode != "ON" && params.Mode != "OFF") {
-		utils.ReturnBadRequest(ctx, nil)
-
-		return
-	}
-
 	p := map[string]any{
 		"pid":  params.Pid,
 		"mode": params.Mode,
@@ -321,13 +284,9 @@ var SubscriptionUpdate = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, p, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, p, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/subscription/update", code, utils.GetMsg(code))
 
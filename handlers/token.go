@@ -1,32 +1,29 @@
 package handlers
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	C "github.com/ultrazg/xyz/constant"
 	"github.com/ultrazg/xyz/utils"
+	"io"
 	"log"
 	"net/http"
 )
 
 type RefreshTokenRequestBody struct {
-	XJikeAccessToken  string `json:"x-jike-access-token" form:"x-jike-access-token"`
-	XJikeRefreshToken string `json:"x-jike-refresh-token" form:"x-jike-refresh-token"`
+	XJikeAccessToken  string `json:"x-jike-access-token" form:"x-jike-access-token" binding:"required"`
+	XJikeRefreshToken string `json:"x-jike-refresh-token" form:"x-jike-refresh-token" binding:"required"`
 }
 
-// RefreshToken 刷新token
+// RefreshToken 刷新token，通过 x-xyz-account 选择账号时使用服务端保存的 token，并保存刷新后的 token
 var RefreshToken = func(ctx *gin.Context) {
 	var params RefreshTokenRequestBody
 
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.XJikeAccessToken == "" || params.XJikeRefreshToken == "" {
-		utils.ReturnBadRequest(ctx, nil)
-
+	account := utils.CurrentAccount(ctx)
+	if account != nil {
+		params.XJikeAccessToken = account.AccessToken
+		params.XJikeRefreshToken = account.RefreshToken
+	} else if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -53,18 +50,63 @@ var RefreshToken = func(ctx *gin.Context) {
 		"x-custom-xiaoyuzhou-app-dev": "",
 	}
 
-	response, code, err := utils.Request(url, http.MethodPost, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodPost, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/app_auth_tokens.refresh", code, utils.GetMsg(code))
 
 		return
 	}
 
-	utils.ReturnJson(response, ctx)
+	if account == nil {
+		utils.ReturnJson(response, ctx)
+
+		return
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		log.Println("Error reading response body:", err)
+
+		utils.ReturnError(ctx, utils.NewError(http.StatusBadGateway, utils.ErrUpstreamInvalidResponse, err.Error()))
+
+		return
+	}
+
+	var data map[string]interface{}
+	_ = json.Unmarshal(body, &data)
+
+	accessToken, _ := data["x-jike-access-token"].(string)
+	refreshToken, _ := data["x-jike-refresh-token"].(string)
+
+	if accessToken == "" {
+		accessToken = response.Header.Get("x-jike-access-token")
+		refreshToken = response.Header.Get("x-jike-refresh-token")
+	}
+
+	if accessToken == "" {
+		utils.ReturnError(ctx, utils.NewError(http.StatusBadGateway, utils.ErrUpstreamInvalidResponse, "missing x-jike-access-token in refresh response"))
+
+		return
+	}
+
+	account, err = utils.UpdateAccountTokens(account.Name, accessToken, refreshToken)
+	if err != nil {
+		log.Println("Error saving account:", err)
+
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": gin.H{
+			"account": account.Info(),
+		},
+	})
 }
@@ -10,23 +10,14 @@ import (
 )
 
 type TopBody struct {
-	Category string `form:"category"`
+	Category string `form:"category" binding:"required,oneof=HOT ROCK NEW"`
 }
 
 // GetTopList 获取完整榜单(最热榜、锋芒榜和新星榜)
 var GetTopList = func(ctx *gin.Context) {
-	var params *TopBody
-
-	err := ctx.ShouldBind(&params)
-	if err != nil {
-		utils.ReturnBadRequest(ctx, err)
-
-		return
-	}
-
-	if params.Category == "" {
-		utils.ReturnBadRequest(ctx, nil)
+	var params TopBody
 
+	if !utils.Bind(ctx, &params) {
 		return
 	}
 
@@ -39,10 +30,6 @@ var GetTopList = func(ctx *gin.Context) {
 		p = "SKYROCKET_EPISODES"
 	case "NEW":
 		p = "NEW_STAR_EPISODES"
-	default:
-		p = "HOT_EPISODES_IN_24_HOURS"
-
-		return
 	}
 
 	h := ctx.Request.Header
@@ -73,13 +60,9 @@ var GetTopList = func(ctx *gin.Context) {
 		"Timezone":                    "Asia/Shanghai",
 	}
 
-	response, code, err := utils.Request(url, http.MethodGet, nil, headers)
+	response, code, err := utils.Request(ctx.Request.Context(), url, http.MethodGet, nil, headers)
 	if err != nil {
-		ctx.JSON(code, gin.H{
-			"code": code,
-			"msg":  utils.GetMsg(code),
-			"data": err.Error(),
-		})
+		utils.ReturnError(ctx, err)
 
 		log.Println("/v1/top-list/get?category="+p, code, utils.GetMsg(code))
 
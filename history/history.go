@@ -0,0 +1,126 @@
+// Package history 保存节目元数据的版本，计算字段级的差异并生成变更记录
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change 一个字段的变化，新增的字段没有 Before，删除的字段没有 After
+type Change struct {
+	Field  string `json:"field"`
+	Kind   string `json:"kind"` // added、removed 或 changed
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Version 一个版本的全部字段，Changes 为相对于上一个版本的变化，第一个版本没有变化
+type Version struct {
+	Version int               `json:"version"`
+	At      time.Time         `json:"at"`
+	Fields  map[string]string `json:"fields"`
+	Changes []Change          `json:"changes"`
+}
+
+// History 一个节目的版本，按时间正序排列
+type History struct {
+	Pid       string    `json:"pid"`
+	Versions  []Version `json:"versions"`
+	CheckedAt time.Time `json:"checkedAt"` // 最近一次获取快照的时间
+	Checks    int       `json:"checks"`
+}
+
+// Diff 比较两个版本的字段，按字段名排列
+func Diff(before, after map[string]string) []Change {
+	changes := []Change{}
+
+	for field, value := range after {
+		old, found := before[field]
+
+		switch {
+		case !found:
+			changes = append(changes, Change{Field: field, Kind: ChangeAdded, After: value})
+		case old != value:
+			changes = append(changes, Change{Field: field, Kind: ChangeChanged, Before: old, After: value})
+		}
+	}
+
+	for field, value := range before {
+		if _, found := after[field]; !found {
+			changes = append(changes, Change{Field: field, Kind: ChangeRemoved, Before: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+// Latest 最新的版本，没有版本时返回 nil
+func (h *History) Latest() *Version {
+	if len(h.Versions) == 0 {
+		return nil
+	}
+
+	return &h.Versions[len(h.Versions)-1]
+}
+
+// Record 记录一次快照，与最新的版本不同时保存为新的版本并返回，否则返回 nil。
+// 最多保留 keep 个版本，超出时删除最早的版本，版本号不会重复使用
+func (h *History) Record(fields map[string]string, at time.Time, keep int) *Version {
+	h.CheckedAt = at
+	h.Checks++
+
+	version := Version{Version: 1, At: at, Fields: fields, Changes: []Change{}}
+
+	if latest := h.Latest(); latest != nil {
+		version.Version = latest.Version + 1
+		if version.Changes = Diff(latest.Fields, fields); len(version.Changes) == 0 {
+			return nil
+		}
+	}
+
+	h.Versions = append(h.Versions, version)
+	if keep > 0 && len(h.Versions) > keep {
+		h.Versions = append([]Version(nil), h.Versions[len(h.Versions)-keep:]...)
+	}
+
+	return h.Latest()
+}
+
+// Entry 变更记录中的一项
+type Entry struct {
+	Version int       `json:"version"`
+	At      time.Time `json:"at"`
+	Changes []Change  `json:"changes"`
+}
+
+// Changelog 从新到旧的变更记录，field 不为空时只包含该字段的变化，第一个版本不包含在内
+func (h *History) Changelog(field string) []Entry {
+	entries := []Entry{}
+
+	for i := len(h.Versions) - 1; i >= 0; i-- {
+		v := h.Versions[i]
+
+		changes := []Change{}
+		for _, c := range v.Changes {
+			if field == "" || c.Field == field {
+				changes = append(changes, c)
+			}
+		}
+
+		if len(changes) > 0 {
+			entries = append(entries, Entry{Version: v.Version, At: v.At, Changes: changes})
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,34 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]string
+		after  map[string]string
+		want   []Change
+	}{
+		{"没有变化", map[string]string{"title": "a"}, map[string]string{"title": "a"}, []Change{}},
+		{"第一个版本全部为新增", nil, map[string]string{"title": "a", "author": "b"}, []Change{
+			{Field: "author", Kind: ChangeAdded, After: "b"},
+			{Field: "title", Kind: ChangeAdded, After: "a"},
+		}},
+		{"新增、删除和修改按字段名排列", map[string]string{"title": "a", "bulletin": "每周更新", "ipLoc": "北京"}, map[string]string{"title": "a", "bulletin": "停更一周", "brief": "简介"}, []Change{
+			{Field: "brief", Kind: ChangeAdded, After: "简介"},
+			{Field: "bulletin", Kind: ChangeChanged, Before: "每周更新", After: "停更一周"},
+			{Field: "ipLoc", Kind: ChangeRemoved, Before: "北京"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
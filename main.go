@@ -4,12 +4,22 @@ import (
 	"github.com/ultrazg/xyz/service"
 	"github.com/ultrazg/xyz/utils"
 	"log"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 && service.IsCommand(os.Args[1]) {
+		err := service.Command(os.Args[1], os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	utils.InitCache()
 	err := service.Start()
 	if err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,173 @@
+package mock
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sendCode 发送验证码，之后使用 VerifyCode 登录
+func (s *Server) sendCode(ctx *gin.Context) {
+	p := body(ctx)
+
+	phone := stringValue(p["mobilePhoneNumber"])
+	if phone == "" {
+		fail(ctx, http.StatusBadRequest, "请输入手机号")
+
+		return
+	}
+
+	s.codes[phone] = true
+
+	success(ctx)
+}
+
+// login 短信登录，手机号不存在时注册新用户，token 在响应头中返回
+func (s *Server) login(ctx *gin.Context) {
+	p := body(ctx)
+
+	phone := stringValue(p["mobilePhoneNumber"])
+
+	if !s.codes[phone] || stringValue(p["verifyCode"]) != VerifyCode {
+		fail(ctx, http.StatusBadRequest, "验证码错误")
+
+		return
+	}
+
+	delete(s.codes, phone)
+
+	var current *user
+	for _, u := range s.data.users {
+		if u.Phone == phone {
+			current = u
+
+			break
+		}
+	}
+
+	signUp := current == nil
+	if signUp {
+		current = &user{
+			Uid:      seedId(kindUser, len(s.data.users)+1),
+			Nickname: fmt.Sprintf("用户%s", phone[max(len(phone)-4, 0):]),
+			Phone:    phone,
+			Gender:   "MALE",
+		}
+		s.data.addUser(current)
+	}
+
+	accessToken, refreshToken := s.issueTokens(current.Uid)
+
+	ctx.Header("x-jike-access-token", accessToken)
+	ctx.Header("x-jike-refresh-token", refreshToken)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"isSignUp": signUp,
+			"user":     s.userJSON(current.Uid, current),
+		},
+	})
+}
+
+// refreshToken 刷新 token，刷新后旧的 access token 和 refresh token 立即失效
+func (s *Server) refreshToken(ctx *gin.Context) {
+	refreshToken := ctx.GetHeader("x-jike-refresh-token")
+
+	t, found := s.refreshTokens[refreshToken]
+	if !found {
+		fail(ctx, http.StatusUnauthorized, "登录已失效，请重新登录")
+
+		return
+	}
+
+	delete(s.refreshTokens, refreshToken)
+
+	for key, access := range s.accessTokens {
+		if access.uid == t.uid {
+			delete(s.accessTokens, key)
+		}
+	}
+
+	accessToken, newRefreshToken := s.issueTokens(t.uid)
+
+	ctx.Header("x-jike-access-token", accessToken)
+	ctx.Header("x-jike-refresh-token", newRefreshToken)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":              true,
+		"x-jike-access-token":  accessToken,
+		"x-jike-refresh-token": newRefreshToken,
+	})
+}
+
+// requireToken 校验 x-jike-access-token，过期或已刷新的 token 返回 401
+func (s *Server) requireToken(ctx *gin.Context) {
+	accessToken := ctx.GetHeader("x-jike-access-token")
+
+	s.mu.Lock()
+	t, found := s.accessTokens[accessToken]
+	s.mu.Unlock()
+
+	if !found {
+		fail(ctx, http.StatusUnauthorized, "请先登录")
+
+		return
+	}
+
+	if time.Now().After(t.expires) {
+		fail(ctx, http.StatusUnauthorized, "登录已过期")
+
+		return
+	}
+
+	ctx.Set(uidContextKey, t.uid)
+	ctx.Next()
+}
+
+// Login 直接为种子数据中的第一个用户签发 token，便于在测试中跳过短信登录
+func (s *Server) Login() (accessToken, refreshToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.issueTokens(s.data.users[0].Uid)
+}
+
+// issueTokens 签发 JWT 格式的 token，exp 为 access token 的过期时间
+func (s *Server) issueTokens(uid string) (string, string) {
+	expires := time.Now().Add(s.TokenTTL)
+
+	accessToken := newToken(uid, expires)
+	refreshToken := newToken(uid, time.Time{})
+
+	s.accessTokens[accessToken] = &token{uid: uid, expires: expires}
+	s.refreshTokens[refreshToken] = &token{uid: uid}
+
+	return accessToken, refreshToken
+}
+
+func newToken(uid string, expires time.Time) string {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+
+	claims := map[string]any{"uid": uid, "jti": hex.EncodeToString(nonce)}
+	if !expires.IsZero() {
+		claims["exp"] = expires.Unix()
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signature := make([]byte, 32)
+	_, _ = rand.Read(signature)
+
+	return base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,205 @@
+package mock
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) commentJSON(uid string, c *comment) gin.H {
+	likes := s.likes[c.Id]
+	_, collected := s.state(uid).collected[c.Id]
+
+	replyCount := 0
+	for _, reply := range s.data.comments {
+		if reply.PrimaryId == c.Id {
+			replyCount++
+		}
+	}
+
+	res := gin.H{
+		"type":         "COMMENT",
+		"id":           c.Id,
+		"owner":        gin.H{"id": c.Eid, "type": "EPISODE"},
+		"author":       s.userJSON(uid, s.data.userById[c.Uid]),
+		"text":         c.Text,
+		"createdAt":    formatTime(c.CreatedAt),
+		"likeCount":    c.LikeCount + len(likes),
+		"liked":        likes[uid],
+		"collected":    collected,
+		"replyCount":   replyCount,
+		"hotSortScore": c.HotScore,
+	}
+
+	if c.PrimaryId != "" {
+		res["primaryCommentId"] = c.PrimaryId
+	}
+
+	return res
+}
+
+// commentParam 按 id 查询评论，不存在时返回 404
+func (s *Server) commentParam(ctx *gin.Context, id string) (*comment, bool) {
+	c, found := s.data.commentById[id]
+	if !found {
+		fail(ctx, http.StatusNotFound, "评论不存在")
+	}
+
+	return c, found
+}
+
+// commentListPrimary 单集的评论，HOT 按热度排序，TIME 按时间倒序，TIMESTAMP 按时间正序，
+// loadMoreKey 为上一页最后一条评论的 {hotSortScore, id, direction}
+func (s *Server) commentListPrimary(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	owner, _ := p["owner"].(map[string]any)
+
+	e, found := s.episodeParam(ctx, stringValue(owner["id"]))
+	if !found {
+		return
+	}
+
+	var comments []*comment
+	for _, c := range s.data.comments {
+		if c.Eid == e.Eid && c.PrimaryId == "" {
+			comments = append(comments, c)
+		}
+	}
+
+	order := stringValue(p["order"])
+	sort.SliceStable(comments, func(i, j int) bool {
+		switch order {
+		case "TIME":
+			return comments[i].CreatedAt.After(comments[j].CreatedAt)
+		case "TIMESTAMP":
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+
+		return comments[i].HotScore > comments[j].HotScore
+	})
+
+	items, more := page(comments, cursorId(p["loadMoreKey"]), pageSize, func(c *comment) string {
+		return c.Id
+	})
+
+	data := []gin.H{}
+	for _, c := range items {
+		data = append(data, s.commentJSON(uid, c))
+	}
+
+	res := gin.H{"data": data}
+	if more {
+		last := items[len(items)-1]
+		res["loadMoreKey"] = gin.H{"hotSortScore": last.HotScore, "id": last.Id, "direction": "NEXT"}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// commentListThread 评论的回复，SMART 按热度排序，TIME 按时间正序
+func (s *Server) commentListThread(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	primary, found := s.commentParam(ctx, stringValue(p["primaryCommentId"]))
+	if !found {
+		return
+	}
+
+	var replies []*comment
+	for _, c := range s.data.comments {
+		if c.PrimaryId == primary.Id {
+			replies = append(replies, c)
+		}
+	}
+
+	smart := stringValue(p["order"]) == "SMART"
+	sort.SliceStable(replies, func(i, j int) bool {
+		if smart {
+			return replies[i].HotScore > replies[j].HotScore
+		}
+
+		return replies[i].CreatedAt.Before(replies[j].CreatedAt)
+	})
+
+	data := []gin.H{}
+	for _, c := range replies {
+		data = append(data, s.commentJSON(uid, c))
+	}
+
+	respond(ctx, data)
+}
+
+func (s *Server) commentCollectCreate(ctx *gin.Context) {
+	c, found := s.commentParam(ctx, stringValue(body(ctx)["commentId"]))
+	if !found {
+		return
+	}
+
+	s.state(currentUid(ctx)).collected[c.Id] = time.Now()
+
+	success(ctx)
+}
+
+func (s *Server) commentCollectRemove(ctx *gin.Context) {
+	c, found := s.commentParam(ctx, stringValue(body(ctx)["commentId"]))
+	if !found {
+		return
+	}
+
+	delete(s.state(currentUid(ctx)).collected, c.Id)
+
+	success(ctx)
+}
+
+// commentCollectList 收藏的评论，按收藏时间倒序
+func (s *Server) commentCollectList(ctx *gin.Context) {
+	uid := currentUid(ctx)
+	collected := s.state(uid).collected
+
+	ids := make([]string, 0, len(collected))
+	for id := range collected {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return collected[ids[i]].After(collected[ids[j]])
+	})
+
+	data := []gin.H{}
+	for _, id := range ids {
+		item := s.commentJSON(uid, s.data.commentById[id])
+		item["collectedAt"] = formatTime(collected[id])
+		data = append(data, item)
+	}
+
+	respond(ctx, data)
+}
+
+func (s *Server) likeUpdate(ctx *gin.Context) {
+	p := body(ctx)
+	target, _ := p["target"].(map[string]any)
+
+	c, found := s.commentParam(ctx, stringValue(target["id"]))
+	if !found {
+		return
+	}
+
+	uid := currentUid(ctx)
+
+	if s.likes[c.Id] == nil {
+		s.likes[c.Id] = map[string]bool{}
+	}
+
+	if boolValue(p["liked"]) {
+		s.likes[c.Id][uid] = true
+	} else {
+		delete(s.likes[c.Id], uid)
+	}
+
+	success(ctx)
+}
@@ -0,0 +1,208 @@
+package mock
+
+import (
+	"fmt"
+	"time"
+)
+
+// 种子数据的 id 与上游格式一致，为 24 位十六进制字符串，前两位区分类型
+const (
+	kindUser     = 0x10
+	kindPodcast  = 0x20
+	kindEpisode  = 0x30
+	kindComment  = 0x40
+	kindCategory = 0x50
+)
+
+// SeedPhone 种子数据中第一个用户的手机号，使用其它手机号登录时会注册新用户
+const SeedPhone = "13800000000"
+
+// seedTime 种子数据的基准时间，数据与运行时间无关，便于在测试中断言
+var seedTime = time.Date(2024, 6, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+type user struct {
+	Uid      string
+	Nickname string
+	Bio      string
+	Phone    string
+	Gender   string
+}
+
+type category struct {
+	Id    string
+	Name  string
+	Emoji string
+}
+
+type podcast struct {
+	Pid               string
+	Title             string
+	Author            string
+	Description       string
+	CategoryId        string
+	PodcasterUid      string
+	SubscriptionCount int
+	Bulletin          string
+}
+
+type episode struct {
+	Eid           string
+	Pid           string
+	Title         string
+	Description   string
+	Shownotes     string
+	Duration      int
+	PubDate       time.Time
+	PlayCount     int
+	ClapCount     int
+	FavoriteCount int
+}
+
+type comment struct {
+	Id        string
+	Eid       string
+	PrimaryId string
+	Uid       string
+	Text      string
+	CreatedAt time.Time
+	LikeCount int
+	HotScore  int
+}
+
+// dataset 种子数据，只读，用户操作产生的数据保存在 userState 中
+type dataset struct {
+	users      []*user
+	categories []*category
+	podcasts   []*podcast
+	episodes   []*episode
+	comments   []*comment
+
+	userById     map[string]*user
+	podcastById  map[string]*podcast
+	episodeById  map[string]*episode
+	commentById  map[string]*comment
+	categoryById map[string]*category
+}
+
+func seedId(kind, n int) string {
+	return fmt.Sprintf("%02x%022x", kind, n)
+}
+
+// newDataset 生成固定的种子数据：8 个用户、4 个分类、6 个节目，每个节目 25 期单集，
+// 每个节目的最新一期有 25 条评论，其余单集有 1 ~ 5 条评论，每条评论有 2 条回复
+func newDataset() *dataset {
+	d := &dataset{
+		userById:     map[string]*user{},
+		podcastById:  map[string]*podcast{},
+		episodeById:  map[string]*episode{},
+		commentById:  map[string]*comment{},
+		categoryById: map[string]*category{},
+	}
+
+	nicknames := []string{"小宇宙", "播客观察员", "深夜电台", "通勤听众", "咖啡与声音", "科技早知道", "历史迷", "跑步的人"}
+	for i, nickname := range nicknames {
+		d.addUser(&user{
+			Uid:      seedId(kindUser, i+1),
+			Nickname: nickname,
+			Bio:      fmt.Sprintf("这是%s的简介", nickname),
+			Phone:    fmt.Sprintf("138%08d", i),
+			Gender:   []string{"MALE", "FEMALE"}[i%2],
+		})
+	}
+
+	for i, name := range []string{"科技", "文化", "商业", "生活"} {
+		c := &category{Id: seedId(kindCategory, i+1), Name: name, Emoji: []string{"💻", "📚", "💼", "🏠"}[i]}
+		d.categories = append(d.categories, c)
+		d.categoryById[c.Id] = c
+	}
+
+	titles := []string{"硅谷早知道", "声东击西", "商业就是这样", "忽左忽右", "日谈公园", "科技乱炖"}
+	books := []string{"三体", "百年孤独", "人类简史", "活着"}
+	for i, title := range titles {
+		p := &podcast{
+			Pid:               seedId(kindPodcast, i+1),
+			Title:             title,
+			Author:            d.users[i%len(d.users)].Nickname,
+			Description:       fmt.Sprintf("%s的节目简介", title),
+			CategoryId:        d.categories[i%len(d.categories)].Id,
+			PodcasterUid:      d.users[i%len(d.users)].Uid,
+			SubscriptionCount: 1000 * (len(titles) - i),
+			Bulletin:          fmt.Sprintf("%s每周更新", title),
+		}
+		d.podcasts = append(d.podcasts, p)
+		d.podcastById[p.Pid] = p
+
+		for j := 0; j < 25; j++ {
+			n := i*25 + j + 1
+			e := &episode{
+				Eid:           seedId(kindEpisode, n),
+				Pid:           p.Pid,
+				Title:         fmt.Sprintf("%s EP%02d", title, 25-j),
+				Description:   fmt.Sprintf("%s第 %d 期", title, 25-j),
+				Duration:      1800 + 60*((n*7)%60),
+				PubDate:       seedTime.Add(-time.Duration(j*72+i) * time.Hour),
+				PlayCount:     (n * 7919) % 100000,
+				ClapCount:     (n * 31) % 500,
+				FavoriteCount: (n * 17) % 300,
+			}
+			e.Shownotes = fmt.Sprintf(`<p>%s</p><p>00:00 开场</p><p>05:30 正题，聊到《%s》</p><p>%02d:00 结尾</p><p>延伸阅读：<a href="https://www.example.com/articles/%d?utm_source=xiaoyuzhou">第 %d 篇文章</a> https://example.com/books/%d/</p>`,
+				e.Description, books[n%len(books)], e.Duration/60-1, n%10, n%10, n%3)
+			d.episodes = append(d.episodes, e)
+			d.episodeById[e.Eid] = e
+
+			count := 1 + n%5
+			if j == 0 {
+				count = 25
+			}
+
+			d.addComments(e, count)
+		}
+	}
+
+	return d
+}
+
+func (d *dataset) addUser(u *user) {
+	d.users = append(d.users, u)
+	d.userById[u.Uid] = u
+}
+
+func (d *dataset) addComments(e *episode, count int) {
+	for k := 0; k < count; k++ {
+		primary := d.addComment(e, "", k, fmt.Sprintf("%s 的第 %d 条评论", e.Title, k+1))
+
+		for r := 0; r < 2; r++ {
+			d.addComment(e, primary.Id, k+r+1, fmt.Sprintf("回复：%s", primary.Text))
+		}
+	}
+}
+
+func (d *dataset) addComment(e *episode, primaryId string, k int, text string) *comment {
+	n := len(d.comments) + 1
+	c := &comment{
+		Id:        seedId(kindComment, n),
+		Eid:       e.Eid,
+		PrimaryId: primaryId,
+		Uid:       d.users[n%len(d.users)].Uid,
+		Text:      text,
+		CreatedAt: e.PubDate.Add(time.Duration(k+1) * time.Hour),
+		LikeCount: (n * 13) % 200,
+		HotScore:  (n * 7) % 1000,
+	}
+	d.comments = append(d.comments, c)
+	d.commentById[c.Id] = c
+
+	return c
+}
+
+// podcastEpisodes 节目的单集，按发布时间倒序
+func (d *dataset) podcastEpisodes(pid string) []*episode {
+	var episodes []*episode
+	for _, e := range d.episodes {
+		if e.Pid == pid {
+			episodes = append(episodes, e)
+		}
+	}
+
+	return episodes
+}
@@ -0,0 +1,421 @@
+package mock
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) episodeJSON(uid string, e *episode) gin.H {
+	p := s.data.podcastById[e.Pid]
+	state := s.state(uid)
+
+	_, favorited := state.favorites[e.Eid]
+
+	commentCount := 0
+	for _, c := range s.data.comments {
+		if c.Eid == e.Eid {
+			commentCount++
+		}
+	}
+
+	res := gin.H{
+		"type":          "EPISODE",
+		"eid":           e.Eid,
+		"pid":           e.Pid,
+		"title":         e.Title,
+		"description":   e.Description,
+		"shownotes":     e.Shownotes,
+		"duration":      e.Duration,
+		"pubDate":       formatTime(e.PubDate),
+		"playCount":     e.PlayCount,
+		"clapCount":     e.ClapCount,
+		"commentCount":  commentCount,
+		"favoriteCount": e.FavoriteCount,
+		"isFavorited":   favorited,
+		"enclosure":     gin.H{"url": "https://mock.xiaoyuzhoufm.com/audio/" + e.Eid + ".m4a"},
+		"image":         image("episode", e.Eid),
+		"podcast": gin.H{
+			"type":   "PODCAST",
+			"pid":    p.Pid,
+			"title":  p.Title,
+			"author": p.Author,
+			"image":  image("podcast", p.Pid),
+		},
+	}
+
+	if progress, found := state.progress[e.Eid]; found {
+		res["playedAt"] = progress.playedAt
+		res["progress"] = progress.progress
+	}
+
+	return res
+}
+
+// episodeParam 按 eid 查询单集，不存在时返回 404
+func (s *Server) episodeParam(ctx *gin.Context, eid string) (*episode, bool) {
+	e, found := s.data.episodeById[eid]
+	if !found {
+		fail(ctx, http.StatusNotFound, "单集不存在")
+	}
+
+	return e, found
+}
+
+// episodeList 节目的单集列表，loadMoreKey 为上一页最后一集的 {pubDate, id, direction}
+func (s *Server) episodeList(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	if _, found := s.podcastParam(ctx, stringValue(p["pid"])); !found {
+		return
+	}
+
+	episodes := append([]*episode{}, s.data.podcastEpisodes(stringValue(p["pid"]))...)
+	if stringValue(p["order"]) == "asc" {
+		for i, j := 0, len(episodes)-1; i < j; i, j = i+1, j-1 {
+			episodes[i], episodes[j] = episodes[j], episodes[i]
+		}
+	}
+
+	items, more := page(episodes, cursorId(p["loadMoreKey"]), intValue(p["limit"], pageSize), func(e *episode) string {
+		return e.Eid
+	})
+
+	data := []gin.H{}
+	for _, e := range items {
+		data = append(data, s.episodeJSON(uid, e))
+	}
+
+	res := gin.H{"data": data}
+	if more {
+		last := items[len(items)-1]
+		res["loadMoreKey"] = gin.H{"pubDate": formatTime(last.PubDate), "id": last.Eid, "direction": "NEXT"}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (s *Server) episodeGet(ctx *gin.Context) {
+	e, found := s.episodeParam(ctx, param(ctx, body(ctx), "eid"))
+	if !found {
+		return
+	}
+
+	respond(ctx, s.episodeJSON(currentUid(ctx), e))
+}
+
+// episodeListByFilter 节目内播放量最高的 10 集
+func (s *Server) episodeListByFilter(ctx *gin.Context) {
+	pid := param(ctx, body(ctx), "pid")
+	if _, found := s.podcastParam(ctx, pid); !found {
+		return
+	}
+
+	episodes := append([]*episode{}, s.data.podcastEpisodes(pid)...)
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].PlayCount > episodes[j].PlayCount
+	})
+
+	var data []gin.H
+	for _, e := range episodes[:10] {
+		data = append(data, s.episodeJSON(currentUid(ctx), e))
+	}
+
+	respond(ctx, data)
+}
+
+func (s *Server) playbackProgressList(ctx *gin.Context) {
+	p := body(ctx)
+	state := s.state(currentUid(ctx))
+
+	eids, _ := p["eids"].([]any)
+
+	data := []gin.H{}
+	for _, eid := range eids {
+		if progress, found := state.progress[stringValue(eid)]; found {
+			data = append(data, gin.H{"eid": progress.eid, "pid": progress.pid, "progress": progress.progress, "playedAt": progress.playedAt})
+		}
+	}
+
+	respond(ctx, data)
+}
+
+func (s *Server) playbackProgressUpdate(ctx *gin.Context) {
+	p := body(ctx)
+	state := s.state(currentUid(ctx))
+
+	items, _ := p["data"].([]any)
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+
+		eid := stringValue(m["eid"])
+		if _, found := s.data.episodeById[eid]; !found {
+			continue
+		}
+
+		state.progress[eid] = &progress{
+			eid:      eid,
+			pid:      stringValue(m["pid"]),
+			progress: intValue(m["progress"], 0),
+			playedAt: stringValue(m["playedAt"]),
+		}
+	}
+
+	success(ctx)
+}
+
+// liveStatsGet 正在收听的人数，为最近 5 分钟内上报播放状态的次数
+func (s *Server) liveStatsGet(ctx *gin.Context) {
+	e, found := s.episodeParam(ctx, param(ctx, body(ctx), "eid"))
+	if !found {
+		return
+	}
+
+	count := 0
+	for _, t := range s.liveReports[e.Eid] {
+		if time.Since(t) < 5*time.Minute {
+			count++
+		}
+	}
+
+	respond(ctx, gin.H{"eid": e.Eid, "listenerCount": count})
+}
+
+func (s *Server) liveStatsReport(ctx *gin.Context) {
+	p := body(ctx)
+	stats, _ := p["playStats"].(map[string]any)
+
+	e, found := s.episodeParam(ctx, stringValue(stats["eid"]))
+	if !found {
+		return
+	}
+
+	s.liveReports[e.Eid] = append(s.liveReports[e.Eid], time.Now())
+
+	success(ctx)
+}
+
+// playedList 用户最近收听的单集
+func (s *Server) playedList(ctx *gin.Context) {
+	uid := param(ctx, body(ctx), "uid")
+
+	data := []gin.H{}
+	for _, item := range s.history(uid) {
+		data = append(data, gin.H{"episode": s.episodeJSON(currentUid(ctx), s.data.episodeById[item.eid]), "playedAt": formatTime(item.playedAt)})
+	}
+
+	respond(ctx, data)
+}
+
+// playedHistory 收听历史，loadMoreKey 为上一页最后一集的 eid
+func (s *Server) playedHistory(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	items, more := page(s.history(uid), cursorId(p["loadMoreKey"]), pageSize, func(item *played) string {
+		return item.eid
+	})
+
+	data := []gin.H{}
+	for _, item := range items {
+		data = append(data, gin.H{"episode": s.episodeJSON(uid, s.data.episodeById[item.eid]), "playedAt": formatTime(item.playedAt)})
+	}
+
+	res := gin.H{"data": data}
+	if more {
+		res["loadMoreKey"] = items[len(items)-1].eid
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// history 收听历史，按收听时间倒序，同一单集只保留最近一次
+func (s *Server) history(uid string) []*played {
+	history := s.state(uid).history
+
+	items := make([]*played, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		items = append(items, history[i])
+	}
+
+	return items
+}
+
+func (s *Server) playedCreate(ctx *gin.Context) {
+	e, found := s.episodeParam(ctx, param(ctx, body(ctx), "eid"))
+	if !found {
+		return
+	}
+
+	state := s.state(currentUid(ctx))
+
+	history := state.history[:0]
+	for _, item := range state.history {
+		if item.eid != e.Eid {
+			history = append(history, item)
+		}
+	}
+
+	state.history = append(history, &played{eid: e.Eid, playedAt: time.Now()})
+
+	success(ctx)
+}
+
+// clapList 精彩时间点，按 duration 将时间线分为 100 段，episodeClaps 为每段的标记数，myClaps 为当前用户标记的段的下标
+func (s *Server) clapList(ctx *gin.Context) {
+	p := body(ctx)
+
+	e, found := s.episodeParam(ctx, stringValue(p["eid"]))
+	if !found {
+		return
+	}
+
+	duration := intValue(p["duration"], e.Duration)
+	if duration <= 0 {
+		duration = e.Duration
+	}
+
+	const segments = 100
+
+	index := func(t int) int {
+		return min(t*segments/duration, segments-1)
+	}
+
+	counts := make([]int, segments)
+
+	// 种子数据在开头和中间各有一个高峰
+	for i := 0; i < e.ClapCount; i++ {
+		t := (i * 37) % duration
+		if i%3 == 0 {
+			t = duration / 2
+		}
+
+		counts[index(t)]++
+	}
+
+	uid := currentUid(ctx)
+	mine := []gin.H{}
+
+	for id, state := range s.states {
+		for _, t := range state.claps[e.Eid] {
+			counts[index(t)]++
+
+			if id == uid {
+				mine = append(mine, gin.H{"index": index(t)})
+			}
+		}
+	}
+
+	episodeClaps := make([]gin.H, segments)
+	for i, count := range counts {
+		episodeClaps[i] = gin.H{"count": count}
+	}
+
+	respond(ctx, gin.H{"episodeClaps": episodeClaps, "myClaps": mine})
+}
+
+func (s *Server) clapCreate(ctx *gin.Context) {
+	p := body(ctx)
+
+	e, found := s.episodeParam(ctx, stringValue(p["eid"]))
+	if !found {
+		return
+	}
+
+	timestamp := intValue(p["timestamp"], -1)
+	if timestamp < 0 || timestamp > e.Duration {
+		fail(ctx, http.StatusBadRequest, "时间点超出单集时长")
+
+		return
+	}
+
+	state := s.state(currentUid(ctx))
+	state.claps[e.Eid] = append(state.claps[e.Eid], timestamp)
+
+	success(ctx)
+}
+
+func (s *Server) favoriteUpdate(ctx *gin.Context) {
+	p := body(ctx)
+
+	e, found := s.episodeParam(ctx, stringValue(p["eid"]))
+	if !found {
+		return
+	}
+
+	state := s.state(currentUid(ctx))
+	if boolValue(p["favorited"]) {
+		state.favorites[e.Eid] = time.Now()
+	} else {
+		delete(state.favorites, e.Eid)
+	}
+
+	success(ctx)
+}
+
+// favoriteList 收藏的单集，按收藏时间倒序
+func (s *Server) favoriteList(ctx *gin.Context) {
+	uid := currentUid(ctx)
+
+	respond(ctx, s.favorites(uid, uid))
+}
+
+func (s *Server) favorites(viewer, uid string) []gin.H {
+	state := s.state(uid)
+
+	eids := make([]string, 0, len(state.favorites))
+	for eid := range state.favorites {
+		eids = append(eids, eid)
+	}
+
+	sort.Slice(eids, func(i, j int) bool {
+		return state.favorites[eids[i]].After(state.favorites[eids[j]])
+	})
+
+	data := []gin.H{}
+	for _, eid := range eids {
+		item := s.episodeJSON(viewer, s.data.episodeById[eid])
+		item["favoritedAt"] = formatTime(state.favorites[eid])
+		data = append(data, item)
+	}
+
+	return data
+}
+
+// inboxList 已订阅节目的最新单集，loadMoreKey 为上一页最后一集的 {pubDate, id}
+func (s *Server) inboxList(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+	state := s.state(uid)
+
+	var episodes []*episode
+	for _, e := range s.data.episodes {
+		if _, found := state.subscriptions[e.Pid]; found {
+			episodes = append(episodes, e)
+		}
+	}
+
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].PubDate.After(episodes[j].PubDate)
+	})
+
+	items, more := page(episodes, cursorId(p["loadMoreKey"]), intValue(p["limit"], pageSize), func(e *episode) string {
+		return e.Eid
+	})
+
+	data := []gin.H{}
+	for _, e := range items {
+		data = append(data, s.episodeJSON(uid, e))
+	}
+
+	res := gin.H{"data": data}
+	if more {
+		last := items[len(items)-1]
+		res["loadMoreKey"] = gin.H{"pubDate": formatTime(last.PubDate), "id": last.Eid}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,343 @@
+package mock
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) podcastJSON(uid string, p *podcast) gin.H {
+	episodes := s.data.podcastEpisodes(p.Pid)
+
+	status := "OFF"
+	starred := false
+	count := p.SubscriptionCount
+	for id, state := range s.states {
+		if sub, found := state.subscriptions[p.Pid]; found {
+			count++
+
+			if id == uid {
+				status = "ON"
+				starred = sub.starred
+			}
+		}
+	}
+
+	return gin.H{
+		"type":                 "PODCAST",
+		"pid":                  p.Pid,
+		"title":                p.Title,
+		"author":               p.Author,
+		"description":          p.Description,
+		"subscriptionCount":    count,
+		"episodeCount":         len(episodes),
+		"latestEpisodePubDate": formatTime(episodes[0].PubDate),
+		"subscriptionStatus":   status,
+		"subscriptionStar":     starred,
+		"image":                image("podcast", p.Pid),
+		"podcasters":           []gin.H{s.userJSON(uid, s.data.userById[p.PodcasterUid])},
+	}
+}
+
+func image(kind, id string) gin.H {
+	url := "https://mock.xiaoyuzhoufm.com/" + kind + "/" + id + ".png"
+
+	return gin.H{"picUrl": url, "largePicUrl": url, "middlePicUrl": url, "smallPicUrl": url, "thumbnailUrl": url}
+}
+
+// podcastParam 按 pid 查询节目，不存在时返回 404
+func (s *Server) podcastParam(ctx *gin.Context, pid string) (*podcast, bool) {
+	p, found := s.data.podcastById[pid]
+	if !found {
+		fail(ctx, http.StatusNotFound, "节目不存在")
+	}
+
+	return p, found
+}
+
+func (s *Server) podcastGet(ctx *gin.Context) {
+	p, found := s.podcastParam(ctx, param(ctx, body(ctx), "pid"))
+	if !found {
+		return
+	}
+
+	respond(ctx, s.podcastJSON(currentUid(ctx), p))
+}
+
+func (s *Server) podcastGetInfo(ctx *gin.Context) {
+	p, found := s.podcastParam(ctx, param(ctx, body(ctx), "pid"))
+	if !found {
+		return
+	}
+
+	respond(ctx, gin.H{
+		"podcast":  s.podcastJSON(currentUid(ctx), p),
+		"category": s.categoryJSON(s.data.categoryById[p.CategoryId]),
+		"ipLoc":    "上海",
+		"subject":  "个人",
+	})
+}
+
+func (s *Server) podcastBulletin(ctx *gin.Context) {
+	p, found := s.podcastParam(ctx, param(ctx, body(ctx), "pid"))
+	if !found {
+		return
+	}
+
+	respond(ctx, gin.H{"pid": p.Pid, "content": p.Bulletin})
+}
+
+func (s *Server) podcastHonorList(ctx *gin.Context) {
+	p, found := s.podcastParam(ctx, param(ctx, body(ctx), "pid"))
+	if !found {
+		return
+	}
+
+	respond(ctx, []gin.H{{"title": p.Title + " 年度播客", "year": 2023}})
+}
+
+// relatedPodcastList 同一分类的节目排在前面
+func (s *Server) relatedPodcastList(ctx *gin.Context) {
+	p, found := s.podcastParam(ctx, param(ctx, body(ctx), "pid"))
+	if !found {
+		return
+	}
+
+	var related []gin.H
+	for _, sameCategory := range []bool{true, false} {
+		for _, other := range s.data.podcasts {
+			if other.Pid != p.Pid && (other.CategoryId == p.CategoryId) == sameCategory {
+				related = append(related, s.podcastJSON(currentUid(ctx), other))
+			}
+		}
+	}
+
+	respond(ctx, related)
+}
+
+func (s *Server) ownedPodcasts(ctx *gin.Context) {
+	uid := param(ctx, body(ctx), "uid")
+
+	podcasts := []gin.H{}
+	for _, p := range s.data.podcasts {
+		if p.PodcasterUid == uid {
+			podcasts = append(podcasts, s.podcastJSON(currentUid(ctx), p))
+		}
+	}
+
+	respond(ctx, podcasts)
+}
+
+func (s *Server) discoveryFeed(ctx *gin.Context) {
+	uid := currentUid(ctx)
+
+	var podcasts []gin.H
+	for _, p := range s.data.podcasts {
+		podcasts = append(podcasts, s.podcastJSON(uid, p))
+	}
+
+	respond(ctx, []gin.H{
+		{"type": "TOP_LIST", "data": s.topListItems(uid, "HOT_EPISODES_IN_24_HOURS")[:10]},
+		{"type": "EDITOR_PICK", "data": podcasts},
+		{"type": "EPISODE_RECOMMEND", "data": s.recommend(uid)},
+	})
+}
+
+// refreshEpisodeRecommend 每次刷新返回不同的推荐单集
+func (s *Server) refreshEpisodeRecommend(ctx *gin.Context) {
+	s.recommendRound++
+
+	respond(ctx, s.recommend(currentUid(ctx)))
+}
+
+func (s *Server) recommend(uid string) []gin.H {
+	var episodes []gin.H
+	for i := 0; i < 6; i++ {
+		e := s.data.episodes[(s.recommendRound*6+i*7)%len(s.data.episodes)]
+		episodes = append(episodes, s.episodeJSON(uid, e))
+	}
+
+	return episodes
+}
+
+// search 按关键字匹配节目、单集标题和用户昵称，loadMoreKey 为偏移量
+func (s *Server) search(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	keyword := strings.ToLower(stringValue(p["keyword"]))
+	kind := stringValue(p["type"])
+	pid := stringValue(p["pid"])
+
+	var results []gin.H
+	if kind == "ALL" || kind == "PODCAST" {
+		for _, item := range s.data.podcasts {
+			if pid == "" && strings.Contains(strings.ToLower(item.Title), keyword) {
+				results = append(results, s.podcastJSON(uid, item))
+			}
+		}
+	}
+
+	if kind == "ALL" || kind == "EPISODE" {
+		for _, item := range s.data.episodes {
+			if (pid == "" || item.Pid == pid) && strings.Contains(strings.ToLower(item.Title), keyword) {
+				results = append(results, s.episodeJSON(uid, item))
+			}
+		}
+	}
+
+	if kind == "ALL" || kind == "USER" {
+		for _, item := range s.data.users {
+			if pid == "" && strings.Contains(strings.ToLower(item.Nickname), keyword) {
+				results = append(results, s.userJSON(uid, item))
+			}
+		}
+	}
+
+	offset := 0
+	if loadMoreKey, found := p["loadMoreKey"].(map[string]any); found {
+		offset = intValue(loadMoreKey["loadMoreKey"], 0)
+	}
+
+	limit := intValue(p["limit"], pageSize)
+	res := gin.H{"data": []gin.H{}}
+
+	if offset < len(results) {
+		end := min(offset+limit, len(results))
+		res["data"] = results[offset:end]
+
+		if end < len(results) {
+			res["loadMoreKey"] = gin.H{"loadMoreKey": end, "searchId": keyword}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (s *Server) searchPreset(ctx *gin.Context) {
+	respond(ctx, gin.H{
+		"presetKeywords": []string{s.data.podcasts[0].Title, s.data.podcasts[1].Title, "EP01"},
+	})
+}
+
+func (s *Server) categoryJSON(c *category) gin.H {
+	return gin.H{"id": c.Id, "name": c.Name, "emoji": c.Emoji, "icon": image("category", c.Id)}
+}
+
+func (s *Server) categoryList(ctx *gin.Context) {
+	var categories []gin.H
+	for _, c := range s.data.categories {
+		categories = append(categories, s.categoryJSON(c))
+	}
+
+	respond(ctx, categories)
+}
+
+func (s *Server) categoryTabs(ctx *gin.Context) {
+	if _, found := s.data.categoryById[param(ctx, body(ctx), "categoryId")]; !found {
+		fail(ctx, http.StatusNotFound, "分类不存在")
+
+		return
+	}
+
+	respond(ctx, []gin.H{
+		{"id": "ALL", "name": "全部"},
+		{"id": "HOT", "name": "近期热门"},
+		{"id": "RECOMMEND", "name": "为你推荐"},
+	})
+}
+
+// categoryPodcasts 分类下的节目及其最新单集，ALL 按最新单集的发布时间排序，其它标签按订阅数排序，loadMoreKey 为偏移量，limit 为每页的数量
+func (s *Server) categoryPodcasts(ctx *gin.Context) {
+	p := body(ctx)
+
+	categoryId := stringValue(p["categoryId"])
+	if _, found := s.data.categoryById[categoryId]; !found {
+		fail(ctx, http.StatusNotFound, "分类不存在")
+
+		return
+	}
+
+	var podcasts []*podcast
+	for _, item := range s.data.podcasts {
+		if item.CategoryId == categoryId {
+			podcasts = append(podcasts, item)
+		}
+	}
+
+	sort.SliceStable(podcasts, func(i, j int) bool {
+		if stringValue(p["tab"]) == "ALL" {
+			return s.data.podcastEpisodes(podcasts[i].Pid)[0].PubDate.After(s.data.podcastEpisodes(podcasts[j].Pid)[0].PubDate)
+		}
+
+		return podcasts[i].SubscriptionCount > podcasts[j].SubscriptionCount
+	})
+
+	offset := intValue(p["loadMoreKey"], 0)
+	limit := intValue(p["limit"], pageSize)
+
+	uid := currentUid(ctx)
+
+	var data []gin.H
+	for i := offset; i < len(podcasts) && i < offset+limit; i++ {
+		data = append(data, gin.H{
+			"podcast": s.podcastJSON(uid, podcasts[i]),
+			"episode": s.episodeJSON(uid, s.data.podcastEpisodes(podcasts[i].Pid)[0]),
+		})
+	}
+
+	res := gin.H{"data": data}
+	if offset+limit < len(podcasts) {
+		res["loadMoreKey"] = offset + limit
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// topLists 榜单的标题，HOT_EPISODES_IN_24_HOURS 按播放量、SKYROCKET_EPISODES 按点赞数、NEW_STAR_EPISODES 按发布时间排序
+var topLists = map[string]string{
+	"HOT_EPISODES_IN_24_HOURS": "最热榜",
+	"SKYROCKET_EPISODES":       "锋芒榜",
+	"NEW_STAR_EPISODES":        "新星榜",
+}
+
+func (s *Server) topList(ctx *gin.Context) {
+	category := param(ctx, body(ctx), "category")
+
+	title, found := topLists[category]
+	if !found {
+		fail(ctx, http.StatusBadRequest, "榜单不存在")
+
+		return
+	}
+
+	respond(ctx, gin.H{
+		"category": category,
+		"title":    title,
+		"items":    s.topListItems(currentUid(ctx), category),
+	})
+}
+
+func (s *Server) topListItems(uid, category string) []gin.H {
+	episodes := append([]*episode{}, s.data.episodes...)
+
+	sort.SliceStable(episodes, func(i, j int) bool {
+		switch category {
+		case "SKYROCKET_EPISODES":
+			return episodes[i].ClapCount > episodes[j].ClapCount
+		case "NEW_STAR_EPISODES":
+			return episodes[i].PubDate.After(episodes[j].PubDate)
+		}
+
+		return episodes[i].PlayCount > episodes[j].PlayCount
+	})
+
+	var items []gin.H
+	for i, e := range episodes[:50] {
+		items = append(items, gin.H{"rank": i + 1, "item": s.episodeJSON(uid, e)})
+	}
+
+	return items
+}
@@ -0,0 +1,332 @@
+// Package mock 模拟 xyz 调用的小宇宙上游接口，数据保存在内存中，不需要网络和真实账号
+//
+// 可以通过 xyz mock-upstream 启动，也可以在测试中配合 httptest 使用：
+//
+//	upstream := httptest.NewServer(mock.NewServer())
+//	defer upstream.Close()
+//
+//	constant.BaseUrl = upstream.URL
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyCode 短信验证码，对所有手机号有效，需要先调用 /v1/auth/sendCode
+const VerifyCode = "123456"
+
+// DefaultTokenTTL access token 的默认有效期
+const DefaultTokenTTL = time.Hour
+
+// pageSize 上游分页接口每页的数量
+const pageSize = 20
+
+const uidContextKey = "mock.uid"
+
+// Server 模拟的上游服务，实现了 http.Handler
+type Server struct {
+	// TokenTTL access token 的有效期，过期后需要刷新
+	TokenTTL time.Duration
+
+	engine *gin.Engine
+	data   *dataset
+
+	mu             sync.Mutex
+	codes          map[string]bool
+	accessTokens   map[string]*token
+	refreshTokens  map[string]*token
+	states         map[string]*userState
+	likes          map[string]map[string]bool
+	liveReports    map[string][]time.Time
+	recommendRound int
+}
+
+type token struct {
+	uid     string
+	expires time.Time
+}
+
+// userState 用户操作产生的数据
+type userState struct {
+	subscriptions map[string]*subscription
+	favorites     map[string]time.Time
+	progress      map[string]*progress
+	history       []*played
+	collected     map[string]time.Time
+	following     map[string]bool
+	blocked       map[string]time.Time
+	preferences   map[string]any
+	claps         map[string][]int
+	playedSeconds map[string]float64
+}
+
+type subscription struct {
+	pid          string
+	subscribedAt time.Time
+	starred      bool
+}
+
+type progress struct {
+	eid      string
+	pid      string
+	progress int
+	playedAt string
+}
+
+type played struct {
+	eid      string
+	playedAt time.Time
+}
+
+// NewServer 创建使用种子数据的上游服务，每个 Server 的数据相互独立
+func NewServer() *Server {
+	gin.SetMode(gin.ReleaseMode)
+
+	s := &Server{
+		TokenTTL:      DefaultTokenTTL,
+		engine:        gin.New(),
+		data:          newDataset(),
+		codes:         map[string]bool{},
+		accessTokens:  map[string]*token{},
+		refreshTokens: map[string]*token{},
+		states:        map[string]*userState{},
+		likes:         map[string]map[string]bool{},
+		liveReports:   map[string][]time.Time{},
+	}
+
+	s.engine.Use(gin.Recovery())
+	s.routes()
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.engine.ServeHTTP(w, r)
+}
+
+// handle 注册接口，上游按接口使用 GET 或 POST，模拟时两者均可。接口依次执行，不需要单独加锁
+func (s *Server) handle(group gin.IRoutes, path string, handler gin.HandlerFunc) {
+	locked := func(ctx *gin.Context) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		handler(ctx)
+	}
+
+	group.GET(path, locked)
+	group.POST(path, locked)
+}
+
+func (s *Server) routes() {
+	s.handle(s.engine, "/v1/auth/sendCode", s.sendCode)
+	s.handle(s.engine, "/v1/auth/loginOrSignUpWithSMS", s.login)
+	s.handle(s.engine, "/app_auth_tokens.refresh", s.refreshToken)
+
+	v1 := s.engine.Group("/v1", s.requireToken)
+
+	s.handle(v1, "/podcast/get", s.podcastGet)
+	s.handle(v1, "/podcast/get-info", s.podcastGetInfo)
+	s.handle(v1, "/podcast-bulletin/get-by-pid", s.podcastBulletin)
+	s.handle(v1, "/podcast-honor/list", s.podcastHonorList)
+	s.handle(v1, "/related-podcast/list", s.relatedPodcastList)
+	s.handle(v1, "/podcaster/owned-podcasts", s.ownedPodcasts)
+
+	s.handle(v1, "/episode/list", s.episodeList)
+	s.handle(v1, "/episode/get", s.episodeGet)
+	s.handle(v1, "/episode/list-by-filter", s.episodeListByFilter)
+	s.handle(v1, "/playback-progress/list", s.playbackProgressList)
+	s.handle(v1, "/playback-progress/update", s.playbackProgressUpdate)
+	s.handle(v1, "/live-stats/episode/get", s.liveStatsGet)
+	s.handle(v1, "/live-stats/report", s.liveStatsReport)
+	s.handle(v1, "/episode-played/list", s.playedList)
+	s.handle(v1, "/episode-played/list-history", s.playedHistory)
+	s.handle(v1, "/episode-played/create", s.playedCreate)
+	s.handle(v1, "/clap/list", s.clapList)
+	s.handle(v1, "/clap/create", s.clapCreate)
+
+	s.handle(v1, "/comment/list-primary", s.commentListPrimary)
+	s.handle(v1, "/comment/list-thread", s.commentListThread)
+	s.handle(v1, "/comment/collect/create", s.commentCollectCreate)
+	s.handle(v1, "/comment/collect/remove", s.commentCollectRemove)
+	s.handle(v1, "/comment/collect/list", s.commentCollectList)
+	s.handle(v1, "/like/update", s.likeUpdate)
+
+	s.handle(v1, "/subscription/list", s.subscriptionList)
+	s.handle(v1, "/subscription-star/list", s.subscriptionStarList)
+	s.handle(v1, "/subscription/list-non-starred", s.subscriptionNonStarredList)
+	s.handle(v1, "/subscription-star/update", s.subscriptionStarUpdate)
+	s.handle(v1, "/subscription/update", s.subscriptionUpdate)
+	s.handle(v1, "/favorite/update", s.favoriteUpdate)
+	s.handle(v1, "/favorite/list", s.favoriteList)
+	s.handle(v1, "/inbox/list", s.inboxList)
+
+	s.handle(v1, "/discovery-feed/list", s.discoveryFeed)
+	s.handle(v1, "/discovery-collection/refresh-episode-recommend", s.refreshEpisodeRecommend)
+	s.handle(v1, "/search/create", s.search)
+	s.handle(v1, "/search/get-preset", s.searchPreset)
+	s.handle(v1, "/category/list-all", s.categoryList)
+	s.handle(v1, "/category/podcast/list-tabs", s.categoryTabs)
+	s.handle(v1, "/category/podcast/list-by-tab", s.categoryPodcasts)
+	s.handle(v1, "/top-list/get", s.topList)
+
+	s.handle(v1, "/profile/get", s.profileGet)
+	s.handle(v1, "/user-stats/get", s.userStats)
+	s.handle(v1, "/unread-count/get", s.unreadCount)
+	s.handle(v1, "/user-relation/list-following", s.followingList)
+	s.handle(v1, "/user-relation/list-follower", s.followerList)
+	s.handle(v1, "/user-relation/update", s.relationUpdate)
+	s.handle(v1, "/blocked-user/list", s.blockedList)
+	s.handle(v1, "/blocked-user/create", s.blockedCreate)
+	s.handle(v1, "/blocked-user/remove", s.blockedRemove)
+	s.handle(v1, "/user-preference/get", s.preferenceGet)
+	s.handle(v1, "/user-preference/update", s.preferenceUpdate)
+	s.handle(v1, "/mileage/get", s.mileageGet)
+	s.handle(v1, "/mileage/list", s.mileageList)
+	s.handle(v1, "/mileage/update", s.mileageUpdate)
+	s.handle(v1, "/pick/list-recent", s.pickRecent)
+	s.handle(v1, "/pick/list-history", s.pickHistory)
+	s.handle(v1, "/sticker/list", s.stickerList)
+	s.handle(v1, "/sticker/get-board", s.stickerBoard)
+}
+
+// state 用户的数据
+func (s *Server) state(uid string) *userState {
+	state, ok := s.states[uid]
+	if !ok {
+		state = &userState{
+			subscriptions: map[string]*subscription{},
+			favorites:     map[string]time.Time{},
+			progress:      map[string]*progress{},
+			collected:     map[string]time.Time{},
+			following:     map[string]bool{},
+			blocked:       map[string]time.Time{},
+			preferences:   map[string]any{"autoPlayNext": true, "showListeningHistory": true},
+			claps:         map[string][]int{},
+			playedSeconds: map[string]float64{},
+		}
+		s.states[uid] = state
+	}
+
+	return state
+}
+
+// currentUid 当前 access token 对应的用户
+func currentUid(ctx *gin.Context) string {
+	return ctx.GetString(uidContextKey)
+}
+
+// body 解析 JSON 请求体，请求体为空或不是 JSON 对象时返回空 map
+func body(ctx *gin.Context) map[string]any {
+	p := map[string]any{}
+
+	b, err := io.ReadAll(ctx.Request.Body)
+	if err != nil || len(bytes.TrimSpace(b)) == 0 {
+		return p
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	_ = decoder.Decode(&p)
+
+	if p == nil {
+		p = map[string]any{}
+	}
+
+	return p
+}
+
+// param 依次从查询参数和请求体中读取字符串参数
+func param(ctx *gin.Context, p map[string]any, key string) string {
+	if v := ctx.Query(key); v != "" {
+		return v
+	}
+
+	return stringValue(p[key])
+}
+
+func stringValue(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+
+	return ""
+}
+
+func intValue(v any, def int) int {
+	n, err := strconv.Atoi(stringValue(v))
+	if err != nil {
+		return def
+	}
+
+	return n
+}
+
+func boolValue(v any) bool {
+	b, _ := strconv.ParseBool(stringValue(v))
+
+	return b
+}
+
+// cursorId 从 loadMoreKey 中读取上一页最后一项的 id，loadMoreKey 可以是对象或字符串
+func cursorId(loadMoreKey any) string {
+	if m, ok := loadMoreKey.(map[string]any); ok {
+		return stringValue(m["id"])
+	}
+
+	return stringValue(loadMoreKey)
+}
+
+// page 返回 id 为 after 的项之后最多 limit 项，以及是否还有下一页
+func page[T any](items []T, after string, limit int, id func(T) string) ([]T, bool) {
+	start := 0
+	if after != "" {
+		for i, item := range items {
+			if id(item) == after {
+				start = i + 1
+
+				break
+			}
+		}
+	}
+
+	if start >= len(items) {
+		return nil, false
+	}
+
+	end := start + limit
+	if end >= len(items) {
+		return items[start:], false
+	}
+
+	return items[start:end], true
+}
+
+func respond(ctx *gin.Context, data any) {
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func success(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// fail 上游的错误响应，toast 为客户端展示的错误信息
+func fail(ctx *gin.Context, status int, msg string) {
+	ctx.AbortWithStatusJSON(status, gin.H{"success": false, "toast": msg})
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
@@ -0,0 +1,137 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// post 以 POST 调用上游接口，返回状态码、响应头和响应体
+func post(t *testing.T, url, path string, headers map[string]string, body map[string]any) (int, http.Header, map[string]any) {
+	t.Helper()
+
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, url+path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+
+	return resp.StatusCode, resp.Header, result
+}
+
+func TestServerAuth(t *testing.T) {
+	s := NewServer()
+	upstream := httptest.NewServer(s)
+	defer upstream.Close()
+
+	phone := map[string]any{"mobilePhoneNumber": "13912345678", "verifyCode": VerifyCode}
+	pid := map[string]any{"pid": seedId(kindPodcast, 1)}
+
+	// 没有发送验证码时不能登录
+	if status, _, _ := post(t, upstream.URL, "/v1/auth/loginOrSignUpWithSMS", nil, phone); status != http.StatusBadRequest {
+		t.Errorf("login without code = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	post(t, upstream.URL, "/v1/auth/sendCode", nil, phone)
+
+	status, header, body := post(t, upstream.URL, "/v1/auth/loginOrSignUpWithSMS", nil, phone)
+	if status != http.StatusOK || header.Get("x-jike-access-token") == "" {
+		t.Fatalf("login = %d %v, want 200 with tokens", status, body)
+	}
+
+	if data, _ := body["data"].(map[string]any); data["isSignUp"] != true {
+		t.Errorf("login data = %v, want isSignUp for a new phone", data)
+	}
+
+	accessToken, refreshToken := header.Get("x-jike-access-token"), header.Get("x-jike-refresh-token")
+
+	_, refreshed, _ := post(t, upstream.URL, "/app_auth_tokens.refresh", map[string]string{"x-jike-refresh-token": refreshToken}, nil)
+
+	expired := NewServer()
+	expired.TokenTTL = -time.Second
+	expiredUpstream := httptest.NewServer(expired)
+	defer expiredUpstream.Close()
+
+	expiredToken, _ := expired.Login()
+	seeded, _ := s.Login()
+
+	tests := []struct {
+		name   string
+		url    string
+		token  string
+		status int
+	}{
+		{"短信登录的 token", upstream.URL, refreshed.Get("x-jike-access-token"), http.StatusOK},
+		{"Login 签发的 token", upstream.URL, seeded, http.StatusOK},
+		{"没有 token", upstream.URL, "", http.StatusUnauthorized},
+		{"刷新后旧的 token 失效", upstream.URL, accessToken, http.StatusUnauthorized},
+		{"过期的 token", expiredUpstream.URL, expiredToken, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, body := post(t, tt.url, "/v1/podcast/get", map[string]string{"x-jike-access-token": tt.token}, pid)
+			if status != tt.status {
+				t.Fatalf("/v1/podcast/get = %d %v, want %d", status, body, tt.status)
+			}
+
+			if data, _ := body["data"].(map[string]any); status == http.StatusOK && data["pid"] != pid["pid"] {
+				t.Errorf("/v1/podcast/get data = %v, want pid %v", data, pid["pid"])
+			}
+		})
+	}
+}
+
+func TestServerPage(t *testing.T) {
+	s := NewServer()
+	upstream := httptest.NewServer(s)
+	defer upstream.Close()
+
+	accessToken, _ := s.Login()
+
+	var (
+		eids    = map[string]bool{}
+		next    any
+		pages   int
+		headers = map[string]string{"x-jike-access-token": accessToken}
+	)
+
+	for {
+		body := map[string]any{"pid": seedId(kindPodcast, 1)}
+		if next != nil {
+			body["loadMoreKey"] = next
+		}
+
+		status, _, result := post(t, upstream.URL, "/v1/episode/list", headers, body)
+		if status != http.StatusOK {
+			t.Fatalf("/v1/episode/list = %d %v", status, result)
+		}
+
+		items, _ := result["data"].([]any)
+		for _, item := range items {
+			eids[item.(map[string]any)["eid"].(string)] = true
+		}
+
+		pages++
+		if next = result["loadMoreKey"]; next == nil || pages > 5 {
+			break
+		}
+	}
+
+	if len(eids) != 25 || pages != 2 {
+		t.Errorf("/v1/episode/list returned %d episodes in %d pages, want 25 in 2", len(eids), pages)
+	}
+}
@@ -0,0 +1,500 @@
+package mock
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) userJSON(viewer string, u *user) gin.H {
+	following := s.state(viewer).following[u.Uid]
+	followed := s.state(u.Uid).following[viewer]
+
+	relation := "STRANGE"
+	switch {
+	case viewer == u.Uid:
+		relation = "SELF"
+	case following && followed:
+		relation = "MUTUAL"
+	case following:
+		relation = "FOLLOWING"
+	case followed:
+		relation = "FOLLOWED"
+	}
+
+	return gin.H{
+		"type":     "USER",
+		"uid":      u.Uid,
+		"nickname": u.Nickname,
+		"bio":      u.Bio,
+		"gender":   u.Gender,
+		"avatar":   gin.H{"picture": image("avatar", u.Uid)},
+		"relation": relation,
+	}
+}
+
+// userParam 按 uid 查询用户，不存在时返回 404
+func (s *Server) userParam(ctx *gin.Context, uid string) (*user, bool) {
+	u, found := s.data.userById[uid]
+	if !found {
+		fail(ctx, http.StatusNotFound, "用户不存在")
+	}
+
+	return u, found
+}
+
+// subscriptions 已订阅的节目，按订阅时间倒序
+func (s *Server) subscriptions(uid string) []*subscription {
+	state := s.state(uid)
+
+	items := make([]*subscription, 0, len(state.subscriptions))
+	for _, sub := range state.subscriptions {
+		items = append(items, sub)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].subscribedAt.Equal(items[j].subscribedAt) {
+			return items[i].pid < items[j].pid
+		}
+
+		return items[i].subscribedAt.After(items[j].subscribedAt)
+	})
+
+	return items
+}
+
+func (s *Server) subscriptionJSON(viewer string, sub *subscription) gin.H {
+	item := s.podcastJSON(viewer, s.data.podcastById[sub.pid])
+	item["subscribedAt"] = formatTime(sub.subscribedAt)
+
+	return item
+}
+
+// subscriptionList 订阅的节目，传入 uid 时查询其他用户的订阅，loadMoreKey 为上一页最后一个节目的 {subscribedAt, id}
+func (s *Server) subscriptionList(ctx *gin.Context) {
+	p := body(ctx)
+	viewer := currentUid(ctx)
+
+	uid := viewer
+	if v := stringValue(p["uid"]); v != "" {
+		if _, found := s.userParam(ctx, v); !found {
+			return
+		}
+
+		uid = v
+	}
+
+	items, more := page(s.subscriptions(uid), cursorId(p["loadMoreKey"]), intValue(p["limit"], pageSize), func(sub *subscription) string {
+		return sub.pid
+	})
+
+	data := []gin.H{}
+	for _, sub := range items {
+		data = append(data, s.subscriptionJSON(viewer, sub))
+	}
+
+	res := gin.H{"data": data}
+	if more {
+		last := items[len(items)-1]
+		res["loadMoreKey"] = gin.H{"subscribedAt": formatTime(last.subscribedAt), "id": last.pid}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (s *Server) subscriptionStarList(ctx *gin.Context) {
+	s.filterSubscriptions(ctx, true)
+}
+
+func (s *Server) subscriptionNonStarredList(ctx *gin.Context) {
+	s.filterSubscriptions(ctx, false)
+}
+
+func (s *Server) filterSubscriptions(ctx *gin.Context, starred bool) {
+	uid := currentUid(ctx)
+
+	data := []gin.H{}
+	for _, sub := range s.subscriptions(uid) {
+		if sub.starred == starred {
+			data = append(data, s.subscriptionJSON(uid, sub))
+		}
+	}
+
+	respond(ctx, data)
+}
+
+// subscriptionUpdate mode 为 ON 时订阅，OFF 时取消订阅
+func (s *Server) subscriptionUpdate(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	item, found := s.podcastParam(ctx, stringValue(p["pid"]))
+	if !found {
+		return
+	}
+
+	state := s.state(uid)
+
+	switch stringValue(p["mode"]) {
+	case "ON":
+		if _, subscribed := state.subscriptions[item.Pid]; !subscribed {
+			state.subscriptions[item.Pid] = &subscription{pid: item.Pid, subscribedAt: time.Now()}
+		}
+	case "OFF":
+		delete(state.subscriptions, item.Pid)
+	default:
+		fail(ctx, http.StatusBadRequest, "mode 只能是 ON 或 OFF")
+
+		return
+	}
+
+	respond(ctx, s.podcastJSON(uid, item))
+}
+
+// subscriptionStarUpdate 星标已订阅的节目，未订阅时返回 400
+func (s *Server) subscriptionStarUpdate(ctx *gin.Context) {
+	p := body(ctx)
+
+	item, found := s.podcastParam(ctx, stringValue(p["pid"]))
+	if !found {
+		return
+	}
+
+	sub, subscribed := s.state(currentUid(ctx)).subscriptions[item.Pid]
+	if !subscribed {
+		fail(ctx, http.StatusBadRequest, "请先订阅节目")
+
+		return
+	}
+
+	sub.starred = boolValue(p["withStar"])
+
+	success(ctx)
+}
+
+// profileGet 查询用户信息，未传入 uid 时查询当前用户
+func (s *Server) profileGet(ctx *gin.Context) {
+	viewer := currentUid(ctx)
+
+	uid := param(ctx, body(ctx), "uid")
+	if uid == "" {
+		uid = viewer
+	}
+
+	u, found := s.userParam(ctx, uid)
+	if !found {
+		return
+	}
+
+	respond(ctx, s.userJSON(viewer, u))
+}
+
+func (s *Server) userStats(ctx *gin.Context) {
+	u, found := s.userParam(ctx, param(ctx, body(ctx), "uid"))
+	if !found {
+		return
+	}
+
+	respond(ctx, gin.H{
+		"followingCount":     len(s.following(u.Uid)),
+		"followerCount":      len(s.followers(u.Uid)),
+		"subscriptionCount":  len(s.state(u.Uid).subscriptions),
+		"favoriteCount":      len(s.state(u.Uid).favorites),
+		"totalPlayedSeconds": s.totalPlayedSeconds(u.Uid),
+	})
+}
+
+func (s *Server) unreadCount(ctx *gin.Context) {
+	respond(ctx, gin.H{"count": len(s.followers(currentUid(ctx)))})
+}
+
+func (s *Server) following(uid string) []string {
+	var uids []string
+	for _, u := range s.data.users {
+		if s.state(uid).following[u.Uid] {
+			uids = append(uids, u.Uid)
+		}
+	}
+
+	return uids
+}
+
+func (s *Server) followers(uid string) []string {
+	var uids []string
+	for _, u := range s.data.users {
+		if s.state(u.Uid).following[uid] {
+			uids = append(uids, u.Uid)
+		}
+	}
+
+	return uids
+}
+
+func (s *Server) usersJSON(viewer string, uids []string) []gin.H {
+	data := []gin.H{}
+	for _, uid := range uids {
+		data = append(data, s.userJSON(viewer, s.data.userById[uid]))
+	}
+
+	return data
+}
+
+// followingList 关注的人，loadMoreKey 为上一页最后一个用户的 {id}
+func (s *Server) followingList(ctx *gin.Context) {
+	p := body(ctx)
+
+	u, found := s.userParam(ctx, stringValue(p["uid"]))
+	if !found {
+		return
+	}
+
+	s.userPage(ctx, s.following(u.Uid), p["loadMoreKey"])
+}
+
+// followerList 粉丝，loadMoreKey 与 followingList 相同
+func (s *Server) followerList(ctx *gin.Context) {
+	p := body(ctx)
+
+	u, found := s.userParam(ctx, stringValue(p["uid"]))
+	if !found {
+		return
+	}
+
+	s.userPage(ctx, s.followers(u.Uid), p["loadMoreKey"])
+}
+
+func (s *Server) userPage(ctx *gin.Context, uids []string, loadMoreKey any) {
+	items, more := page(uids, cursorId(loadMoreKey), pageSize, func(uid string) string {
+		return uid
+	})
+
+	res := gin.H{"data": s.usersJSON(currentUid(ctx), items)}
+	if more {
+		res["loadMoreKey"] = gin.H{"id": items[len(items)-1]}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// relationUpdate relation 为 FOLLOWING 时关注，STRANGE 时取消关注
+func (s *Server) relationUpdate(ctx *gin.Context) {
+	p := body(ctx)
+	uid := currentUid(ctx)
+
+	u, found := s.userParam(ctx, stringValue(p["uid"]))
+	if !found {
+		return
+	}
+
+	if u.Uid == uid {
+		fail(ctx, http.StatusBadRequest, "不能关注自己")
+
+		return
+	}
+
+	switch stringValue(p["relation"]) {
+	case "FOLLOWING":
+		s.state(uid).following[u.Uid] = true
+	case "STRANGE":
+		delete(s.state(uid).following, u.Uid)
+	default:
+		fail(ctx, http.StatusBadRequest, "relation 只能是 FOLLOWING 或 STRANGE")
+
+		return
+	}
+
+	respond(ctx, s.userJSON(uid, u))
+}
+
+func (s *Server) blockedList(ctx *gin.Context) {
+	uid := currentUid(ctx)
+	blocked := s.state(uid).blocked
+
+	uids := make([]string, 0, len(blocked))
+	for id := range blocked {
+		uids = append(uids, id)
+	}
+
+	sort.Slice(uids, func(i, j int) bool {
+		return blocked[uids[i]].After(blocked[uids[j]])
+	})
+
+	respond(ctx, s.usersJSON(uid, uids))
+}
+
+// blockedCreate 拉黑用户，同时取消关注
+func (s *Server) blockedCreate(ctx *gin.Context) {
+	uid := currentUid(ctx)
+
+	u, found := s.userParam(ctx, stringValue(body(ctx)["uid"]))
+	if !found {
+		return
+	}
+
+	s.state(uid).blocked[u.Uid] = time.Now()
+	delete(s.state(uid).following, u.Uid)
+
+	success(ctx)
+}
+
+func (s *Server) blockedRemove(ctx *gin.Context) {
+	u, found := s.userParam(ctx, stringValue(body(ctx)["uid"]))
+	if !found {
+		return
+	}
+
+	delete(s.state(currentUid(ctx)).blocked, u.Uid)
+
+	success(ctx)
+}
+
+func (s *Server) preferenceGet(ctx *gin.Context) {
+	respond(ctx, s.state(currentUid(ctx)).preferences)
+}
+
+// preferenceUpdate 请求体中的每个字段都会覆盖原有的设置
+func (s *Server) preferenceUpdate(ctx *gin.Context) {
+	preferences := s.state(currentUid(ctx)).preferences
+
+	for key, value := range body(ctx) {
+		preferences[key] = value
+	}
+
+	respond(ctx, preferences)
+}
+
+func (s *Server) mileageGet(ctx *gin.Context) {
+	uid := currentUid(ctx)
+	state := s.state(uid)
+
+	respond(ctx, gin.H{
+		"totalPlayedSeconds": s.totalPlayedSeconds(uid),
+		"podcastCount":       len(state.playedSeconds),
+		"episodeCount":       len(state.history),
+	})
+}
+
+func (s *Server) totalPlayedSeconds(uid string) int {
+	total := 0.0
+	for _, seconds := range s.state(uid).playedSeconds {
+		total += seconds
+	}
+
+	return int(total)
+}
+
+// mileageList 按节目统计的收听时长排行，rank 为 TOTAL 或 LAST_THIRTY_DAYS，模拟数据不区分两者
+func (s *Server) mileageList(ctx *gin.Context) {
+	uid := currentUid(ctx)
+	state := s.state(uid)
+
+	pids := make([]string, 0, len(state.playedSeconds))
+	for pid := range state.playedSeconds {
+		pids = append(pids, pid)
+	}
+
+	sort.Slice(pids, func(i, j int) bool {
+		return state.playedSeconds[pids[i]] > state.playedSeconds[pids[j]]
+	})
+
+	data := []gin.H{}
+	for i, pid := range pids {
+		data = append(data, gin.H{
+			"rank":          i + 1,
+			"podcast":       s.podcastJSON(uid, s.data.podcastById[pid]),
+			"playedSeconds": int(state.playedSeconds[pid]),
+		})
+	}
+
+	respond(ctx, data)
+}
+
+// mileageUpdate 累加每段收听记录的时长，时间戳单位为毫秒
+func (s *Server) mileageUpdate(ctx *gin.Context) {
+	state := s.state(currentUid(ctx))
+
+	tracking, _ := body(ctx)["tracking"].([]any)
+	for _, item := range tracking {
+		m, _ := item.(map[string]any)
+
+		pid := stringValue(m["pid"])
+		if _, found := s.data.podcastById[pid]; !found {
+			continue
+		}
+
+		start, _ := strconv.ParseFloat(stringValue(m["startPlayingTimestamp"]), 64)
+		end, _ := strconv.ParseFloat(stringValue(m["endPlayingTimestamp"]), 64)
+
+		if end > start {
+			state.playedSeconds[pid] += (end - start) / 1000
+		}
+	}
+
+	success(ctx)
+}
+
+// pickRecent 「用户的喜欢」，模拟数据为用户收藏的单集
+func (s *Server) pickRecent(ctx *gin.Context) {
+	u, found := s.userParam(ctx, stringValue(body(ctx)["uid"]))
+	if !found {
+		return
+	}
+
+	picks := s.favorites(currentUid(ctx), u.Uid)
+	if len(picks) > 3 {
+		picks = picks[:3]
+	}
+
+	respond(ctx, picks)
+}
+
+func (s *Server) pickHistory(ctx *gin.Context) {
+	u, found := s.userParam(ctx, stringValue(body(ctx)["uid"]))
+	if !found {
+		return
+	}
+
+	respond(ctx, s.favorites(currentUid(ctx), u.Uid))
+}
+
+func (s *Server) stickers(uid string) []gin.H {
+	stickers := []gin.H{sticker("first-listen", "初次收听", "第一次收听播客")}
+
+	if len(s.state(uid).subscriptions) >= 3 {
+		stickers = append(stickers, sticker("subscriber", "订阅达人", "订阅了 3 个以上的节目"))
+	}
+
+	return stickers
+}
+
+func sticker(id, name, description string) gin.H {
+	return gin.H{
+		"id":          id,
+		"name":        name,
+		"description": description,
+		"issuer":      "小宇宙",
+		"image":       image("sticker", id),
+		"ownedAt":     formatTime(seedTime),
+	}
+}
+
+func (s *Server) stickerList(ctx *gin.Context) {
+	u, found := s.userParam(ctx, stringValue(body(ctx)["uid"]))
+	if !found {
+		return
+	}
+
+	respond(ctx, s.stickers(u.Uid))
+}
+
+func (s *Server) stickerBoard(ctx *gin.Context) {
+	u, found := s.userParam(ctx, stringValue(body(ctx)["uid"]))
+	if !found {
+		return
+	}
+
+	respond(ctx, gin.H{"uid": u.Uid, "stickers": s.stickers(u.Uid)})
+}
@@ -0,0 +1,99 @@
+package playback
+
+import "time"
+
+// 未发送的数据最多保留的收听时长记录数，播放状态只在该时间内有意义
+const (
+	MaxPendingTracking = 1000
+	LiveReportTTL      = 5 * time.Minute
+)
+
+// Reports 需要发送到上游的数据
+type Reports struct {
+	Tracking []Tracking `json:"tracking,omitempty"` // /mileage_update 的 tracking
+	Progress []Progress `json:"progress,omitempty"` // /episode_play_progress_update 的 data
+	Live     []Play     `json:"live,omitempty"`     // /live_stats_report
+	History  []Play     `json:"history,omitempty"`  // /episode_played_history_list_update
+}
+
+// Tracking 一段连续的播放，时间戳单位为毫秒
+type Tracking struct {
+	Eid                   string  `json:"eid"`
+	Pid                   string  `json:"pid"`
+	StartPlayingTimestamp float64 `json:"startPlayingTimestamp"`
+	EndPlayingTimestamp   float64 `json:"endPlayingTimestamp"`
+	IsSpeaker             bool    `json:"isSpeaker"`
+	IsOffline             bool    `json:"isOffline"`
+	IsTrial               bool    `json:"isTrial"`
+	WithSpeed             float64 `json:"withSpeed"`
+}
+
+type Progress struct {
+	Pid      string `json:"pid"`
+	Eid      string `json:"eid"`
+	Progress int    `json:"progress"`
+	PlayedAt string `json:"playedAt"`
+}
+
+// Play 开始播放一个单集，At 为毫秒时间戳
+type Play struct {
+	Eid string `json:"eid"`
+	Pid string `json:"pid"`
+	At  int64  `json:"at"`
+}
+
+// Counts 各类数据的数量
+type Counts struct {
+	Tracking int `json:"tracking"`
+	Progress int `json:"progress"`
+	Live     int `json:"live"`
+	History  int `json:"history"`
+}
+
+func (r *Reports) Counts() Counts {
+	return Counts{Tracking: len(r.Tracking), Progress: len(r.Progress), Live: len(r.Live), History: len(r.History)}
+}
+
+func (r *Reports) Empty() bool {
+	return r.Counts() == Counts{}
+}
+
+// Merge 将 next 合并到未发送的数据之后：同一单集的播放进度和收听历史只保留最新的一条，
+// 超过 LiveReportTTL 的播放状态丢弃，收听时长记录超过 MaxPendingTracking 时丢弃最早的
+func (r *Reports) Merge(next *Reports, now time.Time) {
+	r.Tracking = append(r.Tracking, next.Tracking...)
+	if len(r.Tracking) > MaxPendingTracking {
+		r.Tracking = r.Tracking[len(r.Tracking)-MaxPendingTracking:]
+	}
+
+	r.Progress = latest(append(r.Progress, next.Progress...), func(p Progress) string { return p.Eid })
+	r.History = latest(append(r.History, next.History...), func(p Play) string { return p.Eid })
+
+	deadline := now.Add(-LiveReportTTL).UnixMilli()
+
+	var live []Play
+	for _, play := range append(r.Live, next.Live...) {
+		if play.At >= deadline {
+			live = append(live, play)
+		}
+	}
+
+	r.Live = live
+}
+
+// latest 同一 key 只保留最后一项，保持原有顺序
+func latest[T any](items []T, key func(T) string) []T {
+	last := map[string]int{}
+	for i, item := range items {
+		last[key(item)] = i
+	}
+
+	result := items[:0:0]
+	for i, item := range items {
+		if last[key(item)] == i {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package playback
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// sameReports 按 JSON 比较，nil 和空的切片都不会被保存或发送
+func sameReports(a, b *Reports) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+
+	return string(x) == string(y)
+}
+
+func TestReportsMerge(t *testing.T) {
+	now := base
+
+	tests := []struct {
+		name    string
+		pending *Reports
+		next    *Reports
+		want    *Reports
+	}{
+		{
+			name:    "合并到空的未发送数据",
+			pending: &Reports{},
+			next:    &Reports{Tracking: []Tracking{tracking(0, 10, 1)}, Live: []Play{{Eid: eid, Pid: pid, At: at(0)}}},
+			want:    &Reports{Tracking: []Tracking{tracking(0, 10, 1)}, Live: []Play{{Eid: eid, Pid: pid, At: at(0)}}},
+		},
+		{
+			name:    "同一单集的播放进度和收听历史只保留最新的一条",
+			pending: &Reports{Progress: []Progress{{Eid: eid, Progress: 10}, {Eid: "a", Progress: 1}}, History: []Play{{Eid: eid, At: at(0)}}},
+			next:    &Reports{Progress: []Progress{{Eid: eid, Progress: 20}}, History: []Play{{Eid: eid, At: at(30)}}},
+			want:    &Reports{Progress: []Progress{{Eid: "a", Progress: 1}, {Eid: eid, Progress: 20}}, History: []Play{{Eid: eid, At: at(30)}}},
+		},
+		{
+			name:    "丢弃超过 LiveReportTTL 的播放状态",
+			pending: &Reports{Live: []Play{{Eid: "old", At: now.Add(-LiveReportTTL).UnixMilli() - 1}, {Eid: "edge", At: now.Add(-LiveReportTTL).UnixMilli()}}},
+			next:    &Reports{Live: []Play{{Eid: eid, At: at(0)}}},
+			want:    &Reports{Live: []Play{{Eid: "edge", At: now.Add(-LiveReportTTL).UnixMilli()}, {Eid: eid, At: at(0)}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pending.Merge(tt.next, now)
+
+			if !sameReports(tt.pending, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", tt.pending, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportsMergeLimit(t *testing.T) {
+	pending := &Reports{Tracking: make([]Tracking, MaxPendingTracking)}
+	next := &Reports{Tracking: []Tracking{tracking(0, 10, 1), tracking(10, 20, 1)}}
+
+	pending.Merge(next, base)
+
+	if len(pending.Tracking) != MaxPendingTracking {
+		t.Fatalf("len(Tracking) = %d, want %d", len(pending.Tracking), MaxPendingTracking)
+	}
+
+	// 丢弃最早的记录，保留最新的
+	if got := pending.Tracking[MaxPendingTracking-2:]; !reflect.DeepEqual(got, next.Tracking) {
+		t.Errorf("last tracking = %+v, want %+v", got, next.Tracking)
+	}
+
+	if pending.Counts() != (Counts{Tracking: MaxPendingTracking}) || pending.Empty() {
+		t.Errorf("Counts() = %+v, Empty() = %v", pending.Counts(), pending.Empty())
+	}
+
+	if !(&Reports{}).Empty() {
+		t.Error("empty Reports is not Empty()")
+	}
+}
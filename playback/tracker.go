@@ -0,0 +1,202 @@
+// Package playback 根据播放器上报的播放、暂停、拖动、停止和倍速事件维护播放会话，生成上游需要的收听时长、播放进度、播放状态和收听历史
+package playback
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	EventPlay  = "play"
+	EventPause = "pause"
+	EventSeek  = "seek"
+	EventStop  = "stop"
+	EventSpeed = "speed"
+)
+
+// SessionTTL 超过该时间没有事件的会话视为已结束
+const SessionTTL = 6 * time.Hour
+
+// Event 播放器上报的事件
+type Event struct {
+	Type      string  `json:"type" form:"type" binding:"required,oneof=play pause seek stop speed"`
+	Eid       string  `json:"eid" form:"eid" binding:"required,xyzid"`
+	Pid       string  `json:"pid" form:"pid" binding:"required,xyzid"`
+	Position  float64 `json:"position" form:"position" binding:"min=0"`            // 事件发生时的播放位置，单位为秒
+	Speed     float64 `json:"speed" form:"speed" binding:"omitempty,gt=0,lte=4"`   // 倍速，play 和 speed 事件使用，play 时默认为之前的倍速或 1
+	Timestamp int64   `json:"timestamp" form:"timestamp" binding:"omitempty,gt=0"` // 事件发生的时间，毫秒时间戳，默认为收到事件的时间
+}
+
+// Session 一个账号正在收听的一个单集
+type Session struct {
+	Eid       string  `json:"eid"`
+	Pid       string  `json:"pid"`
+	State     string  `json:"state"` // playing、paused 或 stopped
+	Position  float64 `json:"position"`
+	Speed     float64 `json:"speed"`
+	UpdatedAt int64   `json:"updatedAt"`
+
+	startedAt int64 // 当前播放片段开始的时间
+	reported  bool  // 已生成播放状态和收听历史
+}
+
+const (
+	StatePlaying = "playing"
+	StatePaused  = "paused"
+	StateStopped = "stopped"
+)
+
+// EventError 无法处理的事件，index 为事件的下标
+type EventError struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+// Tracker 按账号保存播放会话，会话只保存在内存中
+type Tracker struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]*Session
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{sessions: map[string]map[string]*Session{}}
+}
+
+// Apply 按顺序处理 owner 的事件，返回需要上报的数据和本次涉及的会话。时间早于会话最后一个事件的事件会被忽略
+func (t *Tracker) Apply(owner string, events []Event, now time.Time) (*Reports, []Session, []EventError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(now)
+
+	sessions, found := t.sessions[owner]
+	if !found {
+		sessions = map[string]*Session{}
+		t.sessions[owner] = sessions
+	}
+
+	reports := &Reports{}
+	errs := []EventError{}
+	touched := []*Session{}
+
+	for i, event := range events {
+		at := event.Timestamp
+		if at == 0 {
+			at = now.UnixMilli()
+		}
+
+		s, found := sessions[event.Eid]
+		if !found {
+			s = &Session{Eid: event.Eid, Pid: event.Pid, State: StatePaused, Speed: 1, UpdatedAt: at}
+			sessions[event.Eid] = s
+		}
+
+		if at < s.UpdatedAt {
+			errs = append(errs, EventError{Index: i, Message: fmt.Sprintf("event is earlier than the last event of %s", event.Eid)})
+
+			continue
+		}
+
+		if event.Type == EventSpeed && event.Speed == 0 {
+			errs = append(errs, EventError{Index: i, Message: "speed is required for speed events"})
+
+			continue
+		}
+
+		switch event.Type {
+		case EventPlay:
+			if s.State != StatePlaying {
+				s.State = StatePlaying
+				s.startedAt = at
+			}
+
+			if event.Speed > 0 && event.Speed != s.Speed {
+				s.segment(reports, at)
+				s.Speed = event.Speed
+			}
+
+			if !s.reported {
+				reports.Live = append(reports.Live, Play{Eid: s.Eid, Pid: s.Pid, At: at})
+				reports.History = append(reports.History, Play{Eid: s.Eid, Pid: s.Pid, At: at})
+				s.reported = true
+			}
+		case EventSeek:
+			s.segment(reports, at)
+		case EventSpeed:
+			s.segment(reports, at)
+			s.Speed = event.Speed
+		case EventPause, EventStop:
+			s.segment(reports, at)
+			s.State = StatePaused
+			reports.Progress = append(reports.Progress, Progress{Pid: s.Pid, Eid: s.Eid, Progress: int(event.Position), PlayedAt: playedAt(at)})
+		}
+
+		s.Position = event.Position
+		s.UpdatedAt = at
+
+		if event.Type == EventStop {
+			s.State = StateStopped
+			delete(sessions, event.Eid)
+		}
+
+		touched = append(touched, s)
+	}
+
+	if len(sessions) == 0 {
+		delete(t.sessions, owner)
+	}
+
+	// 同一会话只返回最后的状态
+	result := []Session{}
+	seen := map[*Session]bool{}
+	for i := len(touched) - 1; i >= 0; i-- {
+		if !seen[touched[i]] {
+			seen[touched[i]] = true
+			result = append([]Session{*touched[i]}, result...)
+		}
+	}
+
+	return reports, result, errs
+}
+
+// segment 正在播放时结束当前的播放片段，生成一条收听时长记录，并从 at 开始新的片段
+func (s *Session) segment(reports *Reports, at int64) {
+	if s.State != StatePlaying {
+		return
+	}
+
+	if at > s.startedAt {
+		reports.Tracking = append(reports.Tracking, Tracking{
+			Eid:                   s.Eid,
+			Pid:                   s.Pid,
+			StartPlayingTimestamp: float64(s.startedAt),
+			EndPlayingTimestamp:   float64(at),
+			WithSpeed:             s.Speed,
+		})
+	}
+
+	s.startedAt = at
+}
+
+// expire 删除超过 SessionTTL 没有事件的会话，无法确定播放何时结束，不生成收听时长
+func (t *Tracker) expire(now time.Time) {
+	deadline := now.Add(-SessionTTL).UnixMilli()
+
+	for owner, sessions := range t.sessions {
+		for eid, s := range sessions {
+			if s.UpdatedAt < deadline {
+				delete(sessions, eid)
+			}
+		}
+
+		if len(sessions) == 0 {
+			delete(t.sessions, owner)
+		}
+	}
+}
+
+// playedAt 播放进度中的时间格式，如 2024-05-16T08:44:04.351Z
+func playedAt(at int64) string {
+	return time.UnixMilli(at).UTC().Format("2006-01-02T15:04:05.000Z")
+}
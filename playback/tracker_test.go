@@ -0,0 +1,132 @@
+package playback
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	eid = "300000000000000000000001"
+	pid = "200000000000000000000001"
+)
+
+// base 测试事件的起始时间，at 返回 base 之后 seconds 秒的毫秒时间戳
+var base = time.Date(2024, 5, 16, 8, 0, 0, 0, time.UTC)
+
+func at(seconds int64) int64 {
+	return base.UnixMilli() + seconds*1000
+}
+
+func event(typ string, seconds int64, position, speed float64) Event {
+	return Event{Type: typ, Eid: eid, Pid: pid, Position: position, Speed: speed, Timestamp: at(seconds)}
+}
+
+func tracking(start, end int64, speed float64) Tracking {
+	return Tracking{Eid: eid, Pid: pid, StartPlayingTimestamp: float64(at(start)), EndPlayingTimestamp: float64(at(end)), WithSpeed: speed}
+}
+
+func TestTrackerApply(t *testing.T) {
+	play := Play{Eid: eid, Pid: pid, At: at(0)}
+
+	tests := []struct {
+		name     string
+		events   []Event
+		want     *Reports
+		state    string
+		position float64
+		errs     []int
+	}{
+		{
+			name:     "播放后暂停生成一段收听时长和播放进度",
+			events:   []Event{event(EventPlay, 0, 0, 0), event(EventPause, 60, 60, 0)},
+			want:     &Reports{Tracking: []Tracking{tracking(0, 60, 1)}, Progress: []Progress{{Pid: pid, Eid: eid, Progress: 60, PlayedAt: "2024-05-16T08:01:00.000Z"}}, Live: []Play{play}, History: []Play{play}},
+			state:    StatePaused,
+			position: 60,
+		},
+		{
+			name:     "拖动和倍速切分播放片段",
+			events:   []Event{event(EventPlay, 0, 0, 0), event(EventSeek, 30, 300, 0), event(EventSpeed, 40, 310, 2)},
+			want:     &Reports{Tracking: []Tracking{tracking(0, 30, 1), tracking(30, 40, 1)}, Live: []Play{play}, History: []Play{play}},
+			state:    StatePlaying,
+			position: 310,
+		},
+		{
+			name:     "播放时指定新的倍速",
+			events:   []Event{event(EventPlay, 0, 0, 0), event(EventPlay, 20, 20, 1.5), event(EventStop, 40, 50, 0)},
+			want:     &Reports{Tracking: []Tracking{tracking(0, 20, 1), tracking(20, 40, 1.5)}, Progress: []Progress{{Pid: pid, Eid: eid, Progress: 50, PlayedAt: "2024-05-16T08:00:40.000Z"}}, Live: []Play{play}, History: []Play{play}},
+			state:    StateStopped,
+			position: 50,
+		},
+		{
+			name:     "暂停时拖动不生成收听时长，再次播放不重复上报播放状态",
+			events:   []Event{event(EventPlay, 0, 0, 0), event(EventPause, 10, 10, 0), event(EventSeek, 20, 100, 0), event(EventPlay, 30, 100, 0)},
+			want:     &Reports{Tracking: []Tracking{tracking(0, 10, 1)}, Progress: []Progress{{Pid: pid, Eid: eid, Progress: 10, PlayedAt: "2024-05-16T08:00:10.000Z"}}, Live: []Play{play}, History: []Play{play}},
+			state:    StatePlaying,
+			position: 100,
+		},
+		{
+			name:     "忽略早于最后一个事件的事件和缺少倍速的倍速事件",
+			events:   []Event{event(EventPlay, 10, 0, 0), event(EventPause, 5, 5, 0), event(EventSpeed, 20, 10, 0)},
+			want:     &Reports{Live: []Play{{Eid: eid, Pid: pid, At: at(10)}}, History: []Play{{Eid: eid, Pid: pid, At: at(10)}}},
+			state:    StatePlaying,
+			position: 0,
+			errs:     []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, sessions, errs := NewTracker().Apply("owner", tt.events, base)
+
+			if !reflect.DeepEqual(reports, tt.want) {
+				t.Errorf("Apply() reports = %+v, want %+v", reports, tt.want)
+			}
+
+			if len(sessions) != 1 || sessions[0].State != tt.state || sessions[0].Position != tt.position {
+				t.Errorf("Apply() sessions = %+v, want one %s session at %v", sessions, tt.state, tt.position)
+			}
+
+			indexes := []int{}
+			for _, e := range errs {
+				indexes = append(indexes, e.Index)
+			}
+
+			if tt.errs == nil {
+				tt.errs = []int{}
+			}
+
+			if !reflect.DeepEqual(indexes, tt.errs) {
+				t.Errorf("Apply() errors = %+v, want indexes %v", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestTrackerSessions(t *testing.T) {
+	tracker := NewTracker()
+
+	tracker.Apply("owner", []Event{event(EventPlay, 0, 0, 0)}, base)
+
+	// 会话按账号区分
+	if reports, _, _ := tracker.Apply("other", []Event{event(EventPause, 10, 10, 0)}, base); len(reports.Tracking) != 0 {
+		t.Errorf("other account tracking = %+v, want none", reports.Tracking)
+	}
+
+	if reports, _, _ := tracker.Apply("owner", []Event{event(EventPause, 10, 10, 0)}, base); !reflect.DeepEqual(reports.Tracking, []Tracking{tracking(0, 10, 1)}) {
+		t.Errorf("owner tracking = %+v, want one segment", reports.Tracking)
+	}
+
+	// 停止后会话被删除，再次播放重新上报播放状态
+	tracker.Apply("owner", []Event{event(EventStop, 20, 10, 0)}, base)
+	if reports, _, _ := tracker.Apply("owner", []Event{event(EventPlay, 30, 10, 0)}, base); len(reports.Live) != 1 {
+		t.Errorf("live after stop = %+v, want one report", reports.Live)
+	}
+
+	// 超过 SessionTTL 没有事件的会话过期，不生成收听时长
+	later := base.Add(SessionTTL + time.Hour)
+	reports, sessions, _ := tracker.Apply("owner", []Event{{Type: EventPause, Eid: eid, Pid: pid, Position: 20}}, later)
+	if len(reports.Tracking) != 0 || len(sessions) != 1 || sessions[0].UpdatedAt != later.UnixMilli() {
+		t.Errorf("after expiry = %+v %+v, want a new session without tracking", reports, sessions)
+	}
+}
@@ -0,0 +1,122 @@
+package related
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DOT 导出为 Graphviz 的 DOT 格式，节目的标签为标题，边的标签为相关节目列表中的位置
+func DOT(w io.Writer, g *Graph) error {
+	var b strings.Builder
+
+	b.WriteString("digraph related {\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, depth=%d, subscriptions=%d];\n", dotQuote(n.Pid), dotQuote(n.Title), n.Depth, n.Subscriptions)
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%d];\n", dotQuote(e.From), dotQuote(e.To), e.Rank)
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// dotQuote DOT 中用双引号包围的字符串
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name       `xml:"graphml"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	Keys    []graphMLKey   `xml:"key"`
+	Graph   graphMLElement `xml:"graph"`
+}
+
+type graphMLKey struct {
+	Id   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLElement struct {
+	Id          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	Id   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GraphML 导出为 GraphML 格式，可以在 Gephi、yEd 和 Cytoscape 中打开
+func GraphML(w io.Writer, g *Graph) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{Id: "title", For: "node", Name: "title", Type: "string"},
+			{Id: "author", For: "node", Name: "author", Type: "string"},
+			{Id: "subscriptions", For: "node", Name: "subscriptions", Type: "int"},
+			{Id: "depth", For: "node", Name: "depth", Type: "int"},
+			{Id: "rank", For: "edge", Name: "rank", Type: "int"},
+		},
+		Graph: graphMLElement{Id: "related", EdgeDefault: "directed"},
+	}
+
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{Id: n.Pid, Data: []graphMLData{
+			{Key: "title", Value: n.Title},
+			{Key: "author", Value: n.Author},
+			{Key: "subscriptions", Value: strconv.Itoa(n.Subscriptions)},
+			{Key: "depth", Value: strconv.Itoa(n.Depth)},
+		}})
+	}
+
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: e.From, Target: e.To, Data: []graphMLData{
+			{Key: "rank", Value: strconv.Itoa(e.Rank)},
+		}})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+
+	if err := e.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,418 @@
+// Package related 保存相关节目组成的有向图，计算最短路径、社区和中心度，并导出为 Graphviz DOT、GraphML 和 JSON
+package related
+
+import (
+	"slices"
+	"sort"
+)
+
+// Node 图中的节目，Depth 为与起点的距离
+type Node struct {
+	Pid           string `json:"pid"`
+	Title         string `json:"title"`
+	Author        string `json:"author,omitempty"`
+	Subscriptions int    `json:"subscriptions"`
+	Depth         int    `json:"depth"`
+	Expanded      bool   `json:"expanded"` // 是否已查询该节目的相关节目
+}
+
+// Edge From 的相关节目中有 To，Rank 为 To 在列表中的位置，从 1 开始
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Rank int    `json:"rank"`
+}
+
+// Graph 相关节目图，Nodes 按加入的顺序排列，第一个为起点
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Index 节目在 Nodes 中的位置
+func (g *Graph) Index() map[string]int {
+	index := make(map[string]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		index[n.Pid] = i
+	}
+
+	return index
+}
+
+// Expand 记录 from 的相关节目，未加入的节目以 from 的距离加一加入，返回新加入的节目。
+// 节目数达到 maxNodes 后只记录指向已有节目的边，maxNodes 为 0 时不限制
+func (g *Graph) Expand(from string, related []Node, maxNodes int) []Node {
+	index := g.Index()
+
+	i, found := index[from]
+	if !found {
+		return nil
+	}
+
+	g.Nodes[i].Expanded = true
+	depth := g.Nodes[i].Depth
+
+	// 重新展开时替换原有的边
+	g.Edges = slices.DeleteFunc(slices.Clone(g.Edges), func(e Edge) bool {
+		return e.From == from
+	})
+
+	var added []Node
+	for rank, n := range related {
+		if n.Pid == from {
+			continue
+		}
+
+		if j, found := index[n.Pid]; found {
+			// 只更新信息，不改变距离和展开状态
+			n.Depth, n.Expanded = g.Nodes[j].Depth, g.Nodes[j].Expanded
+			g.Nodes[j] = n
+		} else if maxNodes > 0 && len(g.Nodes) >= maxNodes {
+			continue
+		} else {
+			n.Depth, n.Expanded = depth+1, false
+			index[n.Pid] = len(g.Nodes)
+			g.Nodes = append(g.Nodes, n)
+			added = append(added, n)
+		}
+
+		g.Edges = append(g.Edges, Edge{From: from, To: n.Pid, Rank: rank + 1})
+	}
+
+	return added
+}
+
+// neighbors 每个节目的邻居，directed 为 false 时忽略边的方向，按节目在 Nodes 中的位置排列
+func (g *Graph) neighbors(directed bool) [][]int {
+	index := g.Index()
+	adjacent := make([][]int, len(g.Nodes))
+
+	add := func(a, b int) {
+		if a != b && !slices.Contains(adjacent[a], b) {
+			adjacent[a] = append(adjacent[a], b)
+		}
+	}
+
+	for _, e := range g.Edges {
+		from, ok1 := index[e.From]
+		to, ok2 := index[e.To]
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		add(from, to)
+		if !directed {
+			add(to, from)
+		}
+	}
+
+	for _, a := range adjacent {
+		slices.Sort(a)
+	}
+
+	return adjacent
+}
+
+// ShortestPath from 到 to 经过边数最少的路径，包含首尾；directed 为 true 时只沿着相关节目的方向，没有路径时返回 nil
+func (g *Graph) ShortestPath(from, to string, directed bool) []Node {
+	index := g.Index()
+
+	start, ok1 := index[from]
+	end, ok2 := index[to]
+	if !ok1 || !ok2 {
+		return nil
+	}
+
+	adjacent := g.neighbors(directed)
+
+	previous := make([]int, len(g.Nodes))
+	for i := range previous {
+		previous[i] = -1
+	}
+
+	previous[start] = start
+	queue := []int{start}
+
+	for len(queue) > 0 && previous[end] < 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for _, m := range adjacent[n] {
+			if previous[m] < 0 {
+				previous[m] = n
+				queue = append(queue, m)
+			}
+		}
+	}
+
+	if previous[end] < 0 {
+		return nil
+	}
+
+	var path []Node
+	for n := end; ; n = previous[n] {
+		path = append(path, g.Nodes[n])
+		if n == start {
+			break
+		}
+	}
+
+	slices.Reverse(path)
+
+	return path
+}
+
+// Communities 在忽略方向的图上划分社区：按 Louvain 算法的第一阶段，依次将每个节目移入使模块度增加最多的相邻社区，直到没有节目移动。
+// 结果按社区的大小倒序排列，社区中的节目按订阅数倒序排列
+func (g *Graph) Communities() [][]Node {
+	adjacent := g.neighbors(false)
+
+	// m 为无向边数，total 为社区中节目的度数之和
+	m := 0
+	labels := make([]int, len(g.Nodes))
+	total := make([]int, len(g.Nodes))
+	for i := range labels {
+		labels[i] = i
+		total[i] = len(adjacent[i])
+		m += len(adjacent[i])
+	}
+
+	m /= 2
+
+	for round := 0; round < 100 && m > 0; round++ {
+		moved := false
+
+		for n := range g.Nodes {
+			degree := len(adjacent[n])
+			if degree == 0 {
+				continue
+			}
+
+			// 与每个相邻社区之间的边数
+			links := map[int]int{}
+			for _, neighbor := range adjacent[n] {
+				links[labels[neighbor]]++
+			}
+
+			current := labels[n]
+			total[current] -= degree
+
+			// 移入社区 c 时模块度的增量乘以 m
+			gain := func(c int) float64 {
+				return float64(links[c]) - float64(total[c]*degree)/float64(2*m)
+			}
+
+			best := current
+			for c := range links {
+				if gain(c) > gain(best) || (gain(c) == gain(best) && c < best && best != current) {
+					best = c
+				}
+			}
+
+			total[best] += degree
+			if best != current {
+				labels[n] = best
+				moved = true
+			}
+		}
+
+		if !moved {
+			break
+		}
+	}
+
+	groups := map[int][]Node{}
+	for n, label := range labels {
+		groups[label] = append(groups[label], g.Nodes[n])
+	}
+
+	communities := make([][]Node, 0, len(groups))
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Subscriptions > group[j].Subscriptions
+		})
+
+		communities = append(communities, group)
+	}
+
+	sort.SliceStable(communities, func(i, j int) bool {
+		if len(communities[i]) != len(communities[j]) {
+			return len(communities[i]) > len(communities[j])
+		}
+
+		return communities[i][0].Pid < communities[j][0].Pid
+	})
+
+	return communities
+}
+
+// Centrality 一个节目的中心度
+type Centrality struct {
+	Node
+	InDegree    int     `json:"inDegree"`    // 有多少个节目把它列为相关节目
+	OutDegree   int     `json:"outDegree"`   // 它的相关节目数
+	PageRank    float64 `json:"pageRank"`    // 沿相关节目的方向计算，总和为 1
+	Betweenness float64 `json:"betweenness"` // 忽略方向时经过它的最短路径的比例
+}
+
+const (
+	ByPageRank    = "pagerank"
+	ByInDegree    = "degree"
+	ByBetweenness = "betweenness"
+)
+
+// pageRankDamping PageRank 的阻尼系数
+const pageRankDamping = 0.85
+
+// Central 每个节目的中心度，按 by 指定的指标倒序排列，默认为 PageRank
+func (g *Graph) Central(by string) []Centrality {
+	n := len(g.Nodes)
+	result := make([]Centrality, n)
+	for i, node := range g.Nodes {
+		result[i].Node = node
+	}
+
+	out := g.neighbors(true)
+	for i, targets := range out {
+		result[i].OutDegree = len(targets)
+		for _, t := range targets {
+			result[t].InDegree++
+		}
+	}
+
+	for i, rank := range pageRank(out) {
+		result[i].PageRank = rank
+	}
+
+	for i, b := range betweenness(g.neighbors(false)) {
+		result[i].Betweenness = b
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+
+		switch by {
+		case ByInDegree:
+			if a.InDegree != b.InDegree {
+				return a.InDegree > b.InDegree
+			}
+		case ByBetweenness:
+			if a.Betweenness != b.Betweenness {
+				return a.Betweenness > b.Betweenness
+			}
+		}
+
+		return a.PageRank > b.PageRank
+	})
+
+	return result
+}
+
+// pageRank 迭代计算 PageRank，没有出边的节目的权重平均分给所有节目
+func pageRank(out [][]int) []float64 {
+	n := len(out)
+	if n == 0 {
+		return nil
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	for round := 0; round < 100; round++ {
+		next := make([]float64, n)
+
+		dangling := 0.0
+		for i, targets := range out {
+			if len(targets) == 0 {
+				dangling += rank[i]
+
+				continue
+			}
+
+			for _, t := range targets {
+				next[t] += rank[i] / float64(len(targets))
+			}
+		}
+
+		delta := 0.0
+		for i := range next {
+			next[i] = (1-pageRankDamping)/float64(n) + pageRankDamping*(next[i]+dangling/float64(n))
+			delta += abs(next[i] - rank[i])
+		}
+
+		rank = next
+		if delta < 1e-9 {
+			break
+		}
+	}
+
+	return rank
+}
+
+// betweenness Brandes 算法计算无向图的介数中心度，按 (n-1)(n-2)/2 归一化
+func betweenness(adjacent [][]int) []float64 {
+	n := len(adjacent)
+	result := make([]float64, n)
+
+	for s := range adjacent {
+		var stack []int
+		predecessors := make([][]int, n)
+		sigma := make([]float64, n)
+		distance := make([]int, n)
+		for i := range distance {
+			distance[i] = -1
+		}
+
+		sigma[s], distance[s] = 1, 0
+		queue := []int{s}
+
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+
+			for _, w := range adjacent[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					queue = append(queue, w)
+				}
+
+				if distance[w] == distance[v]+1 {
+					sigma[w] += sigma[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+
+		delta := make([]float64, n)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range predecessors[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+
+			if w != s {
+				result[w] += delta[w]
+			}
+		}
+	}
+
+	// 无向图中每条路径被计算了两次
+	if n > 2 {
+		for i := range result {
+			result[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+
+	return result
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
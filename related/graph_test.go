@@ -0,0 +1,58 @@
+package related
+
+import (
+	"reflect"
+	"testing"
+)
+
+// pids 每个社区中节目的 pid
+func pids(communities [][]Node) [][]string {
+	result := make([][]string, len(communities))
+	for i, community := range communities {
+		for _, n := range community {
+			result[i] = append(result[i], n.Pid)
+		}
+	}
+
+	return result
+}
+
+func TestGraphCommunities(t *testing.T) {
+	node := func(pid string, subscriptions int) Node {
+		return Node{Pid: pid, Subscriptions: subscriptions}
+	}
+
+	tests := []struct {
+		name  string
+		nodes []Node
+		edges []Edge
+		want  [][]string
+	}{
+		{"没有边时每个节目各为一个社区", []Node{node("a", 1), node("b", 2)}, nil, [][]string{{"a"}, {"b"}}},
+		{
+			"两个三角形由一条边相连",
+			[]Node{node("a", 1), node("b", 3), node("c", 2), node("x", 1), node("y", 3), node("z", 2)},
+			[]Edge{
+				{From: "a", To: "b"}, {From: "b", To: "c"}, {From: "c", To: "a"},
+				{From: "x", To: "y"}, {From: "y", To: "z"}, {From: "z", To: "x"},
+				{From: "a", To: "x"},
+			},
+			[][]string{{"b", "c", "a"}, {"y", "z", "x"}},
+		},
+		{
+			"忽略边的方向，孤立的节目排在最后",
+			[]Node{node("a", 1), node("b", 2), node("c", 3), node("d", 9)},
+			[]Edge{{From: "a", To: "b"}, {From: "c", To: "b"}, {From: "a", To: "c"}},
+			[][]string{{"c", "b", "a"}, {"d"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Graph{Nodes: tt.nodes, Edges: tt.edges}
+			if got := pids(g.Communities()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Communities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package resources
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EpisodeURL 单集在小宇宙网页版的地址
+func EpisodeURL(eid string) string {
+	return "https://www.xiaoyuzhoufm.com/episode/" + eid
+}
+
+// clock 时间戳的显示格式，如 05:30、1:02:03
+func clock(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// source 提及的出处，如「节目 · 单集 05:30」
+func (m Mention) source() string {
+	s := m.Title
+	if m.PodcastTitle != "" {
+		s = m.PodcastTitle + " · " + s
+	}
+
+	if m.Timestamp != nil {
+		s += " " + clock(*m.Timestamp)
+	}
+
+	return s
+}
+
+// CSV 每个资源一行，单集列中以「; 」分隔提到它的单集和时间戳
+func CSV(w io.Writer, resources []Resource) error {
+	b := csv.NewWriter(w)
+
+	b.Write([]string{"kind", "title", "url", "domain", "episodes", "last_mentioned", "mentions"})
+	for _, r := range resources {
+		sources := make([]string, len(r.Mentions))
+		for i, m := range r.Mentions {
+			sources[i] = m.source()
+		}
+
+		last := ""
+		if t := r.LastMentioned(); !t.IsZero() {
+			last = t.Format(time.DateOnly)
+		}
+
+		b.Write([]string{r.Kind, r.Title, r.Url, r.Domain, strconv.Itoa(r.Episodes()), last, strings.Join(sources, "; ")})
+	}
+
+	b.Flush()
+
+	return b.Error()
+}
+
+// Markdown 按作品和链接分组的清单，每个资源下列出提到它的单集，时间按 loc 显示
+func Markdown(w io.Writer, resources []Resource, now time.Time, loc *time.Location) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# 节目中提到的资源\n\n%d 个资源，导出于 %s\n", len(resources), now.In(loc).Format(time.DateTime))
+
+	for _, group := range []struct{ kind, heading string }{{KindTitle, "作品"}, {KindLink, "链接"}} {
+		var items []Resource
+		for _, r := range resources {
+			if r.Kind == group.kind {
+				items = append(items, r)
+			}
+		}
+
+		if len(items) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(b, "\n## %s\n\n", group.heading)
+
+		for _, r := range items {
+			switch {
+			case r.Kind == KindTitle:
+				fmt.Fprintf(b, "- 《%s》\n", escape(r.Title))
+			case r.Title != "":
+				fmt.Fprintf(b, "- [%s](%s) · %s\n", escape(r.Title), r.Url, r.Domain)
+			default:
+				fmt.Fprintf(b, "- <%s>\n", r.Url)
+			}
+
+			for _, m := range r.Mentions {
+				fmt.Fprintf(b, "  - [%s](%s)（%s）\n", escape(m.source()), EpisodeURL(m.Eid), m.PubDate.In(loc).Format(time.DateOnly))
+			}
+		}
+	}
+
+	return b.Flush()
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+func escape(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,225 @@
+// Package resources 从 shownotes 中提取提到的链接和《》中的作品名，规范化后去重，建立可查询的索引并导出为 CSV 和 Markdown
+package resources
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ultrazg/xyz/shownotes"
+)
+
+const (
+	KindLink  = "link"  // 链接
+	KindTitle = "title" // 《》中的书、影视、文章、音乐等作品名
+)
+
+// Match shownotes 中的一处提及
+type Match struct {
+	Kind      string `json:"kind"`
+	Key       string `json:"key"`                 // 规范化后的链接或作品名，用于去重
+	Title     string `json:"title"`               // 作品名或链接文字
+	Url       string `json:"url,omitempty"`       // 去掉跟踪参数后的链接
+	Timestamp *int   `json:"timestamp,omitempty"` // 所在行开头的时间戳，单位为秒
+}
+
+var (
+	markdownLink = regexp.MustCompile(`(!?)\[((?:\\.|[^\\\]])*)\]\(([^)\s]+)\)`)
+	autoLink     = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	bareLink     = regexp.MustCompile(`(?i)https?://[^\s<>"'()（）《》【】「」，。；！？、]+`)
+	workTitle    = regexp.MustCompile(`《([^《》]{1,80})》`)
+
+	markdownUnescaper = strings.NewReplacer(`\\`, `\`, `\*`, "*", `\_`, "_", "\\`", "`", `\[`, "[", `\]`, "]")
+)
+
+// Extract 逐行提取 shownotes 中的链接（图片除外）和《》中的作品名，同一单集中相同的资源在同一时间只记录一次
+func Extract(source string) []Match {
+	var matches []Match
+	seen := map[string]bool{}
+
+	add := func(m Match, timestamp *int) {
+		m.Timestamp = timestamp
+
+		key := m.Kind + "\x00" + m.Key
+		if timestamp != nil {
+			key += "\x00" + strconv.Itoa(*timestamp)
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			matches = append(matches, m)
+		}
+	}
+
+	for _, line := range strings.Split(shownotes.Markdown(source), "\n") {
+		var links []Match
+
+		// 链接替换为文字，自动链接替换为空格，剩下的文字中再查找未加链接的地址和作品名
+		plain := markdownLink.ReplaceAllStringFunc(line, func(s string) string {
+			groups := markdownLink.FindStringSubmatch(s)
+			text := markdownUnescaper.Replace(groups[2])
+
+			if m, ok := link(groups[3], text); ok && groups[1] == "" {
+				links = append(links, m)
+			}
+
+			return text
+		})
+
+		plain = autoLink.ReplaceAllStringFunc(plain, func(s string) string {
+			if m, ok := link(s[1:len(s)-1], ""); ok {
+				links = append(links, m)
+			}
+
+			return " "
+		})
+
+		plain = markdownUnescaper.Replace(plain)
+
+		for _, s := range bareLink.FindAllString(plain, -1) {
+			if m, ok := link(s, ""); ok {
+				links = append(links, m)
+			}
+		}
+
+		var timestamp *int
+		if seconds, ok := shownotes.Timestamp(plain); ok {
+			timestamp = &seconds
+		}
+
+		for _, m := range links {
+			add(m, timestamp)
+		}
+
+		for _, groups := range workTitle.FindAllStringSubmatch(plain, -1) {
+			if m, ok := title(groups[1]); ok {
+				add(m, timestamp)
+			}
+		}
+	}
+
+	return matches
+}
+
+func link(raw, text string) (Match, bool) {
+	canonical, key, ok := NormalizeURL(raw)
+	if !ok {
+		return Match{}, false
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if _, textKey, isURL := NormalizeURL(text); isURL && textKey == key {
+		text = ""
+	}
+
+	return Match{Kind: KindLink, Key: key, Title: text, Url: canonical}, true
+}
+
+func title(s string) (Match, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return Match{}, false
+	}
+
+	return Match{Kind: KindTitle, Key: NormalizeTitle(s), Title: s}, true
+}
+
+// trackingParams 分享链接中常见的跟踪参数，utm_ 开头的参数同样去掉。
+// 只包含不会用作其它用途的参数名，from、ref 等在很多网站上是页面本身的参数，不去掉
+var trackingParams = map[string]bool{
+	"spm": true, "ref_src": true, "fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true,
+	"share_source": true, "share_medium": true, "share_plat": true, "share_session_id": true, "share_token": true, "share_from": true,
+	"is_from_webapp": true, "sender_device": true, "xsec_token": true, "xsec_source": true, "vd_source": true, "unique_k": true, "bbid": true,
+}
+
+// hostTrackingParams 只在对应网站（包含子域名）上是跟踪参数的参数名
+var hostTrackingParams = map[string][]string{
+	"youtube.com":      {"si"},
+	"youtu.be":         {"si"},
+	"spotify.com":      {"si"},
+	"mp.weixin.qq.com": {"scene", "chksm", "mpshare", "sharer_sharetime", "sharer_shareid"},
+}
+
+// tracking 参数是否为 host 上的跟踪参数
+func tracking(host, name string) bool {
+	host, name = strings.ToLower(host), strings.ToLower(name)
+	if trackingParams[name] || strings.HasPrefix(name, "utm_") {
+		return true
+	}
+
+	for domain, params := range hostTrackingParams {
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && slices.Contains(params, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NormalizeURL 返回去掉跟踪参数和片段后的链接，以及用于去重的 key：忽略协议、www.、默认端口、末尾的 / 和参数的顺序。
+// 只支持 http 和 https 链接
+func NormalizeURL(raw string) (canonical, key string, ok bool) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), ".,;:!?'\"")
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host = net.JoinHostPort(host, port)
+	}
+
+	query := u.Query()
+	for name := range query {
+		if tracking(u.Hostname(), name) {
+			query.Del(name)
+		}
+	}
+
+	path := u.EscapedPath()
+	if path == "/" {
+		path = ""
+	}
+
+	canonical = scheme + "://" + host + path
+	key = strings.TrimPrefix(host, "www.") + strings.TrimSuffix(path, "/")
+
+	if encoded := query.Encode(); encoded != "" {
+		canonical += "?" + encoded
+		key += "?" + encoded
+	}
+
+	return canonical, key, true
+}
+
+// NormalizeTitle 作品名用于去重的 key：全角字母和数字转换为半角，忽略大小写和空白
+func NormalizeTitle(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if r >= '！' && r <= '～' {
+			runes[i] = r - '！' + '!'
+		}
+	}
+
+	return strings.ToLower(strings.Join(strings.Fields(string(runes)), ""))
+}
+
+// Domain 链接的域名，去掉 www.
+func Domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,84 @@
+package resources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		canonical string
+		key       string
+		ok        bool
+	}{
+		{"去掉 utm 参数和片段", "https://example.com/a?utm_source=x&id=1#top", "https://example.com/a?id=1", "example.com/a?id=1", true},
+		{"参数按名称排序", "https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2", "example.com/a?a=1&b=2", true},
+		{"key 忽略协议、www. 和末尾的 /", "http://www.Example.com/a/", "http://www.example.com/a/", "example.com/a", true},
+		{"去掉默认端口", "https://example.com:443/", "https://example.com", "example.com", true},
+		{"保留其它端口", "http://example.com:8080/a", "http://example.com:8080/a", "example.com:8080/a", true},
+		{"去掉末尾的标点", "https://example.com/a。", "https://example.com/a%E3%80%82", "example.com/a%E3%80%82", true},
+		{"去掉末尾的英文标点", "https://example.com/a?id=1.,", "https://example.com/a?id=1", "example.com/a?id=1", true},
+		{"保留 from 和 ref", "https://example.com/a?from=home&ref=v2", "https://example.com/a?from=home&ref=v2", "example.com/a?from=home&ref=v2", true},
+		{"YouTube 的 si", "https://youtu.be/abc?si=xyz&t=30", "https://youtu.be/abc?t=30", "youtu.be/abc?t=30", true},
+		{"Spotify 子域名的 si", "https://open.spotify.com/episode/abc?si=xyz", "https://open.spotify.com/episode/abc", "open.spotify.com/episode/abc", true},
+		{"其它网站的 si", "https://example.com/a?si=1", "https://example.com/a?si=1", "example.com/a?si=1", true},
+		{"微信公众号文章的 scene", "https://mp.weixin.qq.com/s/abc?scene=1&chksm=2", "https://mp.weixin.qq.com/s/abc", "mp.weixin.qq.com/s/abc", true},
+		{"不支持的协议", "ftp://example.com/a", "", "", false},
+		{"没有域名", "https:///a", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canonical, key, ok := NormalizeURL(tt.raw)
+			if canonical != tt.canonical || key != tt.key || ok != tt.ok {
+				t.Errorf("NormalizeURL(%q) = %q, %q, %v, want %q, %q, %v", tt.raw, canonical, key, ok, tt.canonical, tt.key, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	type match struct {
+		kind, key, title string
+		timestamp        int // -1 表示没有时间戳
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		want   []match
+	}{
+		{"链接和作品名", `<p>推荐<a href="https://example.com/a?utm_source=x">这篇文章</a>和《三体》</p>`, []match{
+			{KindLink, "example.com/a", "这篇文章", -1},
+			{KindTitle, "三体", "三体", -1},
+		}},
+		{"忽略图片", `<p><img src="https://example.com/a.png"></p>`, nil},
+		{"未加链接的地址，链接文字与地址相同时不保存文字", `<p>见 https://example.com/b 和 <a href="https://example.com/b">https://example.com/b</a></p>`, []match{
+			{KindLink, "example.com/b", "", -1},
+		}},
+		{"行首的时间戳，同一时间的相同资源只记录一次", `<p>01:02 聊到《三体》</p><p>03:00 又聊到《三体》</p><p>03:00 《三体》</p>`, []match{
+			{KindTitle, "三体", "三体", 62},
+			{KindTitle, "三体", "三体", 180},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []match
+			for _, m := range Extract(tt.source) {
+				timestamp := -1
+				if m.Timestamp != nil {
+					timestamp = *m.Timestamp
+				}
+
+				got = append(got, match{m.Kind, m.Key, m.Title, timestamp})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
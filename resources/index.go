@@ -0,0 +1,231 @@
+package resources
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Episode 提到资源的单集
+type Episode struct {
+	Eid          string    `json:"eid"`
+	Title        string    `json:"title"`
+	Pid          string    `json:"pid"`
+	PodcastTitle string    `json:"podcastTitle"`
+	PubDate      time.Time `json:"pubDate"`
+}
+
+// Mention 资源在单集中的一处提及
+type Mention struct {
+	Episode
+	Timestamp *int   `json:"timestamp,omitempty"` // 所在行开头的时间戳，单位为秒
+	Text      string `json:"text,omitempty"`      // 链接文字
+}
+
+// Resource 去重后的资源和提到它的单集
+type Resource struct {
+	Key      string    `json:"key"`
+	Kind     string    `json:"kind"`
+	Title    string    `json:"title"` // 作品名，或第一次出现时的链接文字
+	Url      string    `json:"url,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Mentions []Mention `json:"mentions"`
+}
+
+// Episodes 提到该资源的单集数
+func (r Resource) Episodes() int {
+	eids := map[string]bool{}
+	for _, m := range r.Mentions {
+		eids[m.Eid] = true
+	}
+
+	return len(eids)
+}
+
+// LastMentioned 最近一次被提到的单集的发布时间
+func (r Resource) LastMentioned() time.Time {
+	var last time.Time
+	for _, m := range r.Mentions {
+		if m.PubDate.After(last) {
+			last = m.PubDate
+		}
+	}
+
+	return last
+}
+
+// Index 资源索引，Episodes 为已提取的单集
+type Index struct {
+	Episodes  []Episode  `json:"episodes"`
+	Resources []Resource `json:"resources"`
+}
+
+// Has 单集是否已提取
+func (x *Index) Has(eid string) bool {
+	for _, e := range x.Episodes {
+		if e.Eid == eid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add 加入单集中的提及，单集已提取时替换原有的提及
+func (x *Index) Add(e Episode, matches []Match) {
+	x.remove(e.Eid)
+	x.Episodes = append(x.Episodes, e)
+
+	index := make(map[string]int, len(x.Resources))
+	for i, r := range x.Resources {
+		index[r.Kind+"\x00"+r.Key] = i
+	}
+
+	for _, m := range matches {
+		mention := Mention{Episode: e, Timestamp: m.Timestamp}
+		if m.Kind == KindLink {
+			mention.Text = m.Title
+		}
+
+		i, found := index[m.Kind+"\x00"+m.Key]
+		if !found {
+			i = len(x.Resources)
+			index[m.Kind+"\x00"+m.Key] = i
+			x.Resources = append(x.Resources, Resource{Key: m.Key, Kind: m.Kind, Url: m.Url})
+
+			if m.Kind == KindLink {
+				x.Resources[i].Domain = Domain(m.Url)
+			}
+		}
+
+		r := &x.Resources[i]
+		if r.Title == "" {
+			r.Title = m.Title
+		}
+
+		r.Mentions = append(r.Mentions, mention)
+	}
+}
+
+// remove 删除单集和它的提及，没有提及的资源一并删除。总是创建新的切片，不修改之前返回的副本
+func (x *Index) remove(eid string) {
+	episodes := make([]Episode, 0, len(x.Episodes)+1)
+	for _, e := range x.Episodes {
+		if e.Eid != eid {
+			episodes = append(episodes, e)
+		}
+	}
+
+	x.Episodes = episodes
+
+	resources := make([]Resource, 0, len(x.Resources))
+	for _, r := range x.Resources {
+		mentions := make([]Mention, 0, len(r.Mentions))
+		for _, m := range r.Mentions {
+			if m.Eid != eid {
+				mentions = append(mentions, m)
+			}
+		}
+
+		if r.Mentions = mentions; len(mentions) > 0 {
+			resources = append(resources, r)
+		}
+	}
+
+	x.Resources = resources
+}
+
+const (
+	SortEpisodes = "episodes"
+	SortRecent   = "recent"
+	SortTitle    = "title"
+)
+
+// Filter 查询条件，零值表示不限制
+type Filter struct {
+	Keyword     string // 作品名、链接文字或链接中包含的文字，不区分大小写
+	Kind        string // link 或 title
+	Pid         string // 只查询该节目提到的资源，提及也只保留该节目的单集
+	Domain      string // 链接的域名，包含子域名
+	MinEpisodes int    // 最少被提到的单集数
+	Sort        string // episodes 按提到的单集数倒序（默认），recent 按最近被提到的时间倒序，title 按标题
+}
+
+// Search 返回符合条件的资源，提及按单集的发布时间倒序排列
+func (x *Index) Search(f Filter) []Resource {
+	keyword := strings.ToLower(f.Keyword)
+	domain := strings.TrimPrefix(strings.ToLower(f.Domain), "www.")
+
+	result := []Resource{}
+	for _, r := range x.Resources {
+		if f.Kind != "" && r.Kind != f.Kind {
+			continue
+		}
+
+		if domain != "" && r.Domain != domain && !strings.HasSuffix(r.Domain, "."+domain) {
+			continue
+		}
+
+		if keyword != "" && !r.contains(keyword) {
+			continue
+		}
+
+		mentions := []Mention{}
+		for _, m := range r.Mentions {
+			if f.Pid == "" || m.Pid == f.Pid {
+				mentions = append(mentions, m)
+			}
+		}
+
+		r.Mentions = mentions
+		if len(mentions) == 0 || r.Episodes() < f.MinEpisodes {
+			continue
+		}
+
+		sort.SliceStable(r.Mentions, func(i, j int) bool {
+			return r.Mentions[i].PubDate.After(r.Mentions[j].PubDate)
+		})
+
+		result = append(result, r)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		switch f.Sort {
+		case SortRecent:
+			return result[i].LastMentioned().After(result[j].LastMentioned())
+		case SortTitle:
+			return strings.ToLower(result[i].label()) < strings.ToLower(result[j].label())
+		default:
+			if a, b := result[i].Episodes(), result[j].Episodes(); a != b {
+				return a > b
+			}
+
+			return result[i].LastMentioned().After(result[j].LastMentioned())
+		}
+	})
+
+	return result
+}
+
+func (r Resource) contains(keyword string) bool {
+	if strings.Contains(strings.ToLower(r.Title), keyword) || strings.Contains(strings.ToLower(r.Url), keyword) {
+		return true
+	}
+
+	for _, m := range r.Mentions {
+		if strings.Contains(strings.ToLower(m.Text), keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// label 显示的名称，链接没有文字时为链接本身
+func (r Resource) label() string {
+	if r.Title != "" {
+		return r.Title
+	}
+
+	return r.Url
+}
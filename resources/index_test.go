@@ -0,0 +1,55 @@
+package resources
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIndexSearch(t *testing.T) {
+	episode := func(eid, pid string, day int) Episode {
+		return Episode{Eid: eid, Pid: pid, PubDate: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)}
+	}
+
+	book := Match{Kind: KindTitle, Key: "三体", Title: "三体"}
+	blog := Match{Kind: KindLink, Key: "blog.example.com/a", Title: "博客", Url: "https://blog.example.com/a"}
+	video := Match{Kind: KindLink, Key: "youtu.be/abc", Url: "https://youtu.be/abc"}
+
+	x := &Index{}
+	x.Add(episode("e1", "p1", 1), []Match{book, blog})
+	x.Add(episode("e2", "p2", 2), []Match{book})
+	x.Add(episode("e3", "p2", 3), []Match{video})
+	// 重新提取时替换原有的提及
+	x.Add(episode("e1", "p1", 1), []Match{book})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"默认按提到的单集数倒序", Filter{}, []string{"三体", "youtu.be/abc"}},
+		{"按最近被提到的时间倒序", Filter{Sort: SortRecent}, []string{"youtu.be/abc", "三体"}},
+		{"按类型", Filter{Kind: KindLink}, []string{"youtu.be/abc"}},
+		{"按域名", Filter{Domain: "www.youtu.be"}, []string{"youtu.be/abc"}},
+		{"按节目", Filter{Pid: "p1"}, []string{"三体"}},
+		{"最少被提到的单集数", Filter{MinEpisodes: 2}, []string{"三体"}},
+		{"关键词不区分大小写", Filter{Keyword: "ABC"}, []string{"youtu.be/abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := []string{}
+			for _, r := range x.Search(tt.filter) {
+				keys = append(keys, r.Key)
+			}
+
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("Search(%+v) = %v, want %v", tt.filter, keys, tt.want)
+			}
+		})
+	}
+
+	if len(x.Episodes) != 3 || x.Search(Filter{Kind: KindTitle})[0].Mentions[0].Eid != "e2" {
+		t.Errorf("Index = %+v, want 3 episodes with mentions sorted by pubDate descending", x)
+	}
+}
@@ -0,0 +1,468 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/analytics"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// 收听历史最多读取的页数，分类下的节目每个分类最多读取的页数，每次查询播放进度的单集数
+const (
+	analyticsHistoryPages  = 100
+	analyticsCategoryPages = 5
+	analyticsProgressBatch = 50
+	analyticsDefaultTop    = 10
+)
+
+type ListeningStatsRequestBody struct {
+	Year     int    `json:"year" form:"year" binding:"omitempty,min=2020,max=2100"`   // 统计整年，与 from、to 同时传入时忽略 from、to
+	From     string `json:"from" form:"from" binding:"omitempty,datetime=2006-01-02"` // 开始日期，默认为 to 所在年份的 1 月 1 日
+	To       string `json:"to" form:"to" binding:"omitempty,datetime=2006-01-02"`     // 结束日期（包含），默认为今天
+	Timezone string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`    // 计算日期和时段使用的时区，默认为 Asia/Shanghai
+	Top      int    `json:"top" form:"top" binding:"omitempty,min=1,max=50"`          // 收听最多的节目和单集的数量，默认为 10
+}
+
+// ListeningStatsData 收听统计，分类查询失败时节目归入「未分类」，上游记录的收听时长查询失败时 listened 为 null，原因见 errors
+type ListeningStatsData struct {
+	*analytics.Report
+	Errors []PageError `json:"errors"`
+}
+
+// ListeningStats 根据收听历史和播放进度统计一段时间内的收听，并附上上游记录的收听时长
+var ListeningStats = func(ctx *gin.Context) {
+	var params ListeningStatsRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	report, errs, ok := listeningReport(ctx, params)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": ListeningStatsData{Report: report, Errors: errs},
+	})
+}
+
+// ListeningReport 以 HTML 页面返回收听统计，传入 year 时为年度报告
+var ListeningReport = func(ctx *gin.Context) {
+	var params ListeningStatsRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	report, _, ok := listeningReport(ctx, params)
+	if !ok {
+		return
+	}
+
+	title := "收听报告"
+	if params.Year > 0 {
+		title = fmt.Sprintf("%d 年度收听报告", params.Year)
+	}
+
+	var b bytes.Buffer
+	if err := report.HTML(&b, title); err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", b.Bytes())
+}
+
+// listeningReport 读取统计范围内的收听历史、播放进度和节目的分类并汇总
+func listeningReport(ctx *gin.Context, params ListeningStatsRequestBody) (*analytics.Report, []PageError, bool) {
+	options, err := listeningOptions(params)
+	if err != nil {
+		utils.ReturnBadRequest(ctx, err)
+
+		return nil, nil, false
+	}
+
+	plays, failed := listeningHistory(ctx.Request, options.From)
+	if failed == nil {
+		failed = listeningProgress(ctx.Request, plays)
+	}
+
+	if failed != nil {
+		ctx.JSON(failed.Status, failed.Body)
+
+		return nil, nil, false
+	}
+
+	pids := map[string]bool{}
+	for _, play := range plays {
+		pids[play.Pid] = true
+	}
+
+	categories, errs := podcastCategories(ctx.Request, pids)
+
+	report := analytics.Summarize(plays, categories, options)
+
+	listened, listenedErrs := listenedSeconds(ctx.Request, options.Top)
+	report.Listened = listened
+
+	return report, append(errs, listenedErrs...), true
+}
+
+func listeningOptions(params ListeningStatsRequestBody) (analytics.Options, error) {
+	options := analytics.Options{Top: params.Top}
+	if options.Top == 0 {
+		options.Top = analyticsDefaultTop
+	}
+
+	location, err := timezoneOf(params.Timezone)
+	if err != nil {
+		return options, err
+	}
+
+	options.Location = location
+
+	if params.Year > 0 {
+		options.From = time.Date(params.Year, time.January, 1, 0, 0, 0, 0, location)
+		options.To = options.From.AddDate(1, 0, 0)
+
+		return options, nil
+	}
+
+	now := time.Now().In(location)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if params.To != "" {
+		to, _ = time.ParseInLocation("2006-01-02", params.To, location)
+	}
+
+	from := time.Date(to.Year(), time.January, 1, 0, 0, 0, 0, location)
+	if params.From != "" {
+		from, _ = time.ParseInLocation("2006-01-02", params.From, location)
+	}
+
+	if from.After(to) {
+		return options, utils.NewError(http.StatusBadRequest, utils.ErrValidationFailed, "from 不能晚于 to")
+	}
+
+	options.From, options.To = from, to.AddDate(0, 0, 1)
+
+	return options, nil
+}
+
+// timezoneOf 计算日期使用的时区，默认为 Asia/Shanghai
+func timezoneOf(name string) (*time.Location, error) {
+	if name == "" {
+		name = "Asia/Shanghai"
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, utils.NewError(http.StatusBadRequest, utils.ErrValidationFailed, "timezone: "+err.Error())
+	}
+
+	return location, nil
+}
+
+// listeningHistory 按收听时间倒序读取收听历史，读到 from 之前的记录时停止
+func listeningHistory(origin *http.Request, from time.Time) ([]analytics.Play, *BatchResult) {
+	var plays []analytics.Play
+
+	var loadMoreKey any
+	for page := 0; page < analyticsHistoryPages; page++ {
+		body := map[string]any{}
+		if loadMoreKey != nil {
+			body["loadMoreKey"] = loadMoreKey
+		}
+
+		data, failed := callOperation(origin, "/episode_played_history_list", body)
+		if failed != nil {
+			return nil, failed
+		}
+
+		items, _ := data["data"].([]any)
+
+		earlier := false
+		for _, item := range items {
+			play, ok := historyPlay(item)
+			if !ok {
+				continue
+			}
+
+			if play.PlayedAt.Before(from) {
+				earlier = true
+
+				continue
+			}
+
+			plays = append(plays, play)
+		}
+
+		loadMoreKey = data["loadMoreKey"]
+		if earlier || loadMoreKey == nil || len(items) == 0 {
+			break
+		}
+	}
+
+	return plays, nil
+}
+
+// historyPlay 收听历史中的一项，{episode, playedAt}
+func historyPlay(item any) (analytics.Play, bool) {
+	m, _ := item.(map[string]any)
+
+	episode, _ := m["episode"].(map[string]any)
+	if episode == nil {
+		return analytics.Play{}, false
+	}
+
+	playedAt, ok := m["playedAt"].(string)
+	if !ok {
+		playedAt, _ = episode["playedAt"].(string)
+	}
+
+	at, err := time.Parse(time.RFC3339, playedAt)
+	if err != nil {
+		return analytics.Play{}, false
+	}
+
+	eid, _ := episode["eid"].(string)
+	title, _ := episode["title"].(string)
+	pid, _ := episode["pid"].(string)
+	podcastTitle, _ := nested(episode, "podcast", "title").(string)
+	duration, _ := episode["duration"].(float64)
+
+	play := analytics.Play{Eid: eid, Title: title, Pid: pid, PodcastTitle: podcastTitle, PlayedAt: at, Duration: int(duration), Progress: -1}
+	if progress, ok := episode["progress"].(float64); ok {
+		play.Progress = int(progress)
+	}
+
+	return play, true
+}
+
+// listeningProgress 补充收听历史中没有的播放进度，查询不到进度的单集视为 0
+func listeningProgress(origin *http.Request, plays []analytics.Play) *BatchResult {
+	index := map[string][]int{}
+	var eids []any
+
+	for i := range plays {
+		if plays[i].Progress >= 0 {
+			continue
+		}
+
+		plays[i].Progress = 0
+
+		if _, found := index[plays[i].Eid]; !found {
+			eids = append(eids, plays[i].Eid)
+		}
+
+		index[plays[i].Eid] = append(index[plays[i].Eid], i)
+	}
+
+	for start := 0; start < len(eids); start += analyticsProgressBatch {
+		data, failed := callOperation(origin, "/episode_play_progress", map[string]any{"eids": eids[start:min(start+analyticsProgressBatch, len(eids))]})
+		if failed != nil {
+			return failed
+		}
+
+		items, _ := data["data"].([]any)
+		for _, item := range items {
+			m, _ := item.(map[string]any)
+			eid, _ := m["eid"].(string)
+			progress, _ := m["progress"].(float64)
+
+			for _, i := range index[eid] {
+				plays[i].Progress = int(progress)
+			}
+		}
+	}
+
+	return nil
+}
+
+// podcastCategories 从分类下的节目列表中查找 pids 所属的分类，全部找到或读完各分类的前几页后停止。
+// 上游没有查询节目分类的接口，每个分类只读取前 analyticsCategoryPages 页，排在后面的节目会归入「未分类」；
+// 出现在多个分类中的节目归入先读到的分类
+
+func podcastCategories(origin *http.Request, pids map[string]bool) (map[string]analytics.Category, []PageError) {
+	categories := map[string]analytics.Category{}
+	errs := []PageError{}
+
+	if len(pids) == 0 {
+		return categories, errs
+	}
+
+	data, failed := callOperation(origin, "/category_list", map[string]any{})
+	if failed != nil {
+		return categories, append(errs, operationError("categories", "/category_list", failed))
+	}
+
+	items, _ := data["data"].([]any)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, batchWorkers)
+
+	remaining := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(categories) < len(pids)
+	}
+
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+		id, _ := m["id"].(string)
+		name, _ := m["name"].(string)
+		category := analytics.Category{Id: id, Name: name}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			loadMoreKey := 0
+			for page := 0; page < analyticsCategoryPages && remaining(); page++ {
+				body := map[string]any{"categoryId": category.Id, "tab": "ALL"}
+				if loadMoreKey > 0 {
+					body["loadMoreKey"] = loadMoreKey
+				}
+
+				data, failed := callOperation(origin, "/category_podcast_list", body)
+				if failed != nil {
+					mu.Lock()
+					errs = append(errs, operationError("categories", "/category_podcast_list", failed))
+					mu.Unlock()
+
+					return
+				}
+
+				podcasts, _ := data["data"].([]any)
+
+				mu.Lock()
+				for _, podcast := range podcasts {
+					p, _ := podcast.(map[string]any)
+					if inner, ok := p["podcast"].(map[string]any); ok {
+						p = inner
+					}
+
+					pid, _ := p["pid"].(string)
+					if _, found := categories[pid]; pids[pid] && !found {
+						categories[pid] = category
+					}
+				}
+				mu.Unlock()
+
+				next, ok := data["loadMoreKey"].(float64)
+				if !ok || len(podcasts) == 0 {
+					return
+				}
+
+				loadMoreKey = int(next)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return categories, errs
+}
+
+// listenedSeconds 从 /mileage_get 和 /mileage_list 读取上游记录的收听时长，节目只保留累计收听最多的 top 个
+func listenedSeconds(origin *http.Request, top int) (*analytics.Listened, []PageError) {
+	operations := []BatchOperation{
+		{Id: "listened", Path: "/mileage_get", Body: map[string]any{}},
+		{Id: "listened", Path: "/mileage_list", Body: map[string]any{"all": true}},
+		{Id: "listened", Path: "/mileage_list", Body: map[string]any{"all": false}},
+	}
+
+	results := runBatch(origin, operations, make([][]int, len(operations)))
+
+	errs := []PageError{}
+	bodies := make([]map[string]any, len(results))
+
+	for i, result := range results {
+		if !succeeded(result) {
+			errs = append(errs, operationError(operations[i].Id, operations[i].Path, result))
+
+			continue
+		}
+
+		response, _ := result.Body.(map[string]any)
+		bodies[i], _ = response["data"].(map[string]any)
+	}
+
+	if bodies[0] == nil {
+		return nil, errs
+	}
+
+	return listened(bodies[0], bodies[1], bodies[2], top), errs
+}
+
+// listened 汇总上游的收听数据概览和累计、最近 30 天的收听排行，排行查询失败时传入 nil
+func listened(overview, total, recent map[string]any, top int) *analytics.Listened {
+	summary, _ := overview["data"].(map[string]any)
+
+	l := &analytics.Listened{Podcasts: []*analytics.ListenedPodcast{}}
+	l.TotalSeconds = secondsOf(summary["totalPlayedSeconds"])
+	l.LastSevenDaysSeconds = secondsOf(summary["lastSevenDayPlayedSeconds"])
+	l.LastThirtyDaysSeconds = secondsOf(summary["lastThirtyDayPlayedSeconds"])
+
+	recentSeconds := map[string]int{}
+	for _, item := range rankItems(recent) {
+		recentSeconds[item.Pid] = item.Seconds
+	}
+
+	for _, item := range rankItems(total) {
+		item.LastThirtyDaysSeconds = recentSeconds[item.Pid]
+		l.Podcasts = append(l.Podcasts, item)
+	}
+
+	sort.SliceStable(l.Podcasts, func(i, j int) bool {
+		return l.Podcasts[i].Seconds > l.Podcasts[j].Seconds
+	})
+
+	if top > 0 {
+		l.Podcasts = l.Podcasts[:min(top, len(l.Podcasts))]
+	}
+
+	return l
+}
+
+// rankItems 收听排行中的各项，{playedSeconds, podcast}
+func rankItems(body map[string]any) []*analytics.ListenedPodcast {
+	items, _ := body["data"].([]any)
+
+	podcasts := make([]*analytics.ListenedPodcast, 0, len(items))
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+
+		pid, _ := nested(m, "podcast", "pid").(string)
+		if pid == "" {
+			continue
+		}
+
+		title, _ := nested(m, "podcast", "title").(string)
+		podcasts = append(podcasts, &analytics.ListenedPodcast{Pid: pid, Title: title, Seconds: secondsOf(m["playedSeconds"])})
+	}
+
+	return podcasts
+}
+
+// secondsOf 上游返回的秒数，JSON 解码后为 float64
+func secondsOf(v any) int {
+	seconds, _ := v.(float64)
+
+	return int(seconds)
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ultrazg/xyz/analytics"
+)
+
+func TestListened(t *testing.T) {
+	overview := map[string]any{"data": map[string]any{
+		"totalPlayedSeconds":         float64(7200),
+		"lastSevenDayPlayedSeconds":  float64(600),
+		"lastThirtyDayPlayedSeconds": float64(1800),
+	}}
+
+	rank := func(items ...any) map[string]any {
+		return map[string]any{"data": items}
+	}
+
+	item := func(pid, title string, seconds float64) any {
+		return map[string]any{"playedSeconds": seconds, "podcast": map[string]any{"pid": pid, "title": title}}
+	}
+
+	tests := []struct {
+		name   string
+		total  map[string]any
+		recent map[string]any
+		top    int
+		want   *analytics.Listened
+	}{
+		{
+			name:   "合并累计和最近 30 天的排行",
+			total:  rank(item("p1", "节目一", 3000), item("p2", "节目二", 4200)),
+			recent: rank(item("p2", "节目二", 1800)),
+			want: &analytics.Listened{
+				TotalSeconds:          7200,
+				LastSevenDaysSeconds:  600,
+				LastThirtyDaysSeconds: 1800,
+				Podcasts: []*analytics.ListenedPodcast{
+					{Pid: "p2", Title: "节目二", Seconds: 4200, LastThirtyDaysSeconds: 1800},
+					{Pid: "p1", Title: "节目一", Seconds: 3000},
+				},
+			},
+		},
+		{
+			name:  "只保留前 top 个节目，跳过没有 pid 的项",
+			total: rank(item("p1", "节目一", 3000), item("", "", 9000), item("p2", "节目二", 4200)),
+			top:   1,
+			want: &analytics.Listened{
+				TotalSeconds:          7200,
+				LastSevenDaysSeconds:  600,
+				LastThirtyDaysSeconds: 1800,
+				Podcasts:              []*analytics.ListenedPodcast{{Pid: "p2", Title: "节目二", Seconds: 4200}},
+			},
+		},
+		{
+			name: "排行查询失败时节目为空",
+			want: &analytics.Listened{
+				TotalSeconds:          7200,
+				LastSevenDaysSeconds:  600,
+				LastThirtyDaysSeconds: 1800,
+				Podcasts:              []*analytics.ListenedPodcast{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listened(overview, tt.total, tt.recent, tt.top)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listened() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
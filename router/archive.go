@@ -0,0 +1,432 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/comments"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// commentArchiveBucket 评论存档和抓取进度，key 为 eid
+	commentArchiveBucket = "comment_archive"
+	// commentArchiveInterval 所有抓取任务共用的请求间隔
+	commentArchiveInterval = 300 * time.Millisecond
+	// commentArchiveMaxRequests 单个任务最多发送的请求数
+	commentArchiveMaxRequests = 5000
+	// commentArchiveSaveEvery 每发送该数量的请求保存一次进度
+	commentArchiveSaveEvery = 10
+	// commentArchiveDefaultLimit 查询默认返回的评论数
+	commentArchiveDefaultLimit = 50
+)
+
+// commentArchiveJobs 抓取评论的任务，key 为 eid
+var commentArchiveJobs = &crawlJobs[*commentArchive]{
+	name:        "comment archive",
+	bucket:      commentArchiveBucket,
+	limiter:     &rateLimiter{interval: commentArchiveInterval},
+	maxRequests: commentArchiveMaxRequests,
+	saveEvery:   commentArchiveSaveEvery,
+	interrupted: "crawl interrupted",
+	hint:        "call /comment_archive again to continue",
+}
+
+// commentArchive 评论存档和抓取进度
+type commentArchive struct {
+	comments.Archive
+	Crawl commentCrawl `json:"crawl"`
+}
+
+// commentCrawl 抓取进度：先按时间倒序抓取全部主评论，再逐个抓取有回复的主评论的回复
+type commentCrawl struct {
+	crawlStatus
+	Cursor       any      `json:"cursor,omitempty"` // 下一页主评论的 loadMoreKey
+	PrimaryDone  bool     `json:"primaryDone"`
+	Threads      []string `json:"threads,omitempty"`      // 等待抓取回复的主评论
+	ThreadCursor any      `json:"threadCursor,omitempty"` // Threads[0] 下一页回复的 loadMoreKey
+	Skipped      []string `json:"skipped,omitempty"`      // 无法抓取回复的主评论，如已被删除
+}
+
+// commentArchiveJob 正在运行的抓取任务
+type commentArchiveJob struct {
+	*crawlJob[*commentArchive]
+}
+
+type CommentArchiveRequestBody struct {
+	Eid     string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Refresh bool   `json:"refresh" form:"refresh"` // 已完成时重新抓取
+}
+
+// CommentArchiveData 抓取进度
+type CommentArchiveData struct {
+	Eid         string     `json:"eid"`
+	Title       string     `json:"title"`
+	Status      string     `json:"status"` // running、completed 或 failed
+	Primary     int        `json:"primary"`
+	Replies     int        `json:"replies"`
+	Threads     int        `json:"threads"` // 等待抓取回复的主评论数
+	Requests    int        `json:"requests"`
+	Skipped     []string   `json:"skipped"`
+	Error       any        `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// CommentArchive 在后台抓取单集的全部主评论和回复并保存在本地，立即返回抓取进度。
+// 任务正在运行时只返回进度；失败或服务重启后再次调用时从保存的进度继续抓取
+var CommentArchive = func(ctx *gin.Context) {
+	var params CommentArchiveRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	job, running := commentArchiveJobs.start(params.Eid)
+	if job == nil {
+		returnCommentArchive(ctx, running)
+
+		return
+	}
+
+	archive, err := loadCommentArchive(params.Eid)
+	if err != nil {
+		job.cancel()
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	if archive.Crawl.Status == CrawlCompleted && !params.Refresh {
+		job.cancel()
+		returnCommentArchive(ctx, archive)
+
+		return
+	}
+
+	// 失败后继续抓取时保留进度
+	if archive.Crawl.Status == "" || archive.Crawl.Status == CrawlCompleted || params.Refresh {
+		archive = &commentArchive{Archive: comments.Archive{Eid: params.Eid, Title: archive.Title}}
+		archive.Crawl.StartedAt = time.Now()
+	}
+
+	job.launch(ctx, archive, commentArchiveJob{job}.run)
+
+	returnCommentArchive(ctx, job.snapshot())
+}
+
+type CommentArchiveSearchRequestBody struct {
+	Eid      string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Author   string `json:"author" form:"author"`                                     // 作者的 uid，或昵称中包含的文字
+	Keyword  string `json:"keyword" form:"keyword"`                                   // 正文中包含的文字，不区分大小写
+	MinLikes int    `json:"minLikes" form:"minLikes" binding:"min=0"`                 // 最少点赞数
+	From     string `json:"from" form:"from" binding:"omitempty,datetime=2006-01-02"` // 开始日期
+	To       string `json:"to" form:"to" binding:"omitempty,datetime=2006-01-02"`     // 结束日期（包含）
+	Timezone string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`    // 解析日期使用的时区，默认为 Asia/Shanghai
+	Primary  bool   `json:"primary" form:"primary"`                                   // 只查询主评论
+	Sort     string `json:"sort" form:"sort" binding:"omitempty,oneof=time likes"`    // time 按时间正序（默认），likes 按点赞数倒序
+	Limit    int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=500"`     // 默认为 50
+	Offset   int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+type CommentArchiveSearchData struct {
+	Status   string             `json:"status"`
+	Total    int                `json:"total"`
+	Comments []comments.Comment `json:"comments"`
+}
+
+// CommentArchiveSearch 按作者、关键词、点赞数和时间查询保存的评论，任务运行中时查询已抓取的部分
+var CommentArchiveSearch = func(ctx *gin.Context) {
+	var params CommentArchiveSearchRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	archive, ok := archivedComments(ctx, params.Eid)
+	if !ok {
+		return
+	}
+
+	location, err := timezoneOf(params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	filter := comments.Filter{Author: params.Author, Keyword: params.Keyword, MinLikes: params.MinLikes, Primary: params.Primary, Sort: params.Sort}
+	if params.From != "" {
+		filter.From, _ = time.ParseInLocation("2006-01-02", params.From, location)
+	}
+
+	if params.To != "" {
+		to, _ := time.ParseInLocation("2006-01-02", params.To, location)
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = commentArchiveDefaultLimit
+	}
+
+	result := archive.Search(filter)
+	page := result[min(params.Offset, len(result)):min(params.Offset+limit, len(result))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": CommentArchiveSearchData{Status: archive.Crawl.Status, Total: len(result), Comments: page},
+	})
+}
+
+type CommentArchiveExportRequestBody struct {
+	Eid      string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Format   string `json:"format" form:"format" binding:"required,oneof=json markdown html"`
+	Timezone string `json:"timezone" form:"timezone" binding:"omitempty,timezone"` // Markdown 和 HTML 中显示时间使用的时区，默认为 Asia/Shanghai
+}
+
+// commentArchiveFormats 导出格式的 Content-Type 和文件扩展名
+var commentArchiveFormats = map[string][2]string{
+	"json":     {"application/json; charset=utf-8", "json"},
+	"markdown": {"text/markdown; charset=utf-8", "md"},
+	"html":     {"text/html; charset=utf-8", "html"},
+}
+
+// CommentArchiveExport 将保存的评论树导出为 JSON、Markdown 或单个 HTML 页面
+var CommentArchiveExport = func(ctx *gin.Context) {
+	var params CommentArchiveExportRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	archive, ok := archivedComments(ctx, params.Eid)
+	if !ok {
+		return
+	}
+
+	location, err := timezoneOf(params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	var b bytes.Buffer
+
+	now := time.Now()
+	switch params.Format {
+	case "json":
+		err = archive.JSON(&b, now)
+	case "markdown":
+		err = archive.Markdown(&b, now, location)
+	case "html":
+		err = archive.HTML(&b, now, location)
+	}
+
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	format := commentArchiveFormats[params.Format]
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="comments-%s.%s"`, params.Eid, format[1]))
+	ctx.Data(http.StatusOK, format[0], b.Bytes())
+}
+
+// archivedComments 正在抓取时返回已抓取的部分，没有存档时返回 404
+func archivedComments(ctx *gin.Context, eid string) (*commentArchive, bool) {
+	return commentArchiveJobs.find(ctx, eid, &commentArchive{Archive: comments.Archive{Eid: eid}}, "no comment archive for "+eid+", call /comment_archive first")
+}
+
+func returnCommentArchive(ctx *gin.Context, archive *commentArchive) {
+	primary, replies := archive.Counts()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": CommentArchiveData{
+			Eid:         archive.Eid,
+			Title:       archive.Title,
+			Status:      archive.Crawl.Status,
+			Primary:     primary,
+			Replies:     replies,
+			Threads:     len(archive.Crawl.Threads),
+			Requests:    archive.Crawl.Requests,
+			Skipped:     append([]string{}, archive.Crawl.Skipped...),
+			Error:       archive.Crawl.Error,
+			StartedAt:   archive.Crawl.StartedAt,
+			UpdatedAt:   archive.Crawl.UpdatedAt,
+			CompletedAt: archive.Crawl.CompletedAt,
+		},
+	})
+}
+
+func loadCommentArchive(eid string) (*commentArchive, error) {
+	return commentArchiveJobs.load(eid, &commentArchive{Archive: comments.Archive{Eid: eid}})
+}
+
+func (a *commentArchive) progress() *crawlStatus {
+	return &a.Crawl.crawlStatus
+}
+
+// clone 进度的副本
+func (a *commentArchive) clone() *commentArchive {
+	archive := *a
+	archive.Comments = slices.Clone(archive.Comments)
+	archive.Crawl.Threads = slices.Clone(archive.Crawl.Threads)
+	archive.Crawl.Skipped = slices.Clone(archive.Crawl.Skipped)
+
+	return &archive
+}
+
+// run 抓取直到完成、失败或达到请求数上限
+func (j commentArchiveJob) run(origin *http.Request) {
+	if j.snapshot().Title == "" {
+		if data, failed := j.call(origin, "/episode_detail", map[string]any{"eid": j.key}); failed == nil {
+			j.update(func(archive *commentArchive) {
+				archive.Title, _ = nested(data, "data", "title").(string)
+			})
+		}
+	}
+
+	for {
+		archive := j.snapshot()
+
+		if archive.Crawl.PrimaryDone && len(archive.Crawl.Threads) == 0 {
+			j.complete()
+
+			return
+		}
+
+		if j.exhausted() {
+			return
+		}
+
+		if !archive.Crawl.PrimaryDone {
+			if !j.primaryPage(origin, archive.Crawl.Cursor) {
+				return
+			}
+		} else if !j.threadPage(origin, archive.Crawl.Threads[0], archive.Crawl.ThreadCursor) {
+			return
+		}
+
+		j.checkpoint()
+	}
+}
+
+// primaryPage 抓取一页主评论，有回复的主评论加入 Threads
+func (j commentArchiveJob) primaryPage(origin *http.Request, cursor any) bool {
+	body := map[string]any{"id": j.key, "order": "TIME"}
+	if cursor != nil {
+		body["loadMoreKey"] = cursor
+	}
+
+	data, failed := j.call(origin, "/comment_primary", body)
+	if failed != nil {
+		j.fail(operationError("primary", "/comment_primary", failed).Error)
+
+		return false
+	}
+
+	items, _ := data["data"].([]any)
+
+	j.update(func(archive *commentArchive) {
+		for _, item := range items {
+			c, ok := archivedComment(item)
+			if !ok {
+				continue
+			}
+
+			// 没有 replyCount 时无法判断是否有回复，同样抓取
+			_, counted := item.(map[string]any)["replyCount"]
+			if archive.Add(c) && (c.ReplyCount > 0 || !counted) {
+				archive.Crawl.Threads = append(archive.Crawl.Threads, c.Id)
+			}
+		}
+
+		archive.Crawl.Cursor = data["loadMoreKey"]
+		archive.Crawl.PrimaryDone = archive.Crawl.Cursor == nil || len(items) == 0
+	})
+
+	return true
+}
+
+// threadPage 抓取一页回复，最后一页后继续下一条主评论；主评论不存在等无法重试的错误跳过该主评论
+func (j commentArchiveJob) threadPage(origin *http.Request, primaryId string, cursor any) bool {
+	body := map[string]any{"primaryCommentId": primaryId, "order": "TIME"}
+	if cursor != nil {
+		body["loadMoreKey"] = cursor
+	}
+
+	data, failed := j.call(origin, "/comment_thread", body)
+	if failed != nil && retryable(failed.Status) {
+		j.fail(operationError(primaryId, "/comment_thread", failed).Error)
+
+		return false
+	}
+
+	j.update(func(archive *commentArchive) {
+		next := any(nil)
+
+		if failed != nil {
+			archive.Crawl.Skipped = append(archive.Crawl.Skipped, primaryId)
+		} else {
+			items, _ := data["data"].([]any)
+			for _, item := range items {
+				if c, ok := archivedComment(item); ok {
+					if c.PrimaryId == "" {
+						c.PrimaryId = primaryId
+					}
+
+					archive.Add(c)
+				}
+			}
+
+			if len(items) > 0 {
+				next = data["loadMoreKey"]
+			}
+		}
+
+		archive.Crawl.ThreadCursor = next
+		if next == nil {
+			archive.Crawl.Threads = archive.Crawl.Threads[1:]
+		}
+	})
+
+	return true
+}
+
+// archivedComment 上游的评论，没有 id 时忽略
+func archivedComment(item any) (comments.Comment, bool) {
+	m, _ := item.(map[string]any)
+
+	id, _ := m["id"].(string)
+	if id == "" {
+		return comments.Comment{}, false
+	}
+
+	c := comments.Comment{Id: id}
+	c.PrimaryId, _ = m["primaryCommentId"].(string)
+	c.Text, _ = m["text"].(string)
+	c.Author.Uid, _ = nested(m, "author", "uid").(string)
+	c.Author.Nickname, _ = nested(m, "author", "nickname").(string)
+
+	likeCount, _ := m["likeCount"].(float64)
+	replyCount, _ := m["replyCount"].(float64)
+	c.LikeCount, c.ReplyCount = int(likeCount), int(replyCount)
+
+	if createdAt, ok := m["createdAt"].(string); ok {
+		c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	}
+
+	return c, true
+}
@@ -0,0 +1,420 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// 批量请求同时执行的操作数和单个操作的默认超时时间
+const (
+	batchWorkers = 4
+	batchTimeout = 10 * time.Second
+)
+
+// BatchRequestBody 批量请求
+type BatchRequestBody struct {
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=20,unique=Id,dive"`
+}
+
+// BatchOperation 批量请求中的一个操作，body 中的字符串可以使用 {{id.字段路径}} 引用之前操作的结果
+type BatchOperation struct {
+	Id      string         `json:"id" binding:"required,max=64"`
+	Path    string         `json:"path" binding:"required"`
+	Body    map[string]any `json:"body"`
+	Timeout int            `json:"timeout" binding:"omitempty,min=1,max=15000"` // 超时时间，单位为毫秒，默认 10 秒
+}
+
+// BatchResult 单个操作的结果，body 为接口的完整响应体
+type BatchResult struct {
+	Status int `json:"status"`
+	Body   any `json:"body"`
+}
+
+// batchReference 引用之前操作结果中的字段，如 {{detail.data.data.pid}}
+var batchReference = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)((?:\.[^.{}\s]+)*)\s*\}\}`)
+
+// batchEngine 执行批量请求中的操作，注册了除 /batch 外的原有接口，每个操作与直接请求经过相同的中间件
+var batchEngine = sync.OnceValue(func() *gin.Engine {
+	engine := gin.New()
+
+	for _, route := range Routes {
+		if route.Path != "/batch" {
+			engine.Handle(route.Method, route.Path, chain(route)...)
+		}
+	}
+
+	return engine
+})
+
+// Batch、页面聚合等功能接口的 handler 通过 runOperation、runBatch 使用 batchEngine，batchEngine 又遍历 Routes 注册路由，
+// 这些 handler 出现在 Routes 的初始化表达式中会形成初始化循环，因此功能接口统一在 featureRoutes 中列出，在 init 中加入路由表
+func init() {
+	Routes = append(Routes, featureRoutes()...)
+}
+
+// Batch 在一次请求中并发执行多个原有接口，使用调用方的请求头（token、账号、API Key），结果按 id 返回
+var Batch = func(ctx *gin.Context) {
+	var params BatchRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	dependencies, err := batchDependencies(params.Operations)
+	if err != nil {
+		utils.ReturnBadRequest(ctx, err)
+
+		return
+	}
+
+	operations := params.Operations
+	results := runBatch(ctx.Request, operations, dependencies)
+
+	data := make(map[string]*BatchResult, len(operations))
+	for i, operation := range operations {
+		data[operation.Id] = results[i]
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": gin.H{
+			"results": data,
+		},
+	})
+}
+
+// runBatch 并发执行操作，依赖其它操作的操作在依赖完成后执行，返回的结果与 operations 一一对应
+func runBatch(origin *http.Request, operations []BatchOperation, dependencies [][]int) []*BatchResult {
+	results := make([]*BatchResult, len(operations))
+	done := make([]chan struct{}, len(operations))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	index := map[string]int{}
+	for i, operation := range operations {
+		index[operation.Id] = i
+	}
+
+	workers := make(chan struct{}, batchWorkers)
+
+	var wg sync.WaitGroup
+	for i, operation := range operations {
+		wg.Add(1)
+
+		go func(i int, operation BatchOperation) {
+			defer wg.Done()
+			defer close(done[i])
+
+			// 等待依赖的操作完成后再占用 worker，避免 worker 全部被等待中的操作占满
+			for _, dependency := range dependencies[i] {
+				<-done[dependency]
+			}
+
+			body, err := resolveReferences(operation.Body, func(id string) *BatchResult {
+				return results[index[id]]
+			})
+			if err != nil {
+				results[i] = batchError(err)
+
+				return
+			}
+
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			results[i] = runOperation(origin, operation, body)
+		}(i, operation)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// batchDependencies 校验操作的路径和引用，返回每个操作依赖的操作，只能引用排在前面的操作
+func batchDependencies(operations []BatchOperation) ([][]int, error) {
+	paths := map[string]bool{}
+	for _, route := range Routes {
+		if route.Method == http.MethodPost && route.Path != "/batch" {
+			paths[route.Path] = true
+		}
+	}
+
+	e := utils.NewError(http.StatusBadRequest, utils.ErrValidationFailed, "")
+	index := map[string]int{}
+	dependencies := make([][]int, len(operations))
+
+	for i, operation := range operations {
+		if !paths[operation.Path] {
+			e.Details = append(e.Details, utils.FieldError{
+				Field:   fmt.Sprintf("operations[%d].path", i),
+				Rule:    "route",
+				Message: fmt.Sprintf("不支持的接口 %s", operation.Path),
+			})
+		}
+
+		seen := map[int]bool{}
+		for _, id := range referencedIds(operation.Body) {
+			dependency, ok := index[id]
+			if !ok {
+				e.Details = append(e.Details, utils.FieldError{
+					Field:   fmt.Sprintf("operations[%d].body", i),
+					Rule:    "reference",
+					Message: fmt.Sprintf("只能引用排在前面的操作，%s 不存在或排在后面", id),
+				})
+
+				continue
+			}
+
+			if !seen[dependency] {
+				seen[dependency] = true
+				dependencies[i] = append(dependencies[i], dependency)
+			}
+		}
+
+		index[operation.Id] = i
+	}
+
+	if len(e.Details) > 0 {
+		return nil, e
+	}
+
+	return dependencies, nil
+}
+
+// referencedIds body 中引用的操作 id
+func referencedIds(v any) []string {
+	var ids []string
+
+	switch value := v.(type) {
+	case map[string]any:
+		for _, item := range value {
+			ids = append(ids, referencedIds(item)...)
+		}
+	case []any:
+		for _, item := range value {
+			ids = append(ids, referencedIds(item)...)
+		}
+	case string:
+		for _, match := range batchReference.FindAllStringSubmatch(value, -1) {
+			ids = append(ids, match[1])
+		}
+	}
+
+	return ids
+}
+
+// resolveReferences 替换 body 中的引用。字符串只包含一个引用时替换为字段的原始值（保留数字、对象等类型），否则替换为字段的文本
+func resolveReferences(body map[string]any, result func(id string) *BatchResult) (map[string]any, error) {
+	resolved, err := resolveValue(body, result)
+	if err != nil {
+		return nil, err
+	}
+
+	m, _ := resolved.(map[string]any)
+
+	return m, nil
+}
+
+func resolveValue(v any, result func(id string) *BatchResult) (any, error) {
+	switch value := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(value))
+		for key, item := range value {
+			resolved, err := resolveValue(item, result)
+			if err != nil {
+				return nil, err
+			}
+
+			m[key] = resolved
+		}
+
+		return m, nil
+	case []any:
+		items := make([]any, len(value))
+		for i, item := range value {
+			resolved, err := resolveValue(item, result)
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = resolved
+		}
+
+		return items, nil
+	case string:
+		if match := batchReference.FindStringSubmatch(value); match != nil && match[0] == value {
+			return lookupReference(match, result)
+		}
+
+		var err error
+		replaced := batchReference.ReplaceAllStringFunc(value, func(reference string) string {
+			field, e := lookupReference(batchReference.FindStringSubmatch(reference), result)
+			if e != nil {
+				err = e
+
+				return ""
+			}
+
+			if s, ok := field.(string); ok {
+				return s
+			}
+
+			b, _ := json.Marshal(field)
+
+			return string(b)
+		})
+
+		return replaced, err
+	default:
+		return v, nil
+	}
+}
+
+// lookupReference 按字段路径读取操作结果中的字段，数组使用下标，如 {{list.data.data.0.eid}}
+func lookupReference(match []string, result func(id string) *BatchResult) (any, error) {
+	id := match[1]
+
+	r := result(id)
+	if r == nil || r.Status < 200 || r.Status >= 300 {
+		status := 0
+		if r != nil {
+			status = r.Status
+		}
+
+		return nil, fmt.Errorf("operation %q failed with status %d", id, status)
+	}
+
+	var value any = r.Body
+	for _, key := range strings.Split(strings.TrimPrefix(match[2], "."), ".") {
+		if key == "" {
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%s not found in result of %q", strings.TrimSpace(match[0]), id)
+			}
+
+			value = v[i]
+		default:
+			value = nil
+		}
+
+		if value == nil {
+			return nil, fmt.Errorf("%s not found in result of %q", strings.TrimSpace(match[0]), id)
+		}
+	}
+
+	return value, nil
+}
+
+// runOperation 使用调用方的请求头执行一个操作，超时后不再等待其结果
+func runOperation(origin *http.Request, operation BatchOperation, body map[string]any) *BatchResult {
+	timeout := batchTimeout
+	if operation.Timeout > 0 {
+		timeout = time.Duration(operation.Timeout) * time.Millisecond
+	}
+
+	// c 继承 origin 的 context，其中有已校验的 API Key，被调用的接口不再校验和扣减配额
+	c, cancel := context.WithTimeout(origin.Context(), timeout)
+	defer cancel()
+
+	if body == nil {
+		body = map[string]any{}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return internalError(err)
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, operation.Path, bytes.NewReader(payload))
+	if err != nil {
+		return internalError(err)
+	}
+
+	req.Header = origin.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = origin.RemoteAddr
+
+	recorder := httptest.NewRecorder()
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		batchEngine().ServeHTTP(recorder, req)
+	}()
+
+	select {
+	case <-finished:
+	case <-c.Done():
+		e := utils.NewError(http.StatusGatewayTimeout, utils.ErrTimeout, fmt.Sprintf("operation %q timed out after %s", operation.Id, timeout))
+
+		return &BatchResult{Status: e.Status, Body: utils.ErrorBody(e)}
+	}
+
+	var data any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &data); err != nil {
+		data = recorder.Body.String()
+	}
+
+	return &BatchResult{Status: recorder.Code, Body: data}
+}
+
+// callOperation 在进程内调用一个接口，成功时返回上游的响应体，失败时返回该接口的结果
+func callOperation(origin *http.Request, path string, body map[string]any) (map[string]any, *BatchResult) {
+	result := runOperation(origin, BatchOperation{Id: path, Path: path}, body)
+	if !succeeded(result) {
+		return nil, result
+	}
+
+	response, _ := result.Body.(map[string]any)
+	data, _ := response["data"].(map[string]any)
+
+	return data, nil
+}
+
+// operationError 接口失败时在 errors 中返回的错误
+func operationError(section, path string, result *BatchResult) PageError {
+	var e any
+	if body, ok := result.Body.(map[string]any); ok {
+		e = body["error"]
+	}
+
+	return PageError{Section: section, Path: path, Status: result.Status, Error: e}
+}
+
+// batchError 无法执行的操作，如依赖的操作失败或引用的字段不存在
+func batchError(err error) *BatchResult {
+	e := utils.NewError(http.StatusFailedDependency, utils.ErrDependencyFailed, err.Error())
+
+	return &BatchResult{Status: e.Status, Body: utils.ErrorBody(e)}
+}
+
+// internalError 无法构造请求等内部错误
+func internalError(err error) *BatchResult {
+	e := utils.AsError(err)
+
+	return &BatchResult{Status: e.Status, Body: utils.ErrorBody(e)}
+}
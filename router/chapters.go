@@ -0,0 +1,89 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/claps"
+	"github.com/ultrazg/xyz/shownotes"
+	"github.com/ultrazg/xyz/utils"
+)
+
+type EpisodeChaptersRequestBody struct {
+	Eid    string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Format string `json:"format" form:"format" binding:"omitempty,oneof=json chapters markdown text"` // 默认为 json
+}
+
+// EpisodeChaptersData 单集的章节和转换后的 shownotes
+type EpisodeChaptersData struct {
+	Eid      string              `json:"eid"`
+	Title    string              `json:"title"`
+	Duration int                 `json:"duration"`
+	Chapters []shownotes.Chapter `json:"chapters"`
+	Markdown string              `json:"markdown"`
+	Text     string              `json:"text"`
+}
+
+// EpisodeChapters 从单集 shownotes 的时间线中提取章节，并将 shownotes 转换为 Markdown 和纯文本，
+// 以 JSON、Podcasting 2.0 章节、Markdown 或纯文本返回
+var EpisodeChapters = func(ctx *gin.Context) {
+	var params EpisodeChaptersRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	data, failed := callOperation(ctx.Request, "/episode_detail", map[string]any{"eid": params.Eid})
+	if failed != nil {
+		ctx.JSON(failed.Status, failed.Body)
+
+		return
+	}
+
+	notes, _ := nested(data, "data", "shownotes").(string)
+	title, _ := nested(data, "data", "title").(string)
+	duration, _ := nested(data, "data", "duration").(float64)
+
+	chapters := shownotes.Chapters(notes, int(duration))
+
+	switch params.Format {
+	case "chapters":
+		result := claps.Chapters{Version: claps.ChaptersVersion, Chapters: []claps.Chapter{}}
+		for _, chapter := range chapters {
+			result.Chapters = append(result.Chapters, claps.Chapter{StartTime: chapter.Start, EndTime: chapter.End, Title: chapter.Title})
+		}
+
+		var b bytes.Buffer
+
+		encoder := json.NewEncoder(&b)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(result); err != nil {
+			utils.ReturnError(ctx, fmt.Errorf("render chapters: %w", err))
+
+			return
+		}
+
+		ctx.Data(http.StatusOK, "application/json+chapters; charset=utf-8", b.Bytes())
+	case "markdown":
+		ctx.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(shownotes.Markdown(notes)))
+	case "text":
+		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(shownotes.Text(notes)))
+	default:
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": http.StatusOK,
+			"msg":  utils.GetMsg(http.StatusOK),
+			"data": EpisodeChaptersData{
+				Eid:      params.Eid,
+				Title:    title,
+				Duration: int(duration),
+				Chapters: chapters,
+				Markdown: shownotes.Markdown(notes),
+				Text:     shownotes.Text(notes),
+			},
+		})
+	}
+}
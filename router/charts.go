@@ -0,0 +1,590 @@
+package router
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/charts"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// topListArchiveBucket 榜单存档的设置，只有一个 key
+	topListArchiveBucket = "top_list_archive"
+	topListArchiveKey    = "config"
+	// topListSnapshotBucket 榜单快照，key 为「榜单/时间」，如 HOT/20240101T060000Z
+	topListSnapshotBucket = "top_list_snapshots"
+	topListKeyFormat      = "20060102T150405Z"
+	// topListTick 检查是否需要获取快照的间隔
+	topListTick = time.Minute
+	// topListDefaultLimit 默认返回的单集数
+	topListDefaultLimit = 20
+)
+
+// topListCategories 存档的榜单，与 /top_list 的 category 相同
+var topListCategories = []string{"HOT", "ROCK", "NEW"}
+
+// topListIntervals 获取快照的间隔
+var topListIntervals = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+}
+
+// topListLock 同一时间只获取一次快照
+var topListLock sync.Mutex
+
+// topListArchive 榜单存档的设置，Auth 为最近一次开启时的认证信息，定时获取快照时使用
+type topListArchive struct {
+	Interval   string     `json:"interval"`
+	Auth       replayAuth `json:"auth"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	CapturedAt time.Time  `json:"capturedAt"`
+	LastError  any        `json:"lastError,omitempty"`
+}
+
+type TopListArchiveRequestBody struct {
+	Mode     string `json:"mode" form:"mode" binding:"omitempty,oneof=ON OFF"`               // ON 为开启并立即获取一次快照，OFF 为关闭，已保存的快照保留；不传时只返回状态
+	Interval string `json:"interval" form:"interval" binding:"omitempty,oneof=hourly daily"` // 获取快照的间隔，默认为 daily
+}
+
+// TopListCategoryData 一个榜单已保存的快照
+type TopListCategoryData struct {
+	Category  string     `json:"category"`
+	Snapshots int        `json:"snapshots"`
+	First     *time.Time `json:"first,omitempty"`
+	Last      *time.Time `json:"last,omitempty"`
+}
+
+// TopListArchiveData 榜单存档的状态
+type TopListArchiveData struct {
+	Archiving  bool                  `json:"archiving"`
+	Interval   string                `json:"interval,omitempty"`
+	CreatedAt  *time.Time            `json:"createdAt,omitempty"`
+	CapturedAt *time.Time            `json:"capturedAt,omitempty"`
+	LastError  any                   `json:"lastError,omitempty"`
+	Categories []TopListCategoryData `json:"categories"`
+}
+
+// TopListArchive 开启榜单存档：立即获取一次最热榜、锋芒榜和新星榜的快照，之后每小时或每天获取一次。
+// 再次开启时更新间隔和认证信息并立即获取快照
+var TopListArchive = func(ctx *gin.Context) {
+	var params TopListArchiveRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	switch params.Mode {
+	case "ON":
+		archive := &topListArchive{Interval: params.Interval, CreatedAt: time.Now()}
+		if archive.Interval == "" {
+			archive.Interval = "daily"
+		}
+
+		if saved, err := loadTopListArchive(); err == nil {
+			archive.CreatedAt = saved.CreatedAt
+		}
+
+		archive.Auth = replayAuthOf(ctx)
+
+		if failed := captureTopLists(archive, time.Now()); failed != nil {
+			ctx.JSON(failed.Status, failed.Body)
+
+			return
+		}
+	case "OFF":
+		if err := utils.StoreDelete(topListArchiveBucket, topListArchiveKey); err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+	}
+
+	data, err := topListArchiveData()
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+type TopListRankHistoryRequestBody struct {
+	Category string `json:"category" form:"category" binding:"omitempty,oneof=HOT ROCK NEW"` // 默认为全部榜单
+	Eid      string `json:"eid" form:"eid" binding:"omitempty,xyzid"`
+	Pid      string `json:"pid" form:"pid" binding:"required_without=Eid,omitempty,xyzid"` // 不传 eid 时查询节目，排名为节目中排名最好的单集
+	From     string `json:"from" form:"from" binding:"omitempty,datetime=2006-01-02"`      // 开始日期
+	To       string `json:"to" form:"to" binding:"omitempty,datetime=2006-01-02"`          // 结束日期（包含）
+	Timezone string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`         // 解析日期使用的时区，默认为 Asia/Shanghai
+}
+
+// TopListRankHistoryData 一个榜单中的排名历史
+type TopListRankHistoryData struct {
+	Category string `json:"category"`
+	charts.History
+}
+
+// TopListRankHistory 单集或节目在各个榜单中的排名历史
+var TopListRankHistory = func(ctx *gin.Context) {
+	var params TopListRankHistoryRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	from, to, err := dateRange(params.From, params.To, params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	categories := topListCategories
+	if params.Category != "" {
+		categories = []string{params.Category}
+	}
+
+	list := []TopListRankHistoryData{}
+	for _, category := range categories {
+		snapshots, err := loadTopListSnapshots(category, from, to)
+		if err != nil {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		list = append(list, TopListRankHistoryData{Category: category, History: charts.RankHistory(snapshots, params.Eid, params.Pid)})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": list,
+	})
+}
+
+type TopListMoversRequestBody struct {
+	Category string `json:"category" form:"category" binding:"required,oneof=HOT ROCK NEW"`
+	Hours    int    `json:"hours" form:"hours" binding:"omitempty,min=1,max=8760"` // 与多少小时前的快照比较，默认为上一次快照
+	Limit    int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=100"`  // 每个列表返回的数量，默认为 20
+}
+
+// TopListMovers 比较最新的快照和之前的快照，返回上升、下降最多的单集以及新上榜和跌出榜单的单集
+var TopListMovers = func(ctx *gin.Context) {
+	var params TopListMoversRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	keys, err := topListSnapshotKeys(params.Category)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	if len(keys) < 2 {
+		utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, "at least two snapshots of "+params.Category+" are required, call /top_list_archive first"))
+
+		return
+	}
+
+	latest, before := keys[len(keys)-1], keys[len(keys)-2]
+
+	if params.Hours > 0 {
+		// 不晚于 hours 小时前的最后一个快照
+		at := latest.At.Add(-time.Duration(params.Hours) * time.Hour)
+
+		i := sort.Search(len(keys), func(i int) bool {
+			return keys[i].At.After(at)
+		})
+
+		if i == 0 {
+			utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, fmt.Sprintf("no snapshot of %s %d hours before the latest one", params.Category, params.Hours)))
+
+			return
+		}
+
+		before = keys[i-1]
+	}
+
+	snapshots := make([]charts.Snapshot, 2)
+	for i, key := range []topListSnapshotKey{before, latest} {
+		if err := utils.StoreGet(topListSnapshotBucket, key.Key, &snapshots[i]); err != nil {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = topListDefaultLimit
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": charts.Compare(snapshots[0], snapshots[1], limit),
+	})
+}
+
+type TopListLongestRequestBody struct {
+	Category string `json:"category" form:"category" binding:"required,oneof=HOT ROCK NEW"`
+	By       string `json:"by" form:"by" binding:"omitempty,oneof=episode podcast"`   // episode 按单集统计（默认），podcast 按节目统计
+	From     string `json:"from" form:"from" binding:"omitempty,datetime=2006-01-02"` // 开始日期
+	To       string `json:"to" form:"to" binding:"omitempty,datetime=2006-01-02"`     // 结束日期（包含）
+	Timezone string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`    // 解析日期使用的时区，默认为 Asia/Shanghai
+	Limit    int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=200"`     // 默认为 20
+	Offset   int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+type TopListLongestData struct {
+	Snapshots int               `json:"snapshots"`
+	Total     int               `json:"total"`
+	Items     []charts.Charting `json:"items"`
+}
+
+// TopListLongest 按最长连续在榜的快照数排列单集或节目
+var TopListLongest = func(ctx *gin.Context) {
+	var params TopListLongestRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	from, to, err := dateRange(params.From, params.To, params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	snapshots, err := loadTopListSnapshots(params.Category, from, to)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = topListDefaultLimit
+	}
+
+	result := charts.Longest(snapshots, params.By)
+	page := result[min(params.Offset, len(result)):min(params.Offset+limit, len(result))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": TopListLongestData{Snapshots: len(snapshots), Total: len(result), Items: page},
+	})
+}
+
+type TopListExportRequestBody struct {
+	Category string `json:"category" form:"category" binding:"omitempty,oneof=HOT ROCK NEW"` // 默认为全部榜单
+	From     string `json:"from" form:"from" binding:"omitempty,datetime=2006-01-02"`        // 开始日期
+	To       string `json:"to" form:"to" binding:"omitempty,datetime=2006-01-02"`            // 结束日期（包含）
+	Timezone string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`           // 解析日期和显示时间使用的时区，默认为 Asia/Shanghai
+}
+
+// TopListExport 将榜单快照导出为 CSV，每个快照中的每个单集一行
+var TopListExport = func(ctx *gin.Context) {
+	var params TopListExportRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	from, to, err := dateRange(params.From, params.To, params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	location, _ := timezoneOf(params.Timezone)
+
+	categories := topListCategories
+	name := "top_lists"
+	if params.Category != "" {
+		categories = []string{params.Category}
+		name = "top_list_" + strings.ToLower(params.Category)
+	}
+
+	var snapshots []charts.Snapshot
+	for _, category := range categories {
+		s, err := loadTopListSnapshots(category, from, to)
+		if err != nil {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		snapshots = append(snapshots, s...)
+	}
+
+	charts.Sort(snapshots)
+
+	var b bytes.Buffer
+	if err := charts.CSV(&b, snapshots, location); err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", b.Bytes())
+}
+
+// StartTopListArchive 在后台按设置的间隔获取榜单快照
+func StartTopListArchive() {
+	go func() {
+		ticker := time.NewTicker(topListTick)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			CaptureTopLists(time.Now())
+		}
+	}()
+}
+
+// CaptureTopLists 开启了榜单存档且距离上一次快照超过设置的间隔时获取快照
+func CaptureTopLists(now time.Time) {
+	archive, err := loadTopListArchive()
+	if errors.Is(err, utils.ErrStoreNotFound) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("top list archive: %v", err)
+
+		return
+	}
+
+	if now.Sub(archive.CapturedAt) < topListIntervals[archive.Interval] {
+		return
+	}
+
+	if failed := captureTopLists(archive, now); failed != nil {
+		log.Printf("top list archive: status %d", failed.Status)
+	}
+}
+
+// captureTopLists 并发获取全部榜单的快照并保存设置，部分榜单失败时保存成功的部分并在 LastError 中记录失败的原因。
+// 全部失败时不更新 CapturedAt，下一次检查时重试
+func captureTopLists(archive *topListArchive, now time.Time) *BatchResult {
+	topListLock.Lock()
+	defer topListLock.Unlock()
+
+	origin, err := archive.Auth.request("/top_list_archive")
+	if err != nil {
+		return internalError(err)
+	}
+
+	operations := make([]BatchOperation, len(topListCategories))
+	for i, category := range topListCategories {
+		operations[i] = BatchOperation{Id: category, Path: "/top_list", Body: map[string]any{"category": category}}
+	}
+
+	var failed *BatchResult
+
+	captured := false
+	archive.LastError = nil
+	for i, result := range runBatch(origin, operations, make([][]int, len(operations))) {
+		category := topListCategories[i]
+
+		if !succeeded(result) {
+			failed = result
+			archive.LastError = operationError(category, "/top_list", result).Error
+
+			continue
+		}
+
+		response, _ := result.Body.(map[string]any)
+		snapshot := charts.Snapshot{Category: category, At: now, Entries: topListEntries(nested(response, "data", "data", "items"))}
+
+		if err := utils.StorePut(topListSnapshotBucket, category+"/"+now.UTC().Format(topListKeyFormat), snapshot); err != nil {
+			return internalError(err)
+		}
+
+		captured = true
+	}
+
+	if captured {
+		archive.CapturedAt = now
+	}
+
+	if err := utils.StorePut(topListArchiveBucket, topListArchiveKey, archive); err != nil {
+		return internalError(err)
+	}
+
+	return failed
+}
+
+// topListEntries 取出榜单中的单集，没有 rank 时按顺序计算排名
+func topListEntries(items any) []charts.Entry {
+	list, _ := items.([]any)
+
+	entries := []charts.Entry{}
+	for i, item := range list {
+		m, _ := item.(map[string]any)
+
+		episode, ok := m["item"].(map[string]any)
+		if !ok {
+			episode = m
+		}
+
+		e := charts.Entry{Rank: i + 1}
+		if rank, ok := m["rank"].(float64); ok {
+			e.Rank = int(rank)
+		}
+
+		e.Eid, _ = episode["eid"].(string)
+		e.Title, _ = episode["title"].(string)
+		e.Pid, _ = episode["pid"].(string)
+		e.PodcastTitle, _ = nested(episode, "podcast", "title").(string)
+
+		if e.Pid == "" {
+			e.Pid, _ = nested(episode, "podcast", "pid").(string)
+		}
+
+		playCount, _ := episode["playCount"].(float64)
+		clapCount, _ := episode["clapCount"].(float64)
+		e.PlayCount, e.ClapCount = int(playCount), int(clapCount)
+
+		if e.Eid != "" {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// dateRange 将开始、结束日期转换为 [from, to) 的时间范围，零值表示不限制
+func dateRange(from, to, timezone string) (time.Time, time.Time, error) {
+	location, err := timezoneOf(timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	var start, end time.Time
+	if from != "" {
+		start, _ = time.ParseInLocation("2006-01-02", from, location)
+	}
+
+	if to != "" {
+		end, _ = time.ParseInLocation("2006-01-02", to, location)
+		end = end.AddDate(0, 0, 1)
+	}
+
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return start, end, utils.NewError(http.StatusBadRequest, utils.ErrValidationFailed, "from 不能晚于 to")
+	}
+
+	return start, end, nil
+}
+
+func topListArchiveData() (*TopListArchiveData, error) {
+	data := &TopListArchiveData{Categories: []TopListCategoryData{}}
+
+	archive, err := loadTopListArchive()
+	switch {
+	case err == nil:
+		data.Archiving = true
+		data.Interval = archive.Interval
+		data.CreatedAt = &archive.CreatedAt
+		data.CapturedAt = &archive.CapturedAt
+		data.LastError = archive.LastError
+	case !errors.Is(err, utils.ErrStoreNotFound):
+		return nil, err
+	}
+
+	for _, category := range topListCategories {
+		keys, err := topListSnapshotKeys(category)
+		if err != nil {
+			return nil, err
+		}
+
+		c := TopListCategoryData{Category: category, Snapshots: len(keys)}
+		if len(keys) > 0 {
+			c.First, c.Last = &keys[0].At, &keys[len(keys)-1].At
+		}
+
+		data.Categories = append(data.Categories, c)
+	}
+
+	return data, nil
+}
+
+func loadTopListArchive() (*topListArchive, error) {
+	archive := &topListArchive{}
+
+	return archive, utils.StoreGet(topListArchiveBucket, topListArchiveKey, archive)
+}
+
+// topListSnapshotKey 一个已保存的快照，At 从 key 中解析
+type topListSnapshotKey struct {
+	Key string
+	At  time.Time
+}
+
+// topListSnapshotKeys 按时间正序列出一个榜单的快照，只读取 key，不解密快照的内容
+func topListSnapshotKeys(category string) ([]topListSnapshotKey, error) {
+	keys, err := utils.StoreKeys(topListSnapshotBucket, category+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]topListSnapshotKey, 0, len(keys))
+	for _, key := range keys {
+		if at, err := time.Parse(topListKeyFormat, strings.TrimPrefix(key, category+"/")); err == nil {
+			result = append(result, topListSnapshotKey{Key: key, At: at})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].At.Before(result[j].At)
+	})
+
+	return result, nil
+}
+
+// loadTopListSnapshots 按时间正序读取一个榜单在 [from, to) 内的快照，零值表示不限制，范围外的快照不读取
+func loadTopListSnapshots(category string, from, to time.Time) ([]charts.Snapshot, error) {
+	keys, err := topListSnapshotKeys(category)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []charts.Snapshot{}
+	for _, key := range keys {
+		if (!from.IsZero() && key.At.Before(from)) || (!to.IsZero() && !key.At.Before(to)) {
+			continue
+		}
+
+		var snapshot charts.Snapshot
+		if err := utils.StoreGet(topListSnapshotBucket, key.Key, &snapshot); err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
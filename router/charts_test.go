@@ -0,0 +1,119 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ultrazg/xyz/charts"
+	C "github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/utils"
+)
+
+func TestCaptureTopLists(t *testing.T) {
+	initTestStore(t)
+
+	// 上游只返回以 ok 开头的榜单，ok 为 all 时返回全部榜单，其它榜单返回 400
+	var ok string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if ok != "all" && (ok == "" || !strings.HasPrefix(r.URL.Query().Get("category"), ok)) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data":{"items":[{"item":{"eid":"` + testEid + `","pid":"` + testPid + `"}}]}}`))
+	}))
+	defer upstream.Close()
+
+	baseUrl := C.BaseUrl
+	C.BaseUrl = upstream.URL
+	t.Cleanup(func() { C.BaseUrl = baseUrl })
+
+	before := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		ok       string
+		captured bool
+		failed   bool
+	}{
+		{"全部成功", "all", true, false},
+		{"部分榜单失败时保存成功的部分", "HOT", true, true},
+		{"全部失败时不更新 CapturedAt", "", false, true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok = tt.ok
+			now := before.Add(time.Duration(i+1) * time.Hour)
+
+			archive := &topListArchive{Interval: "hourly", Auth: replayAuth{AccessToken: "token"}, CapturedAt: before}
+			failed := captureTopLists(archive, now)
+
+			if (failed != nil) != tt.failed || (archive.LastError != nil) != tt.failed {
+				t.Errorf("failed = %v, lastError = %v, want failed = %v", failed, archive.LastError, tt.failed)
+			}
+
+			if captured := archive.CapturedAt.Equal(now); captured != tt.captured {
+				t.Errorf("CapturedAt = %v, want updated = %v", archive.CapturedAt, tt.captured)
+			}
+		})
+	}
+}
+
+func TestLoadTopListSnapshots(t *testing.T) {
+	initTestStore(t)
+
+	at := func(hour int) time.Time {
+		return time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+	}
+
+	for _, s := range []charts.Snapshot{
+		{Category: "HOT", At: at(2)},
+		{Category: "HOT", At: at(1)},
+		{Category: "HOT", At: at(3)},
+		{Category: "NEW", At: at(1)},
+	} {
+		if err := utils.StorePut(topListSnapshotBucket, s.Category+"/"+s.At.Format(topListKeyFormat), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		from     time.Time
+		to       time.Time
+		want     []time.Time
+	}{
+		{"按时间正序", "HOT", time.Time{}, time.Time{}, []time.Time{at(1), at(2), at(3)}},
+		{"只读取该榜单", "NEW", time.Time{}, time.Time{}, []time.Time{at(1)}},
+		{"包含开始时间，不包含结束时间", "HOT", at(2), at(3), []time.Time{at(2)}},
+		{"没有快照", "ROCK", time.Time{}, time.Time{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots, err := loadTopListSnapshots(tt.category, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(snapshots) != len(tt.want) {
+				t.Fatalf("got %d snapshots, want %d", len(snapshots), len(tt.want))
+			}
+
+			for i, s := range snapshots {
+				if s.Category != tt.category || !s.At.Equal(tt.want[i]) {
+					t.Errorf("snapshots[%d] = %s %v, want %s %v", i, s.Category, s.At, tt.category, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/claps"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// 默认的片段数、SVG 尺寸
+const (
+	clapDefaultTop    = 5
+	clapDefaultWidth  = 600
+	clapDefaultHeight = 60
+)
+
+type ClapHighlightsRequestBody struct {
+	Eid      string `json:"eid" form:"eid" binding:"required,xyzid"`
+	Duration int    `json:"duration" form:"duration" binding:"omitempty,gt=0"`                    // 单集时长，单位为秒，默认从单集详情中读取
+	Bins     int    `json:"bins" form:"bins" binding:"omitempty,min=1,max=1000"`                  // 热力图的段数，默认与上游相同，不能超过上游的段数
+	Top      int    `json:"top" form:"top" binding:"omitempty,min=1,max=20"`                      // 最多返回的片段数，默认为 5
+	Format   string `json:"format" form:"format" binding:"omitempty,oneof=json chapters vtt svg"` // 默认为 json
+	Width    int    `json:"width" form:"width" binding:"omitempty,min=50,max=4000"`               // SVG 的宽度，默认为 600
+	Height   int    `json:"height" form:"height" binding:"omitempty,min=10,max=1000"`             // SVG 的高度，默认为 60
+}
+
+// ClapHighlightsData 热力图和精彩片段
+type ClapHighlightsData struct {
+	Duration   int               `json:"duration"`
+	Total      int               `json:"total"` // 全部标记数
+	Bins       []claps.Bin       `json:"bins"`
+	Highlights []claps.Highlight `json:"highlights"`
+}
+
+// ClapHighlights 将单集的精彩时间点汇总为热力图并检测标记集中的片段，
+// 以 JSON、Podcasting 2.0 章节、WebVTT 或 SVG 迷你折线图返回
+var ClapHighlights = func(ctx *gin.Context) {
+	var params ClapHighlightsRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	duration := params.Duration
+	if duration == 0 {
+		data, failed := callOperation(ctx.Request, "/episode_detail", map[string]any{"eid": params.Eid})
+		if failed != nil {
+			ctx.JSON(failed.Status, failed.Body)
+
+			return
+		}
+
+		seconds, _ := nested(data, "data", "duration").(float64)
+		if duration = int(seconds); duration <= 0 {
+			utils.ReturnError(ctx, utils.NewError(http.StatusBadGateway, utils.ErrUpstreamInvalidResponse, "episode has no duration, pass duration instead"))
+
+			return
+		}
+	}
+
+	data, failed := callOperation(ctx.Request, "/episode_clap", map[string]any{"eid": params.Eid, "duration": duration})
+	if failed != nil {
+		ctx.JSON(failed.Status, failed.Body)
+
+		return
+	}
+
+	counts, mine := clapCounts(data)
+
+	top := params.Top
+	if top == 0 {
+		top = clapDefaultTop
+	}
+
+	bins := claps.Heatmap(counts, mine, duration, params.Bins)
+	highlights := claps.Detect(bins, top)
+
+	var b bytes.Buffer
+	var err error
+
+	switch params.Format {
+	case "chapters":
+		err = claps.WriteChapters(&b, highlights)
+		if err == nil {
+			ctx.Data(http.StatusOK, "application/json+chapters; charset=utf-8", b.Bytes())
+		}
+	case "vtt":
+		err = claps.WriteVTT(&b, highlights)
+		if err == nil {
+			ctx.Data(http.StatusOK, "text/vtt; charset=utf-8", b.Bytes())
+		}
+	case "svg":
+		width, height := params.Width, params.Height
+		if width == 0 {
+			width = clapDefaultWidth
+		}
+
+		if height == 0 {
+			height = clapDefaultHeight
+		}
+
+		err = claps.WriteSVG(&b, bins, highlights, duration, width, height)
+		if err == nil {
+			ctx.Data(http.StatusOK, "image/svg+xml; charset=utf-8", b.Bytes())
+		}
+	default:
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": http.StatusOK,
+			"msg":  utils.GetMsg(http.StatusOK),
+			"data": ClapHighlightsData{Duration: duration, Total: total, Bins: bins, Highlights: highlights},
+		})
+	}
+
+	if err != nil {
+		utils.ReturnError(ctx, fmt.Errorf("render %s: %w", params.Format, err))
+	}
+}
+
+// clapCounts /episode_clap 返回的 episodeClaps 中每段的标记数和 myClaps 中当前用户标记的段的下标
+func clapCounts(data map[string]any) (counts []int, mine []int) {
+	segments, _ := nested(data, "data", "episodeClaps").([]any)
+	for _, segment := range segments {
+		s, _ := segment.(map[string]any)
+		count, _ := s["count"].(float64)
+		counts = append(counts, int(count))
+	}
+
+	mines, _ := nested(data, "data", "myClaps").([]any)
+	for _, item := range mines {
+		m, _ := item.(map[string]any)
+		if index, ok := m["index"].(float64); ok {
+			mine = append(mine, int(index))
+		}
+	}
+
+	return counts, mine
+}
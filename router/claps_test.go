@@ -0,0 +1,41 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClapCounts(t *testing.T) {
+	data := map[string]any{
+		"data": map[string]any{
+			"episodeClaps": []any{
+				map[string]any{"count": float64(3)},
+				map[string]any{},
+				map[string]any{"count": float64(7)},
+			},
+			"myClaps": []any{
+				map[string]any{"index": float64(2)},
+				map[string]any{"index": float64(2)},
+				"invalid",
+			},
+		},
+	}
+
+	counts, mine := clapCounts(data)
+	if want := []int{3, 0, 7}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	if want := []int{2, 2}; !reflect.DeepEqual(mine, want) {
+		t.Errorf("mine = %v, want %v", mine, want)
+	}
+
+	want := []any{
+		map[string]any{"index": 0, "timestamp": 0, "count": 3, "mine": 0},
+		map[string]any{"index": 1, "timestamp": 100, "count": 0, "mine": 0},
+		map[string]any{"index": 2, "timestamp": 200, "count": 7, "mine": 2},
+	}
+	if got := clapSegments(data, 300); !reflect.DeepEqual(got, want) {
+		t.Errorf("clapSegments() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,305 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// 后台任务的状态
+const (
+	CrawlRunning   = "running"
+	CrawlCompleted = "completed"
+	CrawlFailed    = "failed"
+)
+
+// crawlRetries 后台抓取时上游不可用或限流的重试次数，间隔从 1 秒开始加倍
+const crawlRetries = 3
+
+// crawlStatus 后台任务共有的进度
+type crawlStatus struct {
+	Status      string     `json:"status"`
+	Requests    int        `json:"requests"`
+	Error       any        `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// crawlState 后台任务保存的数据：progress 返回其中的进度，clone 返回可以在锁外读取的副本
+type crawlState[S any] interface {
+	progress() *crawlStatus
+	clone() S
+}
+
+// crawlJobs 同一类后台任务。同一 key 同时只运行一个任务，运行中时从内存中读取最新的进度，结束时保存到 bucket
+type crawlJobs[S crawlState[S]] struct {
+	name        string       // 日志中的任务名
+	bucket      string       // 保存进度的 bucket，key 与任务相同
+	limiter     *rateLimiter // 同类任务共用的请求间隔
+	maxRequests int          // 单个任务最多发送的请求数，0 为不限制
+	saveEvery   int          // 每发送该数量的请求保存一次进度，0 为只在结束时保存
+	interrupted string       // 服务在任务运行中退出时的错误信息，如 crawl interrupted
+	hint        string       // 任务中断或达到请求数上限时的提示，如 call /comment_archive again to continue
+	running     sync.Map
+}
+
+// crawlJob 正在运行的任务
+type crawlJob[S crawlState[S]] struct {
+	jobs    *crawlJobs[S]
+	key     string
+	mu      sync.Mutex
+	state   S
+	started bool
+	saved   int // 上次保存时的请求数，只在任务的 goroutine 中使用
+}
+
+// start 为 key 创建任务，任务在 launch 或 cancel 之前持有锁，同时查询进度的请求会等待它开始。
+// 同一 key 已有任务在运行时返回 nil 和该任务的进度
+func (c *crawlJobs[S]) start(key string) (*crawlJob[S], S) {
+	job := &crawlJob[S]{jobs: c, key: key}
+	job.mu.Lock()
+
+	for {
+		running, loaded := c.running.LoadOrStore(key, job)
+		if !loaded {
+			return job, job.state
+		}
+
+		// 等待的任务被 cancel 时已从 running 中移除，重新创建
+		if state, ok := running.(*crawlJob[S]).current(); ok {
+			return nil, state
+		}
+	}
+}
+
+// current 正在运行的任务的进度
+func (c *crawlJobs[S]) current(key string) (S, bool) {
+	if job, found := c.running.Load(key); found {
+		return job.(*crawlJob[S]).current()
+	}
+
+	var zero S
+
+	return zero, false
+}
+
+// load 读取保存的进度，没有时返回 state。没有对应的任务时，保存的 running 说明服务在任务运行中退出
+func (c *crawlJobs[S]) load(key string, state S) (S, error) {
+	err := utils.StoreGet(c.bucket, key, state)
+	if err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+		var zero S
+
+		return zero, err
+	}
+
+	if p := state.progress(); p.Status == CrawlRunning {
+		p.Status = CrawlFailed
+		p.Error = utils.NewError(http.StatusInternalServerError, utils.ErrInternal, c.interrupted+", "+c.hint)
+	}
+
+	return state, nil
+}
+
+// find 正在运行时返回已完成的部分，否则读取保存的进度；从未运行过时返回 404，错误信息为 missing
+func (c *crawlJobs[S]) find(ctx *gin.Context, key string, state S, missing string) (S, bool) {
+	if running, ok := c.current(key); ok {
+		return running, true
+	}
+
+	state, err := c.load(key, state)
+	if err == nil && state.progress().Status == "" {
+		err = utils.NewError(http.StatusNotFound, utils.ErrNotFound, missing)
+	}
+
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return state, false
+	}
+
+	return state, true
+}
+
+// cancel 不运行 start 创建的任务
+func (j *crawlJob[S]) cancel() {
+	j.jobs.running.Delete(j.key)
+	j.mu.Unlock()
+}
+
+// launch 从 state 开始在后台运行任务，结束时移除任务并保存进度。
+// 任务在请求结束后继续运行，使用不会被取消的 context，并保留请求头中的认证信息和已校验的 API Key，抓取时不再扣减配额
+func (j *crawlJob[S]) launch(ctx *gin.Context, state S, run func(origin *http.Request)) {
+	// 继续运行时保留进度，但请求数按本次任务重新计算，否则达到上限后每次继续都会立即失败
+	p := state.progress()
+	p.Status = CrawlRunning
+	p.Requests = 0
+	p.Error = nil
+	p.UpdatedAt = time.Now()
+	p.CompletedAt = nil
+
+	j.state, j.started = state, true
+	j.mu.Unlock()
+
+	origin := ctx.Request.Clone(context.WithoutCancel(ctx.Request.Context()))
+
+	go func() {
+		defer j.jobs.running.Delete(j.key)
+		defer j.save()
+
+		run(origin)
+	}()
+}
+
+// current 当前进度的副本，任务还没有开始时返回 false
+func (j *crawlJob[S]) current() (S, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.started {
+		var zero S
+
+		return zero, false
+	}
+
+	return j.state.clone(), true
+}
+
+// snapshot 当前进度的副本
+func (j *crawlJob[S]) snapshot() S {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state.clone()
+}
+
+// status 当前的状态和请求数
+func (j *crawlJob[S]) status() crawlStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return *j.state.progress()
+}
+
+// update 在锁内修改进度
+func (j *crawlJob[S]) update(fn func(state S)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	fn(j.state)
+	j.state.progress().UpdatedAt = time.Now()
+}
+
+func (j *crawlJob[S]) save() {
+	if err := utils.StorePut(j.jobs.bucket, j.key, j.snapshot()); err != nil {
+		log.Printf("%s %s: %v", j.jobs.name, j.key, err)
+	}
+}
+
+// checkpoint 距上次保存已发送 saveEvery 个请求时保存进度
+func (j *crawlJob[S]) checkpoint() {
+	if j.jobs.saveEvery == 0 {
+		return
+	}
+
+	if requests := j.status().Requests; requests-j.saved >= j.jobs.saveEvery {
+		j.save()
+		j.saved = requests
+	}
+}
+
+// exhausted 达到请求数上限时将任务标记为失败
+func (j *crawlJob[S]) exhausted() bool {
+	if j.jobs.maxRequests == 0 || j.status().Requests < j.jobs.maxRequests {
+		return false
+	}
+
+	j.fail(utils.NewError(http.StatusTooManyRequests, utils.ErrQuotaExceeded, fmt.Sprintf("stopped after %d requests, %s", j.jobs.maxRequests, j.jobs.hint)))
+
+	return true
+}
+
+// call 按同类任务共用的请求间隔调用接口，上游不可用或限流时重试
+func (j *crawlJob[S]) call(origin *http.Request, path string, body map[string]any) (map[string]any, *BatchResult) {
+	return throttledCall(origin, j.jobs.limiter, path, body, func() {
+		j.update(func(state S) {
+			state.progress().Requests++
+		})
+	})
+}
+
+func (j *crawlJob[S]) complete() {
+	j.update(func(state S) {
+		now := time.Now()
+		p := state.progress()
+		p.Status = CrawlCompleted
+		p.CompletedAt = &now
+	})
+}
+
+func (j *crawlJob[S]) fail(e any) {
+	j.update(func(state S) {
+		p := state.progress()
+		p.Status = CrawlFailed
+		p.Error = e
+	})
+}
+
+// throttledCall 按 limiter 的间隔调用接口，上游不可用或限流时重试，每发送一次请求调用一次 sent
+func throttledCall(origin *http.Request, limiter *rateLimiter, path string, body map[string]any, sent func()) (map[string]any, *BatchResult) {
+	backoff := time.Second
+
+	for attempt := 0; ; attempt++ {
+		limiter.wait()
+
+		data, failed := callOperation(origin, path, body)
+		sent()
+
+		if failed == nil || !retryable(failed.Status) || failed.Status == http.StatusUnauthorized || attempt == crawlRetries {
+			return data, failed
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// rateLimiter 多个任务共用的固定间隔限流
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait 等待到下一个可以发送请求的时间
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	at := time.Now()
+	if l.next.After(at) {
+		at = l.next
+	}
+
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+// retryable 上游不可用、超时、限流或 token 失效时可以重试，不能重复发送的写操作还需要通过 notSent 确认没有发送
+func retryable(status int) bool {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return true
+	case status == http.StatusUnauthorized, status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	}
+
+	return false
+}
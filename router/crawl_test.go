@@ -0,0 +1,151 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+)
+
+type testCrawl struct {
+	crawlStatus
+	Items []string `json:"items"`
+}
+
+func (c *testCrawl) progress() *crawlStatus {
+	return &c.crawlStatus
+}
+
+func (c *testCrawl) clone() *testCrawl {
+	clone := *c
+	clone.Items = slices.Clone(clone.Items)
+
+	return &clone
+}
+
+func TestCrawlJobs(t *testing.T) {
+	initTestStore(t)
+
+	jobs := &crawlJobs[*testCrawl]{name: "test", bucket: "test_crawl", interrupted: "test interrupted", hint: "call /test again"}
+
+	for key, status := range map[string]string{"running": CrawlRunning, "completed": CrawlCompleted} {
+		if err := utils.StorePut(jobs.bucket, key, &testCrawl{crawlStatus: crawlStatus{Status: status}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		status string
+		error  bool
+	}{
+		{"没有保存的进度", "missing", "", false},
+		{"服务在运行中退出", "running", CrawlFailed, true},
+		{"已完成", "completed", CrawlCompleted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := jobs.load(tt.key, &testCrawl{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if state.Status != tt.status || (state.Error != nil) != tt.error {
+				t.Errorf("status = %q, error = %v, want %q, error = %v", state.Status, state.Error, tt.status, tt.error)
+			}
+		})
+	}
+
+	t.Run("同一 key 只运行一个任务", func(t *testing.T) {
+		job, _ := jobs.start("job")
+		if job == nil {
+			t.Fatal("start() = nil, want a new job")
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			if again, running := jobs.start("job"); again != nil || len(running.Items) != 1 {
+				t.Errorf("start() while running = %v, %v, want the running job's progress", again, running)
+			}
+		}()
+
+		release := make(chan struct{})
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/test", nil)
+
+		job.launch(ctx, &testCrawl{Items: []string{"a"}}, func(origin *http.Request) {
+			<-release
+			job.complete()
+		})
+
+		<-done
+		close(release)
+
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			if _, running := jobs.current("job"); !running {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatal("job still running")
+			}
+		}
+
+		saved, err := jobs.load("job", &testCrawl{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if saved.Status != CrawlCompleted || saved.CompletedAt == nil {
+			t.Errorf("saved status = %q, completedAt = %v, want completed", saved.Status, saved.CompletedAt)
+		}
+	})
+
+	t.Run("取消后可以重新开始", func(t *testing.T) {
+		job, _ := jobs.start("cancel")
+		job.cancel()
+
+		if job, _ := jobs.start("cancel"); job == nil {
+			t.Error("start() after cancel = nil, want a new job")
+		} else {
+			job.cancel()
+		}
+	})
+}
+
+func TestCrawlJobExhausted(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRequests int
+		requests    int
+		want        bool
+	}{
+		{"不限制请求数", 0, 100, false},
+		{"未达到上限", 10, 9, false},
+		{"达到上限", 10, 10, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &crawlJobs[*testCrawl]{maxRequests: tt.maxRequests, hint: "call /test again"}
+			job := &crawlJob[*testCrawl]{jobs: jobs, state: &testCrawl{crawlStatus: crawlStatus{Status: CrawlRunning, Requests: tt.requests}}}
+
+			if got := job.exhausted(); got != tt.want {
+				t.Errorf("exhausted() = %v, want %v", got, tt.want)
+			}
+
+			if failed := job.status().Status == CrawlFailed; failed != tt.want {
+				t.Errorf("status = %q, want failed = %v", job.status().Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package router
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/directory"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// directoryBucket 节目目录和抓取进度，分类对所有账号相同，只有一个 key
+	directoryBucket = "directory"
+	directoryKey    = "directory"
+	// directoryInterval 抓取目录的请求间隔
+	directoryInterval = 500 * time.Millisecond
+	// directoryMaxRequests 单个任务最多发送的请求数
+	directoryMaxRequests = 5000
+	// directorySaveEvery 每发送该数量的请求保存一次进度
+	directorySaveEvery = 10
+	// directoryDefaultPages 每个标签默认最多抓取的页数
+	directoryDefaultPages = 20
+	// directoryDefaultLimit 查询默认返回的节目数
+	directoryDefaultLimit = 50
+)
+
+// directoryJobs 抓取目录的任务，key 为 directoryKey
+var directoryJobs = &crawlJobs[*podcastDirectory]{
+	name:        "directory",
+	bucket:      directoryBucket,
+	limiter:     &rateLimiter{interval: directoryInterval},
+	maxRequests: directoryMaxRequests,
+	saveEvery:   directorySaveEvery,
+	interrupted: "crawl interrupted",
+	hint:        "call /directory_crawl again to continue",
+}
+
+// podcastDirectory 节目目录和抓取进度
+type podcastDirectory struct {
+	directory.Directory
+	Crawl directoryCrawl `json:"crawl"`
+}
+
+// directoryCrawl 抓取进度：先查询全部分类和每个分类的标签，再逐页查询每个标签下的节目。
+// Queue 中为尚未抓取完的标签和下一页的位置，失败或服务重启后再次调用时从该位置继续
+type directoryCrawl struct {
+	crawlStatus
+	Queue   []directoryPage `json:"queue,omitempty"`
+	Tabs    int             `json:"tabs"`              // 全部标签数
+	Pages   int             `json:"pages"`             // 本次任务已抓取的页数
+	Skipped []string        `json:"skipped,omitempty"` // 无法查询的标签，格式为「分类 id/标签 id」
+}
+
+// directoryPage 一个标签下一页的位置
+type directoryPage struct {
+	CategoryId  string `json:"categoryId"`
+	Tab         string `json:"tab"`
+	LoadMoreKey any    `json:"loadMoreKey,omitempty"`
+	Pages       int    `json:"pages"` // 已抓取的页数
+}
+
+// directoryJob 正在运行的抓取任务
+type directoryJob struct {
+	*crawlJob[*podcastDirectory]
+}
+
+type DirectoryCrawlRequestBody struct {
+	Pages   int  `json:"pages" form:"pages" binding:"omitempty,min=1,max=500"` // 每个标签最多抓取的页数，默认为 20
+	Refresh bool `json:"refresh" form:"refresh"`                               // 已完成时重新抓取全部分类，更新已保存的节目
+}
+
+// DirectoryCrawlData 抓取进度
+type DirectoryCrawlData struct {
+	Status      string     `json:"status"` // running、completed 或 failed
+	Categories  int        `json:"categories"`
+	Tabs        int        `json:"tabs"`
+	Pending     int        `json:"pending"` // 尚未抓取完的标签数
+	Podcasts    int        `json:"podcasts"`
+	Pages       int        `json:"pages"`
+	Requests    int        `json:"requests"`
+	Skipped     []string   `json:"skipped"`
+	Error       any        `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// DirectoryCrawl 在后台限速抓取全部分类和标签下的节目并保存在本地，立即返回抓取进度。
+// 任务正在运行或已完成时只返回进度；失败、服务重启后再次调用时从中断的位置继续，传入 refresh 时重新抓取
+var DirectoryCrawl = func(ctx *gin.Context) {
+	var params DirectoryCrawlRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	job, running := directoryJobs.start(directoryKey)
+	if job == nil {
+		returnDirectoryCrawl(ctx, running)
+
+		return
+	}
+
+	saved, err := loadDirectory()
+	if err != nil {
+		job.cancel()
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	if saved.Crawl.Status == CrawlCompleted && !params.Refresh {
+		job.cancel()
+		returnDirectoryCrawl(ctx, saved)
+
+		return
+	}
+
+	queue := saved.Crawl.Queue
+	if params.Refresh {
+		queue = nil
+	}
+
+	saved.Crawl = directoryCrawl{Queue: queue, Tabs: saved.Crawl.Tabs}
+	saved.Crawl.StartedAt = time.Now()
+
+	pages := params.Pages
+	if pages == 0 {
+		pages = directoryDefaultPages
+	}
+
+	job.launch(ctx, saved, func(origin *http.Request) {
+		directoryJob{job}.run(origin, pages)
+	})
+
+	returnDirectoryCrawl(ctx, job.snapshot())
+}
+
+// DirectoryCategoryData 分类、标签和分类下的节目数
+type DirectoryCategoryData struct {
+	directory.Category
+	Podcasts int `json:"podcasts"`
+}
+
+// DirectoryCategories 目录中的分类和标签，任务运行中时返回已抓取的部分
+var DirectoryCategories = func(ctx *gin.Context) {
+	d, ok := crawledDirectory(ctx)
+	if !ok {
+		return
+	}
+
+	list := []DirectoryCategoryData{}
+	for _, c := range d.Categories {
+		list = append(list, DirectoryCategoryData{Category: c, Podcasts: d.Count(c.Id)})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": list,
+	})
+}
+
+type DirectorySearchRequestBody struct {
+	Keyword          string `json:"keyword" form:"keyword"`                                                          // 标题、作者或简介中包含的文字，不区分大小写
+	CategoryId       string `json:"categoryId" form:"categoryId"`                                                    // 分类 id
+	Tab              string `json:"tab" form:"tab"`                                                                  // 分类下的标签 id，与 categoryId 一起使用
+	MinSubscriptions int    `json:"minSubscriptions" form:"minSubscriptions" binding:"min=0"`                        // 最少订阅数
+	MaxSubscriptions int    `json:"maxSubscriptions" form:"maxSubscriptions" binding:"min=0"`                        // 最多订阅数
+	MinEpisodes      int    `json:"minEpisodes" form:"minEpisodes" binding:"min=0"`                                  // 最少单集数
+	MaxEpisodes      int    `json:"maxEpisodes" form:"maxEpisodes" binding:"min=0"`                                  // 最多单集数
+	UpdatedAfter     string `json:"updatedAfter" form:"updatedAfter" binding:"omitempty,datetime=2006-01-02"`        // 最新单集的发布日期不早于该日期
+	UpdatedBefore    string `json:"updatedBefore" form:"updatedBefore" binding:"omitempty,datetime=2006-01-02"`      // 最新单集的发布日期不晚于该日期
+	Timezone         string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`                           // 解析日期使用的时区，默认为 Asia/Shanghai
+	Sort             string `json:"sort" form:"sort" binding:"omitempty,oneof=subscriptions episodes updated title"` // subscriptions 按订阅数（默认），episodes 按单集数，updated 按最新单集的发布时间，均为倒序；title 按标题
+	Reverse          bool   `json:"reverse" form:"reverse"`                                                          // 与默认的顺序相反
+	Limit            int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=500"`                            // 默认为 50
+	Offset           int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+type DirectorySearchData struct {
+	Status   string              `json:"status"`
+	Total    int                 `json:"total"`
+	Podcasts []directory.Podcast `json:"podcasts"`
+}
+
+// DirectorySearch 按分类、标签、订阅数、单集数和最近更新时间查询目录中的节目，支持 GET 和 POST，任务运行中时查询已抓取的部分
+var DirectorySearch = func(ctx *gin.Context) {
+	var params DirectorySearchRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	after, before, err := dateRange(params.UpdatedAfter, params.UpdatedBefore, params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	d, ok := crawledDirectory(ctx)
+	if !ok {
+		return
+	}
+
+	result := d.Search(directory.Filter{
+		Keyword:          params.Keyword,
+		CategoryId:       params.CategoryId,
+		Tab:              params.Tab,
+		MinSubscriptions: params.MinSubscriptions,
+		MaxSubscriptions: params.MaxSubscriptions,
+		MinEpisodes:      params.MinEpisodes,
+		MaxEpisodes:      params.MaxEpisodes,
+		UpdatedAfter:     after,
+		UpdatedBefore:    before,
+		Sort:             params.Sort,
+		Reverse:          params.Reverse,
+	})
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = directoryDefaultLimit
+	}
+
+	page := result[min(params.Offset, len(result)):min(params.Offset+limit, len(result))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": DirectorySearchData{Status: d.Crawl.Status, Total: len(result), Podcasts: page},
+	})
+}
+
+// crawledDirectory 正在抓取时返回已抓取的部分，没有目录时返回 404
+func crawledDirectory(ctx *gin.Context) (*podcastDirectory, bool) {
+	return directoryJobs.find(ctx, directoryKey, &podcastDirectory{}, "no podcast directory, call /directory_crawl first")
+}
+
+func returnDirectoryCrawl(ctx *gin.Context, d *podcastDirectory) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": DirectoryCrawlData{
+			Status:      d.Crawl.Status,
+			Categories:  len(d.Categories),
+			Tabs:        d.Crawl.Tabs,
+			Pending:     len(d.Crawl.Queue),
+			Podcasts:    len(d.Podcasts),
+			Pages:       d.Crawl.Pages,
+			Requests:    d.Crawl.Requests,
+			Skipped:     append([]string{}, d.Crawl.Skipped...),
+			Error:       d.Crawl.Error,
+			StartedAt:   d.Crawl.StartedAt,
+			UpdatedAt:   d.Crawl.UpdatedAt,
+			CompletedAt: d.Crawl.CompletedAt,
+		},
+	})
+}
+
+func loadDirectory() (*podcastDirectory, error) {
+	return directoryJobs.load(directoryKey, &podcastDirectory{})
+}
+
+func (d *podcastDirectory) progress() *crawlStatus {
+	return &d.Crawl.crawlStatus
+}
+
+// clone 进度的副本，节目只在替换时修改，复制 map 即可
+func (d *podcastDirectory) clone() *podcastDirectory {
+	c := *d
+	c.Categories = slices.Clone(c.Categories)
+	c.Podcasts = maps.Clone(c.Podcasts)
+	c.Crawl.Queue = slices.Clone(c.Crawl.Queue)
+	c.Crawl.Skipped = slices.Clone(c.Crawl.Skipped)
+
+	return &c
+}
+
+// run 没有未完成的标签时先查询分类和标签，再逐页抓取每个标签下的节目，直到完成、失败或达到请求数上限
+func (j directoryJob) run(origin *http.Request, maxPages int) {
+	if len(j.snapshot().Crawl.Queue) == 0 && !j.categories(origin) {
+		return
+	}
+
+	for {
+		d := j.snapshot()
+
+		if len(d.Crawl.Queue) == 0 {
+			j.complete()
+
+			return
+		}
+
+		if j.exhausted() {
+			return
+		}
+
+		page := d.Crawl.Queue[0]
+
+		body := map[string]any{"categoryId": page.CategoryId, "tab": page.Tab}
+		if page.LoadMoreKey != nil {
+			body["loadMoreKey"] = page.LoadMoreKey
+		}
+
+		data, failed := j.call(origin, "/category_podcast_list", body)
+		if failed != nil && retryable(failed.Status) {
+			j.fail(operationError(page.CategoryId+"/"+page.Tab, "/category_podcast_list", failed).Error)
+
+			return
+		}
+
+		j.update(func(d *podcastDirectory) {
+			d.Crawl.Queue = d.Crawl.Queue[1:]
+
+			if failed != nil {
+				d.Crawl.Skipped = append(d.Crawl.Skipped, page.CategoryId+"/"+page.Tab)
+
+				return
+			}
+
+			now := time.Now()
+
+			items, _ := data["data"].([]any)
+			for _, item := range items {
+				if p := directoryPodcast(item); p.Pid != "" {
+					p.SeenAt = now
+					d.Add(p, page.CategoryId, page.Tab)
+				}
+			}
+
+			d.Crawl.Pages++
+
+			// 还有下一页时放回队首，下次从该位置继续
+			page.Pages++
+			if page.LoadMoreKey = data["loadMoreKey"]; page.LoadMoreKey != nil && len(items) > 0 && page.Pages < maxPages {
+				d.Crawl.Queue = append([]directoryPage{page}, d.Crawl.Queue...)
+			}
+		})
+
+		j.checkpoint()
+	}
+}
+
+// categories 查询全部分类和每个分类的标签，将每个标签加入待抓取的队列
+func (j directoryJob) categories(origin *http.Request) bool {
+	data, failed := j.call(origin, "/category_list", map[string]any{})
+	if failed != nil {
+		j.fail(operationError("categories", "/category_list", failed).Error)
+
+		return false
+	}
+
+	var categories []directory.Category
+	var queue []directoryPage
+
+	items, _ := data["data"].([]any)
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+		if m["id"] == nil {
+			continue
+		}
+
+		c := directory.Category{Id: fmt.Sprint(m["id"]), Tabs: []directory.Tab{}}
+		c.Name, _ = m["name"].(string)
+
+		data, failed := j.call(origin, "/category_list_tab", map[string]any{"categoryId": c.Id})
+		if failed != nil {
+			j.fail(operationError(c.Id, "/category_list_tab", failed).Error)
+
+			return false
+		}
+
+		tabs, _ := data["data"].([]any)
+		for _, tab := range tabs {
+			t, _ := tab.(map[string]any)
+			if t["id"] == nil {
+				continue
+			}
+
+			name, _ := t["name"].(string)
+			c.Tabs = append(c.Tabs, directory.Tab{Id: fmt.Sprint(t["id"]), Name: name})
+			queue = append(queue, directoryPage{CategoryId: c.Id, Tab: fmt.Sprint(t["id"])})
+		}
+
+		categories = append(categories, c)
+	}
+
+	j.update(func(d *podcastDirectory) {
+		d.Categories = categories
+		d.Crawl.Queue = queue
+		d.Crawl.Tabs = len(queue)
+	})
+
+	return true
+}
+
+// directoryPodcast 分类节目列表中的一项，可能为节目本身或 {podcast, episode}
+func directoryPodcast(item any) directory.Podcast {
+	m, _ := item.(map[string]any)
+	if podcast, ok := m["podcast"].(map[string]any); ok {
+		m = podcast
+	}
+
+	p := directory.Podcast{Categories: []directory.Membership{}}
+	p.Pid, _ = m["pid"].(string)
+	p.Title, _ = m["title"].(string)
+	p.Author, _ = m["author"].(string)
+	p.Brief, _ = m["brief"].(string)
+	p.Image, _ = nested(m, "image", "picUrl").(string)
+
+	subscriptions, _ := m["subscriptionCount"].(float64)
+	episodes, _ := m["episodeCount"].(float64)
+	p.Subscriptions, p.Episodes = int(subscriptions), int(episodes)
+
+	if pubDate, ok := m["latestEpisodePubDate"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, pubDate); err == nil {
+			p.LatestEpisode = &t
+		}
+	}
+
+	p.Title, p.Brief = strings.TrimSpace(p.Title), strings.TrimSpace(p.Brief)
+
+	return p
+}
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	C "github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/mock"
+)
+
+// emptyCategory 测试中没有标签的分类
+const emptyCategory = "500000000000000000000004"
+
+// directoryUpstream 模拟的上游，每页只返回一个节目，emptyCategory 没有标签，并记录每一页被请求的次数
+type directoryUpstream struct {
+	server *mock.Server
+	mu     sync.Mutex
+	pages  map[string]int // 「分类 id/标签 id/loadMoreKey」
+	lists  int            // 查询全部分类的次数
+}
+
+func (u *directoryUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+
+	var body map[string]any
+	_ = json.Unmarshal(b, &body)
+
+	u.mu.Lock()
+	switch r.URL.Path {
+	case "/v1/category/list-all":
+		u.lists++
+	case "/v1/category/podcast/list-by-tab":
+		u.pages[fmt.Sprintf("%v/%v/%v", body["categoryId"], body["tab"], body["loadMoreKey"])]++
+
+		body["limit"] = 1
+		b, _ = json.Marshal(body)
+	}
+	u.mu.Unlock()
+
+	if r.URL.Path == "/v1/category/podcast/list-tabs" && body["categoryId"] == emptyCategory {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[]}`))
+
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	u.server.ServeHTTP(w, r)
+}
+
+func TestDirectoryCrawl(t *testing.T) {
+	initTestStore(t)
+	withoutRedis(t)
+
+	server := mock.NewServer()
+	upstream := &directoryUpstream{server: server, pages: map[string]int{}}
+
+	ts := httptest.NewServer(upstream)
+	defer ts.Close()
+
+	baseUrl, limiter, maxRequests := C.BaseUrl, directoryJobs.limiter, directoryJobs.maxRequests
+	C.BaseUrl, directoryJobs.limiter = ts.URL, &rateLimiter{}
+	t.Cleanup(func() {
+		C.BaseUrl, directoryJobs.limiter, directoryJobs.maxRequests = baseUrl, limiter, maxRequests
+	})
+
+	accessToken, _ := server.Login()
+
+	engine := gin.New()
+	RegisterRouters(engine)
+
+	post := func(t *testing.T, path, body string, data any) {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-jike-access-token", accessToken)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("POST %s = %d %s", path, recorder.Code, recorder.Body.String())
+		}
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), &struct{ Data any }{data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// crawl 开始抓取并等待任务结束，返回保存的进度
+	crawl := func(t *testing.T, body string) *podcastDirectory {
+		t.Helper()
+
+		post(t, "/directory_crawl", body, &DirectoryCrawlData{})
+
+		for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			if _, running := directoryJobs.current(directoryKey); !running {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatal("crawl still running")
+			}
+		}
+
+		d, err := loadDirectory()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return d
+	}
+
+	// 种子数据中分类 1、2 各有 2 个节目，分类 3、4 各有 1 个；每页 1 个节目，分类 4 没有标签
+	const pages, requests = 15, 20
+
+	t.Run("达到请求数上限后从队列继续", func(t *testing.T) {
+		directoryJobs.maxRequests = 10
+
+		data := crawl(t, "{}").Crawl
+		if data.Status != CrawlFailed || data.Requests != 10 || len(data.Queue) == 0 {
+			t.Fatalf("first crawl = %+v, want failed after 10 requests with pending tabs", data)
+		}
+
+		if e, _ := data.Error.(map[string]any); e["code"] != "QUOTA_EXCEEDED" {
+			t.Errorf("error = %v, want QUOTA_EXCEEDED", data.Error)
+		}
+
+		directoryJobs.maxRequests = maxRequests
+
+		d := crawl(t, "{}")
+		resumed := d.Crawl
+		if resumed.Status != CrawlCompleted || len(resumed.Queue) != 0 || len(resumed.Skipped) != 0 {
+			t.Fatalf("resumed crawl = %+v, want completed", resumed)
+		}
+
+		// 继续时不再查询分类，已抓取的页也不会重复请求
+		if upstream.lists != 1 || data.Requests+resumed.Requests != requests || data.Pages+resumed.Pages != pages {
+			t.Errorf("requests = %d + %d, pages = %d + %d, category lists = %d, want %d requests, %d pages, 1 list",
+				data.Requests, resumed.Requests, data.Pages, resumed.Pages, upstream.lists, requests, pages)
+		}
+
+		for page, n := range upstream.pages {
+			if n != 1 {
+				t.Errorf("page %s requested %d times", page, n)
+			}
+		}
+
+		if len(upstream.pages) != pages || len(d.Categories) != 4 || resumed.Tabs != 9 || len(d.Podcasts) != 5 {
+			t.Errorf("crawled %d pages, %d categories, %d tabs, %d podcasts, want %d pages, 4 categories, 9 tabs, 5 podcasts",
+				len(upstream.pages), len(d.Categories), resumed.Tabs, len(d.Podcasts), pages)
+		}
+	})
+
+	t.Run("没有标签的分类", func(t *testing.T) {
+		var categories []DirectoryCategoryData
+		post(t, "/directory_categories", "{}", &categories)
+
+		podcasts := map[string]int{}
+		for _, c := range categories {
+			podcasts[c.Id] = c.Podcasts
+
+			if c.Id == emptyCategory && (c.Tabs == nil || len(c.Tabs) != 0) {
+				t.Errorf("tabs = %v, want empty", c.Tabs)
+			}
+		}
+
+		want := map[string]int{fixtureCategory: 2, "500000000000000000000002": 2, "500000000000000000000003": 1, emptyCategory: 0}
+		if fmt.Sprint(podcasts) != fmt.Sprint(want) {
+			t.Errorf("podcasts = %v, want %v", podcasts, want)
+		}
+
+		var search DirectorySearchData
+		post(t, "/directory_search", `{"categoryId":"`+emptyCategory+`"}`, &search)
+
+		if search.Total != 0 || len(search.Podcasts) != 0 {
+			t.Errorf("search = %+v, want no podcasts", search)
+		}
+	})
+
+	t.Run("已完成时只返回进度", func(t *testing.T) {
+		lists := upstream.lists
+
+		var data DirectoryCrawlData
+		post(t, "/directory_crawl", "{}", &data)
+
+		if data.Status != CrawlCompleted || upstream.lists != lists {
+			t.Errorf("crawl = %+v after %d category lists, want completed without requests", data, upstream.lists-lists)
+		}
+	})
+
+	t.Run("refresh 重新抓取", func(t *testing.T) {
+		d := crawl(t, `{"refresh":true}`)
+
+		if d.Crawl.Status != CrawlCompleted || upstream.lists != 2 || d.Crawl.Requests != requests || d.Crawl.Pages != pages || len(d.Podcasts) != 5 {
+			t.Errorf("refreshed crawl = %+v after %d category lists, want %d requests and %d pages", d.Crawl, upstream.lists, requests, pages)
+		}
+	})
+}
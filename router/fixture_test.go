@@ -0,0 +1,207 @@
+package router
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	C "github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/mock"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// 种子数据中的第一个节目、单集、评论、分类和第二个用户
+const (
+	fixturePid      = "200000000000000000000001"
+	fixtureEid      = "300000000000000000000001"
+	fixtureComment  = "400000000000000000000001"
+	fixtureCategory = "500000000000000000000001"
+	fixtureUid      = "100000000000000000000002"
+)
+
+// localRoutes 不请求上游的原有接口
+var localRoutes = map[string]bool{
+	"/accounts":        true,
+	"/accounts/remove": true,
+}
+
+// withoutRedis 使用无法连接的 Redis，带有缓存的接口每次都不命中缓存
+func withoutRedis(t *testing.T) {
+	t.Helper()
+
+	client := utils.RedisClient
+	utils.RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+
+	t.Cleanup(func() {
+		_ = utils.RedisClient.Close()
+		utils.RedisClient = client
+	})
+}
+
+// TestHandlersReplayFixtures 对模拟的上游录制每个请求上游的接口，关闭上游后回放，结果与录制时相同
+func TestHandlersReplayFixtures(t *testing.T) {
+	initTestStore(t)
+	withoutRedis(t)
+
+	server := mock.NewServer()
+	upstream := httptest.NewServer(server)
+	defer upstream.Close()
+
+	baseUrl := C.BaseUrl
+	C.BaseUrl = upstream.URL
+	t.Cleanup(func() { C.BaseUrl = baseUrl })
+
+	dir := t.TempDir()
+	t.Cleanup(func() { _ = utils.UseFixtures("", "") })
+
+	accessToken, refreshToken := server.Login()
+	origin, err := replayAuth{AccessToken: accessToken}.request("/batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 按顺序调用：/login 需要先发送验证码，/refresh_token 会使 access token 失效，放在最后
+	tests := []struct {
+		path     string
+		body     map[string]any
+		redacted bool // 响应中的 token 录制时被脱敏，回放时只比较状态码
+	}{
+		{"/sendCode", map[string]any{"mobilePhoneNumber": "13800000000"}, false},
+		{"/login", map[string]any{"mobilePhoneNumber": "13800000000", "verifyCode": mock.VerifyCode}, true},
+		{"/subscription", nil, false},
+		{"/subscription_update", map[string]any{"pid": fixturePid, "mode": "ON"}, false},
+		{"/subscription_star", nil, false},
+		{"/subscription_non_starred", nil, false},
+		{"/subscription_star_update", map[string]any{"pid": fixturePid, "withStar": true}, false},
+		{"/search", map[string]any{"type": "ALL", "keyword": "节目"}, false},
+		{"/search_preset", nil, false},
+		{"/episode_list", map[string]any{"pid": fixturePid, "order": "desc"}, false},
+		{"/episode_list_by_filter", map[string]any{"pid": fixturePid}, false},
+		{"/episode_detail", map[string]any{"eid": fixtureEid}, false},
+		{"/podcast_detail", map[string]any{"pid": fixturePid}, false},
+		{"/podcast_get_info", map[string]any{"pid": fixturePid}, false},
+		{"/podcast_honor_list", map[string]any{"pid": fixturePid}, false},
+		{"/podcast_related", map[string]any{"pid": fixturePid}, false},
+		{"/podcast_bulletin", map[string]any{"pid": fixturePid}, false},
+		{"/profile", nil, false},
+		{"/sticker", map[string]any{"uid": fixtureUid}, false},
+		{"/sticker_board", map[string]any{"uid": fixtureUid}, false},
+		{"/episode_play_progress", map[string]any{"eids": []string{fixtureEid}}, false},
+		{"/episode_play_progress_update", map[string]any{"data": []map[string]any{{"pid": fixturePid, "eid": fixtureEid, "progress": 60, "playedAt": "2024-05-16T08:44:04.351Z"}}}, false},
+		{"/comment_primary", map[string]any{"id": fixtureEid, "order": "HOT"}, false},
+		{"/comment_thread", map[string]any{"primaryCommentId": fixtureComment, "order": "SMART"}, false},
+		{"/comment_collect_create", map[string]any{"commentId": fixtureComment}, false},
+		{"/comment_collect_list", nil, false},
+		{"/comment_collect_remove", map[string]any{"commentId": fixtureComment}, false},
+		{"/comment_like_update", map[string]any{"id": fixtureComment, "liked": true}, false},
+		{"/discovery", nil, false},
+		{"/refresh_episode_recommend", nil, false},
+		{"/episode_live_count", map[string]any{"eid": fixtureEid}, false},
+		{"/live_stats_report", map[string]any{"eid": fixtureEid, "pid": fixturePid}, false},
+		{"/episode_clap", map[string]any{"eid": fixtureEid, "duration": 3600}, false},
+		{"/episode_clap_create", map[string]any{"eid": fixtureEid, "timestamp": 60, "duration": 3600}, false},
+		{"/inbox_list", nil, false},
+		{"/category_list", nil, false},
+		{"/category_list_tab", map[string]any{"categoryId": fixtureCategory}, false},
+		{"/category_podcast_list", map[string]any{"categoryId": fixtureCategory, "tab": "ALL"}, false},
+		{"/favorite_episode_update", map[string]any{"eid": fixtureEid, "favorited": true}, false},
+		{"/favorite_episode_list", nil, false},
+		{"/episode_played_history_list", nil, false},
+		{"/episode_played_history_list_update", map[string]any{"eid": fixtureEid}, false},
+		{"/unread_count", nil, false},
+		{"/user_stats", map[string]any{"uid": fixtureUid}, false},
+		{"/get_profile", map[string]any{"uid": fixtureUid}, false},
+		{"/mileage_get", nil, false},
+		{"/mileage_list", map[string]any{"all": true}, false},
+		{"/mileage_update", map[string]any{"tracking": []map[string]any{{"eid": fixtureEid, "pid": fixturePid, "startPlayingTimestamp": 1715848800000, "endPlayingTimestamp": 1715849400000}}}, false},
+		{"/played_list", map[string]any{"uid": fixtureUid}, false},
+		{"/pick_list_recent", map[string]any{"uid": fixtureUid}, false},
+		{"/pick_list_history", map[string]any{"uid": fixtureUid}, false},
+		{"/owned_podcasts", map[string]any{"uid": fixtureUid}, false},
+		{"/top_list", map[string]any{"category": "HOT"}, false},
+		{"/following_list", map[string]any{"uid": fixtureUid}, false},
+		{"/follower_list", map[string]any{"uid": fixtureUid}, false},
+		{"/relation_update", map[string]any{"uid": fixtureUid, "relation": "FOLLOWING"}, false},
+		{"/blocked_user_create", map[string]any{"uid": fixtureUid}, false},
+		{"/blocked_user_lists", nil, false},
+		{"/blocked_user_remove", map[string]any{"uid": fixtureUid}, false},
+		{"/user_preference_get", nil, false},
+		{"/user_preference_update", map[string]any{"type": "FOLLOW_SYNC", "flag": true}, false},
+		{"/refresh_token", map[string]any{"x-jike-access-token": accessToken, "x-jike-refresh-token": refreshToken}, true},
+	}
+
+	// 每个请求上游的接口都需要录制
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		covered[tt.path] = true
+	}
+
+	features := map[string]bool{}
+	for _, route := range featureRoutes() {
+		features[route.Path] = true
+	}
+
+	for _, route := range Routes {
+		if !features[route.Path] && !localRoutes[route.Path] && !covered[route.Path] {
+			t.Errorf("no fixture for %s %s", route.Method, route.Path)
+		}
+	}
+
+	call := func(path string, body map[string]any) *BatchResult {
+		return runOperation(origin, BatchOperation{Id: path, Path: path}, body)
+	}
+
+	if err := utils.UseFixtures(utils.FixtureRecord, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := make([]*BatchResult, len(tests))
+	for i, tt := range tests {
+		recorded[i] = call(tt.path, tt.body)
+	}
+
+	missing := call("/podcast_get_info", map[string]any{"pid": "000000000000000000000000"})
+
+	upstream.Close()
+
+	if err := utils.UseFixtures(utils.FixtureReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range tests {
+		t.Run(strings.TrimPrefix(tt.path, "/"), func(t *testing.T) {
+			if !succeeded(recorded[i]) {
+				t.Fatalf("recorded %s = %d %v, want a success", tt.path, recorded[i].Status, recorded[i].Body)
+			}
+
+			replayed := call(tt.path, tt.body)
+
+			if tt.redacted {
+				if replayed.Status != recorded[i].Status {
+					t.Errorf("replayed %s = %d %v, want %d", tt.path, replayed.Status, replayed.Body, recorded[i].Status)
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(replayed, recorded[i]) {
+				t.Errorf("replayed %s = %d %v, want %d %v", tt.path, replayed.Status, replayed.Body, recorded[i].Status, recorded[i].Body)
+			}
+		})
+	}
+
+	// 上游返回的错误同样可以回放
+	if succeeded(missing) {
+		t.Errorf("recorded missing podcast = %d %v, want a failure", missing.Status, missing.Body)
+	} else if replayed := call("/podcast_get_info", map[string]any{"pid": "000000000000000000000000"}); !reflect.DeepEqual(replayed, missing) {
+		t.Errorf("replayed missing podcast = %d %v, want %d %v", replayed.Status, replayed.Body, missing.Status, missing.Body)
+	}
+
+	// 没有录制的请求不会发送到上游
+	if replayed := call("/comment_primary", map[string]any{"id": fixtureEid, "order": "TIME"}); succeeded(replayed) {
+		t.Errorf("unrecorded /comment_primary = %d %v, want a failure", replayed.Status, replayed.Body)
+	}
+}
@@ -0,0 +1,441 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/related"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// graphBucket 相关节目图，key 为起点的 pid
+	graphBucket = "podcast_graph"
+	// graphInterval 查询相关节目的请求间隔
+	graphInterval = 500 * time.Millisecond
+	// graphSaveEvery 每发送该数量的请求保存一次进度
+	graphSaveEvery = 10
+	// graphDefaultDepth 默认展开的层数
+	graphDefaultDepth = 2
+	// graphDefaultMaxNodes 默认最多包含的节目数
+	graphDefaultMaxNodes = 200
+	// graphDefaultLimit 中心度默认返回的节目数
+	graphDefaultLimit = 20
+)
+
+// graphJobs 展开相关节目图的任务，key 为起点的 pid
+var graphJobs = &crawlJobs[*podcastGraph]{
+	name:        "podcast graph",
+	bucket:      graphBucket,
+	limiter:     &rateLimiter{interval: graphInterval},
+	saveEvery:   graphSaveEvery,
+	interrupted: "expansion interrupted",
+	hint:        "call /podcast_graph again to continue",
+}
+
+// podcastGraph 从一个节目出发展开的相关节目图和展开进度。
+// 节目按广度优先的顺序加入，未展开且距离小于 Depth 的节目即为待展开的队列，失败或服务重启后再次调用时从该位置继续
+type podcastGraph struct {
+	Pid string `json:"pid"`
+	related.Graph
+	Crawl graphCrawl `json:"crawl"`
+}
+
+type graphCrawl struct {
+	crawlStatus
+	Depth    int      `json:"depth"`
+	MaxNodes int      `json:"maxNodes"`
+	Skipped  []string `json:"skipped,omitempty"` // 无法查询相关节目的 pid
+}
+
+// pending 待展开的节目，按加入的顺序排列
+func (g *podcastGraph) pending() []related.Node {
+	var list []related.Node
+	for _, n := range g.Nodes {
+		if !n.Expanded && n.Depth < g.Crawl.Depth && !slices.Contains(g.Crawl.Skipped, n.Pid) {
+			list = append(list, n)
+		}
+	}
+
+	return list
+}
+
+// graphJob 正在运行的展开任务
+type graphJob struct {
+	*crawlJob[*podcastGraph]
+}
+
+type PodcastGraphRequestBody struct {
+	Pid      string `json:"pid" form:"pid" binding:"required,xyzid"`
+	Depth    int    `json:"depth" form:"depth" binding:"omitempty,min=1,max=4"`          // 展开的层数，默认为 2
+	MaxNodes int    `json:"maxNodes" form:"maxNodes" binding:"omitempty,min=2,max=2000"` // 最多包含的节目数，默认为 200
+	Refresh  bool   `json:"refresh" form:"refresh"`                                      // 丢弃已保存的图，重新展开
+}
+
+// PodcastGraphData 展开进度
+type PodcastGraphData struct {
+	Pid         string     `json:"pid"`
+	Status      string     `json:"status"` // running、completed 或 failed
+	Depth       int        `json:"depth"`
+	MaxNodes    int        `json:"maxNodes"`
+	Nodes       int        `json:"nodes"`
+	Edges       int        `json:"edges"`
+	Pending     int        `json:"pending"` // 待展开的节目数
+	Requests    int        `json:"requests"`
+	Skipped     []string   `json:"skipped"`
+	Error       any        `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// PodcastGraph 在后台限速逐层查询相关节目，保存节目和它们之间的边，立即返回展开进度。
+// 任务正在运行，或已完成且层数、节目数不超过上次的设置时只返回进度；设置更大时在已有的图上继续展开，传入 refresh 时重新展开
+var PodcastGraph = func(ctx *gin.Context) {
+	var params PodcastGraphRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	job, running := graphJobs.start(params.Pid)
+	if job == nil {
+		returnPodcastGraph(ctx, running)
+
+		return
+	}
+
+	saved, err := loadPodcastGraph(params.Pid)
+	if err != nil {
+		job.cancel()
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	depth, maxNodes := params.Depth, params.MaxNodes
+	if depth == 0 {
+		depth = max(saved.Crawl.Depth, graphDefaultDepth)
+	}
+
+	if maxNodes == 0 {
+		maxNodes = max(saved.Crawl.MaxNodes, graphDefaultMaxNodes)
+	}
+
+	if saved.Crawl.Status == CrawlCompleted && !params.Refresh && depth <= saved.Crawl.Depth && maxNodes <= saved.Crawl.MaxNodes {
+		job.cancel()
+		returnPodcastGraph(ctx, saved)
+
+		return
+	}
+
+	if params.Refresh || len(saved.Nodes) == 0 {
+		saved.Graph = related.Graph{Nodes: []related.Node{{Pid: params.Pid}}, Edges: []related.Edge{}}
+	} else if maxNodes > saved.Crawl.MaxNodes && len(saved.Nodes) >= saved.Crawl.MaxNodes {
+		// 上次因节目数达到上限而少加入了节目，重新展开全部节目
+		for i := range saved.Nodes {
+			saved.Nodes[i].Expanded = false
+		}
+	}
+
+	saved.Crawl = graphCrawl{Depth: depth, MaxNodes: maxNodes}
+	saved.Crawl.StartedAt = time.Now()
+
+	job.launch(ctx, saved, graphJob{job}.run)
+
+	returnPodcastGraph(ctx, job.snapshot())
+}
+
+type PodcastGraphPathRequestBody struct {
+	Pid      string `json:"pid" form:"pid" binding:"required,xyzid"` // 图的起点
+	From     string `json:"from" form:"from" binding:"omitempty,xyzid"`
+	To       string `json:"to" form:"to" binding:"required,xyzid"`
+	Directed bool   `json:"directed" form:"directed"` // 只沿着「A 的相关节目中有 B」的方向，默认忽略方向
+}
+
+type PodcastGraphPathData struct {
+	Found  bool           `json:"found"`
+	Length int            `json:"length"` // 经过的边数
+	Path   []related.Node `json:"path"`
+}
+
+// PodcastGraphPath 图中两个节目之间经过边数最少的路径，from 默认为图的起点
+var PodcastGraphPath = func(ctx *gin.Context) {
+	var params PodcastGraphPathRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	g, ok := expandedGraph(ctx, params.Pid)
+	if !ok {
+		return
+	}
+
+	from := params.From
+	if from == "" {
+		from = params.Pid
+	}
+
+	index := g.Index()
+	for _, pid := range []string{from, params.To} {
+		if _, found := index[pid]; !found {
+			utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, fmt.Sprintf("podcast %s is not in the graph", pid)))
+
+			return
+		}
+	}
+
+	data := PodcastGraphPathData{Path: []related.Node{}}
+	if path := g.ShortestPath(from, params.To, params.Directed); path != nil {
+		data = PodcastGraphPathData{Found: true, Length: len(path) - 1, Path: path}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+type PodcastGraphClustersRequestBody struct {
+	Pid     string `json:"pid" form:"pid" binding:"required,xyzid"`
+	MinSize int    `json:"minSize" form:"minSize" binding:"omitempty,min=1"` // 最少包含的节目数，默认为 2
+}
+
+type PodcastGraphCluster struct {
+	Size     int            `json:"size"`
+	Podcasts []related.Node `json:"podcasts"` // 按订阅数倒序排列
+}
+
+// PodcastGraphClusters 按模块度将图中的节目划分为联系紧密的社区，按社区的大小倒序排列
+var PodcastGraphClusters = func(ctx *gin.Context) {
+	var params PodcastGraphClustersRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	g, ok := expandedGraph(ctx, params.Pid)
+	if !ok {
+		return
+	}
+
+	minSize := params.MinSize
+	if minSize == 0 {
+		minSize = 2
+	}
+
+	list := []PodcastGraphCluster{}
+	for _, c := range g.Communities() {
+		if len(c) >= minSize {
+			list = append(list, PodcastGraphCluster{Size: len(c), Podcasts: c})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": list,
+	})
+}
+
+type PodcastGraphCentralRequestBody struct {
+	Pid   string `json:"pid" form:"pid" binding:"required,xyzid"`
+	By    string `json:"by" form:"by" binding:"omitempty,oneof=pagerank degree betweenness"` // pagerank（默认）、degree 按被列为相关节目的次数、betweenness 按介数中心度
+	Limit int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=500"`               // 默认为 20
+}
+
+// PodcastGraphCentral 图中最核心的节目，同时返回入度、出度、PageRank 和介数中心度
+var PodcastGraphCentral = func(ctx *gin.Context) {
+	var params PodcastGraphCentralRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	g, ok := expandedGraph(ctx, params.Pid)
+	if !ok {
+		return
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = graphDefaultLimit
+	}
+
+	result := g.Central(params.By)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": result[:min(limit, len(result))],
+	})
+}
+
+type PodcastGraphExportRequestBody struct {
+	Pid    string `json:"pid" form:"pid" binding:"required,xyzid"`
+	Format string `json:"format" form:"format" binding:"omitempty,oneof=json dot graphml"` // json（默认）、dot 或 graphml
+}
+
+// PodcastGraphExport 导出相关节目图，dot 可以用 Graphviz 绘制，graphml 可以在 Gephi 等工具中打开，json 包含全部节目和边
+var PodcastGraphExport = func(ctx *gin.Context) {
+	var params PodcastGraphExportRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	g, ok := expandedGraph(ctx, params.Pid)
+	if !ok {
+		return
+	}
+
+	var b bytes.Buffer
+	var err error
+
+	format, contentType := params.Format, "application/json; charset=utf-8"
+	switch format {
+	case "dot":
+		contentType = "text/vnd.graphviz; charset=utf-8"
+		err = related.DOT(&b, &g.Graph)
+	case "graphml":
+		contentType = "application/xml; charset=utf-8"
+		err = related.GraphML(&b, &g.Graph)
+	default:
+		format = "json"
+		err = json.NewEncoder(&b).Encode(g.Graph)
+	}
+
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="podcast_graph_%s.%s"`, params.Pid, format))
+	ctx.Data(http.StatusOK, contentType, b.Bytes())
+}
+
+// expandedGraph 正在展开时返回已展开的部分，没有图时返回 404
+func expandedGraph(ctx *gin.Context, pid string) (*podcastGraph, bool) {
+	return graphJobs.find(ctx, pid, &podcastGraph{Pid: pid}, "no graph for this podcast, call /podcast_graph first")
+}
+
+func returnPodcastGraph(ctx *gin.Context, g *podcastGraph) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": PodcastGraphData{
+			Pid:         g.Pid,
+			Status:      g.Crawl.Status,
+			Depth:       g.Crawl.Depth,
+			MaxNodes:    g.Crawl.MaxNodes,
+			Nodes:       len(g.Nodes),
+			Edges:       len(g.Edges),
+			Pending:     len(g.pending()),
+			Requests:    g.Crawl.Requests,
+			Skipped:     append([]string{}, g.Crawl.Skipped...),
+			Error:       g.Crawl.Error,
+			StartedAt:   g.Crawl.StartedAt,
+			UpdatedAt:   g.Crawl.UpdatedAt,
+			CompletedAt: g.Crawl.CompletedAt,
+		},
+	})
+}
+
+func loadPodcastGraph(pid string) (*podcastGraph, error) {
+	return graphJobs.load(pid, &podcastGraph{Pid: pid})
+}
+
+func (g *podcastGraph) progress() *crawlStatus {
+	return &g.Crawl.crawlStatus
+}
+
+// clone 进度的副本
+func (g *podcastGraph) clone() *podcastGraph {
+	c := *g
+	c.Nodes = slices.Clone(c.Nodes)
+	c.Edges = slices.Clone(c.Edges)
+	c.Crawl.Skipped = slices.Clone(c.Crawl.Skipped)
+
+	return &c
+}
+
+// run 按广度优先的顺序查询待展开节目的相关节目，直到没有待展开的节目或失败
+func (j graphJob) run(origin *http.Request) {
+	if !j.seed(origin) {
+		return
+	}
+
+	for {
+		g := j.snapshot()
+
+		pending := g.pending()
+		if len(pending) == 0 {
+			j.complete()
+
+			return
+		}
+
+		n := pending[0]
+
+		data, failed := j.call(origin, "/podcast_related", map[string]any{"pid": n.Pid})
+
+		if failed != nil && retryable(failed.Status) {
+			j.fail(operationError(n.Pid, "/podcast_related", failed).Error)
+
+			return
+		}
+
+		j.update(func(g *podcastGraph) {
+			if failed != nil {
+				g.Crawl.Skipped = append(g.Crawl.Skipped, n.Pid)
+
+				return
+			}
+
+			items, _ := data["data"].([]any)
+
+			list := make([]related.Node, 0, len(items))
+			for _, item := range items {
+				if p := directoryPodcast(item); p.Pid != "" {
+					list = append(list, related.Node{Pid: p.Pid, Title: p.Title, Author: p.Author, Subscriptions: p.Subscriptions})
+				}
+			}
+
+			g.Expand(n.Pid, list, g.Crawl.MaxNodes)
+		})
+
+		j.checkpoint()
+	}
+}
+
+// seed 起点还没有标题时查询节目详情
+func (j graphJob) seed(origin *http.Request) bool {
+	g := j.snapshot()
+	if g.Nodes[0].Title != "" {
+		return true
+	}
+
+	data, failed := j.call(origin, "/podcast_detail", map[string]any{"pid": g.Pid})
+
+	if failed != nil {
+		j.fail(operationError(g.Pid, "/podcast_detail", failed).Error)
+
+		return false
+	}
+
+	p := directoryPodcast(data["data"])
+
+	j.update(func(g *podcastGraph) {
+		g.Nodes[0].Title, g.Nodes[0].Author, g.Nodes[0].Subscriptions = p.Title, p.Author, p.Subscriptions
+	})
+
+	return true
+}
@@ -0,0 +1,604 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/graphql"
+	"github.com/ultrazg/xyz/shownotes"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// GraphQL 查询的限制，分页的 first 不能超过上游一页的数量
+const (
+	graphqlMaxDepth   = 10
+	graphqlMaxCost    = 500
+	graphqlPageSize   = 20
+	graphqlLoaderWait = 2 * time.Millisecond
+)
+
+type GraphQLRequestBody struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// GraphQL 执行 GraphQL 查询，响应为标准的 {data, errors} 格式。查询不合法时返回 400，字段的错误在 errors 中返回
+var GraphQL = func(ctx *gin.Context) {
+	var params GraphQLRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	c := context.WithValue(ctx.Request.Context(), graphqlContextKey{}, newGraphQLContext(ctx.Request))
+
+	result := graphqlSchema().Execute(c, graphql.Params{
+		Query:         params.Query,
+		OperationName: params.OperationName,
+		Variables:     params.Variables,
+		MaxDepth:      graphqlMaxDepth,
+		MaxCost:       graphqlMaxCost,
+	})
+
+	status := http.StatusOK
+	if result.Data == nil {
+		status = http.StatusBadRequest
+	}
+
+	ctx.JSON(status, result)
+}
+
+// GraphQLSchema 以 SDL 格式返回 schema
+var GraphQLSchema = func(ctx *gin.Context) {
+	ctx.String(http.StatusOK, graphqlSchema().SDL())
+}
+
+type graphqlContextKey struct{}
+
+// graphqlContext 一次 GraphQL 请求的状态，单集和节目的加载在请求内合并、去重
+type graphqlContext struct {
+	request  *http.Request
+	workers  chan struct{}
+	episodes *graphql.Loader[string, map[string]any]
+	podcasts *graphql.Loader[string, map[string]any]
+}
+
+func newGraphQLContext(request *http.Request) *graphqlContext {
+	c := &graphqlContext{request: request, workers: make(chan struct{}, batchWorkers)}
+
+	c.episodes = graphql.NewLoader(c.loadAll("/episode_detail", "eid"), graphqlLoaderWait, 0)
+	c.podcasts = graphql.NewLoader(c.loadAll("/podcast_detail", "pid"), graphqlLoaderWait, 0)
+
+	return c
+}
+
+func fromContext(ctx context.Context) *graphqlContext {
+	return ctx.Value(graphqlContextKey{}).(*graphqlContext)
+}
+
+// graphqlError 调用接口失败时的错误，状态码和错误码放在 extensions 中
+type graphqlError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+func (e *graphqlError) Extensions() map[string]any {
+	return map[string]any{"status": e.status, "code": e.code}
+}
+
+// call 使用调用方的请求头调用一个原有接口，返回上游的响应体
+func (c *graphqlContext) call(path string, body map[string]any) (map[string]any, error) {
+	c.workers <- struct{}{}
+	defer func() { <-c.workers }()
+
+	data, failed := callOperation(c.request, path, body)
+	if failed != nil {
+		e := &graphqlError{status: failed.Status, code: utils.ErrInternal, message: utils.GetMsg(failed.Status)}
+
+		response, _ := failed.Body.(map[string]any)
+		if detail, ok := response["error"].(map[string]any); ok {
+			if code, ok := detail["code"].(string); ok {
+				e.code = code
+			}
+
+			if message, ok := detail["message"].(string); ok && message != "" {
+				e.message = fmt.Sprintf("%s: %s", path, message)
+			}
+		}
+
+		return nil, e
+	}
+
+	return data, nil
+}
+
+// loadAll Loader 的 BatchFunc，上游没有批量查询的接口，同一批次的 key 并发请求
+func (c *graphqlContext) loadAll(path, key string) graphql.BatchFunc[string, map[string]any] {
+	return func(ctx context.Context, keys []string) ([]map[string]any, []error) {
+		values := make([]map[string]any, len(keys))
+		errs := make([]error, len(keys))
+
+		var wg sync.WaitGroup
+		for i, k := range keys {
+			wg.Add(1)
+
+			go func(i int, k string) {
+				defer wg.Done()
+
+				data, err := c.call(path, map[string]any{key: k})
+				if err != nil {
+					errs[i] = err
+
+					return
+				}
+
+				values[i], _ = data["data"].(map[string]any)
+			}(i, k)
+		}
+
+		wg.Wait()
+
+		return values, errs
+	}
+}
+
+// list 调用返回列表的接口，返回上游响应中的 data
+func (c *graphqlContext) list(path string, body map[string]any) (any, error) {
+	data, err := c.call(path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return data["data"], nil
+}
+
+// page 调用分页接口，first 和 after 转换为上游的 loadMoreKey
+func (c *graphqlContext) page(path string, body map[string]any, args map[string]any) (map[string]any, int, error) {
+	first, _ := args["first"].(int)
+	if first < 1 || first > graphqlPageSize {
+		return nil, 0, fmt.Errorf("first must be between 1 and %d", graphqlPageSize)
+	}
+
+	if after, _ := args["after"].(string); after != "" {
+		loadMoreKey, err := utils.DecodeCursor(after)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		body["loadMoreKey"] = loadMoreKey
+	}
+
+	data, err := c.call(path, body)
+
+	return data, first, err
+}
+
+// connection 将上游的一页转换为 relay 风格的 connection。first 小于一页的数量时截断，截断处的游标由 cursorOf 根据最后一项生成
+func connection(data map[string]any, first int, node func(item map[string]any) any, cursorOf func(i int, item map[string]any) any) map[string]any {
+	items, _ := data["data"].([]any)
+	loadMoreKey := data["loadMoreKey"]
+
+	truncated := len(items) > first
+	if truncated {
+		items = items[:first]
+	}
+
+	edges := make([]any, 0, len(items))
+	nodes := make([]any, 0, len(items))
+
+	var endCursor any
+	for i, item := range items {
+		m, _ := item.(map[string]any)
+
+		cursor := utils.EncodeCursor(cursorOf(i, m))
+		if i == len(items)-1 && !truncated && loadMoreKey != nil {
+			cursor = utils.EncodeCursor(loadMoreKey)
+		}
+
+		edges = append(edges, map[string]any{"cursor": cursor, "node": node(m)})
+		nodes = append(nodes, node(m))
+		endCursor = cursor
+	}
+
+	return map[string]any{
+		"edges": edges,
+		"nodes": nodes,
+		"pageInfo": map[string]any{
+			"hasNextPage": truncated || loadMoreKey != nil,
+			"endCursor":   endCursor,
+		},
+	}
+}
+
+func itself(item map[string]any) any {
+	return item
+}
+
+// nested 读取嵌套的字段，如 nested(m, "enclosure", "url")
+func nested(m map[string]any, keys ...string) any {
+	var value any = m
+
+	for _, key := range keys {
+		current, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value = current[key]
+	}
+
+	return value
+}
+
+func source(p graphql.ResolveParams) map[string]any {
+	m, _ := p.Source.(map[string]any)
+
+	return m
+}
+
+func nonNull(t graphql.Type) graphql.Type {
+	return &graphql.NonNull{OfType: t}
+}
+
+func listOf(t graphql.Type) graphql.Type {
+	return &graphql.NonNull{OfType: &graphql.List{OfType: &graphql.NonNull{OfType: t}}}
+}
+
+// pageArgs 分页参数
+func pageArgs(extra ...*graphql.Arg) []*graphql.Arg {
+	return append([]*graphql.Arg{
+		{Name: "first", Type: graphql.Int, Default: graphqlPageSize, Description: fmt.Sprintf("返回的数量，1 到 %d", graphqlPageSize)},
+		{Name: "after", Type: graphql.String, Description: "上一页的 endCursor 或某一项的 cursor"},
+	}, extra...)
+}
+
+// connectionOf 创建 XConnection 和 XEdge 类型
+func connectionOf(node *graphql.Object, pageInfo *graphql.Object) *graphql.Object {
+	edge := &graphql.Object{
+		Name: node.Name + "Edge",
+		Fields: []*graphql.Field{
+			{Name: "cursor", Type: nonNull(graphql.String), Description: "可作为 after 参数从该项之后继续查询"},
+			{Name: "node", Type: nonNull(node)},
+		},
+	}
+
+	return &graphql.Object{
+		Name: node.Name + "Connection",
+		Fields: []*graphql.Field{
+			{Name: "edges", Type: listOf(edge)},
+			{Name: "nodes", Type: listOf(node)},
+			{Name: "pageInfo", Type: nonNull(pageInfo)},
+		},
+	}
+}
+
+func stringFields(names ...string) []*graphql.Field {
+	fields := make([]*graphql.Field, len(names))
+	for i, name := range names {
+		fields[i] = &graphql.Field{Name: name, Type: graphql.String}
+	}
+
+	return fields
+}
+
+func intFields(names ...string) []*graphql.Field {
+	fields := make([]*graphql.Field, len(names))
+	for i, name := range names {
+		fields[i] = &graphql.Field{Name: name, Type: graphql.Int}
+	}
+
+	return fields
+}
+
+// graphqlSchema Podcast、Episode、User、Comment、Clap、Chapter、Sticker、Category 的 schema，resolver 调用原有接口
+var graphqlSchema = sync.OnceValue(func() *graphql.Schema {
+	episodeOrder := &graphql.Enum{Name: "EpisodeOrder", Description: "单集排序，NEWEST 为从新到旧", Values: []string{"NEWEST", "OLDEST"}}
+	commentOrder := &graphql.Enum{Name: "CommentOrder", Description: "评论排序", Values: []string{"HOT", "TIME", "TIMESTAMP"}}
+	replyOrder := &graphql.Enum{Name: "ReplyOrder", Description: "回复排序", Values: []string{"SMART", "TIME"}}
+
+	image := &graphql.Object{Name: "Image", Fields: stringFields("picUrl", "largePicUrl", "middlePicUrl", "smallPicUrl", "thumbnailUrl")}
+
+	pageInfo := &graphql.Object{
+		Name: "PageInfo",
+		Fields: []*graphql.Field{
+			{Name: "hasNextPage", Type: nonNull(graphql.Boolean)},
+			{Name: "endCursor", Type: graphql.String},
+		},
+	}
+
+	podcast := &graphql.Object{Name: "Podcast", Description: "节目"}
+	episode := &graphql.Object{Name: "Episode", Description: "单集"}
+	user := &graphql.Object{Name: "User", Description: "用户"}
+	comment := &graphql.Object{Name: "Comment", Description: "评论"}
+	category := &graphql.Object{Name: "Category", Description: "分类"}
+
+	clap := &graphql.Object{
+		Name:        "Clap",
+		Description: "精彩时间点，时间线按单集时长等分为若干段",
+		Fields: []*graphql.Field{
+			{Name: "index", Type: nonNull(graphql.Int), Description: "段的下标"},
+			{Name: "timestamp", Type: nonNull(graphql.Int), Description: "段的开始时间，单位为秒"},
+			{Name: "count", Type: nonNull(graphql.Int), Description: "标记数"},
+			{Name: "mine", Type: nonNull(graphql.Int), Description: "当前用户的标记数"},
+		},
+	}
+
+	chapter := &graphql.Object{
+		Name:        "Chapter",
+		Description: "从 shownotes 的时间线中提取的章节",
+		Fields: []*graphql.Field{
+			{Name: "start", Type: nonNull(graphql.Int), Description: "开始时间，单位为秒"},
+			{Name: "end", Type: nonNull(graphql.Int), Description: "结束时间，单位为秒"},
+			{Name: "title", Type: nonNull(graphql.String)},
+		},
+	}
+
+	sticker := &graphql.Object{
+		Name:        "Sticker",
+		Description: "贴纸",
+		Fields: append([]*graphql.Field{
+			{Name: "id", Type: nonNull(graphql.ID)},
+			{Name: "image", Type: image},
+		}, stringFields("name", "description", "issuer", "number", "ownedAt")...),
+	}
+
+	categoryTab := &graphql.Object{
+		Name:        "CategoryTab",
+		Description: "分类下的标签",
+		Fields: []*graphql.Field{
+			{Name: "id", Type: nonNull(graphql.ID)},
+			{Name: "name", Type: graphql.String},
+		},
+	}
+
+	episodeConnection := connectionOf(episode, pageInfo)
+	commentConnection := connectionOf(comment, pageInfo)
+	podcastConnection := connectionOf(podcast, pageInfo)
+
+	podcast.Fields = append(append([]*graphql.Field{
+		{Name: "pid", Type: nonNull(graphql.ID)},
+		{Name: "image", Type: image},
+		{Name: "podcasters", Type: listOf(user), Resolve: func(p graphql.ResolveParams) (any, error) {
+			if podcasters, ok := source(p)["podcasters"].([]any); ok {
+				return podcasters, nil
+			}
+
+			return []any{}, nil
+		}, Cost: -1},
+	}, stringFields("title", "author", "brief", "description", "latestEpisodePubDate", "subscriptionStatus")...), append(intFields("subscriptionCount", "episodeCount"),
+		&graphql.Field{Name: "episodes", Type: nonNull(episodeConnection), Args: pageArgs(
+			&graphql.Arg{Name: "order", Type: episodeOrder, Default: "NEWEST"},
+		), Resolve: func(p graphql.ResolveParams) (any, error) {
+			order := "desc"
+			if p.Args["order"] == "OLDEST" {
+				order = "asc"
+			}
+
+			data, first, err := fromContext(p.Context).page("/episode_list", map[string]any{"pid": source(p)["pid"], "order": order}, p.Args)
+			if err != nil {
+				return nil, err
+			}
+
+			return connection(data, first, itself, func(i int, item map[string]any) any {
+				return map[string]any{"pubDate": item["pubDate"], "id": item["eid"], "direction": "NEXT"}
+			}), nil
+		}},
+		&graphql.Field{Name: "popularEpisodes", Type: listOf(episode), Description: "节目内「最受欢迎」的单集", Resolve: func(p graphql.ResolveParams) (any, error) {
+			return fromContext(p.Context).list("/episode_list_by_filter", map[string]any{"pid": source(p)["pid"]})
+		}},
+		&graphql.Field{Name: "related", Type: listOf(podcast), Description: "相关节目", Resolve: func(p graphql.ResolveParams) (any, error) {
+			return fromContext(p.Context).list("/podcast_related", map[string]any{"pid": source(p)["pid"]})
+		}},
+	)...)
+
+	episode.Fields = append(append([]*graphql.Field{
+		{Name: "eid", Type: nonNull(graphql.ID)},
+		{Name: "pid", Type: graphql.ID},
+		{Name: "isFavorited", Type: graphql.Boolean},
+		{Name: "image", Type: image},
+		{Name: "audioUrl", Type: graphql.String, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return nested(source(p), "enclosure", "url"), nil
+		}, Cost: -1},
+	}, stringFields("title", "description", "shownotes", "pubDate")...), append(intFields("duration", "playCount", "clapCount", "commentCount", "favoriteCount"),
+		&graphql.Field{Name: "podcast", Type: podcast, Description: "所属节目，同一次查询中的节目合并加载", Resolve: func(p graphql.ResolveParams) (any, error) {
+			pid, _ := source(p)["pid"].(string)
+			if pid == "" {
+				pid, _ = nested(source(p), "podcast", "pid").(string)
+			}
+
+			if pid == "" {
+				return nil, nil
+			}
+
+			return fromContext(p.Context).podcasts.Load(p.Context, pid)
+		}},
+		&graphql.Field{Name: "comments", Type: nonNull(commentConnection), Args: pageArgs(
+			&graphql.Arg{Name: "order", Type: commentOrder, Default: "HOT"},
+		), Resolve: func(p graphql.ResolveParams) (any, error) {
+			data, first, err := fromContext(p.Context).page("/comment_primary", map[string]any{"id": source(p)["eid"], "order": p.Args["order"]}, p.Args)
+			if err != nil {
+				return nil, err
+			}
+
+			return connection(data, first, itself, func(i int, item map[string]any) any {
+				key := map[string]any{"id": item["id"], "direction": "NEXT"}
+				if score, found := item["hotSortScore"]; found {
+					key["hotSortScore"] = score
+				}
+
+				return key
+			}), nil
+		}},
+		&graphql.Field{Name: "claps", Type: listOf(clap), Resolve: func(p graphql.ResolveParams) (any, error) {
+			duration, _ := source(p)["duration"].(float64)
+			if duration <= 0 {
+				return nil, fmt.Errorf("episode has no duration")
+			}
+
+			data, err := fromContext(p.Context).call("/episode_clap", map[string]any{"eid": source(p)["eid"], "duration": int(duration)})
+			if err != nil {
+				return nil, err
+			}
+
+			return clapSegments(data, int(duration)), nil
+		}},
+		&graphql.Field{Name: "chapters", Type: listOf(chapter), Description: "从 shownotes 的时间线中提取的章节，没有时间线时为空列表", Resolve: func(p graphql.ResolveParams) (any, error) {
+			notes, _ := source(p)["shownotes"].(string)
+			duration, _ := source(p)["duration"].(float64)
+
+			return chapterList(shownotes.Chapters(notes, int(duration))), nil
+		}, Cost: -1},
+	)...)
+
+	comment.Fields = append(append([]*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "liked", Type: graphql.Boolean},
+		{Name: "collected", Type: graphql.Boolean},
+		{Name: "author", Type: user},
+		{Name: "replyCount", Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if count, found := source(p)["threadReplyCount"]; found {
+				return count, nil
+			}
+
+			return source(p)["replyCount"], nil
+		}, Cost: -1},
+	}, stringFields("text", "createdAt")...), append(intFields("likeCount"),
+		&graphql.Field{Name: "replies", Type: listOf(comment), Args: []*graphql.Arg{
+			{Name: "order", Type: replyOrder, Default: "SMART"},
+		}, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return fromContext(p.Context).list("/comment_thread", map[string]any{"primaryCommentId": source(p)["id"], "order": p.Args["order"]})
+		}},
+	)...)
+
+	user.Fields = append(append([]*graphql.Field{
+		{Name: "uid", Type: nonNull(graphql.ID)},
+		{Name: "avatar", Type: image, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return nested(source(p), "avatar", "picture"), nil
+		}, Cost: -1},
+	}, stringFields("nickname", "bio", "gender", "ipLoc", "relation")...),
+		&graphql.Field{Name: "stickers", Type: listOf(sticker), Description: "已获得的贴纸", Resolve: func(p graphql.ResolveParams) (any, error) {
+			return fromContext(p.Context).list("/sticker", map[string]any{"uid": source(p)["uid"]})
+		}},
+		&graphql.Field{Name: "podcasts", Type: listOf(podcast), Description: "创建的节目", Resolve: func(p graphql.ResolveParams) (any, error) {
+			return fromContext(p.Context).list("/owned_podcasts", map[string]any{"uid": source(p)["uid"]})
+		}},
+	)
+
+	category.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "name", Type: graphql.String},
+		{Name: "emoji", Type: graphql.String},
+		{Name: "icon", Type: image},
+		{Name: "tabs", Type: listOf(categoryTab), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return fromContext(p.Context).list("/category_list_tab", map[string]any{"categoryId": source(p)["id"]})
+		}},
+		{Name: "podcasts", Type: nonNull(podcastConnection), Args: pageArgs(
+			&graphql.Arg{Name: "tab", Type: graphql.String, Default: "ALL", Description: "标签的 id，见 tabs"},
+		), Resolve: func(p graphql.ResolveParams) (any, error) {
+			data, first, err := fromContext(p.Context).page("/category_podcast_list", map[string]any{"categoryId": source(p)["id"], "tab": p.Args["tab"]}, p.Args)
+			if err != nil {
+				return nil, err
+			}
+
+			// 分类下的节目使用偏移量分页
+			offset := 0
+			if after, _ := p.Args["after"].(string); after != "" {
+				loadMoreKey, _ := utils.DecodeCursor(after)
+				if n, ok := loadMoreKey.(float64); ok {
+					offset = int(n)
+				}
+			}
+
+			return connection(data, first, func(item map[string]any) any {
+				return item["podcast"]
+			}, func(i int, item map[string]any) any {
+				return offset + i + 1
+			}), nil
+		}},
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.Field{
+			{Name: "podcast", Type: podcast, Args: []*graphql.Arg{{Name: "pid", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return fromContext(p.Context).podcasts.Load(p.Context, p.Args["pid"].(string))
+			}},
+			{Name: "episode", Type: episode, Args: []*graphql.Arg{{Name: "eid", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return fromContext(p.Context).episodes.Load(p.Context, p.Args["eid"].(string))
+			}},
+			{Name: "user", Type: user, Args: []*graphql.Arg{{Name: "uid", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return fromContext(p.Context).list("/get_profile", map[string]any{"uid": p.Args["uid"]})
+			}},
+			{Name: "me", Type: user, Description: "当前登录的用户", Resolve: func(p graphql.ResolveParams) (any, error) {
+				return fromContext(p.Context).list("/profile", map[string]any{})
+			}},
+			{Name: "categories", Type: listOf(category), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return fromContext(p.Context).list("/category_list", map[string]any{})
+			}},
+			{Name: "category", Type: category, Args: []*graphql.Arg{{Name: "id", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.ResolveParams) (any, error) {
+				categories, err := fromContext(p.Context).list("/category_list", map[string]any{})
+				if err != nil {
+					return nil, err
+				}
+
+				items, _ := categories.([]any)
+				for _, item := range items {
+					if m, ok := item.(map[string]any); ok && m["id"] == p.Args["id"] {
+						return m, nil
+					}
+				}
+
+				return nil, nil
+			}},
+		},
+	}
+
+	schema, err := graphql.NewSchema(query)
+	if err != nil {
+		panic(err)
+	}
+
+	return schema
+})
+
+// chapterList 将章节转换为 Chapter 列表
+func chapterList(chapters []shownotes.Chapter) []any {
+	result := make([]any, len(chapters))
+	for i, chapter := range chapters {
+		result[i] = map[string]any{"start": chapter.Start, "end": chapter.End, "title": chapter.Title}
+	}
+
+	return result
+}
+
+// clapSegments 将 /episode_clap 的返回转换为 Clap 列表
+func clapSegments(data map[string]any, duration int) []any {
+	counts, mine := clapCounts(data)
+
+	mineCounts := map[int]int{}
+	for _, index := range mine {
+		mineCounts[index]++
+	}
+
+	result := make([]any, len(counts))
+	for i, count := range counts {
+		result[i] = map[string]any{
+			"index":     i,
+			"timestamp": i * duration / len(counts),
+			"count":     count,
+			"mine":      mineCounts[i],
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,552 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/history"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// podcastWatchBucket 关注的节目，key 为 pid/账号，同一节目可以被多个账号关注
+	podcastWatchBucket = "podcast_watch"
+	// podcastHistoryBucket 节目元数据的版本，key 为 pid
+	podcastHistoryBucket = "podcast_history"
+	// podcastWatchTick 检查是否有节目需要获取快照的间隔，获取快照的间隔由 -watch-interval 指定
+	podcastWatchTick = time.Minute
+	// podcastHistoryKeep 每个节目最多保留的版本数
+	podcastHistoryKeep = 200
+	// podcastHistoryDefaultLimit 默认返回的变更记录数
+	podcastHistoryDefaultLimit = 50
+	// podcastWebhookTimeout 发送变更事件的超时时间
+	podcastWebhookTimeout = 10 * time.Second
+)
+
+// PodcastChangedEvent 节目元数据变化时发送到 webhook 的事件类型
+const PodcastChangedEvent = "podcast.changed"
+
+var (
+	// podcastWatchLocks 同一节目的快照依次获取，key 为 pid
+	podcastWatchLocks    sync.Map
+	podcastWebhookClient = &http.Client{Timeout: podcastWebhookTimeout}
+)
+
+// podcastWatch 一个账号关注的节目，Auth 为该账号最近一次关注时的认证信息，定时获取快照时使用
+type podcastWatch struct {
+	Owner     string     `json:"owner"`
+	Pid       string     `json:"pid"`
+	Webhook   string     `json:"webhook,omitempty"`
+	Auth      replayAuth `json:"auth"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LastError any        `json:"lastError,omitempty"`
+}
+
+type PodcastWatchRequestBody struct {
+	Pid     string `json:"pid" form:"pid" binding:"required,xyzid"`
+	Mode    string `json:"mode" form:"mode" binding:"required,oneof=ON OFF"`    // ON 为关注并立即获取一次快照，OFF 为取消关注，已保存的版本保留
+	Webhook string `json:"webhook" form:"webhook" binding:"omitempty,http_url"` // 元数据变化时以 POST 发送事件的地址
+}
+
+// PodcastWatchData 关注的节目和最新的版本
+type PodcastWatchData struct {
+	Pid       string           `json:"pid"`
+	Title     string           `json:"title"`
+	Watching  bool             `json:"watching"` // 当前账号是否关注
+	Webhook   string           `json:"webhook,omitempty"`
+	CreatedAt *time.Time       `json:"createdAt,omitempty"`
+	CheckedAt *time.Time       `json:"checkedAt,omitempty"`
+	Versions  int              `json:"versions"`
+	Latest    *history.Version `json:"latest,omitempty"`
+	LastError any              `json:"lastError,omitempty"`
+}
+
+// PodcastWatch 关注节目的元数据变化：立即获取一次节目详情、主体信息和公告的快照，之后按 -watch-interval 定时获取，
+// 与上一个版本不同时保存为新的版本，并向关注该节目的所有账号的 webhook 发送变更事件。再次关注时更新 webhook 并立即获取快照。
+// 关注按账号区分，取消关注只影响当前账号
+var PodcastWatch = func(ctx *gin.Context) {
+	var params PodcastWatchRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	owner := ownerOf(ctx)
+
+	if params.Mode == "OFF" {
+		if err := utils.StoreDelete(podcastWatchBucket, podcastWatchKey(params.Pid, owner)); err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		returnPodcastWatch(ctx, owner, params.Pid)
+
+		return
+	}
+
+	watch := &podcastWatch{Owner: owner, Pid: params.Pid, Webhook: params.Webhook, CreatedAt: time.Now()}
+	if saved, err := loadPodcastWatch(params.Pid, owner); err == nil {
+		watch.CreatedAt = saved.CreatedAt
+	}
+
+	watch.Auth = replayAuthOf(ctx)
+
+	if _, failed := checkPodcast(watch, time.Now()); failed != nil {
+		ctx.JSON(failed.Status, failed.Body)
+
+		return
+	}
+
+	returnPodcastWatch(ctx, owner, params.Pid)
+}
+
+// PodcastWatchList 当前账号关注的节目，按关注时间排列
+var PodcastWatchList = func(ctx *gin.Context) {
+	watches, err := loadPodcastWatches("", ownerOf(ctx))
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	list := []PodcastWatchData{}
+	for _, watch := range watches {
+		data, err := podcastWatchData(watch.Owner, watch.Pid)
+		if err != nil {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		list = append(list, *data)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(*list[j].CreatedAt)
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": list,
+	})
+}
+
+type PodcastHistoryRequestBody struct {
+	Pid    string `json:"pid" form:"pid" binding:"required,xyzid"`
+	Field  string `json:"field" form:"field"`                                   // 只返回该字段的变化，如 title、bulletin
+	Limit  int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=200"` // 默认为 50
+	Offset int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+// PodcastHistoryData 节目当前的元数据和从新到旧的变更记录
+type PodcastHistoryData struct {
+	Pid       string            `json:"pid"`
+	Title     string            `json:"title"`
+	Watching  bool              `json:"watching"`
+	CheckedAt time.Time         `json:"checkedAt"`
+	Checks    int               `json:"checks"`
+	Versions  int               `json:"versions"`
+	Current   map[string]string `json:"current"`
+	Total     int               `json:"total"`
+	Changelog []history.Entry   `json:"changelog"`
+}
+
+// PodcastHistory 节目元数据的变更记录，支持 GET /podcast_history?pid= 和 POST。不需要认证信息，带上时 watching 为当前账号是否关注
+var PodcastHistory = func(ctx *gin.Context) {
+	var params PodcastHistoryRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	h, err := loadPodcastHistory(params.Pid)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	latest := h.Latest()
+	if latest == nil {
+		utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, "no history for "+params.Pid+", call /podcast_watch first"))
+
+		return
+	}
+
+	_, err = loadPodcastWatch(params.Pid, ownerOf(ctx))
+	watching := err == nil
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = podcastHistoryDefaultLimit
+	}
+
+	changelog := h.Changelog(params.Field)
+	page := changelog[min(params.Offset, len(changelog)):min(params.Offset+limit, len(changelog))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": PodcastHistoryData{
+			Pid:       h.Pid,
+			Title:     latest.Fields["title"],
+			Watching:  watching,
+			CheckedAt: h.CheckedAt,
+			Checks:    h.Checks,
+			Versions:  len(h.Versions),
+			Current:   latest.Fields,
+			Total:     len(changelog),
+			Changelog: page,
+		},
+	})
+}
+
+// StartPodcastWatch 在后台定期获取关注节目的快照
+func StartPodcastWatch() {
+	go func() {
+		ticker := time.NewTicker(podcastWatchTick)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			CheckPodcasts(time.Now())
+		}
+	}()
+}
+
+// CheckPodcasts 获取距离上一次快照超过 -watch-interval 的关注节目的快照。同一节目只获取一次，
+// 依次使用关注该节目的账号的认证信息，直到获取成功
+func CheckPodcasts(now time.Time) {
+	watches, err := loadPodcastWatches("", "")
+	if err != nil {
+		log.Printf("podcast watch: %v", err)
+
+		return
+	}
+
+	checked := map[string]bool{}
+
+	for _, watch := range watches {
+		if checked[watch.Pid] {
+			continue
+		}
+
+		h, err := loadPodcastHistory(watch.Pid)
+		if err != nil {
+			log.Printf("podcast watch %s: %v", watch.Pid, err)
+
+			continue
+		}
+
+		if now.Sub(h.CheckedAt) < utils.WatchInterval {
+			continue
+		}
+
+		if _, failed := checkPodcast(watch, now); failed != nil {
+			log.Printf("podcast watch %s (%s): status %d", watch.Pid, watch.Owner, failed.Status)
+
+			continue
+		}
+
+		checked[watch.Pid] = true
+	}
+}
+
+// checkPodcast 以 watch 的认证信息获取节目的快照并保存 watch，有变化时向关注该节目的所有账号的 webhook 发送变更事件，返回新的版本
+func checkPodcast(watch *podcastWatch, now time.Time) (*history.Version, *BatchResult) {
+	lock, _ := podcastWatchLocks.LoadOrStore(watch.Pid, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	fields, failed := podcastSnapshot(watch)
+
+	watch.LastError = nil
+	if failed != nil {
+		watch.LastError = operationError(watch.Pid, "/podcast_detail", failed).Error
+	}
+
+	var version *history.Version
+
+	if failed == nil {
+		h, err := loadPodcastHistory(watch.Pid)
+		if err != nil {
+			return nil, internalError(err)
+		}
+
+		version = h.Record(fields, now, podcastHistoryKeep)
+
+		if err := utils.StorePut(podcastHistoryBucket, watch.Pid, h); err != nil {
+			return nil, internalError(err)
+		}
+
+		// 第一个版本没有变化，不发送事件
+		if version != nil && len(version.Changes) > 0 {
+			if err := notifyPodcastWatches(watch, version); err != nil {
+				return nil, internalError(err)
+			}
+		}
+	}
+
+	if err := utils.StorePut(podcastWatchBucket, podcastWatchKey(watch.Pid, watch.Owner), watch); err != nil {
+		return nil, internalError(err)
+	}
+
+	return version, failed
+}
+
+// notifyPodcastWatches 向关注该节目的所有账号的 webhook 发送变更事件，发送失败的原因记录在各自的 LastError 中。
+// watch 由调用方保存，其它账号的关注在这里保存
+func notifyPodcastWatches(watch *podcastWatch, version *history.Version) error {
+	watches, err := loadPodcastWatches(watch.Pid, "")
+	if err != nil {
+		return err
+	}
+
+	for _, w := range watches {
+		if w.Owner == watch.Owner {
+			w = watch
+		}
+
+		if w.Webhook == "" {
+			continue
+		}
+
+		w.LastError = nil
+		if err := sendPodcastEvent(w.Webhook, w.Pid, version); err != nil {
+			w.LastError = utils.NewError(http.StatusBadGateway, utils.ErrUpstreamUnavailable, "webhook: "+err.Error())
+		}
+
+		if w == watch {
+			continue
+		}
+
+		if err := utils.StorePut(podcastWatchBucket, podcastWatchKey(w.Pid, w.Owner), w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// podcastSnapshot 并发获取节目详情、主体信息和公告，取出需要跟踪的字段。
+// 节目详情失败或其它请求可以重试时返回错误；没有公告等无法重试的错误时忽略对应的字段
+func podcastSnapshot(watch *podcastWatch) (map[string]string, *BatchResult) {
+	origin, err := watch.Auth.request("/podcast_watch")
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	body := map[string]any{"pid": watch.Pid}
+	results := runBatch(origin, []BatchOperation{
+		{Id: "detail", Path: "/podcast_detail", Body: body},
+		{Id: "info", Path: "/podcast_get_info", Body: body},
+		{Id: "bulletin", Path: "/podcast_bulletin", Body: body},
+	}, make([][]int, 3))
+
+	sections := make([]map[string]any, len(results))
+	for i, result := range results {
+		if !succeeded(result) {
+			if i == 0 || retryable(result.Status) {
+				return nil, result
+			}
+
+			continue
+		}
+
+		response, _ := result.Body.(map[string]any)
+		sections[i], _ = nested(response, "data", "data").(map[string]any)
+	}
+
+	return podcastFields(sections[0], sections[1], sections[2]), nil
+}
+
+// podcastFields 需要跟踪的字段，值为空的字段不保存
+func podcastFields(detail, info, bulletin map[string]any) map[string]string {
+	fields := map[string]string{}
+
+	set := func(name string, value any) {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = strings.TrimSpace(v)
+		case bool, float64:
+			s = fmt.Sprint(v)
+		}
+
+		if s != "" {
+			fields[name] = s
+		}
+	}
+
+	for _, name := range []string{"title", "author", "brief", "description"} {
+		set(name, detail[name])
+	}
+
+	set("image", nested(detail, "image", "picUrl"))
+
+	// 主播按 uid 排列，避免顺序变化被记录为修改
+	var hosts []string
+	podcasters, _ := detail["podcasters"].([]any)
+	for _, item := range podcasters {
+		podcaster, _ := item.(map[string]any)
+		uid, _ := podcaster["uid"].(string)
+		nickname, _ := podcaster["nickname"].(string)
+		hosts = append(hosts, uid+"\x00"+nickname)
+	}
+
+	sort.Strings(hosts)
+	for i, host := range hosts {
+		uid, nickname, _ := strings.Cut(host, "\x00")
+		hosts[i] = fmt.Sprintf("%s (%s)", nickname, uid)
+	}
+
+	set("hosts", strings.Join(hosts, ", "))
+
+	set("subject", info["subject"])
+	set("ipLoc", info["ipLoc"])
+	set("category", nested(info, "category", "name"))
+
+	set("bulletin", bulletin["content"])
+	set("bulletinCreatedAt", bulletin["createdAt"])
+	if bulletin["content"] != nil {
+		set("bulletinOffline", bulletin["offline"])
+	}
+
+	return fields
+}
+
+// sendPodcastEvent 向 webhook 发送变更事件
+func sendPodcastEvent(webhook, pid string, version *history.Version) error {
+	event, err := json.Marshal(gin.H{
+		"event":   PodcastChangedEvent,
+		"pid":     pid,
+		"title":   version.Fields["title"],
+		"version": version.Version,
+		"at":      version.At,
+		"changes": version.Changes,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := podcastWebhookClient.Post(webhook, "application/json", bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func returnPodcastWatch(ctx *gin.Context, owner, pid string) {
+	data, err := podcastWatchData(owner, pid)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+// podcastWatchData owner 关注的状态和最新的版本，不返回保存的认证信息
+func podcastWatchData(owner, pid string) (*PodcastWatchData, error) {
+	h, err := loadPodcastHistory(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &PodcastWatchData{Pid: pid, Versions: len(h.Versions), Latest: h.Latest()}
+	if data.Latest != nil {
+		data.Title = data.Latest.Fields["title"]
+	}
+
+	if !h.CheckedAt.IsZero() {
+		data.CheckedAt = &h.CheckedAt
+	}
+
+	watch, err := loadPodcastWatch(pid, owner)
+	if errors.Is(err, utils.ErrStoreNotFound) {
+		return data, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.Watching = true
+	data.Webhook = watch.Webhook
+	data.CreatedAt = &watch.CreatedAt
+	data.LastError = watch.LastError
+
+	return data, nil
+}
+
+// podcastWatchKey 以 pid 开头，可以按节目查找关注的账号；pid 中没有 /，/ 之后为账号
+func podcastWatchKey(pid, owner string) string {
+	return pid + "/" + owner
+}
+
+func loadPodcastWatch(pid, owner string) (*podcastWatch, error) {
+	watch := &podcastWatch{}
+
+	return watch, utils.StoreGet(podcastWatchBucket, podcastWatchKey(pid, owner), watch)
+}
+
+// loadPodcastWatches 关注的节目，pid 或 owner 为空时不按其筛选
+func loadPodcastWatches(pid, owner string) ([]*podcastWatch, error) {
+	prefix := ""
+	if pid != "" {
+		prefix = podcastWatchKey(pid, "")
+	}
+
+	keys, err := utils.StoreKeys(podcastWatchBucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var watches []*podcastWatch
+	for _, key := range keys {
+		if _, keyOwner, _ := strings.Cut(key, "/"); owner != "" && keyOwner != owner {
+			continue
+		}
+
+		watch := &podcastWatch{}
+		if err := utils.StoreGet(podcastWatchBucket, key, watch); err != nil {
+			return nil, err
+		}
+
+		watches = append(watches, watch)
+	}
+
+	return watches, nil
+}
+
+func loadPodcastHistory(pid string) (*history.History, error) {
+	h := &history.History{Pid: pid}
+
+	err := utils.StoreGet(podcastHistoryBucket, pid, h)
+	if err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+		return nil, err
+	}
+
+	return h, nil
+}
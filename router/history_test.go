@@ -0,0 +1,129 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ultrazg/xyz/history"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	testPid      = "5e280fab418a84a0461fa8a0"
+	testOtherPid = "5e280fab418a84a0461fa8a1"
+)
+
+func TestLoadPodcastWatches(t *testing.T) {
+	initTestStore(t)
+
+	for _, watch := range []*podcastWatch{
+		{Owner: "account:alice", Pid: testPid},
+		{Owner: "account:bob", Pid: testPid},
+		{Owner: "account:alice", Pid: testOtherPid},
+		{Owner: "account:alice/bob", Pid: testOtherPid},
+	} {
+		if err := utils.StorePut(podcastWatchBucket, podcastWatchKey(watch.Pid, watch.Owner), watch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		pid   string
+		owner string
+		want  []string
+	}{
+		{"全部", "", "", []string{testPid + "/account:alice", testPid + "/account:bob", testOtherPid + "/account:alice", testOtherPid + "/account:alice/bob"}},
+		{"按节目", testPid, "", []string{testPid + "/account:alice", testPid + "/account:bob"}},
+		{"按账号", "", "account:alice", []string{testPid + "/account:alice", testOtherPid + "/account:alice"}},
+		{"账号名包含 /", "", "account:alice/bob", []string{testOtherPid + "/account:alice/bob"}},
+		{"按节目和账号", testOtherPid, "account:bob", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watches, err := loadPodcastWatches(tt.pid, tt.owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, watch := range watches {
+				got = append(got, podcastWatchKey(watch.Pid, watch.Owner))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadPodcastWatches(%q, %q) = %v, want %v", tt.pid, tt.owner, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyPodcastWatches(t *testing.T) {
+	initTestStore(t)
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.URL.Path)
+		mu.Unlock()
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer webhook.Close()
+
+	watches := []*podcastWatch{
+		{Owner: "account:alice", Pid: testPid, Webhook: webhook.URL + "/alice"},
+		{Owner: "account:bob", Pid: testPid, Webhook: webhook.URL + "/broken"},
+		{Owner: "account:carol", Pid: testPid},
+		{Owner: "account:alice", Pid: testOtherPid, Webhook: webhook.URL + "/other"},
+	}
+	for _, watch := range watches {
+		if err := utils.StorePut(podcastWatchBucket, podcastWatchKey(watch.Pid, watch.Owner), watch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	version := &history.Version{Version: 2, At: time.Now(), Fields: map[string]string{"title": "新标题"}, Changes: []history.Change{{Field: "title", Kind: "changed", Before: "旧标题", After: "新标题"}}}
+	if err := notifyPodcastWatches(watches[2], version); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(received)
+	if want := []string{"/alice", "/broken"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("received = %v, want %v", received, want)
+	}
+
+	tests := []struct {
+		name   string
+		owner  string
+		failed bool
+	}{
+		{"发送成功", "account:alice", false},
+		{"发送失败时记录原因", "account:bob", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watch, err := loadPodcastWatch(testPid, tt.owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if failed := watch.LastError != nil; failed != tt.failed {
+				t.Errorf("LastError = %v, want failed = %v", watch.LastError, tt.failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/utils"
+)
+
+var (
+	spec     map[string]any
+	specOnce sync.Once
+
+	pathParam = regexp.MustCompile(`:(\w+)`)
+
+	// 请求上游的接口可以直接传 x-jike-access-token，也可以通过 API Key 或会话 Cookie 选择服务端保存的账号
+	upstreamSecurity = []map[string][]string{
+		{"accessToken": {}},
+		{"apiKey": {}, "account": {}},
+		{"session": {}, "account": {}},
+	}
+)
+
+// OpenAPI 返回根据路由表和请求体类型生成的 OpenAPI 3.1 文档
+var OpenAPI = func(ctx *gin.Context) {
+	specOnce.Do(func() {
+		spec = Spec()
+	})
+
+	ctx.JSON(http.StatusOK, spec)
+}
+
+// Spec 根据 Routes 和 V2Routes 生成 OpenAPI 3.1 文档
+func Spec() map[string]any {
+	schemas := map[string]any{
+		"Response": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code": map[string]any{"type": "integer"},
+				"msg":  map[string]any{"type": "string"},
+				"data": map[string]any{"description": "上游接口返回的原始数据"},
+			},
+		},
+		"ErrorResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code":  map[string]any{"type": "integer"},
+				"msg":   map[string]any{"type": "string"},
+				"error": map[string]any{"$ref": "#/components/schemas/Error"},
+			},
+		},
+		"V2Response": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"data":   map[string]any{"description": "上游接口返回的数据"},
+				"cursor": map[string]any{"type": "string", "description": "下一页游标，没有下一页时不返回"},
+				"meta":   map[string]any{"type": "object", "description": "上游接口返回的其它字段"},
+			},
+		},
+		"V2Error": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"error": map[string]any{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+
+	schemaOf(reflect.TypeOf(utils.Error{}), schemas)
+
+	paths := map[string]any{}
+
+	for _, route := range Routes {
+		operation := map[string]any{
+			"operationId": operationId(route.Method, route.Path),
+			"summary":     route.Summary,
+			"tags":        []string{"v1"},
+			"x-scope":     scopeOf(route, utils.ScopeRead),
+			"responses": map[string]any{
+				"200":     response("Response"),
+				"default": response("ErrorResponse"),
+			},
+		}
+
+		if route.Body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(route.Body), schemas)},
+				},
+			}
+		}
+
+		if route.Auth {
+			operation["security"] = upstreamSecurity
+		} else if route.Session {
+			operation["security"] = []map[string][]string{{"apiKey": {}}, {"session": {}}}
+		}
+
+		addOperation(paths, route.Path, route.Method, operation)
+	}
+
+	for _, route := range V2Routes {
+		path := "/v2" + route.Path
+		var parameters []map[string]any
+
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+
+		for _, name := range route.Query {
+			parameters = append(parameters, map[string]any{
+				"name":   name,
+				"in":     "query",
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+
+		operation := map[string]any{
+			"operationId": operationId(route.Method, path),
+			"summary":     route.Summary,
+			"tags":        []string{"v2"},
+			"x-scope":     scopeOf(route, v2Scope(route)),
+			"security":    upstreamSecurity,
+			"responses": map[string]any{
+				"200":     response("V2Response"),
+				"default": response("V2Error"),
+			},
+		}
+
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		addOperation(paths, path, route.Method, operation)
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "xyz",
+			"description": "小宇宙FM API",
+			"version":     constant.Version,
+		},
+		"tags": []map[string]any{
+			{"name": "v1", "description": "原有的 POST 接口"},
+			{"name": "v2", "description": "REST 风格接口"},
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"accessToken": map[string]any{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "x-jike-access-token",
+				},
+				"apiKey": map[string]any{
+					"type": "apiKey",
+					"in":   "header",
+					"name": utils.ApiKeyHeader,
+				},
+				"session": map[string]any{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": utils.SessionCookie,
+				},
+				"account": map[string]any{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        utils.AccountHeader,
+					"description": "使用服务端保存的账号请求上游，需同时提供 API Key 或会话 Cookie",
+				},
+			},
+		},
+	}
+}
+
+// scopeOf 开启 -auth 时访问接口所需的 API Key 权限
+func scopeOf(route Route, fallback string) string {
+	if route.Scope != "" {
+		return route.Scope
+	}
+
+	return fallback
+}
+
+// v2Scope /v2 的 GET 接口默认需要 read 权限，其它方法默认需要 write 权限
+func v2Scope(route Route) string {
+	if route.Method == http.MethodGet {
+		return utils.ScopeRead
+	}
+
+	return utils.ScopeWrite
+}
+
+func addOperation(paths map[string]any, path, method string, operation map[string]any) {
+	path = pathParam.ReplaceAllString(path, "{$1}")
+
+	item, ok := paths[path].(map[string]any)
+	if !ok {
+		item = map[string]any{}
+		paths[path] = item
+	}
+
+	item[strings.ToLower(method)] = operation
+}
+
+func operationId(method, path string) string {
+	name := strings.NewReplacer("/", "_", ":", "", "-", "_").Replace(strings.Trim(path, "/"))
+
+	return strings.ToLower(method) + "_" + name
+}
+
+func response(schema string) map[string]any {
+	return map[string]any{
+		"description": schema,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/" + schema},
+			},
+		},
+	}
+}
+
+// schemaOf 根据 Go 类型生成 JSON Schema，具名结构体放入 components.schemas 并返回引用
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+
+		if _, ok := schemas[t.Name()]; !ok {
+			// 先占位，避免自引用的类型无限递归
+			schemas[t.Name()] = map[string]any{}
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := utils.FieldName(field)
+		if name == "" {
+			continue
+		}
+
+		schema := schemaOf(field.Type, schemas)
+		if applyRules(schema, field.Tag.Get("binding")) {
+			required = append(required, name)
+		}
+
+		properties[name] = schema
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// applyRules 将 binding 标签中的校验规则写入 schema，返回字段是否必填
+//
+// dive 之后的规则作用于数组元素
+func applyRules(schema map[string]any, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	required := false
+	target := schema
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		numeric := target["type"] == "integer" || target["type"] == "number"
+
+		switch name {
+		case "required":
+			required = true
+			if target["type"] == "string" {
+				target["minLength"] = 1
+			}
+		case "dive":
+			items, ok := target["items"].(map[string]any)
+			if !ok {
+				return required
+			}
+
+			target = items
+		case "oneof":
+			target["enum"] = strings.Fields(param)
+		case "xyzid":
+			target["pattern"] = utils.XyzIdPattern
+		case "numeric":
+			target["pattern"] = `^[0-9]+$`
+		case "min", "gte":
+			if target["type"] == "array" {
+				target["minItems"] = number(param)
+			} else if numeric {
+				target["minimum"] = number(param)
+			}
+		case "max", "lte":
+			if target["type"] == "array" {
+				target["maxItems"] = number(param)
+			} else if numeric {
+				target["maximum"] = number(param)
+			}
+		case "gt":
+			if numeric {
+				target["exclusiveMinimum"] = number(param)
+			}
+		}
+	}
+
+	return required
+}
+
+func number(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+
+	return f
+}
@@ -0,0 +1,260 @@
+package router
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ultrazg/xyz/utils"
+)
+
+var templateParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// specJSON 序列化后的文档，与 /openapi.json 返回的内容相同
+func specJSON(t *testing.T) map[string]any {
+	t.Helper()
+
+	b, err := json.Marshal(Spec())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// resolve 返回 $ref 指向的 schema，不是引用时原样返回
+func resolve(t *testing.T, doc map[string]any, schema map[string]any) map[string]any {
+	t.Helper()
+
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	resolved, ok := schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any)
+	if !ok || !strings.HasPrefix(ref, "#/components/schemas/") {
+		t.Fatalf("unresolved $ref %s", ref)
+	}
+
+	return resolved
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	doc := specJSON(t)
+	paths := doc["paths"].(map[string]any)
+
+	operation := func(t *testing.T, path, method string) map[string]any {
+		t.Helper()
+
+		item, ok := paths[pathParam.ReplaceAllString(path, "{$1}")].(map[string]any)
+		if !ok {
+			t.Fatalf("no path item for %s", path)
+		}
+
+		op, ok := item[strings.ToLower(method)].(map[string]any)
+		if !ok {
+			t.Fatalf("no %s operation for %s", method, path)
+		}
+
+		return op
+	}
+
+	for _, route := range Routes {
+		t.Run(route.Method+" "+route.Path, func(t *testing.T) {
+			op := operation(t, route.Path, route.Method)
+
+			if op["x-scope"] != scopeOf(route, utils.ScopeRead) {
+				t.Errorf("x-scope = %v, want %s", op["x-scope"], scopeOf(route, utils.ScopeRead))
+			}
+
+			if route.Body == nil {
+				if op["requestBody"] != nil {
+					t.Errorf("requestBody = %v, want none", op["requestBody"])
+				}
+
+				return
+			}
+
+			content, _ := op["requestBody"].(map[string]any)["content"].(map[string]any)
+			media, _ := content["application/json"].(map[string]any)
+			if media == nil {
+				t.Fatal("no application/json request body")
+			}
+
+			// 请求体类型的每个字段都出现在 schema 中，同名的类型不会互相覆盖
+			schema := resolve(t, doc, media["schema"].(map[string]any))
+			properties, _ := schema["properties"].(map[string]any)
+
+			body := reflect.TypeOf(route.Body)
+			for i := 0; i < body.NumField(); i++ {
+				name := utils.FieldName(body.Field(i))
+				if body.Field(i).IsExported() && name != "" && properties[name] == nil {
+					t.Errorf("field %s missing from the request body schema", name)
+				}
+			}
+		})
+	}
+
+	for _, route := range V2Routes {
+		t.Run(route.Method+" /v2"+route.Path, func(t *testing.T) {
+			op := operation(t, "/v2"+route.Path, route.Method)
+
+			if op["x-scope"] != scopeOf(route, v2Scope(route)) {
+				t.Errorf("x-scope = %v, want %s", op["x-scope"], scopeOf(route, v2Scope(route)))
+			}
+
+			want := map[string]bool{}
+			for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+				want[match[1]] = true
+			}
+
+			for _, name := range route.Query {
+				want[name] = true
+			}
+
+			got := map[string]bool{}
+			parameters, _ := op["parameters"].([]any)
+			for _, p := range parameters {
+				got[p.(map[string]any)["name"].(string)] = true
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parameters = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSpecValid(t *testing.T) {
+	doc := specJSON(t)
+
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v, want 3.1.0", doc["openapi"])
+	}
+
+	info, _ := doc["info"].(map[string]any)
+	if info["title"] == "" || info["version"] == nil {
+		t.Errorf("info = %v, want title and version", info)
+	}
+
+	components := doc["components"].(map[string]any)
+	securitySchemes := components["securitySchemes"].(map[string]any)
+
+	tags := map[string]bool{}
+	for _, tag := range doc["tags"].([]any) {
+		tags[tag.(map[string]any)["name"].(string)] = true
+	}
+
+	methods := map[string]bool{"get": true, "put": true, "post": true, "delete": true, "patch": true}
+	operationIds := map[string]string{}
+
+	for path, item := range doc["paths"].(map[string]any) {
+		if !strings.HasPrefix(path, "/") || strings.Contains(path, ":") {
+			t.Errorf("invalid path template %s", path)
+		}
+
+		for method, v := range item.(map[string]any) {
+			op := v.(map[string]any)
+			name := strings.ToUpper(method) + " " + path
+
+			if !methods[method] {
+				t.Errorf("%s: unknown method", name)
+			}
+
+			id, _ := op["operationId"].(string)
+			if previous, ok := operationIds[id]; ok || id == "" {
+				t.Errorf("%s: operationId %q already used by %s", name, id, previous)
+			}
+
+			operationIds[id] = name
+
+			responses, _ := op["responses"].(map[string]any)
+			if responses["200"] == nil || responses["default"] == nil {
+				t.Errorf("%s: responses = %v, want 200 and default", name, responses)
+			}
+
+			for _, tag := range op["tags"].([]any) {
+				if !tags[tag.(string)] {
+					t.Errorf("%s: undefined tag %v", name, tag)
+				}
+			}
+
+			security, _ := op["security"].([]any)
+			for _, requirement := range security {
+				for scheme := range requirement.(map[string]any) {
+					if securitySchemes[scheme] == nil {
+						t.Errorf("%s: undefined security scheme %s", name, scheme)
+					}
+				}
+			}
+
+			// 路径模板中的参数都声明为必填的路径参数，且没有多余的路径参数
+			want := map[string]bool{}
+			for _, match := range templateParam.FindAllStringSubmatch(path, -1) {
+				want[match[1]] = true
+			}
+
+			got := map[string]bool{}
+			parameters, _ := op["parameters"].([]any)
+			for _, p := range parameters {
+				parameter := p.(map[string]any)
+				if parameter["in"] == "path" {
+					got[parameter["name"].(string)] = parameter["required"] == true
+				}
+			}
+
+			for param := range want {
+				if !got[param] {
+					t.Errorf("%s: path parameter %s not declared as required", name, param)
+				}
+			}
+
+			if len(got) != len(want) {
+				t.Errorf("%s: path parameters = %v, want %v", name, got, want)
+			}
+		}
+	}
+
+	// 所有引用都能解析，必填字段都在 properties 中
+	var walk func(v any)
+	walk = func(v any) {
+		switch value := v.(type) {
+		case map[string]any:
+			if _, ok := value["$ref"]; ok {
+				resolve(t, doc, value)
+			}
+
+			if required, ok := value["required"].([]any); ok {
+				properties, _ := value["properties"].(map[string]any)
+				for _, name := range required {
+					if properties[name.(string)] == nil {
+						t.Errorf("required field %v not in properties %v", name, properties)
+					}
+				}
+			}
+
+			for _, item := range value {
+				walk(item)
+			}
+		case []any:
+			for _, item := range value {
+				walk(item)
+			}
+		}
+	}
+
+	walk(doc)
+
+	if len(operationIds) != len(Routes)+len(V2Routes) {
+		t.Errorf("%d operations, want %d", len(operationIds), len(Routes)+len(V2Routes))
+	}
+}
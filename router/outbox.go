@@ -0,0 +1,497 @@
+package router
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// OutboxHeader 请求头为 true 或 1 时，上游不可用的写操作保存到待发送队列，稍后按顺序重放
+const OutboxHeader = "x-xyz-outbox"
+
+const (
+	// outboxBucket 待发送队列，key 为账号
+	outboxBucket = "outbox"
+	// outboxInterval 后台重放的间隔
+	outboxInterval = 30 * time.Second
+	// outboxExpire 超过该时间仍未发送成功的操作标记为失败
+	outboxExpire = 7 * 24 * time.Hour
+	// outboxKeep 每个账号保留的已完成操作数，用于查询结果
+	outboxKeep = 100
+)
+
+const (
+	OutboxPending    = "pending"
+	OutboxSucceeded  = "succeeded"
+	OutboxFailed     = "failed"
+	OutboxSuperseded = "superseded"
+)
+
+// outboxOnce 不能重复发送的写操作。请求可能已经到达上游时（发送后连接断开、超时或上游返回 5xx）不入队也不重试，
+// 只有确定没有发送成功时（无法连接上游或被上游限流）才入队和重试
+var outboxOnce = map[string]bool{
+	"/episode_clap_create": true,
+	"/comment_like_update": true,
+}
+
+// outboxCoalesce 可以合并的写操作：新操作覆盖同一账号排队中的旧操作，返回旧操作剩余的请求体，nil 表示旧操作被完全覆盖
+var outboxCoalesce = map[string]func(older, newer map[string]any) map[string]any{
+	"/favorite_episode_update":      coalesceBy("eid"),
+	"/episode_play_progress_update": coalesceProgress,
+}
+
+// 同一账号的队列串行读写，保证按顺序发送
+var outboxLocks sync.Map
+
+// OutboxOperation 待发送队列中的一个写操作
+type OutboxOperation struct {
+	Id           string         `json:"id"`
+	Path         string         `json:"path"`
+	Body         map[string]any `json:"body"`
+	Status       string         `json:"status"` // pending、succeeded、failed 或 superseded
+	Attempts     int            `json:"attempts"`
+	LastError    any            `json:"lastError,omitempty"`
+	SupersededBy string         `json:"supersededBy,omitempty"` // 被合并时为覆盖它的操作
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+// OutboxPause 上游返回 401 时队列暂停，避免用失效的 token 反复重放
+type OutboxPause struct {
+	At    time.Time `json:"at"`
+	Error any       `json:"error"`
+}
+
+// outboxQueue 一个账号的待发送队列，Auth 为最近一次入队时的认证信息
+type outboxQueue struct {
+	Auth       replayAuth         `json:"auth"`
+	Paused     *OutboxPause       `json:"paused,omitempty"`
+	Operations []*OutboxOperation `json:"operations"`
+}
+
+type OutboxRequestBody struct {
+	Status string `json:"status" form:"status" binding:"omitempty,oneof=pending succeeded failed superseded"` // 只返回该状态的操作
+	Replay bool   `json:"replay" form:"replay"`                                                               // 查询前立即重放，队列暂停时以本次请求的认证信息恢复
+}
+
+type OutboxData struct {
+	Pending    int                `json:"pending"`
+	Paused     *OutboxPause       `json:"paused,omitempty"` // 队列因 token 失效暂停时的时间和原因
+	Operations []*OutboxOperation `json:"operations"`
+}
+
+// Outbox 查询当前账号的待发送队列，按入队顺序返回
+var Outbox = func(ctx *gin.Context) {
+	var params OutboxRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	owner := ownerOf(ctx)
+
+	var (
+		queue *outboxQueue
+		err   error
+	)
+
+	if params.Replay {
+		auth := replayAuthOf(ctx)
+		queue, err = replayOutbox(owner, time.Now(), &auth)
+	} else {
+		queue, err = loadOutbox(owner)
+	}
+
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	operations := []*OutboxOperation{}
+	for _, operation := range queue.Operations {
+		if params.Status == "" || operation.Status == params.Status {
+			operations = append(operations, operation)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": OutboxData{Pending: queue.pending(), Paused: queue.Paused, Operations: operations},
+	})
+}
+
+// outbox 开启了 OutboxHeader 时，上游不可用（5xx）的写操作保存到队列并返回 202，outboxOnce 中的操作只在确定没有发送成功时入队。
+// 队列中还有未发送的操作时，新操作校验参数后直接入队，保证同一账号按顺序发送
+func outbox(route Route) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if enabled, _ := strconv.ParseBool(ctx.GetHeader(OutboxHeader)); !enabled {
+			ctx.Next()
+
+			return
+		}
+
+		payload, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			utils.ReturnBadRequest(ctx, err)
+			ctx.Abort()
+
+			return
+		}
+
+		restore := func() {
+			ctx.Request.Body = io.NopCloser(bytes.NewReader(payload))
+		}
+
+		restore()
+
+		// 无法解析的请求体由 handler 返回参数错误
+		var body map[string]any
+		if err := json.Unmarshal(payload, &body); err != nil {
+			ctx.Next()
+
+			return
+		}
+
+		owner := ownerOf(ctx)
+
+		lock, _ := outboxLocks.LoadOrStore(owner, &sync.Mutex{})
+		lock.(*sync.Mutex).Lock()
+		defer lock.(*sync.Mutex).Unlock()
+
+		queue, err := loadOutbox(owner)
+		if err != nil {
+			utils.ReturnError(ctx, err)
+			ctx.Abort()
+
+			return
+		}
+
+		if queue.pending() > 0 {
+			if route.Body != nil && !utils.Bind(ctx, reflect.New(reflect.TypeOf(route.Body)).Interface()) {
+				ctx.Abort()
+
+				return
+			}
+
+			accept(ctx, owner, queue, route.Path, body)
+
+			return
+		}
+
+		writer := utils.NewBufferedWriter(ctx.Writer)
+		ctx.Writer = writer
+
+		ctx.Next()
+
+		ctx.Writer = writer.ResponseWriter
+
+		var response any
+		_ = json.Unmarshal(writer.Bytes(), &response)
+
+		if writer.Status() >= http.StatusInternalServerError && (!outboxOnce[route.Path] || notSent(response)) {
+			accept(ctx, owner, queue, route.Path, body)
+
+			return
+		}
+
+		ctx.Writer.WriteHeader(writer.Status())
+		_, _ = ctx.Writer.Write(writer.Bytes())
+	}
+}
+
+// accept 将写操作加入队列并返回 202 和操作 id
+func accept(ctx *gin.Context, owner string, queue *outboxQueue, path string, body map[string]any) {
+	now := time.Now()
+
+	queue.Auth = replayAuthOf(ctx)
+
+	operation := queue.enqueue(path, body, now)
+	position := queue.pending()
+
+	if err := saveOutbox(owner, queue); err != nil {
+		utils.ReturnError(ctx, err)
+		ctx.Abort()
+
+		return
+	}
+
+	ctx.AbortWithStatusJSON(http.StatusAccepted, gin.H{
+		"code": http.StatusAccepted,
+		"msg":  utils.GetMsg(http.StatusAccepted),
+		"data": gin.H{"id": operation.Id, "status": operation.Status, "position": position, "paused": queue.Paused != nil},
+	})
+}
+
+// StartOutbox 在后台定期重放各账号的待发送队列
+func StartOutbox() {
+	go func() {
+		ticker := time.NewTicker(outboxInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ReplayOutbox()
+		}
+	}()
+}
+
+// ReplayOutbox 重放所有账号的待发送队列
+func ReplayOutbox() {
+	var owners []string
+
+	err := utils.StoreEach(outboxBucket, func(key string, _ []byte) error {
+		owners = append(owners, key)
+
+		return nil
+	})
+	if err != nil {
+		log.Printf("outbox: %v", err)
+
+		return
+	}
+
+	for _, owner := range owners {
+		if _, err := replayOutbox(owner, time.Now(), nil); err != nil {
+			log.Printf("outbox %s: %v", owner, err)
+		}
+	}
+}
+
+// replayOutbox 按顺序发送 owner 未发送的操作，遇到可以重试的错误时停止，之后的操作等待下一次重放；上游返回 401 时暂停队列。
+// 暂停的队列在账号的 token 刷新后由后台恢复，或在调用方查询时传入 auth 立即恢复，auth 同时替换队列保存的认证信息
+func replayOutbox(owner string, now time.Time, auth *replayAuth) (*outboxQueue, error) {
+	lock, _ := outboxLocks.LoadOrStore(owner, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	queue, err := loadOutbox(owner)
+	if err != nil || queue.pending() == 0 {
+		return queue, err
+	}
+
+	if auth != nil {
+		queue.Auth = *auth
+	} else if queue.Paused != nil && !queue.refreshed() {
+		return queue, nil
+	}
+
+	queue.Paused = nil
+
+	// API Key 已吊销时同样暂停
+	origin, err := queue.Auth.request("/outbox")
+	if err != nil {
+		if e := utils.AsError(err); e.Status == http.StatusUnauthorized {
+			queue.Paused = &OutboxPause{At: now, Error: e}
+
+			return queue, saveOutbox(owner, queue)
+		}
+
+		return nil, err
+	}
+
+	for _, operation := range queue.Operations {
+		if operation.Status != OutboxPending {
+			continue
+		}
+
+		operation.UpdatedAt = now
+
+		if now.Sub(operation.CreatedAt) > outboxExpire {
+			operation.Status = OutboxFailed
+			operation.LastError = utils.NewError(http.StatusGatewayTimeout, utils.ErrTimeout, fmt.Sprintf("operation expired after %s", outboxExpire))
+
+			continue
+		}
+
+		result := runOperation(origin, BatchOperation{Id: operation.Id, Path: operation.Path}, operation.Body)
+		operation.Attempts++
+
+		if succeeded(result) {
+			operation.Status = OutboxSucceeded
+			operation.LastError = nil
+
+			continue
+		}
+
+		operation.LastError = operationError(operation.Id, operation.Path, result).Error
+
+		if result.Status == http.StatusUnauthorized {
+			queue.Paused = &OutboxPause{At: now, Error: operation.LastError}
+
+			break
+		}
+
+		if retryable(result.Status) && (!outboxOnce[operation.Path] || notSent(result.Body)) {
+			break
+		}
+
+		operation.Status = OutboxFailed
+	}
+
+	return queue, saveOutbox(owner, queue)
+}
+
+func loadOutbox(owner string) (*outboxQueue, error) {
+	queue := &outboxQueue{}
+
+	err := utils.StoreGet(outboxBucket, owner, queue)
+	if err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+func saveOutbox(owner string, queue *outboxQueue) error {
+	queue.prune()
+
+	return utils.StorePut(outboxBucket, owner, queue)
+}
+
+// enqueue 加入一个操作，并合并排队中被它覆盖的同类操作
+func (q *outboxQueue) enqueue(path string, body map[string]any, now time.Time) *OutboxOperation {
+	operation := &OutboxOperation{Id: newOutboxId(), Path: path, Body: body, Status: OutboxPending, CreatedAt: now, UpdatedAt: now}
+
+	if coalesce, ok := outboxCoalesce[path]; ok {
+		for _, older := range q.Operations {
+			if older.Status != OutboxPending || older.Path != path {
+				continue
+			}
+
+			if remaining := coalesce(older.Body, body); remaining != nil {
+				older.Body = remaining
+			} else {
+				older.Status = OutboxSuperseded
+				older.SupersededBy = operation.Id
+				older.UpdatedAt = now
+			}
+		}
+	}
+
+	q.Operations = append(q.Operations, operation)
+
+	return operation
+}
+
+// refreshed 暂停后账号的 token 是否已经刷新或重新登录，只有通过 x-xyz-account 选择账号的队列可以自动恢复
+func (q *outboxQueue) refreshed() bool {
+	if q.Auth.Account == "" {
+		return false
+	}
+
+	account, err := utils.GetAccount(q.Auth.Account)
+
+	return err == nil && account.RefreshedAt.After(q.Paused.At)
+}
+
+func (q *outboxQueue) pending() int {
+	count := 0
+	for _, operation := range q.Operations {
+		if operation.Status == OutboxPending {
+			count++
+		}
+	}
+
+	return count
+}
+
+// prune 保留所有未发送的操作和最近 outboxKeep 个已完成的操作
+func (q *outboxQueue) prune() {
+	drop := len(q.Operations) - q.pending() - outboxKeep
+
+	operations := q.Operations[:0]
+	for _, operation := range q.Operations {
+		if operation.Status != OutboxPending && drop > 0 {
+			drop--
+
+			continue
+		}
+
+		operations = append(operations, operation)
+	}
+
+	q.Operations = operations
+}
+
+// notSent 失败的请求是否确定没有被上游处理：无法连接上游或被上游限流
+func notSent(body any) bool {
+	m, _ := body.(map[string]any)
+
+	switch code, _ := nested(m, "error", "code").(string); code {
+	case utils.ErrUpstreamUnavailable, utils.ErrUpstreamRateLimited:
+		return true
+	}
+
+	return false
+}
+
+// coalesceBy field 相同的操作只保留最后一次，如收藏和取消收藏同一单集
+func coalesceBy(field string) func(older, newer map[string]any) map[string]any {
+	return func(older, newer map[string]any) map[string]any {
+		if fmt.Sprint(older[field]) == fmt.Sprint(newer[field]) {
+			return nil
+		}
+
+		return older
+	}
+}
+
+// coalesceProgress 同一单集的播放进度只保留最后一次，旧操作中其它单集的进度保留
+func coalesceProgress(older, newer map[string]any) map[string]any {
+	latest := map[string]bool{}
+	for _, item := range progressItems(newer) {
+		latest[fmt.Sprint(item["eid"])] = true
+	}
+
+	var remaining []any
+	for _, item := range progressItems(older) {
+		if !latest[fmt.Sprint(item["eid"])] {
+			remaining = append(remaining, item)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	body := maps.Clone(older)
+	body["data"] = remaining
+
+	return body
+}
+
+func progressItems(body map[string]any) []map[string]any {
+	data, _ := body["data"].([]any)
+
+	items := make([]map[string]any, 0, len(data))
+	for _, item := range data {
+		if m, ok := item.(map[string]any); ok {
+			items = append(items, m)
+		}
+	}
+
+	return items
+}
+
+// newOutboxId 随机的操作 id
+func newOutboxId() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
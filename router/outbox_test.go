@@ -0,0 +1,254 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	C "github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const testEid = "6638a3a58bb7d8fddd3bbfda"
+
+// 上游的行为：hangUp 读取请求后断开连接，unreachable 无法连接
+const (
+	hangUp      = -1
+	unreachable = 0
+)
+
+func TestReplayOutbox(t *testing.T) {
+	initTestStore(t)
+
+	var status atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := int(status.Load())
+		if code == hangUp {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	baseUrl := C.BaseUrl
+	t.Cleanup(func() { C.BaseUrl = baseUrl })
+
+	favorite := map[string]any{"eid": testEid, "favorited": true}
+	clap := map[string]any{"eid": testEid, "timestamp": 10, "duration": 100}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     map[string]any
+		upstream int
+		want     string
+		paused   bool
+	}{
+		{"上游恢复后发送", "/favorite_episode_update", favorite, http.StatusOK, OutboxSucceeded, false},
+		{"token 失效时暂停", "/favorite_episode_update", favorite, http.StatusUnauthorized, OutboxPending, true},
+		{"上游 5xx 时等待下一次重放", "/favorite_episode_update", favorite, http.StatusInternalServerError, OutboxPending, false},
+		{"上游参数错误时标记为失败", "/favorite_episode_update", favorite, http.StatusBadRequest, OutboxFailed, false},
+		{"上游 5xx 后不重发精彩时间点", "/episode_clap_create", clap, http.StatusInternalServerError, OutboxFailed, false},
+		{"发送后连接断开时不重发精彩时间点", "/episode_clap_create", clap, hangUp, OutboxFailed, false},
+		{"无法连接上游时重发精彩时间点", "/episode_clap_create", clap, unreachable, OutboxPending, false},
+		{"上游限流时重发精彩时间点", "/episode_clap_create", clap, http.StatusTooManyRequests, OutboxPending, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			C.BaseUrl = upstream.URL
+			if tt.upstream == unreachable {
+				C.BaseUrl = closed.URL
+			}
+
+			status.Store(int32(tt.upstream))
+
+			owner := "token:" + tt.name
+			queue := &outboxQueue{Auth: replayAuth{AccessToken: "token"}}
+			operation := queue.enqueue(tt.path, tt.body, time.Now())
+
+			if err := saveOutbox(owner, queue); err != nil {
+				t.Fatal(err)
+			}
+
+			queue, err := replayOutbox(owner, time.Now(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := queue.Operations[0]
+			if got.Id != operation.Id || got.Status != tt.want || got.Attempts != 1 {
+				t.Errorf("operation = %s after %d attempts, want %s after 1 attempt (lastError %v)", got.Status, got.Attempts, tt.want, got.LastError)
+			}
+
+			if paused := queue.Paused != nil; paused != tt.paused {
+				t.Errorf("paused = %v, want %v", paused, tt.paused)
+			}
+		})
+	}
+}
+
+func TestOutboxPause(t *testing.T) {
+	initTestStore(t)
+
+	if _, err := utils.SaveAccount("before", "access", "refresh", "uid", "before"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	pausedAt := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := utils.SaveAccount("after", "access", "refresh", "uid", "after"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		auth replayAuth
+		want bool
+	}{
+		{"按 access-token 区分的队列", replayAuth{AccessToken: "token"}, false},
+		{"账号不存在", replayAuth{Account: "missing"}, false},
+		{"账号在暂停前刷新", replayAuth{Account: "before"}, false},
+		{"账号在暂停后刷新", replayAuth{Account: "after"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &outboxQueue{Auth: tt.auth, Paused: &OutboxPause{At: pausedAt}}
+			if got := queue.refreshed(); got != tt.want {
+				t.Errorf("refreshed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("暂停的队列不在后台重放", func(t *testing.T) {
+		queue := &outboxQueue{Auth: replayAuth{AccessToken: "token"}, Paused: &OutboxPause{At: pausedAt}}
+		queue.enqueue("/favorite_episode_update", map[string]any{"eid": testEid}, pausedAt)
+
+		if err := saveOutbox("token:paused", queue); err != nil {
+			t.Fatal(err)
+		}
+
+		queue, err := replayOutbox("token:paused", time.Now(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if queue.Paused == nil || queue.Operations[0].Attempts != 0 {
+			t.Errorf("paused = %v, attempts = %d, want the queue to stay paused", queue.Paused, queue.Operations[0].Attempts)
+		}
+	})
+
+	t.Run("API Key 已吊销时暂停", func(t *testing.T) {
+		queue := &outboxQueue{Auth: replayAuth{AccessToken: "token", ApiKey: "revoked"}}
+		queue.enqueue("/favorite_episode_update", map[string]any{"eid": testEid}, pausedAt)
+
+		if err := saveOutbox("token:revoked", queue); err != nil {
+			t.Fatal(err)
+		}
+
+		queue, err := replayOutbox("token:revoked", time.Now(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if queue.Paused == nil || queue.Operations[0].Attempts != 0 {
+			t.Errorf("paused = %v, attempts = %d, want the queue to be paused before sending", queue.Paused, queue.Operations[0].Attempts)
+		}
+	})
+}
+
+func TestNotSent(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"无法连接上游", utils.ErrUpstreamUnavailable, true},
+		{"上游限流", utils.ErrUpstreamRateLimited, true},
+		{"发送后连接断开", utils.ErrUpstreamConnectionLost, false},
+		{"请求上游超时", utils.ErrUpstreamTimeout, false},
+		{"上游返回 5xx", utils.ErrUpstreamError, false},
+		{"操作超时", utils.ErrTimeout, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{"error": map[string]any{"code": tt.code}}
+			if got := notSent(body); got != tt.want {
+				t.Errorf("notSent(%s) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutboxEnqueue(t *testing.T) {
+	now := time.Now()
+	progress := func(eids ...string) map[string]any {
+		data := make([]any, len(eids))
+		for i, eid := range eids {
+			data[i] = map[string]any{"eid": eid}
+		}
+
+		return map[string]any{"data": data}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		older  map[string]any
+		newer  map[string]any
+		status string
+		body   map[string]any
+	}{
+		{"收藏同一单集", "/favorite_episode_update", map[string]any{"eid": "a", "favorited": true}, map[string]any{"eid": "a", "favorited": false}, OutboxSuperseded, map[string]any{"eid": "a", "favorited": true}},
+		{"收藏其它单集", "/favorite_episode_update", map[string]any{"eid": "a"}, map[string]any{"eid": "b"}, OutboxPending, map[string]any{"eid": "a"}},
+		{"同一单集的播放进度", "/episode_play_progress_update", progress("a"), progress("a"), OutboxSuperseded, progress("a")},
+		{"部分单集的播放进度", "/episode_play_progress_update", progress("a", "b"), progress("b"), OutboxPending, progress("a")},
+		{"不能合并的操作", "/episode_clap_create", map[string]any{"eid": "a"}, map[string]any{"eid": "a"}, OutboxPending, map[string]any{"eid": "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &outboxQueue{}
+			older := queue.enqueue(tt.path, tt.older, now)
+			newer := queue.enqueue(tt.path, tt.newer, now)
+
+			if older.Status != tt.status {
+				t.Errorf("older.Status = %s, want %s", older.Status, tt.status)
+			}
+
+			if tt.status == OutboxSuperseded && older.SupersededBy != newer.Id {
+				t.Errorf("older.SupersededBy = %s, want %s", older.SupersededBy, newer.Id)
+			}
+
+			if !reflect.DeepEqual(older.Body, tt.body) {
+				t.Errorf("older.Body = %v, want %v", older.Body, tt.body)
+			}
+		})
+	}
+}
+
+func initTestStore(t *testing.T) {
+	t.Helper()
+
+	if err := utils.InitStore(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = utils.CloseStore() })
+}
@@ -0,0 +1,349 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+)
+
+type PodcastPageRequestBody struct {
+	Pid string `json:"pid" form:"pid" binding:"required,xyzid"`
+}
+
+// PodcastPageData 节目页，podcast 以外的部分请求失败时为 null，失败原因见 errors
+type PodcastPageData struct {
+	Podcast         *PagePodcast     `json:"podcast"`
+	Info            *PagePodcastInfo `json:"info"`
+	Bulletin        *PageBulletin    `json:"bulletin"`
+	Honors          []PageHonor      `json:"honors"`
+	Related         []PagePodcast    `json:"related"`
+	PopularEpisodes []PageEpisode    `json:"popularEpisodes"`
+	Errors          []PageError      `json:"errors"`
+}
+
+type EpisodePageRequestBody struct {
+	Eid          string `json:"eid" form:"eid" binding:"required,xyzid"`
+	CommentOrder string `json:"commentOrder" form:"commentOrder" binding:"omitempty,oneof=HOT TIME TIMESTAMP"` // 评论排序，默认为 HOT
+}
+
+// EpisodePageData 单集页，episode 以外的部分请求失败时为 null，失败原因见 errors
+type EpisodePageData struct {
+	Episode   *PageEpisode   `json:"episode"`
+	Claps     *PageClaps     `json:"claps"`
+	Comments  *PageComments  `json:"comments"`
+	LiveCount *PageLiveCount `json:"liveCount"`
+	Progress  []PageProgress `json:"progress"`
+	Errors    []PageError    `json:"errors"`
+}
+
+// PageImage 封面、头像等图片的地址
+type PageImage struct {
+	PicUrl       string `json:"picUrl"`
+	LargePicUrl  string `json:"largePicUrl,omitempty"`
+	MiddlePicUrl string `json:"middlePicUrl,omitempty"`
+	SmallPicUrl  string `json:"smallPicUrl,omitempty"`
+	ThumbnailUrl string `json:"thumbnailUrl,omitempty"`
+}
+
+// PageUser 节目的主播
+type PageUser struct {
+	Uid      string      `json:"uid"`
+	Nickname string      `json:"nickname"`
+	Bio      string      `json:"bio,omitempty"`
+	Gender   string      `json:"gender,omitempty"`
+	IpLoc    string      `json:"ipLoc,omitempty"`
+	Relation string      `json:"relation,omitempty"` // 与当前账号的关系，如 STRANGE、FOLLOWING
+	Avatar   *PageAvatar `json:"avatar,omitempty"`
+}
+
+type PageAvatar struct {
+	Picture *PageImage `json:"picture"`
+}
+
+// PagePodcast 节目，时间为上游返回的 ISO 8601 字符串
+type PagePodcast struct {
+	Pid                  string     `json:"pid"`
+	Title                string     `json:"title"`
+	Author               string     `json:"author,omitempty"`
+	Brief                string     `json:"brief,omitempty"`
+	Description          string     `json:"description,omitempty"`
+	Image                *PageImage `json:"image,omitempty"`
+	SubscriptionCount    int        `json:"subscriptionCount"`
+	EpisodeCount         int        `json:"episodeCount"`
+	LatestEpisodePubDate string     `json:"latestEpisodePubDate,omitempty"`
+	SubscriptionStatus   string     `json:"subscriptionStatus,omitempty"` // ON 为已订阅
+	SubscriptionStar     bool       `json:"subscriptionStar"`
+	HasPopularEpisodes   bool       `json:"hasPopularEpisodes"`
+	PayType              string     `json:"payType,omitempty"`
+	Podcasters           []PageUser `json:"podcasters,omitempty"`
+}
+
+// PagePodcastInfo 节目的主体信息
+type PagePodcastInfo struct {
+	Subject  string `json:"subject"`
+	IpLoc    string `json:"ipLoc"`
+	Category *struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"category,omitempty"`
+}
+
+// PageBulletin 节目公告
+type PageBulletin struct {
+	Id                 string `json:"id"`
+	Content            string `json:"content"`
+	CreatedAt          string `json:"createdAt"`
+	Offline            bool   `json:"offline"`
+	UniqueVisitorCount int    `json:"uniqueVisitorCount"`
+}
+
+// PageHonor 荣誉墙中的一项
+type PageHonor struct {
+	Id            string `json:"id"`
+	Title         string `json:"title"`
+	CampaignTitle string `json:"campaignTitle"`
+	Url           string `json:"url,omitempty"`
+}
+
+// PageEpisode 单集，duration 的单位为秒
+type PageEpisode struct {
+	Eid           string     `json:"eid"`
+	Pid           string     `json:"pid"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	Shownotes     string     `json:"shownotes,omitempty"`
+	Duration      int        `json:"duration"`
+	PubDate       string     `json:"pubDate"`
+	PlayCount     int        `json:"playCount"`
+	ClapCount     int        `json:"clapCount"`
+	CommentCount  int        `json:"commentCount"`
+	FavoriteCount int        `json:"favoriteCount"`
+	IsFavorited   bool       `json:"isFavorited"`
+	IsPlayed      bool       `json:"isPlayed"`
+	IsFinished    bool       `json:"isFinished"`
+	PayType       string     `json:"payType,omitempty"`
+	IpLoc         string     `json:"ipLoc,omitempty"`
+	Image         *PageImage `json:"image,omitempty"`
+	Enclosure     *struct {
+		Url string `json:"url"`
+	} `json:"enclosure,omitempty"`
+	Podcast *PagePodcast `json:"podcast,omitempty"`
+}
+
+// PageClaps 精彩时间点，episodeClaps 为每个时间点的标记数，myClaps 为当前账号标记的时间点的下标
+type PageClaps struct {
+	EpisodeClaps []struct {
+		Count int `json:"count"`
+	} `json:"episodeClaps"`
+	MyClaps []struct {
+		Index int `json:"index"`
+	} `json:"myClaps"`
+}
+
+// PageLiveCount 正在收听的人数，上游返回展示用的文字
+type PageLiveCount struct {
+	AudiencesCountText string `json:"audiencesCountText"`
+}
+
+// PageProgress 单集的播放进度，progress 的单位为秒
+type PageProgress struct {
+	Eid      string `json:"eid"`
+	Pid      string `json:"pid"`
+	Progress int    `json:"progress"`
+	PlayedAt string `json:"playedAt"`
+}
+
+// PageComments 评论的第一页，与 /comment_primary 的 data 相同，loadMoreKey 可直接传给 /comment_primary 加载下一页
+type PageComments struct {
+	Data        any `json:"data"`
+	LoadMoreKey any `json:"loadMoreKey,omitempty"`
+}
+
+// PageError 页面中请求失败的部分
+type PageError struct {
+	Section string `json:"section"`
+	Path    string `json:"path"`
+	Status  int    `json:"status"`
+	Error   any    `json:"error"`
+}
+
+// PodcastPage 并发请求节目详情、主体信息、公告、荣誉墙、相关节目和「最受欢迎」单集，合并为一个响应
+var PodcastPage = func(ctx *gin.Context) {
+	var params PodcastPageRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	pid := map[string]any{"pid": params.Pid}
+
+	sections, ok := runPage(ctx, []BatchOperation{
+		{Id: "podcast", Path: "/podcast_detail", Body: pid},
+		{Id: "info", Path: "/podcast_get_info", Body: pid},
+		{Id: "bulletin", Path: "/podcast_bulletin", Body: pid},
+		{Id: "honors", Path: "/podcast_honor_list", Body: pid},
+		{Id: "related", Path: "/podcast_related", Body: pid},
+		{Id: "popularEpisodes", Path: "/episode_list_by_filter", Body: pid},
+	})
+	if !ok {
+		return
+	}
+
+	data := PodcastPageData{
+		Podcast:         pageSection[*PagePodcast](sections, "podcast"),
+		Info:            pageSection[*PagePodcastInfo](sections, "info"),
+		Bulletin:        pageSection[*PageBulletin](sections, "bulletin"),
+		Honors:          pageSection[[]PageHonor](sections, "honors"),
+		Related:         pageSection[[]PagePodcast](sections, "related"),
+		PopularEpisodes: pageSection[[]PageEpisode](sections, "popularEpisodes"),
+	}
+	data.Errors = sections.errors
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+// EpisodePage 并发请求单集详情、评论、正在收听的人数和播放进度，精彩时间点使用单集详情中的时长在其后请求，合并为一个响应
+var EpisodePage = func(ctx *gin.Context) {
+	var params EpisodePageRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	order := params.CommentOrder
+	if order == "" {
+		order = "HOT"
+	}
+
+	sections, ok := runPage(ctx, []BatchOperation{
+		{Id: "episode", Path: "/episode_detail", Body: map[string]any{"eid": params.Eid}},
+		{Id: "claps", Path: "/episode_clap", Body: map[string]any{"eid": params.Eid, "duration": "{{episode.data.data.duration}}"}},
+		{Id: "comments", Path: "/comment_primary", Body: map[string]any{"id": params.Eid, "order": order}},
+		{Id: "liveCount", Path: "/episode_live_count", Body: map[string]any{"eid": params.Eid}},
+		{Id: "progress", Path: "/episode_play_progress", Body: map[string]any{"eids": []any{params.Eid}}},
+	})
+	if !ok {
+		return
+	}
+
+	data := EpisodePageData{
+		Episode:   pageSection[*PageEpisode](sections, "episode"),
+		Claps:     pageSection[*PageClaps](sections, "claps"),
+		LiveCount: pageSection[*PageLiveCount](sections, "liveCount"),
+		Progress:  pageSection[[]PageProgress](sections, "progress"),
+	}
+
+	if body := sections.body("comments"); body != nil {
+		data.Comments = &PageComments{Data: body["data"], LoadMoreKey: body["loadMoreKey"]}
+	}
+
+	data.Errors = sections.errors
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+// pageSections 页面各部分的结果，key 为操作 id
+type pageSections struct {
+	results map[string]*BatchResult
+	paths   map[string]string
+	errors  []PageError
+}
+
+// runPage 执行页面的各个请求，第一个请求是页面的主体，主体失败时直接返回它的错误
+func runPage(ctx *gin.Context, operations []BatchOperation) (*pageSections, bool) {
+	dependencies, err := batchDependencies(operations)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return nil, false
+	}
+
+	results := runBatch(ctx.Request, operations, dependencies)
+
+	if main := results[0]; !succeeded(main) {
+		ctx.JSON(main.Status, main.Body)
+
+		return nil, false
+	}
+
+	sections := &pageSections{results: map[string]*BatchResult{}, paths: map[string]string{}, errors: []PageError{}}
+	for i, operation := range operations {
+		sections.paths[operation.Id] = operation.Path
+
+		result := results[i]
+		if succeeded(result) {
+			sections.results[operation.Id] = result
+
+			continue
+		}
+
+		sections.errors = append(sections.errors, operationError(operation.Id, operation.Path, result))
+	}
+
+	return sections, true
+}
+
+func succeeded(result *BatchResult) bool {
+	return result.Status >= 200 && result.Status < 300
+}
+
+// body 上游的响应体，即接口响应中的 data，请求失败时返回 nil
+func (s *pageSections) body(id string) map[string]any {
+	result, found := s.results[id]
+	if !found {
+		return nil
+	}
+
+	body, _ := result.Body.(map[string]any)
+	data, _ := body["data"].(map[string]any)
+
+	return data
+}
+
+// data 上游响应体中的 data 字段，请求失败时返回 nil
+func (s *pageSections) data(id string) any {
+	body := s.body(id)
+	if body == nil {
+		return nil
+	}
+
+	return body["data"]
+}
+
+// pageSection 将上游响应体中的 data 字段转换为 T，请求失败时返回零值；字段类型与上游不一致时同样返回零值，并在 errors 中说明
+func pageSection[T any](s *pageSections, id string) T {
+	var v T
+
+	data := s.data(id)
+	if data == nil {
+		return v
+	}
+
+	b, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(b, &v)
+	}
+
+	if err != nil {
+		path := s.paths[id]
+		e := utils.NewError(http.StatusBadGateway, utils.ErrUpstreamInvalidResponse, fmt.Sprintf("unexpected %s response: %v", path, err))
+		s.errors = append(s.errors, PageError{Section: id, Path: path, Status: e.Status, Error: e})
+
+		var zero T
+
+		return zero
+	}
+
+	return v
+}
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPageSection(t *testing.T) {
+	ok := func(data any) *BatchResult {
+		return &BatchResult{Status: http.StatusOK, Body: map[string]any{"code": 200.0, "data": map[string]any{"data": data}}}
+	}
+
+	sections := &pageSections{
+		results: map[string]*BatchResult{
+			"podcast":  ok(map[string]any{"pid": testPid, "title": "节目", "subscriptionCount": 12.0, "podcasters": []any{map[string]any{"uid": "u", "nickname": "主播", "avatar": map[string]any{"picture": map[string]any{"picUrl": "https://example.com/a.png"}}}}, "unknown": true}),
+			"honors":   ok([]any{}),
+			"invalid":  ok(map[string]any{"pid": 1.0}),
+			"progress": ok([]any{map[string]any{"eid": testEid, "progress": 30.0}}),
+		},
+		paths:  map[string]string{"podcast": "/podcast_detail", "honors": "/podcast_honor_list", "invalid": "/podcast_detail", "progress": "/episode_play_progress", "bulletin": "/podcast_bulletin"},
+		errors: []PageError{},
+	}
+
+	tests := []struct {
+		name   string
+		got    func() any
+		want   any
+		errors int
+	}{
+		{"对象", func() any { return pageSection[*PagePodcast](sections, "podcast") }, &PagePodcast{
+			Pid: testPid, Title: "节目", SubscriptionCount: 12,
+			Podcasters: []PageUser{{Uid: "u", Nickname: "主播", Avatar: &PageAvatar{Picture: &PageImage{PicUrl: "https://example.com/a.png"}}}},
+		}, 0},
+		{"空列表", func() any { return pageSection[[]PageHonor](sections, "honors") }, []PageHonor{}, 0},
+		{"列表", func() any { return pageSection[[]PageProgress](sections, "progress") }, []PageProgress{{Eid: testEid, Progress: 30}}, 0},
+		{"请求失败时为零值", func() any { return pageSection[*PageBulletin](sections, "bulletin") }, (*PageBulletin)(nil), 0},
+		{"字段类型不一致时为零值并记录错误", func() any { return pageSection[*PagePodcast](sections, "invalid") }, (*PagePodcast)(nil), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(sections.errors)
+
+			if got := tt.got(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pageSection() = %#v, want %#v", got, tt.want)
+			}
+
+			if added := sections.errors[before:]; len(added) != tt.errors {
+				t.Errorf("errors = %+v, want %d", added, tt.errors)
+			} else if tt.errors > 0 && (added[0].Section != "invalid" || added[0].Path != "/podcast_detail" || added[0].Status != http.StatusBadGateway) {
+				t.Errorf("error = %+v, want a 502 for invalid /podcast_detail", added[0])
+			}
+		})
+	}
+}
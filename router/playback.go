@@ -0,0 +1,279 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/playback"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// playbackBucket 未能发送到上游的数据，key 为账号
+	playbackBucket = "playback"
+	// playbackInterval 后台重新发送的间隔
+	playbackInterval = time.Minute
+)
+
+var (
+	playbackTracker = playback.NewTracker()
+	// 同一账号的事件串行处理，避免未发送的数据被重复发送
+	playbackLocks sync.Map
+)
+
+// playbackPending 一个账号未能发送到上游的数据，Auth 为最近一次上报时的认证信息，后台重新发送时使用。
+// 后台发送时上游返回 401 则暂停，不再用失效的 token 重试，直到该账号再次上报
+type playbackPending struct {
+	Auth    replayAuth       `json:"auth"`
+	Paused  bool             `json:"paused,omitempty"`
+	Reports playback.Reports `json:"reports"`
+}
+
+type PlaybackEventsRequestBody struct {
+	Events []playback.Event `json:"events" form:"events" binding:"max=100,dive"` // 为空时只重试之前未发送的数据
+}
+
+// PlaybackEventsData 处理事件后的会话状态，以及本次发送和仍未发送的数据数量
+type PlaybackEventsData struct {
+	Sessions []playback.Session    `json:"sessions"`
+	Sent     playback.Counts       `json:"sent"`
+	Pending  playback.Counts       `json:"pending"`
+	Rejected []playback.EventError `json:"rejected"`
+	Errors   []PageError           `json:"errors"`
+}
+
+// PlaybackEvents 接收播放器的播放、暂停、拖动、停止和倍速事件，生成并发送收听时长、播放进度、播放状态和收听历史。
+// 发送失败且可以重试的数据保存在本地，由后台定期重新发送，该账号下次上报事件时也会一起发送
+var PlaybackEvents = func(ctx *gin.Context) {
+	var params PlaybackEventsRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	owner := ownerOf(ctx)
+
+	lock, _ := playbackLocks.LoadOrStore(owner, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	now := time.Now()
+	reports, sessions, rejected := playbackTracker.Apply(owner, params.Events, now)
+
+	pending, err := loadPlayback(owner)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	pending.Reports.Merge(reports, now)
+
+	remaining, sent, errs := sendPlayback(ctx.Request, &pending.Reports)
+
+	if err := savePlayback(owner, &playbackPending{Auth: replayAuthOf(ctx), Reports: *remaining}); err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": PlaybackEventsData{
+			Sessions: sessions,
+			Sent:     sent,
+			Pending:  remaining.Counts(),
+			Rejected: rejected,
+			Errors:   errs,
+		},
+	})
+}
+
+// StartPlayback 在后台定期重新发送各账号未发送的播放数据
+func StartPlayback() {
+	go func() {
+		ticker := time.NewTicker(playbackInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			FlushPlayback()
+		}
+	}()
+}
+
+// FlushPlayback 重新发送所有账号未发送的播放数据
+func FlushPlayback() {
+	var owners []string
+
+	err := utils.StoreEach(playbackBucket, func(key string, _ []byte) error {
+		owners = append(owners, key)
+
+		return nil
+	})
+	if err != nil {
+		log.Printf("playback: %v", err)
+
+		return
+	}
+
+	for _, owner := range owners {
+		if err := flushPlayback(owner, time.Now()); err != nil {
+			log.Printf("playback %s: %v", owner, err)
+		}
+	}
+}
+
+// flushPlayback 以最近一次上报时的认证信息重新发送 owner 未发送的数据，过期的播放状态在发送前丢弃
+func flushPlayback(owner string, now time.Time) error {
+	lock, _ := playbackLocks.LoadOrStore(owner, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	pending, err := loadPlayback(owner)
+	if err != nil || pending.Paused {
+		return err
+	}
+
+	pending.Reports.Merge(&playback.Reports{}, now)
+
+	// API Key 已吊销时同样暂停
+	origin, err := pending.Auth.request("/playback/events")
+	if err != nil {
+		if e := utils.AsError(err); e.Status == http.StatusUnauthorized {
+			pending.Paused = true
+
+			return savePlayback(owner, pending)
+		}
+
+		return err
+	}
+
+	remaining, _, errs := sendPlayback(origin, &pending.Reports)
+
+	pending.Reports = *remaining
+	for _, e := range errs {
+		if e.Status == http.StatusUnauthorized {
+			pending.Paused = true
+		}
+	}
+
+	return savePlayback(owner, pending)
+}
+
+func loadPlayback(owner string) (*playbackPending, error) {
+	pending := &playbackPending{}
+
+	err := utils.StoreGet(playbackBucket, owner, pending)
+	if err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// savePlayback 保存未发送的数据，全部发送后删除
+func savePlayback(owner string, pending *playbackPending) error {
+	if !pending.Reports.Empty() {
+		return utils.StorePut(playbackBucket, owner, pending)
+	}
+
+	if err := utils.StoreDelete(playbackBucket, owner); err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// ownerOf 播放会话、未发送数据和待发送队列的归属：选择了账号时为账号名，否则为 access token 的摘要
+func ownerOf(ctx *gin.Context) string {
+	if account := utils.CurrentAccount(ctx); account != nil {
+		return "account:" + account.Name
+	}
+
+	sum := sha256.Sum256([]byte(ctx.GetHeader("x-jike-access-token")))
+
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// sendPlayback 并发发送收听时长（一次请求）、播放进度（一次请求）、播放状态和收听历史（每个单集一次请求），
+// 返回需要重试的数据、已发送的数量和失败的原因
+func sendPlayback(origin *http.Request, reports *playback.Reports) (*playback.Reports, playback.Counts, []PageError) {
+	remaining := &playback.Reports{}
+	sent := playback.Counts{}
+	errs := []PageError{}
+
+	var operations []BatchOperation
+	// apply 与 operations 一一对应，根据发送结果更新已发送的数量或需要重试的数据
+	var apply []func(ok, retry bool)
+
+	if len(reports.Tracking) > 0 {
+		operations = append(operations, BatchOperation{Id: "mileage", Path: "/mileage_update", Body: map[string]any{"tracking": reports.Tracking}})
+		apply = append(apply, func(ok, retry bool) {
+			if ok {
+				sent.Tracking = len(reports.Tracking)
+			} else if retry {
+				remaining.Tracking = reports.Tracking
+			}
+		})
+	}
+
+	if len(reports.Progress) > 0 {
+		operations = append(operations, BatchOperation{Id: "progress", Path: "/episode_play_progress_update", Body: map[string]any{"data": reports.Progress}})
+		apply = append(apply, func(ok, retry bool) {
+			if ok {
+				sent.Progress = len(reports.Progress)
+			} else if retry {
+				remaining.Progress = reports.Progress
+			}
+		})
+	}
+
+	for _, play := range reports.Live {
+		operations = append(operations, BatchOperation{Id: "live:" + play.Eid, Path: "/live_stats_report", Body: map[string]any{"eid": play.Eid, "pid": play.Pid}})
+		apply = append(apply, func(ok, retry bool) {
+			if ok {
+				sent.Live++
+			} else if retry {
+				remaining.Live = append(remaining.Live, play)
+			}
+		})
+	}
+
+	for _, play := range reports.History {
+		operations = append(operations, BatchOperation{Id: "history:" + play.Eid, Path: "/episode_played_history_list_update", Body: map[string]any{"eid": play.Eid}})
+		apply = append(apply, func(ok, retry bool) {
+			if ok {
+				sent.History++
+			} else if retry {
+				remaining.History = append(remaining.History, play)
+			}
+		})
+	}
+
+	results := runBatch(origin, operations, make([][]int, len(operations)))
+
+	for i, result := range results {
+		ok := succeeded(result)
+		if !ok {
+			errs = append(errs, operationError(operations[i].Id, operations[i].Path, result))
+		}
+
+		// 上报不是幂等的，可能已经被上游处理时重试会重复计算收听时长，与参数错误等一样丢弃这部分数据
+		apply[i](ok, !ok && resendable(result))
+	}
+
+	return remaining, sent, errs
+}
+
+// resendable 上报确定没有被上游处理时才能重新发送：无法连接上游、被上游限流或 token 失效。
+// 上游返回 5xx、超时或发送后连接断开时，上游可能已经处理了该请求
+func resendable(result *BatchResult) bool {
+	return result.Status == http.StatusUnauthorized || notSent(result.Body)
+}
@@ -0,0 +1,149 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	C "github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/playback"
+)
+
+func TestFlushPlayback(t *testing.T) {
+	initTestStore(t)
+
+	var status, requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	baseUrl := C.BaseUrl
+	C.BaseUrl = upstream.URL
+	t.Cleanup(func() { C.BaseUrl = baseUrl })
+
+	reports := playback.Reports{
+		Tracking: []playback.Tracking{{Eid: testEid, Pid: "5e280fab418a84a0461fa8a0", StartPlayingTimestamp: 1715848984351, EndPlayingTimestamp: 1715849044351, WithSpeed: 1}},
+	}
+
+	tests := []struct {
+		name     string
+		paused   bool
+		upstream int
+		requests int32
+		stored   bool
+		after    bool // 发送后是否暂停
+	}{
+		{"发送成功后删除", false, http.StatusOK, 1, false, false},
+		{"被上游限流时保留", false, http.StatusTooManyRequests, 1, true, false},
+		{"上游返回 5xx 时可能已处理，丢弃", false, http.StatusInternalServerError, 1, false, false},
+		{"token 失效时暂停", false, http.StatusUnauthorized, 1, true, true},
+		{"参数错误时丢弃", false, http.StatusBadRequest, 1, false, false},
+		{"暂停时不在后台发送", true, http.StatusOK, 0, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := "token:" + tt.name
+			if err := savePlayback(owner, &playbackPending{Auth: replayAuth{AccessToken: "token"}, Paused: tt.paused, Reports: reports}); err != nil {
+				t.Fatal(err)
+			}
+
+			status.Store(int32(tt.upstream))
+			requests.Store(0)
+
+			if err := flushPlayback(owner, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("requests = %d, want %d", got, tt.requests)
+			}
+
+			pending, err := loadPlayback(owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored := !pending.Reports.Empty(); stored != tt.stored || pending.Paused != tt.after {
+				t.Errorf("stored = %v, paused = %v, want %v, %v", stored, pending.Paused, tt.stored, tt.after)
+			}
+		})
+	}
+}
+
+func TestSendPlaybackRetry(t *testing.T) {
+	initTestStore(t)
+
+	reports := &playback.Reports{
+		Tracking: []playback.Tracking{{Eid: testEid, Pid: testPid, StartPlayingTimestamp: 1715848984351, EndPlayingTimestamp: 1715849044351, WithSpeed: 1}},
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc // 为 nil 时上游无法连接
+		retry   bool
+	}{
+		{"502 时请求已发送，不重试", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}, false},
+		{"超时时请求已发送，不重试", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}, false},
+		{"发送后连接断开，不重试", func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}, false},
+		{"无法连接上游，重试", nil, true},
+		{"被上游限流，重试", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, true},
+		{"token 失效，重试", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(tt.handler)
+			if tt.handler == nil {
+				upstream.Close()
+			} else {
+				defer upstream.Close()
+			}
+
+			baseUrl := C.BaseUrl
+			C.BaseUrl = upstream.URL
+			t.Cleanup(func() { C.BaseUrl = baseUrl })
+
+			origin, err := replayAuth{AccessToken: "token"}.request("/playback/events")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			remaining, sent, errs := sendPlayback(origin.WithContext(ctx), reports)
+			if sent.Tracking != 0 || len(errs) != 1 {
+				t.Fatalf("sent = %+v, errors = %+v", sent, errs)
+			}
+
+			if retry := len(remaining.Tracking) > 0; retry != tt.retry {
+				t.Errorf("retry = %v, want %v (error %+v)", retry, tt.retry, errs[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,537 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/timeseries"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// popularityTargetBucket 采集热度的节目和单集，key 为「类型/id」，如 episode/xxx
+	popularityTargetBucket = "popularity_targets"
+	// popularitySeriesBucket 热度的时间序列，key 与 popularityTargetBucket 相同
+	popularitySeriesBucket = "popularity_series"
+	// popularityTick 检查是否有节目或单集需要采集的间隔，采集的间隔由 -sample-interval 指定
+	popularityTick = time.Minute
+	// popularityDefaultLimit 默认返回的数量
+	popularityDefaultLimit = 20
+)
+
+const (
+	PopularityEpisode = "episode"
+	PopularityPodcast = "podcast"
+)
+
+// popularityFields 单集和节目采集的计数，单集的 listeners 为正在收听的人数
+var popularityFields = map[string][]timeseries.Field{
+	PopularityEpisode: {{Name: "plays"}, {Name: "comments"}, {Name: "claps"}, {Name: "favorites"}, {Name: "listeners", Gauge: true}},
+	PopularityPodcast: {{Name: "subscriptions"}, {Name: "episodes"}},
+}
+
+// popularityCounters 详情中的计数字段
+var popularityCounters = map[string]map[string]string{
+	PopularityEpisode: {"plays": "playCount", "comments": "commentCount", "claps": "clapCount", "favorites": "favoriteCount"},
+	PopularityPodcast: {"subscriptions": "subscriptionCount", "episodes": "episodeCount"},
+}
+
+// popularityDefaultField 排列增长时默认使用的计数
+var popularityDefaultField = map[string]string{
+	PopularityEpisode: "plays",
+	PopularityPodcast: "subscriptions",
+}
+
+// popularityLocks 同一对象依次采集，key 与 popularityTargetBucket 相同
+var popularityLocks sync.Map
+
+// popularityTarget 采集热度的节目或单集，Auth 为最近一次开始采集时的认证信息，定时采集时使用
+type popularityTarget struct {
+	Kind      string     `json:"kind"`
+	Id        string     `json:"id"`
+	Title     string     `json:"title"`
+	Auth      replayAuth `json:"auth"`
+	CreatedAt time.Time  `json:"createdAt"`
+	SampledAt time.Time  `json:"sampledAt"`
+	LastError any        `json:"lastError,omitempty"`
+}
+
+func (t *popularityTarget) key() string {
+	return t.Kind + "/" + t.Id
+}
+
+type PopularityTrackRequestBody struct {
+	Eid  string `json:"eid" form:"eid" binding:"omitempty,xyzid"`
+	Pid  string `json:"pid" form:"pid" binding:"required_without=Eid,omitempty,xyzid"` // 不传 eid 时采集节目
+	Mode string `json:"mode" form:"mode" binding:"required,oneof=ON OFF"`              // ON 为开始采集并立即采集一次，OFF 为停止采集，已采集的数据保留
+}
+
+// PopularityTargetData 采集热度的节目或单集和最近一次采集的计数
+type PopularityTargetData struct {
+	Kind      string            `json:"kind"`
+	Id        string            `json:"id"`
+	Title     string            `json:"title"`
+	Tracking  bool              `json:"tracking"`
+	CreatedAt *time.Time        `json:"createdAt,omitempty"`
+	SampledAt *time.Time        `json:"sampledAt,omitempty"`
+	Latest    *timeseries.Value `json:"latest,omitempty"`
+	LastError any               `json:"lastError,omitempty"`
+}
+
+// PopularityTrack 开始采集节目或单集的热度：立即采集一次，之后按 -sample-interval 定时采集。
+// 单集采集播放、评论、点赞、收藏数和正在收听的人数，节目采集订阅数和单集数
+var PopularityTrack = func(ctx *gin.Context) {
+	var params PopularityTrackRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	target := &popularityTarget{Kind: PopularityPodcast, Id: params.Pid, CreatedAt: time.Now()}
+	if params.Eid != "" {
+		target.Kind, target.Id = PopularityEpisode, params.Eid
+	}
+
+	if params.Mode == "OFF" {
+		if err := utils.StoreDelete(popularityTargetBucket, target.key()); err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		returnPopularityTarget(ctx, target.Kind, target.Id)
+
+		return
+	}
+
+	if saved, err := loadPopularityTarget(target.key()); err == nil {
+		target.CreatedAt = saved.CreatedAt
+	}
+
+	target.Auth = replayAuthOf(ctx)
+
+	if failed := samplePopularity(target, time.Now()); failed != nil {
+		ctx.JSON(failed.Status, failed.Body)
+
+		return
+	}
+
+	returnPopularityTarget(ctx, target.Kind, target.Id)
+}
+
+// PopularityTrackList 全部采集热度的节目和单集，按开始采集的时间排列
+var PopularityTrackList = func(ctx *gin.Context) {
+	targets, err := loadPopularityTargets()
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	list := []PopularityTargetData{}
+	for _, target := range targets {
+		data, err := popularityTargetData(target.Kind, target.Id)
+		if err != nil {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		list = append(list, *data)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": list,
+	})
+}
+
+type PopularitySeriesRequestBody struct {
+	Eid        string `json:"eid" form:"eid" binding:"omitempty,xyzid"`
+	Pid        string `json:"pid" form:"pid" binding:"required_without=Eid,omitempty,xyzid"`
+	From       string `json:"from" form:"from" binding:"omitempty,datetime=2006-01-02"`            // 开始日期
+	To         string `json:"to" form:"to" binding:"omitempty,datetime=2006-01-02"`                // 结束日期（包含）
+	Timezone   string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`               // 解析日期使用的时区，默认为 Asia/Shanghai
+	Resolution string `json:"resolution" form:"resolution" binding:"omitempty,oneof=raw hour day"` // 默认为保留了查询范围内全部数据的最细的精度
+}
+
+// PopularitySeriesData 时间序列和每个计数在查询范围内的变化
+type PopularitySeriesData struct {
+	Kind       string              `json:"kind"`
+	Id         string              `json:"id"`
+	Title      string              `json:"title"`
+	Tracking   bool                `json:"tracking"`
+	Resolution string              `json:"resolution"`
+	Fields     []timeseries.Field  `json:"fields"`
+	Points     []timeseries.Value  `json:"points"`
+	Growth     []timeseries.Growth `json:"growth"`
+}
+
+// PopularitySeries 节目或单集热度的时间序列，支持 GET /popularity_series?eid= 和 POST。
+// 原始采样保留 7 天，按小时的数据保留 90 天，按天的数据一直保留；按小时和按天的数据中累计的计数为区间内最后一次采样的值，正在收听的人数为最大值
+var PopularitySeries = func(ctx *gin.Context) {
+	var params PopularitySeriesRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	kind, id := PopularityPodcast, params.Pid
+	if params.Eid != "" {
+		kind, id = PopularityEpisode, params.Eid
+	}
+
+	from, to, err := dateRange(params.From, params.To, params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	series, err := loadPopularitySeries(kind + "/" + id)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	if series.Since.IsZero() {
+		utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, "no samples of "+kind+" "+id+", call /popularity_track first"))
+
+		return
+	}
+
+	target, err := popularityTargetData(kind, id)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	resolution, points := series.Range(from, to, params.Resolution)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": PopularitySeriesData{
+			Kind:       kind,
+			Id:         id,
+			Title:      target.Title,
+			Tracking:   target.Tracking,
+			Resolution: resolution,
+			Fields:     series.Fields,
+			Points:     points,
+			Growth:     series.Summarize(points),
+		},
+	})
+}
+
+type PopularityGrowthRequestBody struct {
+	Kind   string `json:"kind" form:"kind" binding:"omitempty,oneof=episode podcast"` // 默认为 episode
+	Field  string `json:"field" form:"field"`                                         // 排列使用的计数，单集默认为 plays，节目默认为 subscriptions
+	Hours  int    `json:"hours" form:"hours" binding:"omitempty,min=1,max=8760"`      // 统计最近多少小时的变化，默认为 24
+	Limit  int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=200"`       // 默认为 20
+	Offset int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+// PopularityGrowthItem 一个节目或单集的计数在统计时间内的变化
+type PopularityGrowthItem struct {
+	Kind  string `json:"kind"`
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	timeseries.Growth
+}
+
+type PopularityGrowthData struct {
+	Field string                 `json:"field"`
+	From  time.Time              `json:"from"`
+	Total int                    `json:"total"`
+	Items []PopularityGrowthItem `json:"items"`
+}
+
+// PopularityGrowth 按计数在最近一段时间内的增长倒序排列采集的节目或单集，没有两次以上采集的不包含在内
+var PopularityGrowth = func(ctx *gin.Context) {
+	var params PopularityGrowthRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	kind := params.Kind
+	if kind == "" {
+		kind = PopularityEpisode
+	}
+
+	field := params.Field
+	if field == "" {
+		field = popularityDefaultField[kind]
+	}
+
+	if !popularityHasField(kind, field) {
+		utils.ReturnError(ctx, utils.NewError(http.StatusBadRequest, utils.ErrValidationFailed, "field: unknown "+kind+" field "+field))
+
+		return
+	}
+
+	hours := params.Hours
+	if hours == 0 {
+		hours = 24
+	}
+
+	from := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	targets, err := loadPopularityTargets()
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	items := []PopularityGrowthItem{}
+	for _, target := range targets {
+		if target.Kind != kind {
+			continue
+		}
+
+		series, err := loadPopularitySeries(target.key())
+		if err != nil {
+			utils.ReturnError(ctx, err)
+
+			return
+		}
+
+		_, points := series.Range(from, time.Time{}, "")
+		for _, g := range series.Summarize(points) {
+			if g.Field == field {
+				items = append(items, PopularityGrowthItem{Kind: kind, Id: target.Id, Title: target.Title, Growth: g})
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Change > items[j].Change
+	})
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = popularityDefaultLimit
+	}
+
+	page := items[min(params.Offset, len(items)):min(params.Offset+limit, len(items))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": PopularityGrowthData{Field: field, From: from, Total: len(items), Items: page},
+	})
+}
+
+// StartPopularity 在后台定期采集节目和单集的热度
+func StartPopularity() {
+	go func() {
+		ticker := time.NewTicker(popularityTick)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			SamplePopularity(time.Now())
+		}
+	}()
+}
+
+// SamplePopularity 采集距离上一次采集超过 -sample-interval 的节目和单集
+func SamplePopularity(now time.Time) {
+	targets, err := loadPopularityTargets()
+	if err != nil {
+		log.Printf("popularity: %v", err)
+
+		return
+	}
+
+	for _, target := range targets {
+		if now.Sub(target.SampledAt) < utils.SampleInterval {
+			continue
+		}
+
+		if failed := samplePopularity(target, now); failed != nil {
+			log.Printf("popularity %s: status %d", target.key(), failed.Status)
+		}
+	}
+}
+
+// samplePopularity 采集一次并保存采集的对象，详情查询失败时不保存本次采集；正在收听的人数查询失败时省略
+func samplePopularity(target *popularityTarget, now time.Time) *BatchResult {
+	lock, _ := popularityLocks.LoadOrStore(target.key(), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	origin, err := target.Auth.request("/popularity_track")
+	if err != nil {
+		return internalError(err)
+	}
+
+	var operations []BatchOperation
+	if target.Kind == PopularityEpisode {
+		body := map[string]any{"eid": target.Id}
+		operations = []BatchOperation{{Id: "detail", Path: "/episode_detail", Body: body}, {Id: "live", Path: "/episode_live_count", Body: body}}
+	} else {
+		operations = []BatchOperation{{Id: "detail", Path: "/podcast_detail", Body: map[string]any{"pid": target.Id}}}
+	}
+
+	results := runBatch(origin, operations, make([][]int, len(operations)))
+
+	target.LastError = nil
+	if !succeeded(results[0]) {
+		target.LastError = operationError(target.key(), operations[0].Path, results[0]).Error
+
+		if err := utils.StorePut(popularityTargetBucket, target.key(), target); err != nil {
+			return internalError(err)
+		}
+
+		return results[0]
+	}
+
+	response, _ := results[0].Body.(map[string]any)
+	detail, _ := nested(response, "data", "data").(map[string]any)
+
+	values := map[string]int64{}
+	for name, key := range popularityCounters[target.Kind] {
+		if v, ok := detail[key].(float64); ok {
+			values[name] = int64(v)
+		}
+	}
+
+	if len(results) > 1 && succeeded(results[1]) {
+		response, _ := results[1].Body.(map[string]any)
+		if v, ok := nested(response, "data", "data", "listenerCount").(float64); ok {
+			values["listeners"] = int64(v)
+		}
+	}
+
+	if title, ok := detail["title"].(string); ok {
+		target.Title = strings.TrimSpace(title)
+	}
+
+	series, err := loadPopularitySeries(target.key())
+	if err != nil {
+		return internalError(err)
+	}
+
+	if len(series.Fields) == 0 {
+		series.Fields = popularityFields[target.Kind]
+	}
+
+	location, _ := timezoneOf("")
+	series.Add(now, values, timeseries.DefaultRetention, location)
+
+	if err := utils.StorePut(popularitySeriesBucket, target.key(), series); err != nil {
+		return internalError(err)
+	}
+
+	target.SampledAt = now
+	if err := utils.StorePut(popularityTargetBucket, target.key(), target); err != nil {
+		return internalError(err)
+	}
+
+	return nil
+}
+
+func popularityHasField(kind, field string) bool {
+	for _, f := range popularityFields[kind] {
+		if f.Name == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func returnPopularityTarget(ctx *gin.Context, kind, id string) {
+	data, err := popularityTargetData(kind, id)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+// popularityTargetData 采集的状态和最近一次采集的计数，不返回保存的认证信息
+func popularityTargetData(kind, id string) (*PopularityTargetData, error) {
+	series, err := loadPopularitySeries(kind + "/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &PopularityTargetData{Kind: kind, Id: id, Latest: series.Latest()}
+	if data.Latest != nil {
+		data.SampledAt = &data.Latest.At
+	}
+
+	target, err := loadPopularityTarget(kind + "/" + id)
+	if errors.Is(err, utils.ErrStoreNotFound) {
+		return data, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.Title = target.Title
+	data.Tracking = true
+	data.CreatedAt = &target.CreatedAt
+	data.LastError = target.LastError
+
+	return data, nil
+}
+
+func loadPopularityTarget(key string) (*popularityTarget, error) {
+	target := &popularityTarget{}
+
+	return target, utils.StoreGet(popularityTargetBucket, key, target)
+}
+
+// loadPopularityTargets 全部采集的对象，按开始采集的时间排列
+func loadPopularityTargets() ([]*popularityTarget, error) {
+	var targets []*popularityTarget
+
+	err := utils.StoreEach(popularityTargetBucket, func(_ string, value []byte) error {
+		target := &popularityTarget{}
+		if err := json.Unmarshal(value, target); err != nil {
+			return err
+		}
+
+		targets = append(targets, target)
+
+		return nil
+	})
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].CreatedAt.Before(targets[j].CreatedAt)
+	})
+
+	return targets, err
+}
+
+func loadPopularitySeries(key string) (*timeseries.Series, error) {
+	series := &timeseries.Series{}
+
+	err := utils.StoreGet(popularitySeriesBucket, key, series)
+	if err != nil && !errors.Is(err, utils.ErrStoreNotFound) {
+		return nil, err
+	}
+
+	return series, nil
+}
@@ -0,0 +1,73 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// replayAuth 后台任务（待发送队列、榜单存档、关注节目、热度采集）之后以调用方的身份请求其它接口时使用的认证信息，只保存重放需要的部分：
+// 通过 x-xyz-account 选择账号时只保存账号名，重放时使用服务端保存的最新 token，否则保存 access-token；
+// API Key 只保存其 Id，refresh-token、Cookie 等不保存
+type replayAuth struct {
+	Account     string `json:"account,omitempty"`
+	AccessToken string `json:"accessToken,omitempty"`
+	ApiKey      string `json:"apiKey,omitempty"`
+}
+
+// replayAuthOf 当前请求的认证信息
+func replayAuthOf(ctx *gin.Context) replayAuth {
+	var auth replayAuth
+
+	if account := utils.CurrentAccount(ctx); account != nil {
+		auth.Account = account.Name
+	} else {
+		auth.AccessToken = ctx.GetHeader("x-jike-access-token")
+	}
+
+	if apiKey := utils.CurrentApiKey(ctx); apiKey != nil {
+		auth.ApiKey = apiKey.Id()
+	}
+
+	return auth
+}
+
+// request 创建以 auth 的身份调用其它接口的 origin。API Key 加入 context，被调用的接口视为已校验、不扣减配额，
+// API Key 已吊销时返回 401
+func (a replayAuth) request(path string) (*http.Request, error) {
+	c := context.Background()
+
+	if a.ApiKey != "" {
+		apiKey, err := utils.LookupApiKey(a.ApiKey)
+		if errors.Is(err, utils.ErrStoreNotFound) {
+			return nil, utils.NewError(http.StatusUnauthorized, utils.ErrUnauthorized, "xyz api key has been revoked")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		c = utils.WithApiKey(c, apiKey)
+	}
+
+	origin, err := http.NewRequestWithContext(c, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	origin.RemoteAddr = strings.TrimPrefix(path, "/")
+
+	if a.Account != "" {
+		origin.Header.Set(utils.AccountHeader, a.Account)
+	}
+
+	if a.AccessToken != "" {
+		origin.Header.Set("x-jike-access-token", a.AccessToken)
+	}
+
+	return origin, nil
+}
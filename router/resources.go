@@ -0,0 +1,446 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/resources"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// resourceIndexBucket 资源索引和提取进度，key 为账号
+	resourceIndexBucket = "resource_index"
+	// resourceIndexInterval 所有提取任务共用的请求间隔
+	resourceIndexInterval = 300 * time.Millisecond
+	// resourceIndexMaxRequests 单个任务最多发送的请求数
+	resourceIndexMaxRequests = 5000
+	// resourceIndexSaveEvery 每发送该数量的请求保存一次进度
+	resourceIndexSaveEvery = 10
+	// resourceIndexDefaultEpisodes 每个节目默认提取的最近单集数
+	resourceIndexDefaultEpisodes = 20
+	// resourceIndexDefaultLimit 查询默认返回的资源数
+	resourceIndexDefaultLimit = 50
+)
+
+// resourceIndexJobs 提取资源的任务，key 为账号
+var resourceIndexJobs = &crawlJobs[*resourceIndex]{
+	name:        "resource index",
+	bucket:      resourceIndexBucket,
+	limiter:     &rateLimiter{interval: resourceIndexInterval},
+	maxRequests: resourceIndexMaxRequests,
+	saveEvery:   resourceIndexSaveEvery,
+	interrupted: "indexing interrupted",
+	hint:        "call /resource_index again to continue",
+}
+
+// resourceIndex 资源索引和提取进度
+type resourceIndex struct {
+	resources.Index
+	Crawl resourceCrawl `json:"crawl"`
+}
+
+// resourceCrawl 提取进度：先查询订阅的节目和每个节目最近的单集，再逐个查询未提取的单集详情。
+// 已提取的单集不会重复查询，失败或服务重启后再次调用时从未提取的单集继续
+type resourceCrawl struct {
+	crawlStatus
+	Podcasts int      `json:"podcasts"`
+	Pending  []string `json:"pending,omitempty"` // 等待提取的单集
+	Skipped  []string `json:"skipped,omitempty"` // 无法查询详情的单集，如已被删除
+}
+
+// resourceIndexJob 正在运行的提取任务
+type resourceIndexJob struct {
+	*crawlJob[*resourceIndex]
+}
+
+type ResourceIndexRequestBody struct {
+	Episodes int  `json:"episodes" form:"episodes" binding:"omitempty,min=1,max=200"` // 每个节目提取的最近单集数，默认为 20
+	Refresh  bool `json:"refresh" form:"refresh"`                                     // 已完成时重新查询订阅和单集列表，提取新的单集
+	Reindex  bool `json:"reindex" form:"reindex"`                                     // 同时重新提取已提取的单集
+}
+
+// ResourceIndexData 提取进度
+type ResourceIndexData struct {
+	Status      string     `json:"status"` // running、completed 或 failed
+	Podcasts    int        `json:"podcasts"`
+	Episodes    int        `json:"episodes"` // 已提取的单集数
+	Pending     int        `json:"pending"`  // 等待提取的单集数
+	Resources   int        `json:"resources"`
+	Requests    int        `json:"requests"`
+	Skipped     []string   `json:"skipped"`
+	Error       any        `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// ResourceIndex 在后台查询订阅节目最近的单集，提取 shownotes 中的链接和《》中的作品名并保存在本地，立即返回提取进度。
+// 任务正在运行或已完成时只返回进度；失败、服务重启后或传入 refresh 时再次调用，只提取尚未提取的单集
+var ResourceIndex = func(ctx *gin.Context) {
+	var params ResourceIndexRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	owner := ownerOf(ctx)
+
+	job, running := resourceIndexJobs.start(owner)
+	if job == nil {
+		returnResourceIndex(ctx, running)
+
+		return
+	}
+
+	index, err := loadResourceIndex(owner)
+	if err != nil {
+		job.cancel()
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	if index.Crawl.Status == CrawlCompleted && !params.Refresh && !params.Reindex {
+		job.cancel()
+		returnResourceIndex(ctx, index)
+
+		return
+	}
+
+	index.Crawl = resourceCrawl{}
+	index.Crawl.StartedAt = time.Now()
+
+	episodes := params.Episodes
+	if episodes == 0 {
+		episodes = resourceIndexDefaultEpisodes
+	}
+
+	job.launch(ctx, index, func(origin *http.Request) {
+		resourceIndexJob{job}.run(origin, episodes, params.Reindex)
+	})
+
+	returnResourceIndex(ctx, job.snapshot())
+}
+
+type ResourceSearchRequestBody struct {
+	Keyword     string `json:"keyword" form:"keyword"`                                           // 作品名、链接文字或链接中包含的文字，不区分大小写
+	Kind        string `json:"kind" form:"kind" binding:"omitempty,oneof=link title"`            // link 为链接，title 为《》中的作品名
+	Pid         string `json:"pid" form:"pid" binding:"omitempty,xyzid"`                         // 只查询该节目提到的资源
+	Domain      string `json:"domain" form:"domain"`                                             // 链接的域名，包含子域名
+	MinEpisodes int    `json:"minEpisodes" form:"minEpisodes" binding:"min=0"`                   // 最少被提到的单集数
+	Sort        string `json:"sort" form:"sort" binding:"omitempty,oneof=episodes recent title"` // episodes 按提到的单集数（默认），recent 按最近被提到的时间，title 按标题
+	Limit       int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=500"`             // 默认为 50
+	Offset      int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+type ResourceSearchData struct {
+	Status    string               `json:"status"`
+	Total     int                  `json:"total"`
+	Resources []resources.Resource `json:"resources"`
+}
+
+// ResourceSearch 按关键词、类型、节目和域名查询提到的资源，每个资源带有提到它的单集和时间戳，任务运行中时查询已提取的部分
+var ResourceSearch = func(ctx *gin.Context) {
+	var params ResourceSearchRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	index, ok := indexedResources(ctx)
+	if !ok {
+		return
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = resourceIndexDefaultLimit
+	}
+
+	result := index.Search(resources.Filter{Keyword: params.Keyword, Kind: params.Kind, Pid: params.Pid, Domain: params.Domain, MinEpisodes: params.MinEpisodes, Sort: params.Sort})
+	page := result[min(params.Offset, len(result)):min(params.Offset+limit, len(result))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": ResourceSearchData{Status: index.Crawl.Status, Total: len(result), Resources: page},
+	})
+}
+
+type ResourceExportRequestBody struct {
+	Keyword     string `json:"keyword" form:"keyword"`                                           // 作品名、链接文字或链接中包含的文字，不区分大小写
+	Kind        string `json:"kind" form:"kind" binding:"omitempty,oneof=link title"`            // link 为链接，title 为《》中的作品名
+	Pid         string `json:"pid" form:"pid" binding:"omitempty,xyzid"`                         // 只查询该节目提到的资源
+	Domain      string `json:"domain" form:"domain"`                                             // 链接的域名，包含子域名
+	MinEpisodes int    `json:"minEpisodes" form:"minEpisodes" binding:"min=0"`                   // 最少被提到的单集数
+	Sort        string `json:"sort" form:"sort" binding:"omitempty,oneof=episodes recent title"` // episodes 按提到的单集数（默认），recent 按最近被提到的时间，title 按标题
+	Format      string `json:"format" form:"format" binding:"required,oneof=csv markdown"`
+	Timezone    string `json:"timezone" form:"timezone" binding:"omitempty,timezone"` // Markdown 中显示时间使用的时区，默认为 Asia/Shanghai
+}
+
+// resourceExportFormats 导出格式的 Content-Type 和文件扩展名
+var resourceExportFormats = map[string][2]string{
+	"csv":      {"text/csv; charset=utf-8", "csv"},
+	"markdown": {"text/markdown; charset=utf-8", "md"},
+}
+
+// ResourceExport 将符合条件的资源导出为 CSV 或 Markdown 清单
+var ResourceExport = func(ctx *gin.Context) {
+	var params ResourceExportRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	index, ok := indexedResources(ctx)
+	if !ok {
+		return
+	}
+
+	location, err := timezoneOf(params.Timezone)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	var b bytes.Buffer
+
+	result := index.Search(resources.Filter{Keyword: params.Keyword, Kind: params.Kind, Pid: params.Pid, Domain: params.Domain, MinEpisodes: params.MinEpisodes, Sort: params.Sort})
+	switch params.Format {
+	case "csv":
+		err = resources.CSV(&b, result)
+	case "markdown":
+		err = resources.Markdown(&b, result, time.Now(), location)
+	}
+
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	format := resourceExportFormats[params.Format]
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="resources.%s"`, format[1]))
+	ctx.Data(http.StatusOK, format[0], b.Bytes())
+}
+
+// indexedResources 正在提取时返回已提取的部分，没有索引时返回 404
+func indexedResources(ctx *gin.Context) (*resourceIndex, bool) {
+	return resourceIndexJobs.find(ctx, ownerOf(ctx), &resourceIndex{}, "no resource index, call /resource_index first")
+}
+
+func returnResourceIndex(ctx *gin.Context, index *resourceIndex) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": ResourceIndexData{
+			Status:      index.Crawl.Status,
+			Podcasts:    index.Crawl.Podcasts,
+			Episodes:    len(index.Episodes),
+			Pending:     len(index.Crawl.Pending),
+			Resources:   len(index.Resources),
+			Requests:    index.Crawl.Requests,
+			Skipped:     append([]string{}, index.Crawl.Skipped...),
+			Error:       index.Crawl.Error,
+			StartedAt:   index.Crawl.StartedAt,
+			UpdatedAt:   index.Crawl.UpdatedAt,
+			CompletedAt: index.Crawl.CompletedAt,
+		},
+	})
+}
+
+func loadResourceIndex(owner string) (*resourceIndex, error) {
+	return resourceIndexJobs.load(owner, &resourceIndex{})
+}
+
+func (index *resourceIndex) progress() *crawlStatus {
+	return &index.Crawl.crawlStatus
+}
+
+// clone 进度的副本
+func (index *resourceIndex) clone() *resourceIndex {
+	c := *index
+	c.Episodes = slices.Clone(c.Episodes)
+	c.Resources = slices.Clone(c.Resources)
+	c.Crawl.Pending = slices.Clone(c.Crawl.Pending)
+	c.Crawl.Skipped = slices.Clone(c.Crawl.Skipped)
+
+	return &c
+}
+
+// run 查询订阅的节目和最近的单集，逐个提取未提取的单集，直到完成、失败或达到请求数上限
+func (j resourceIndexJob) run(origin *http.Request, perPodcast int, reindex bool) {
+	podcasts, ok := j.subscriptions(origin)
+	if !ok {
+		return
+	}
+
+	j.update(func(index *resourceIndex) {
+		index.Crawl.Podcasts = len(podcasts)
+	})
+
+	// 单集列表中的标题、节目和发布时间，详情中没有时使用
+	episodes := map[string]resources.Episode{}
+
+	for _, pid := range podcasts {
+		items, ok := j.episodes(origin, pid, perPodcast)
+		if !ok {
+			return
+		}
+
+		for _, e := range items {
+			if _, found := episodes[e.Eid]; found {
+				continue
+			}
+
+			episodes[e.Eid] = e
+
+			j.update(func(index *resourceIndex) {
+				if reindex || !index.Has(e.Eid) {
+					index.Crawl.Pending = append(index.Crawl.Pending, e.Eid)
+				}
+			})
+		}
+	}
+
+	for {
+		index := j.snapshot()
+
+		if len(index.Crawl.Pending) == 0 {
+			j.complete()
+
+			return
+		}
+
+		if j.exhausted() {
+			return
+		}
+
+		eid := index.Crawl.Pending[0]
+
+		data, failed := j.call(origin, "/episode_detail", map[string]any{"eid": eid})
+		if failed != nil && retryable(failed.Status) {
+			j.fail(operationError(eid, "/episode_detail", failed).Error)
+
+			return
+		}
+
+		j.update(func(index *resourceIndex) {
+			index.Crawl.Pending = index.Crawl.Pending[1:]
+
+			if failed != nil {
+				index.Crawl.Skipped = append(index.Crawl.Skipped, eid)
+
+				return
+			}
+
+			detail, _ := data["data"].(map[string]any)
+			notes, _ := detail["shownotes"].(string)
+
+			index.Add(indexedEpisode(detail, episodes[eid]), resources.Extract(notes))
+		})
+
+		j.checkpoint()
+	}
+}
+
+// subscriptions 全部订阅的节目
+func (j resourceIndexJob) subscriptions(origin *http.Request) ([]string, bool) {
+	var pids []string
+	var cursor any
+
+	for {
+		body := map[string]any{}
+		if cursor != nil {
+			body["loadMoreKey"] = cursor
+		}
+
+		data, failed := j.call(origin, "/subscription", body)
+		if failed != nil {
+			j.fail(operationError("subscription", "/subscription", failed).Error)
+
+			return nil, false
+		}
+
+		items, _ := data["data"].([]any)
+		for _, item := range items {
+			if pid, _ := nested(map[string]any{"item": item}, "item", "pid").(string); pid != "" {
+				pids = append(pids, pid)
+			}
+		}
+
+		if cursor = data["loadMoreKey"]; cursor == nil || len(items) == 0 {
+			return pids, true
+		}
+	}
+}
+
+// episodes 节目最近的 limit 个单集
+func (j resourceIndexJob) episodes(origin *http.Request, pid string, limit int) ([]resources.Episode, bool) {
+	var result []resources.Episode
+	var cursor any
+
+	for len(result) < limit {
+		body := map[string]any{"pid": pid, "order": "desc"}
+		if cursor != nil {
+			body["loadMoreKey"] = cursor
+		}
+
+		data, failed := j.call(origin, "/episode_list", body)
+		if failed != nil {
+			j.fail(operationError(pid, "/episode_list", failed).Error)
+
+			return nil, false
+		}
+
+		items, _ := data["data"].([]any)
+		for _, item := range items {
+			m, _ := item.(map[string]any)
+			if e := indexedEpisode(m, resources.Episode{}); e.Eid != "" && len(result) < limit {
+				result = append(result, e)
+			}
+		}
+
+		if cursor = data["loadMoreKey"]; cursor == nil || len(items) == 0 {
+			break
+		}
+	}
+
+	return result, true
+}
+
+// indexedEpisode 上游的单集，缺少的字段使用 fallback 中的值
+func indexedEpisode(m map[string]any, fallback resources.Episode) resources.Episode {
+	e := fallback
+
+	if eid, _ := m["eid"].(string); eid != "" {
+		e.Eid = eid
+	}
+
+	if title, _ := m["title"].(string); title != "" {
+		e.Title = title
+	}
+
+	if pid, _ := m["pid"].(string); pid != "" {
+		e.Pid = pid
+	}
+
+	if title, _ := nested(m, "podcast", "title").(string); title != "" {
+		e.PodcastTitle = title
+	}
+
+	if pubDate, ok := m["pubDate"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, pubDate); err == nil {
+			e.PubDate = t
+		}
+	}
+
+	return e
+}
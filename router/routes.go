@@ -0,0 +1,176 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/handlers"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// Route 路由表中的一项，同时用于注册路由和生成 OpenAPI 文档
+type Route struct {
+	Method  string
+	Path    string
+	Summary string
+	Auth    bool     // 是否需要 x-jike-access-token
+	Session bool     // 是否需要 xyz 签发的 API Key 或会话 Cookie
+	Scope   string   // 开启 -auth 时 API Key 所需的权限，默认为 read，/v2 的非 GET 接口默认为 write
+	Cache   bool     // 是否使用 WithConditionalGet 缓存响应
+	Outbox  bool     // 是否支持通过 x-xyz-outbox 在上游不可用时保存到待发送队列
+	Body    any      // 请求体类型，仅用于生成文档
+	Query   []string // 查询参数，仅用于生成文档
+	Handler gin.HandlerFunc
+}
+
+// Routes 原有的 POST 接口
+var Routes = []Route{
+	{Method: http.MethodPost, Path: "/sendCode", Summary: "发送验证码", Scope: utils.ScopeWrite, Body: handlers.SendCodeRequestBody{}, Handler: handlers.SendCode},
+	{Method: http.MethodPost, Path: "/login", Summary: "验证码登录", Scope: utils.ScopeWrite, Body: handlers.LoginOrSignUpWithSMSRequestBody{}, Handler: handlers.Login},
+	{Method: http.MethodPost, Path: "/subscription", Summary: "订阅列表", Auth: true, Cache: true, Body: handlers.SubscriptionBody{}, Handler: handlers.Subscription},
+	{Method: http.MethodPost, Path: "/subscription_update", Summary: "更新订阅", Scope: utils.ScopeWrite, Auth: true, Outbox: true, Body: handlers.SubscriptionUpdateRequestBody{}, Handler: handlers.SubscriptionUpdate},
+	{Method: http.MethodPost, Path: "/subscription_star", Summary: "星标订阅", Auth: true, Handler: handlers.StarSubscription},
+	{Method: http.MethodPost, Path: "/subscription_non_starred", Summary: "未加星标订阅", Auth: true, Handler: handlers.NonStarredSubscription},
+	{Method: http.MethodPost, Path: "/subscription_star_update", Summary: "更新星标订阅", Scope: utils.ScopeWrite, Auth: true, Body: handlers.UpdateStarSubscriptionRequestBody{}, Handler: handlers.UpdateStarSubscription},
+	{Method: http.MethodPost, Path: "/search", Summary: "搜索", Auth: true, Body: handlers.SearchRequestBody{}, Handler: handlers.Search},
+	{Method: http.MethodPost, Path: "/search_preset", Summary: "「你可能想搜的内容」", Auth: true, Handler: handlers.SearchPreset},
+	{Method: http.MethodPost, Path: "/refresh_token", Summary: "刷新 token", Scope: utils.ScopeWrite, Body: handlers.RefreshTokenRequestBody{}, Handler: handlers.RefreshToken},
+	{Method: http.MethodPost, Path: "/accounts", Summary: "账号列表", Scope: utils.ScopeAdmin, Session: true, Handler: handlers.AccountList},
+	{Method: http.MethodPost, Path: "/accounts/remove", Summary: "删除账号", Scope: utils.ScopeAdmin, Session: true, Body: handlers.AccountRemoveRequestBody{}, Handler: handlers.AccountRemove},
+	{Method: http.MethodPost, Path: "/episode_list", Summary: "剧集列表", Auth: true, Cache: true, Body: handlers.EpisodeListRequestBody{}, Handler: handlers.EpisodeList},
+	{Method: http.MethodPost, Path: "/episode_list_by_filter", Summary: "节目内「最受欢迎」单集列表", Auth: true, Body: handlers.EpisodeListByFilterRequestBody{}, Handler: handlers.EpisodeListByFilter},
+	{Method: http.MethodPost, Path: "/episode_detail", Summary: "查询单集详情", Auth: true, Cache: true, Body: handlers.EpisodeDetailRequestBody{}, Handler: handlers.EpisodeDetail},
+	{Method: http.MethodPost, Path: "/podcast_detail", Summary: "查询节目详情", Auth: true, Cache: true, Body: handlers.PodcastDetailRequestBody{}, Handler: handlers.PodcastDetail},
+	{Method: http.MethodPost, Path: "/podcast_get_info", Summary: "获取节目主体信息", Auth: true, Body: handlers.PodcastGetInfoBody{}, Handler: handlers.PodcastGetInfo},
+	{Method: http.MethodPost, Path: "/podcast_honor_list", Summary: "获取节目荣誉墙", Auth: true, Body: handlers.PodcastHonorListBody{}, Handler: handlers.PodcastHonorList},
+	{Method: http.MethodPost, Path: "/podcast_related", Summary: "相关节目推荐", Auth: true, Body: handlers.RelatedPodcastListRequestBody{}, Handler: handlers.RelatedPodcastList},
+	{Method: http.MethodPost, Path: "/podcast_bulletin", Summary: "获取节目公告", Auth: true, Body: handlers.PodcastBulletinRequestBody{}, Handler: handlers.PodcastBulletin},
+	{Method: http.MethodPost, Path: "/profile", Summary: "根据 uid 查询用户信息", Auth: true, Cache: true, Handler: handlers.Profile},
+	{Method: http.MethodPost, Path: "/sticker", Summary: "根据 uid 查询已获得的贴纸", Auth: true, Body: handlers.StickerListRequestBody{}, Handler: handlers.StickerList},
+	{Method: http.MethodPost, Path: "/sticker_board", Summary: "查询我的贴纸墙", Auth: true, Body: handlers.StickerBoardRequestBody{}, Handler: handlers.StickerBoard},
+	{Method: http.MethodPost, Path: "/episode_play_progress", Summary: "查询单集播放进度", Auth: true, Body: handlers.PlaybackProgressRequestBody{}, Handler: handlers.PlaybackProgress},
+	{Method: http.MethodPost, Path: "/episode_play_progress_update", Summary: "更新单集播放进度", Scope: utils.ScopeWrite, Auth: true, Outbox: true, Body: handlers.UpdatePlaybackProgressRequestBody{}, Handler: handlers.UpdatePlaybackProgress},
+	{Method: http.MethodPost, Path: "/comment_primary", Summary: "查询单集的评论", Auth: true, Body: handlers.CommentPrimaryRequestBody{}, Handler: handlers.CommentPrimary},
+	{Method: http.MethodPost, Path: "/comment_thread", Summary: "查询回复评论", Auth: true, Body: handlers.CommentThreadRequestBody{}, Handler: handlers.CommentThread},
+	{Method: http.MethodPost, Path: "/comment_collect_create", Summary: "收藏评论", Scope: utils.ScopeWrite, Auth: true, Body: handlers.CommentCollect{}, Handler: handlers.CreateCommentCollect},
+	{Method: http.MethodPost, Path: "/comment_collect_remove", Summary: "取消收藏评论", Scope: utils.ScopeWrite, Auth: true, Body: handlers.CommentCollect{}, Handler: handlers.RemoveCommentCollect},
+	{Method: http.MethodPost, Path: "/comment_collect_list", Summary: "获取收藏评论列表", Auth: true, Handler: handlers.CommentCollectList},
+	{Method: http.MethodPost, Path: "/comment_like_update", Summary: "点赞/取消点赞评论", Scope: utils.ScopeWrite, Auth: true, Outbox: true, Body: handlers.CommentLikeUpdateBody{}, Handler: handlers.CommentLikeUpdate},
+	{Method: http.MethodPost, Path: "/discovery", Summary: "首页榜单、精选节目、推荐等", Auth: true, Body: handlers.DiscoveryRequestBody{}, Handler: handlers.Discovery},
+	{Method: http.MethodPost, Path: "/refresh_episode_recommend", Summary: "首页大家都在听-刷新推荐", Auth: true, Handler: handlers.RefreshEpisodeRecommend},
+	{Method: http.MethodPost, Path: "/episode_live_count", Summary: "正在收听的人数", Auth: true, Body: handlers.EpisodeDetailRequestBody{}, Handler: handlers.Live},
+	{Method: http.MethodPost, Path: "/live_stats_report", Summary: "上报播放状态", Scope: utils.ScopeWrite, Auth: true, Body: handlers.LiveStatsReportRequestBody{}, Handler: handlers.LiveStatsReport},
+	{Method: http.MethodPost, Path: "/episode_clap", Summary: "精彩时间点", Auth: true, Body: handlers.ClapRequestBody{}, Handler: handlers.Clap},
+	{Method: http.MethodPost, Path: "/episode_clap_create", Summary: "标记精彩时间点", Scope: utils.ScopeWrite, Auth: true, Outbox: true, Body: handlers.CreateClapRequestBody{}, Handler: handlers.CreateClap},
+	{Method: http.MethodPost, Path: "/inbox_list", Summary: "订阅更新列表", Auth: true, Cache: true, Body: handlers.InboxListRequestBody{}, Handler: handlers.InboxList},
+	{Method: http.MethodPost, Path: "/category_list", Summary: "全部分类", Auth: true, Handler: handlers.CategoryList},
+	{Method: http.MethodPost, Path: "/category_list_tab", Summary: "获取分类下的标签", Auth: true, Body: handlers.CategoryListTabByIdRequestBody{}, Handler: handlers.CategoryListTabById},
+	{Method: http.MethodPost, Path: "/category_podcast_list", Summary: "根据标签获取分类下的节目列表", Auth: true, Body: handlers.CategoryPodcastListByTabRequestBody{}, Handler: handlers.CategoryPodcastListByTab},
+	{Method: http.MethodPost, Path: "/favorite_episode_update", Summary: "更新收藏单集", Scope: utils.ScopeWrite, Auth: true, Outbox: true, Body: handlers.UpdateFavoriteRequestBody{}, Handler: handlers.UpdateEpisodeFavorite},
+	{Method: http.MethodPost, Path: "/favorite_episode_list", Summary: "获取收藏单集列表", Auth: true, Handler: handlers.FavoriteEpisodeList},
+	{Method: http.MethodPost, Path: "/episode_played_history_list", Summary: "收听历史", Auth: true, Cache: true, Body: handlers.EpisodePlayedHistoryListRequestBody{}, Handler: handlers.EpisodePlayedHistoryList},
+	{Method: http.MethodPost, Path: "/episode_played_history_list_update", Summary: "更新收听历史", Scope: utils.ScopeWrite, Auth: true, Body: handlers.UpdateEpisodePlayedHistoryListRequestBody{}, Handler: handlers.UpdateEpisodePlayedHistoryList},
+	{Method: http.MethodPost, Path: "/unread_count", Summary: "未读消息", Auth: true, Handler: handlers.UnreadCount},
+	{Method: http.MethodPost, Path: "/user_stats", Summary: "用户统计数据", Auth: true, Body: handlers.UserStatsBody{}, Handler: handlers.GetUserStats},
+	{Method: http.MethodPost, Path: "/get_profile", Summary: "根据 uid 查询用户信息", Auth: true, Body: handlers.GetProfileByUidBody{}, Handler: handlers.GetProfileByUid},
+	{Method: http.MethodPost, Path: "/mileage_get", Summary: "获取收听数据概览", Auth: true, Handler: handlers.GetMileage},
+	{Method: http.MethodPost, Path: "/mileage_list", Summary: "获取收听排行", Auth: true, Body: handlers.MileageBody{}, Handler: handlers.GetMileageList},
+	{Method: http.MethodPost, Path: "/mileage_update", Summary: "更新收听数据概览", Scope: utils.ScopeWrite, Auth: true, Body: handlers.UpdateMileageRequestBody{}, Handler: handlers.UpdateMileage},
+	{Method: http.MethodPost, Path: "/played_list", Summary: "获取收听历史记录", Auth: true, Body: handlers.PlayedListBody{}, Handler: handlers.PlayedList},
+	{Method: http.MethodPost, Path: "/pick_list_recent", Summary: "获取「用户的喜欢」部分片段", Auth: true, Body: handlers.PickBody{}, Handler: handlers.PickListRecent},
+	{Method: http.MethodPost, Path: "/pick_list_history", Summary: "获取「用户的喜欢」全部内容", Auth: true, Body: handlers.PickBody{}, Handler: handlers.PickListHistory},
+	{Method: http.MethodPost, Path: "/owned_podcasts", Summary: "获取用户创建的播客", Auth: true, Body: handlers.OwnedPodcastsListBody{}, Handler: handlers.OwnedPodcastsList},
+	{Method: http.MethodPost, Path: "/top_list", Summary: "获取榜单", Auth: true, Body: handlers.TopBody{}, Handler: handlers.GetTopList},
+	{Method: http.MethodPost, Path: "/following_list", Summary: "获取「我」关注的人", Auth: true, Body: handlers.FollowingBody{}, Handler: handlers.FollowingList},
+	{Method: http.MethodPost, Path: "/follower_list", Summary: "获取关注「我」的人", Auth: true, Body: handlers.FollowingBody{}, Handler: handlers.FollowerList},
+	{Method: http.MethodPost, Path: "/blocked_user_lists", Summary: "查询黑名单列表", Auth: true, Handler: handlers.BlockedUserLists},
+	{Method: http.MethodPost, Path: "/blocked_user_create", Summary: "将用户加入黑名单", Scope: utils.ScopeWrite, Auth: true, Body: handlers.BlockedUserBody{}, Handler: handlers.BlockedUserCreate},
+	{Method: http.MethodPost, Path: "/blocked_user_remove", Summary: "将用户移出黑名单", Scope: utils.ScopeWrite, Auth: true, Body: handlers.BlockedUserBody{}, Handler: handlers.BlockedUserRemove},
+	{Method: http.MethodPost, Path: "/user_preference_get", Summary: "获取用户偏好设置", Auth: true, Handler: handlers.UserPreferenceGet},
+	{Method: http.MethodPost, Path: "/user_preference_update", Summary: "更新用户偏好设置", Scope: utils.ScopeWrite, Auth: true, Body: handlers.UserPreferenceUpdateBody{}, Handler: handlers.UserPreferenceUpdate},
+	{Method: http.MethodPost, Path: "/relation_update", Summary: "关注/取关用户", Scope: utils.ScopeWrite, Auth: true, Body: handlers.RelationUpdateRequestBody{}, Handler: handlers.RelationUpdate},
+}
+
+// featureRoutes 通过 runOperation、runBatch 调用其它接口的功能接口，由 batch.go 中的 init 加入 Routes
+func featureRoutes() []Route {
+	return []Route{
+		// 批量请求
+		{Method: http.MethodPost, Path: "/batch", Summary: "批量请求", Body: BatchRequestBody{}, Handler: Batch},
+
+		// 收听统计
+		{Method: http.MethodPost, Path: "/listening_stats", Summary: "收听统计", Auth: true, Body: ListeningStatsRequestBody{}, Handler: ListeningStats},
+		{Method: http.MethodPost, Path: "/listening_report", Summary: "收听报告（HTML）", Auth: true, Body: ListeningStatsRequestBody{}, Handler: ListeningReport},
+
+		// 评论存档
+		{Method: http.MethodPost, Path: "/comment_archive", Summary: "抓取并保存单集的全部评论", Scope: utils.ScopeWrite, Auth: true, Body: CommentArchiveRequestBody{}, Handler: CommentArchive},
+		{Method: http.MethodPost, Path: "/comment_archive_search", Summary: "查询保存的评论", Body: CommentArchiveSearchRequestBody{}, Handler: CommentArchiveSearch},
+		{Method: http.MethodPost, Path: "/comment_archive_export", Summary: "导出保存的评论", Body: CommentArchiveExportRequestBody{}, Handler: CommentArchiveExport},
+
+		// 单集章节
+		{Method: http.MethodPost, Path: "/episode_chapters", Summary: "单集章节和 shownotes 的 Markdown、纯文本", Auth: true, Body: EpisodeChaptersRequestBody{}, Handler: EpisodeChapters},
+
+		// 榜单存档
+		{Method: http.MethodPost, Path: "/top_list_archive", Summary: "开启或关闭榜单存档", Scope: utils.ScopeWrite, Auth: true, Body: TopListArchiveRequestBody{}, Handler: TopListArchive},
+		{Method: http.MethodPost, Path: "/top_list_rank_history", Summary: "单集或节目的榜单排名历史", Body: TopListRankHistoryRequestBody{}, Handler: TopListRankHistory},
+		{Method: http.MethodPost, Path: "/top_list_movers", Summary: "榜单中排名变化最大的单集", Body: TopListMoversRequestBody{}, Handler: TopListMovers},
+		{Method: http.MethodPost, Path: "/top_list_longest", Summary: "在榜时间最长的单集或节目", Body: TopListLongestRequestBody{}, Handler: TopListLongest},
+		{Method: http.MethodPost, Path: "/top_list_export", Summary: "导出榜单快照", Body: TopListExportRequestBody{}, Handler: TopListExport},
+
+		// 精彩片段
+		{Method: http.MethodPost, Path: "/episode_clap_highlights", Summary: "精彩时间点热力图和精彩片段", Auth: true, Body: ClapHighlightsRequestBody{}, Handler: ClapHighlights},
+
+		// 节目目录
+		{Method: http.MethodPost, Path: "/directory_crawl", Summary: "抓取全部分类下的节目", Scope: utils.ScopeWrite, Auth: true, Body: DirectoryCrawlRequestBody{}, Handler: DirectoryCrawl},
+		{Method: http.MethodPost, Path: "/directory_categories", Summary: "节目目录中的分类", Handler: DirectoryCategories},
+		{Method: http.MethodGet, Path: "/directory_search", Summary: "查询节目目录", Query: []string{"keyword", "categoryId", "tab", "minSubscriptions", "maxSubscriptions", "minEpisodes", "maxEpisodes", "updatedAfter", "updatedBefore", "timezone", "sort", "reverse", "limit", "offset"}, Handler: DirectorySearch},
+		{Method: http.MethodPost, Path: "/directory_search", Summary: "查询节目目录", Body: DirectorySearchRequestBody{}, Handler: DirectorySearch},
+
+		// 相关节目图
+		{Method: http.MethodPost, Path: "/podcast_graph", Summary: "从一个节目出发逐层展开相关节目", Scope: utils.ScopeWrite, Auth: true, Body: PodcastGraphRequestBody{}, Handler: PodcastGraph},
+		{Method: http.MethodPost, Path: "/podcast_graph_path", Summary: "相关节目图中两个节目之间的最短路径", Body: PodcastGraphPathRequestBody{}, Handler: PodcastGraphPath},
+		{Method: http.MethodPost, Path: "/podcast_graph_clusters", Summary: "相关节目图中的社区", Body: PodcastGraphClustersRequestBody{}, Handler: PodcastGraphClusters},
+		{Method: http.MethodPost, Path: "/podcast_graph_central", Summary: "相关节目图中最核心的节目", Body: PodcastGraphCentralRequestBody{}, Handler: PodcastGraphCentral},
+		{Method: http.MethodGet, Path: "/podcast_graph_export", Summary: "导出相关节目图", Query: []string{"pid", "format"}, Handler: PodcastGraphExport},
+		{Method: http.MethodPost, Path: "/podcast_graph_export", Summary: "导出相关节目图", Body: PodcastGraphExportRequestBody{}, Handler: PodcastGraphExport},
+
+		// GraphQL
+		{Method: http.MethodPost, Path: "/graphql", Summary: "GraphQL 查询", Auth: true, Body: GraphQLRequestBody{}, Handler: GraphQL},
+
+		// 节目变更记录，关注按账号区分，变更记录是公开的节目元数据，不需要认证信息
+		{Method: http.MethodPost, Path: "/podcast_watch", Summary: "关注或取消关注节目的元数据变化", Scope: utils.ScopeWrite, Auth: true, Body: PodcastWatchRequestBody{}, Handler: PodcastWatch},
+		{Method: http.MethodPost, Path: "/podcast_watch_list", Summary: "关注元数据变化的节目", Auth: true, Handler: PodcastWatchList},
+		{Method: http.MethodGet, Path: "/podcast_history", Summary: "节目元数据的变更记录", Query: []string{"pid", "field", "limit", "offset"}, Handler: PodcastHistory},
+		{Method: http.MethodPost, Path: "/podcast_history", Summary: "节目元数据的变更记录", Body: PodcastHistoryRequestBody{}, Handler: PodcastHistory},
+
+		// 待发送队列
+		{Method: http.MethodPost, Path: "/outbox", Summary: "查询待发送队列", Auth: true, Body: OutboxRequestBody{}, Handler: Outbox},
+
+		// 页面聚合数据
+		{Method: http.MethodPost, Path: "/podcast_page", Summary: "节目页聚合数据", Auth: true, Body: PodcastPageRequestBody{}, Handler: PodcastPage},
+		{Method: http.MethodPost, Path: "/episode_page", Summary: "单集页聚合数据", Auth: true, Body: EpisodePageRequestBody{}, Handler: EpisodePage},
+
+		// 播放事件
+		{Method: http.MethodPost, Path: "/playback/events", Summary: "上报播放事件", Scope: utils.ScopeWrite, Auth: true, Body: PlaybackEventsRequestBody{}, Handler: PlaybackEvents},
+
+		// 热度采集
+		{Method: http.MethodPost, Path: "/popularity_track", Summary: "开始或停止采集节目、单集的热度", Scope: utils.ScopeWrite, Auth: true, Body: PopularityTrackRequestBody{}, Handler: PopularityTrack},
+		{Method: http.MethodPost, Path: "/popularity_track_list", Summary: "采集热度的节目和单集", Handler: PopularityTrackList},
+		{Method: http.MethodGet, Path: "/popularity_series", Summary: "节目、单集热度的时间序列", Query: []string{"eid", "pid", "from", "to", "timezone", "resolution"}, Handler: PopularitySeries},
+		{Method: http.MethodPost, Path: "/popularity_series", Summary: "节目、单集热度的时间序列", Body: PopularitySeriesRequestBody{}, Handler: PopularitySeries},
+		{Method: http.MethodPost, Path: "/popularity_growth", Summary: "热度增长最快的节目或单集", Body: PopularityGrowthRequestBody{}, Handler: PopularityGrowth},
+
+		// 资源索引
+		{Method: http.MethodPost, Path: "/resource_index", Summary: "提取订阅节目的 shownotes 中提到的资源", Scope: utils.ScopeWrite, Auth: true, Body: ResourceIndexRequestBody{}, Handler: ResourceIndex},
+		{Method: http.MethodPost, Path: "/resource_search", Summary: "查询节目中提到的资源", Auth: true, Body: ResourceSearchRequestBody{}, Handler: ResourceSearch},
+		{Method: http.MethodPost, Path: "/resource_export", Summary: "导出节目中提到的资源", Auth: true, Body: ResourceExportRequestBody{}, Handler: ResourceExport},
+
+		// 关注关系
+		{Method: http.MethodPost, Path: "/social_snapshot", Summary: "获取全部关注和粉丝的快照", Scope: utils.ScopeWrite, Auth: true, Body: SocialSnapshotRequestBody{}, Handler: SocialSnapshot},
+		{Method: http.MethodGet, Path: "/social_relations", Summary: "互相关注、未回关的人和粉丝", Auth: true, Query: []string{"kind", "keyword", "limit", "offset"}, Handler: SocialRelations},
+		{Method: http.MethodPost, Path: "/social_relations", Summary: "互相关注、未回关的人和粉丝", Auth: true, Body: SocialRelationsRequestBody{}, Handler: SocialRelations},
+		{Method: http.MethodPost, Path: "/social_changes", Summary: "新增的粉丝和取消关注的人", Auth: true, Body: SocialChangesRequestBody{}, Handler: SocialChanges},
+		{Method: http.MethodPost, Path: "/social_export", Summary: "导出关注关系", Auth: true, Handler: SocialExport},
+		{Method: http.MethodPost, Path: "/social_bulk_relation", Summary: "批量关注或取关", Scope: utils.ScopeWrite, Auth: true, Body: SocialBulkRelationRequestBody{}, Handler: SocialBulkRelation},
+		{Method: http.MethodPost, Path: "/social_bulk_status", Summary: "批量关注或取关的进度", Auth: true, Handler: SocialBulkStatus},
+	}
+}
@@ -0,0 +1,33 @@
+package router
+
+import "testing"
+
+func TestRoutes(t *testing.T) {
+	seen := map[string]bool{}
+	for _, route := range Routes {
+		key := route.Method + " " + route.Path
+		if seen[key] {
+			t.Errorf("%s registered twice", key)
+		}
+
+		seen[key] = true
+	}
+
+	tests := []struct {
+		name  string
+		route string
+	}{
+		{"原有接口", "POST /login"},
+		{"批量请求", "POST /batch"},
+		{"功能接口", "POST /podcast_page"},
+		{"GET 查询", "GET /podcast_history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !seen[tt.route] {
+				t.Errorf("%s not registered", tt.route)
+			}
+		})
+	}
+}
@@ -0,0 +1,648 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/social"
+	"github.com/ultrazg/xyz/utils"
+)
+
+const (
+	// socialGraphBucket 关注和粉丝的快照、变化记录和获取进度，key 为账号
+	socialGraphBucket = "social_graph"
+	// socialBulkBucket 最近一次批量关注或取关的进度，key 为账号
+	socialBulkBucket = "social_bulk"
+	// socialInterval 查询关注和粉丝列表的请求间隔
+	socialInterval = 500 * time.Millisecond
+	// socialRelationInterval 批量关注或取关的请求间隔，比查询更慢以免触发风控
+	socialRelationInterval = 2 * time.Second
+	// socialMaxRequests 单次快照最多发送的请求数
+	socialMaxRequests = 5000
+	// socialMaxChanges 最多保留的变化记录数
+	socialMaxChanges = 1000
+	// socialDefaultLimit 查询默认返回的数量
+	socialDefaultLimit = 50
+)
+
+var (
+	// socialJobs 获取快照的任务，key 为账号
+	socialJobs = &crawlJobs[*socialGraph]{
+		name:        "social",
+		bucket:      socialGraphBucket,
+		limiter:     &rateLimiter{interval: socialInterval},
+		maxRequests: socialMaxRequests,
+		interrupted: "snapshot interrupted",
+		hint:        "call /social_snapshot again",
+	}
+	// socialBulkJobs 批量关注或取关的任务，key 为账号
+	socialBulkJobs = &crawlJobs[*socialBulk]{
+		name:        "social bulk",
+		bucket:      socialBulkBucket,
+		limiter:     &rateLimiter{interval: socialRelationInterval},
+		interrupted: "bulk relation interrupted",
+		hint:        "call /social_bulk_relation again with the pending users",
+	}
+)
+
+// socialGraph 最近一次完成的快照、变化记录和获取进度。
+// 快照在全部列表获取完成后才替换，获取失败时保留上一次的快照
+type socialGraph struct {
+	Snapshot *social.Snapshot `json:"snapshot,omitempty"`
+	Changes  []social.Change  `json:"changes"` // 按时间正序排列
+	Crawl    socialCrawl      `json:"crawl"`
+}
+
+type socialCrawl struct {
+	crawlStatus
+	Fetched int `json:"fetched"` // 本次任务已获取的用户数
+}
+
+// socialJob 正在运行的快照任务
+type socialJob struct {
+	*crawlJob[*socialGraph]
+}
+
+type SocialSnapshotRequestBody struct {
+	Refresh bool `json:"refresh" form:"refresh"` // 已完成时重新获取，与上一次的快照比较
+}
+
+// SocialSnapshotData 获取进度和最近一次快照的统计
+type SocialSnapshotData struct {
+	Status       string        `json:"status"` // running、completed 或 failed
+	Uid          string        `json:"uid"`
+	Nickname     string        `json:"nickname"`
+	SnapshotAt   *time.Time    `json:"snapshotAt,omitempty"` // 最近一次完成的快照的时间
+	Counts       social.Counts `json:"counts"`
+	NewFollowers int           `json:"newFollowers"` // 与上一次快照相比新增的粉丝
+	Unfollowers  int           `json:"unfollowers"`  // 与上一次快照相比取消关注的粉丝
+	Fetched      int           `json:"fetched"`
+	Requests     int           `json:"requests"`
+	Error        any           `json:"error,omitempty"`
+	StartedAt    time.Time     `json:"startedAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	CompletedAt  *time.Time    `json:"completedAt,omitempty"`
+}
+
+// SocialSnapshot 在后台限速逐页获取当前账号的全部关注和粉丝，完成后与上一次的快照比较并记录变化，立即返回获取进度。
+// 任务正在运行或已完成时只返回进度，传入 refresh 时重新获取
+var SocialSnapshot = func(ctx *gin.Context) {
+	var params SocialSnapshotRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	owner := ownerOf(ctx)
+
+	job, running := socialJobs.start(owner)
+	if job == nil {
+		returnSocialSnapshot(ctx, running)
+
+		return
+	}
+
+	graph, err := loadSocialGraph(owner)
+	if err != nil {
+		job.cancel()
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	if graph.Crawl.Status == CrawlCompleted && !params.Refresh {
+		job.cancel()
+		returnSocialSnapshot(ctx, graph)
+
+		return
+	}
+
+	graph.Crawl = socialCrawl{}
+	graph.Crawl.StartedAt = time.Now()
+
+	job.launch(ctx, graph, socialJob{job}.run)
+
+	returnSocialSnapshot(ctx, job.snapshot())
+}
+
+type SocialRelationsRequestBody struct {
+	Kind    string `json:"kind" form:"kind" binding:"required,oneof=following followers mutual not_following_back fans"` // not_following_back 为关注了但没有回关的人，fans 为没有回关的粉丝
+	Keyword string `json:"keyword" form:"keyword"`                                                                       // 昵称或 uid 中包含的文字，不区分大小写
+	Limit   int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=500"`                                         // 默认为 50
+	Offset  int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+type SocialRelationsData struct {
+	SnapshotAt time.Time         `json:"snapshotAt"`
+	Total      int               `json:"total"`
+	Users      []social.Relation `json:"users"`
+}
+
+// SocialRelations 最近一次快照中的关注、粉丝、互相关注、关注了但没有回关的人和没有回关的粉丝，支持 GET 和 POST
+var SocialRelations = func(ctx *gin.Context) {
+	var params SocialRelationsRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	graph, ok := snapshottedGraph(ctx)
+	if !ok {
+		return
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = socialDefaultLimit
+	}
+
+	result := graph.Snapshot.Filter(params.Kind, params.Keyword)
+	page := result[min(params.Offset, len(result)):min(params.Offset+limit, len(result))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": SocialRelationsData{SnapshotAt: graph.Snapshot.At, Total: len(result), Users: page},
+	})
+}
+
+type SocialChangesRequestBody struct {
+	Kind   string `json:"kind" form:"kind" binding:"omitempty,oneof=follower_added follower_removed following_added following_removed"` // 默认为全部
+	Limit  int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=500"`                                                         // 默认为 50
+	Offset int    `json:"offset" form:"offset" binding:"min=0"`
+}
+
+type SocialChangesData struct {
+	Total   int             `json:"total"`
+	Changes []social.Change `json:"changes"`
+}
+
+// SocialChanges 每次快照与上一次相比的变化，按时间倒序排列：新增的粉丝、取消关注的粉丝、新关注和取消关注的人
+var SocialChanges = func(ctx *gin.Context) {
+	var params SocialChangesRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	graph, ok := snapshottedGraph(ctx)
+	if !ok {
+		return
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = socialDefaultLimit
+	}
+
+	result := []social.Change{}
+	for i := len(graph.Changes) - 1; i >= 0; i-- {
+		if c := graph.Changes[i]; params.Kind == "" || c.Kind == params.Kind {
+			result = append(result, c)
+		}
+	}
+
+	page := result[min(params.Offset, len(result)):min(params.Offset+limit, len(result))]
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": SocialChangesData{Total: len(result), Changes: page},
+	})
+}
+
+// SocialExport 将最近一次快照导出为 CSV 边表，每条关注关系一行
+var SocialExport = func(ctx *gin.Context) {
+	graph, ok := snapshottedGraph(ctx)
+	if !ok {
+		return
+	}
+
+	var b bytes.Buffer
+	if err := social.CSV(&b, graph.Snapshot); err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="social_%s.csv"`, graph.Snapshot.Uid))
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", b.Bytes())
+}
+
+// snapshottedGraph 快照任务运行中时返回上一次完成的快照，没有快照时返回 404
+func snapshottedGraph(ctx *gin.Context) (*socialGraph, bool) {
+	owner := ownerOf(ctx)
+
+	if graph, ok := socialJobs.current(owner); ok && graph.Snapshot != nil {
+		return graph, true
+	}
+
+	graph, err := loadSocialGraph(owner)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return nil, false
+	}
+
+	if graph.Snapshot == nil {
+		utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, "no social snapshot, call /social_snapshot first"))
+
+		return nil, false
+	}
+
+	return graph, true
+}
+
+func returnSocialSnapshot(ctx *gin.Context, graph *socialGraph) {
+	data := SocialSnapshotData{
+		Status:      graph.Crawl.Status,
+		Fetched:     graph.Crawl.Fetched,
+		Requests:    graph.Crawl.Requests,
+		Error:       graph.Crawl.Error,
+		StartedAt:   graph.Crawl.StartedAt,
+		UpdatedAt:   graph.Crawl.UpdatedAt,
+		CompletedAt: graph.Crawl.CompletedAt,
+	}
+
+	if s := graph.Snapshot; s != nil {
+		data.Uid, data.Nickname, data.SnapshotAt, data.Counts = s.Uid, s.Nickname, &s.At, s.Counts()
+
+		for _, c := range graph.Changes {
+			switch {
+			case !c.At.Equal(s.At):
+			case c.Kind == social.ChangeFollowerAdded:
+				data.NewFollowers++
+			case c.Kind == social.ChangeFollowerRemoved:
+				data.Unfollowers++
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+func loadSocialGraph(owner string) (*socialGraph, error) {
+	return socialJobs.load(owner, &socialGraph{Changes: []social.Change{}})
+}
+
+func (graph *socialGraph) progress() *crawlStatus {
+	return &graph.Crawl.crawlStatus
+}
+
+// clone 进度的副本，快照只在替换时修改，复制指针即可
+func (graph *socialGraph) clone() *socialGraph {
+	c := *graph
+	c.Changes = slices.Clone(c.Changes)
+
+	return &c
+}
+
+// run 查询当前账号，逐页获取全部关注和粉丝，完成后替换快照并记录变化
+func (j socialJob) run(origin *http.Request) {
+	data, failed := j.call(origin, "/profile", map[string]any{})
+	if failed != nil {
+		j.fail(operationError("profile", "/profile", failed).Error)
+
+		return
+	}
+
+	me := socialUser(data["data"])
+	if me.Uid == "" {
+		j.fail(utils.NewError(http.StatusBadGateway, utils.ErrUpstreamUnavailable, "profile has no uid"))
+
+		return
+	}
+
+	following, ok := j.users(origin, "/following_list", me.Uid)
+	if !ok {
+		return
+	}
+
+	followers, ok := j.users(origin, "/follower_list", me.Uid)
+	if !ok {
+		return
+	}
+
+	j.update(func(graph *socialGraph) {
+		now := time.Now()
+		current := &social.Snapshot{Uid: me.Uid, Nickname: me.Nickname, At: now, Following: following, Followers: followers}
+
+		// 切换了账号时不比较
+		previous := graph.Snapshot
+		if previous != nil && previous.Uid != current.Uid {
+			previous = nil
+		}
+
+		graph.Changes = append(graph.Changes, social.Diff(previous, current)...)
+		graph.Changes = graph.Changes[max(len(graph.Changes)-socialMaxChanges, 0):]
+		graph.Snapshot = current
+	})
+
+	j.complete()
+}
+
+// users 逐页获取关注或粉丝列表中的全部用户
+func (j socialJob) users(origin *http.Request, path, uid string) ([]social.User, bool) {
+	users := []social.User{}
+	seen := map[string]bool{}
+
+	var cursor any
+
+	for {
+		if j.exhausted() {
+			return nil, false
+		}
+
+		body := map[string]any{"uid": uid}
+		if cursor != nil {
+			body["loadMoreKey"] = cursor
+		}
+
+		data, failed := j.call(origin, path, body)
+		if failed != nil {
+			j.fail(operationError(uid, path, failed).Error)
+
+			return nil, false
+		}
+
+		items, _ := data["data"].([]any)
+		for _, item := range items {
+			if u := socialUser(item); u.Uid != "" && !seen[u.Uid] {
+				seen[u.Uid] = true
+				users = append(users, u)
+			}
+		}
+
+		j.update(func(graph *socialGraph) {
+			graph.Crawl.Fetched += len(items)
+		})
+
+		if cursor = data["loadMoreKey"]; cursor == nil || len(items) == 0 {
+			return users, true
+		}
+	}
+}
+
+// socialUser 关注或粉丝列表中的一项
+func socialUser(item any) social.User {
+	m, _ := item.(map[string]any)
+
+	u := social.User{}
+	u.Uid, _ = m["uid"].(string)
+	u.Nickname, _ = m["nickname"].(string)
+	u.Avatar, _ = nested(m, "avatar", "picture", "picUrl").(string)
+
+	return u
+}
+
+const (
+	SocialActionFollow   = "follow"
+	SocialActionUnfollow = "unfollow"
+	SocialActionSkip     = "skip"
+)
+
+type SocialBulkRelationRequestBody struct {
+	Uids     []string `json:"uids" form:"uids" binding:"required_without=Select,max=500,dive,required"` // 要关注或取关的用户，最多 500 个
+	Select   string   `json:"select" form:"select" binding:"omitempty,oneof=not_following_back fans"`   // 不传 uids 时从最近一次快照中选择：not_following_back 为关注了但没有回关的人，fans 为没有回关的粉丝
+	Relation string   `json:"relation" form:"relation" binding:"required,oneof=FOLLOWING STRANGE"`      // FOLLOWING 为关注，STRANGE 为取关
+	DryRun   bool     `json:"dryRun" form:"dryRun"`                                                     // 只返回将要执行的操作，不发送请求
+}
+
+// SocialBulkItem 批量任务中的一个用户
+type SocialBulkItem struct {
+	Uid      string `json:"uid"`
+	Nickname string `json:"nickname,omitempty"`
+	Action   string `json:"action"`           // follow、unfollow 或 skip
+	Reason   string `json:"reason,omitempty"` // 跳过的原因
+	Status   string `json:"status,omitempty"` // pending、done 或 failed，试运行时为空
+	Error    any    `json:"error,omitempty"`
+}
+
+// socialBulk 批量任务和进度
+type socialBulk struct {
+	crawlStatus
+	Relation string           `json:"relation"`
+	DryRun   bool             `json:"dryRun"`
+	Items    []SocialBulkItem `json:"items"`
+}
+
+// SocialBulkData 批量任务的进度
+type SocialBulkData struct {
+	Status      string           `json:"status"` // running、completed 或 failed，试运行时为空
+	Relation    string           `json:"relation"`
+	DryRun      bool             `json:"dryRun"`
+	Total       int              `json:"total"`
+	Pending     int              `json:"pending"`
+	Done        int              `json:"done"`
+	Failed      int              `json:"failed"`
+	Skipped     int              `json:"skipped"`
+	Items       []SocialBulkItem `json:"items"`
+	Error       any              `json:"error,omitempty"`
+	StartedAt   time.Time        `json:"startedAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	CompletedAt *time.Time       `json:"completedAt,omitempty"`
+}
+
+// socialBulkJob 正在运行的批量任务
+type socialBulkJob struct {
+	*crawlJob[*socialBulk]
+}
+
+// SocialBulkRelation 在后台按较慢的间隔批量关注或取关，立即返回进度；有快照时跳过已是目标关系的用户。
+// 传入 dryRun 时只返回将要执行的操作。每个账号同时只运行一个批量任务，运行中时返回该任务的进度
+var SocialBulkRelation = func(ctx *gin.Context) {
+	var params SocialBulkRelationRequestBody
+
+	if !utils.Bind(ctx, &params) {
+		return
+	}
+
+	owner := ownerOf(ctx)
+
+	if running, ok := socialBulkJobs.current(owner); ok && !params.DryRun {
+		returnSocialBulk(ctx, running)
+
+		return
+	}
+
+	graph, err := loadSocialGraph(owner)
+	if err != nil {
+		utils.ReturnError(ctx, err)
+
+		return
+	}
+
+	uids := params.Uids
+	if len(uids) == 0 {
+		if graph.Snapshot == nil {
+			utils.ReturnError(ctx, utils.NewError(http.StatusNotFound, utils.ErrNotFound, "no social snapshot to select from, call /social_snapshot first"))
+
+			return
+		}
+
+		for _, r := range graph.Snapshot.Filter(params.Select, "") {
+			uids = append(uids, r.Uid)
+		}
+	}
+
+	now := time.Now()
+
+	bulk := &socialBulk{Relation: params.Relation, DryRun: params.DryRun, Items: socialBulkPlan(graph.Snapshot, uids, params.Relation)}
+	bulk.StartedAt, bulk.UpdatedAt = now, now
+	if params.DryRun {
+		returnSocialBulk(ctx, bulk)
+
+		return
+	}
+
+	for i := range bulk.Items {
+		if bulk.Items[i].Action != SocialActionSkip {
+			bulk.Items[i].Status = "pending"
+		}
+	}
+
+	job, running := socialBulkJobs.start(owner)
+	if job == nil {
+		returnSocialBulk(ctx, running)
+
+		return
+	}
+
+	job.launch(ctx, bulk, socialBulkJob{job}.run)
+
+	returnSocialBulk(ctx, job.snapshot())
+}
+
+// SocialBulkStatus 正在运行或最近一次批量任务的进度
+var SocialBulkStatus = func(ctx *gin.Context) {
+	bulk, ok := socialBulkJobs.find(ctx, ownerOf(ctx), &socialBulk{}, "no bulk relation job")
+	if !ok {
+		return
+	}
+
+	returnSocialBulk(ctx, bulk)
+}
+
+// socialBulkPlan 每个用户要执行的操作，跳过重复的用户；有快照时跳过已关注（关注时）或未关注（取关时）的用户
+func socialBulkPlan(snapshot *social.Snapshot, uids []string, relation string) []SocialBulkItem {
+	relations := map[string]social.Relation{}
+	if snapshot != nil {
+		for _, r := range snapshot.Relations() {
+			relations[r.Uid] = r
+		}
+	}
+
+	action := SocialActionFollow
+	if relation == "STRANGE" {
+		action = SocialActionUnfollow
+	}
+
+	items := []SocialBulkItem{}
+	seen := map[string]bool{}
+
+	for _, uid := range uids {
+		if seen[uid] {
+			continue
+		}
+
+		seen[uid] = true
+
+		r := relations[uid]
+		item := SocialBulkItem{Uid: uid, Nickname: r.Nickname, Action: action}
+
+		switch {
+		case snapshot != nil && uid == snapshot.Uid:
+			item.Action, item.Reason = SocialActionSkip, "self"
+		case snapshot != nil && action == SocialActionFollow && r.Following:
+			item.Action, item.Reason = SocialActionSkip, "already following"
+		case snapshot != nil && action == SocialActionUnfollow && !r.Following:
+			item.Action, item.Reason = SocialActionSkip, "not following"
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func returnSocialBulk(ctx *gin.Context, bulk *socialBulk) {
+	data := SocialBulkData{
+		Status:      bulk.Status,
+		Relation:    bulk.Relation,
+		DryRun:      bulk.DryRun,
+		Total:       len(bulk.Items),
+		Items:       append([]SocialBulkItem{}, bulk.Items...),
+		Error:       bulk.Error,
+		StartedAt:   bulk.StartedAt,
+		UpdatedAt:   bulk.UpdatedAt,
+		CompletedAt: bulk.CompletedAt,
+	}
+
+	for _, item := range bulk.Items {
+		switch {
+		case item.Action == SocialActionSkip:
+			data.Skipped++
+		case item.Status == "done":
+			data.Done++
+		case item.Status == "failed":
+			data.Failed++
+		default:
+			data.Pending++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  utils.GetMsg(http.StatusOK),
+		"data": data,
+	})
+}
+
+func (bulk *socialBulk) progress() *crawlStatus {
+	return &bulk.crawlStatus
+}
+
+// clone 进度的副本
+func (bulk *socialBulk) clone() *socialBulk {
+	c := *bulk
+	c.Items = slices.Clone(c.Items)
+
+	return &c
+}
+
+// run 逐个发送关注或取关的请求；上游不可用或限流且重试失败时停止，其余的失败记录在对应的用户中
+func (j socialBulkJob) run(origin *http.Request) {
+	bulk := j.snapshot()
+
+	for i, item := range bulk.Items {
+		if item.Action == SocialActionSkip {
+			continue
+		}
+
+		_, failed := j.call(origin, "/relation_update", map[string]any{"uid": item.Uid, "relation": bulk.Relation})
+		if failed != nil && retryable(failed.Status) {
+			j.fail(operationError(item.Uid, "/relation_update", failed).Error)
+
+			return
+		}
+
+		j.update(func(bulk *socialBulk) {
+			bulk.Items[i].Status = "done"
+
+			if failed != nil {
+				bulk.Items[i].Status = "failed"
+				bulk.Items[i].Error = operationError(item.Uid, "/relation_update", failed).Error
+			}
+		})
+	}
+
+	j.complete()
+}
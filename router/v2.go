@@ -0,0 +1,147 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultrazg/xyz/handlers"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// V2Routes /v2 REST 风格接口，内部复用原有接口的 handler，均需要 x-jike-access-token
+var V2Routes = []Route{
+	{Method: http.MethodGet, Path: "/podcasts/:pid", Summary: "查询节目详情", Cache: true, Handler: utils.Rest(handlers.PodcastDetail, param("pid"))},
+	{Method: http.MethodGet, Path: "/podcasts/:pid/info", Summary: "获取节目主体信息", Handler: utils.Rest(handlers.PodcastGetInfo, param("pid"))},
+	{Method: http.MethodGet, Path: "/podcasts/:pid/honors", Summary: "获取节目荣誉墙", Handler: utils.Rest(handlers.PodcastHonorList, param("pid"))},
+	{Method: http.MethodGet, Path: "/podcasts/:pid/related", Summary: "相关节目推荐", Handler: utils.Rest(handlers.RelatedPodcastList, param("pid"))},
+	{Method: http.MethodGet, Path: "/podcasts/:pid/bulletin", Summary: "获取节目公告", Handler: utils.Rest(handlers.PodcastBulletin, param("pid"))},
+	{Method: http.MethodGet, Path: "/podcasts/:pid/episodes/popular", Summary: "节目内「最受欢迎」单集列表", Handler: utils.Rest(handlers.EpisodeListByFilter, param("pid"))},
+	{Method: http.MethodGet, Path: "/podcasts/:pid/episodes", Summary: "剧集列表", Query: []string{"order", "cursor"}, Cache: true, Handler: utils.Rest(handlers.EpisodeList, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{
+			"pid":   ctx.Param("pid"),
+			"order": ctx.DefaultQuery("order", "desc"),
+		}, nil
+	})},
+
+	{Method: http.MethodGet, Path: "/episodes/:eid", Summary: "查询单集详情", Cache: true, Handler: utils.Rest(handlers.EpisodeDetail, param("eid"))},
+	{Method: http.MethodGet, Path: "/episodes/:eid/live", Summary: "正在收听的人数", Handler: utils.Rest(handlers.Live, param("eid"))},
+	{Method: http.MethodGet, Path: "/episodes/:eid/progress", Summary: "查询单集播放进度", Handler: utils.Rest(handlers.PlaybackProgress, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{"eids": []string{ctx.Param("eid")}}, nil
+	})},
+	{Method: http.MethodGet, Path: "/episodes/:eid/comments", Summary: "查询单集的评论", Query: []string{"order", "cursor"}, Handler: utils.Rest(handlers.CommentPrimary, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{
+			"id":    ctx.Param("eid"),
+			"order": ctx.DefaultQuery("order", "HOT"),
+		}, nil
+	})},
+	{Method: http.MethodGet, Path: "/episodes/:eid/claps", Summary: "精彩时间点", Query: []string{"duration"}, Handler: utils.Rest(handlers.Clap, func(ctx *gin.Context) (map[string]any, error) {
+		duration, err := strconv.Atoi(ctx.Query("duration"))
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"eid": ctx.Param("eid"), "duration": duration}, nil
+	})},
+	{Method: http.MethodGet, Path: "/comments/:id/replies", Summary: "查询回复评论", Query: []string{"order"}, Handler: utils.Rest(handlers.CommentThread, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{
+			"primaryCommentId": ctx.Param("id"),
+			"order":            ctx.DefaultQuery("order", "SMART"),
+		}, nil
+	})},
+
+	{Method: http.MethodGet, Path: "/users/:uid", Summary: "根据 uid 查询用户信息", Handler: utils.Rest(handlers.GetProfileByUid, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/stats", Summary: "用户统计数据", Handler: utils.Rest(handlers.GetUserStats, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/stickers", Summary: "查询已获得的贴纸", Handler: utils.Rest(handlers.StickerList, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/sticker-board", Summary: "查询贴纸墙", Handler: utils.Rest(handlers.StickerBoard, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/podcasts", Summary: "获取用户创建的播客", Handler: utils.Rest(handlers.OwnedPodcastsList, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/played", Summary: "获取收听历史记录", Handler: utils.Rest(handlers.PlayedList, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/picks", Summary: "获取「用户的喜欢」全部内容", Query: []string{"cursor"}, Handler: utils.Rest(handlers.PickListHistory, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/following", Summary: "获取用户关注的人", Handler: utils.Rest(handlers.FollowingList, param("uid"))},
+	{Method: http.MethodGet, Path: "/users/:uid/followers", Summary: "获取关注用户的人", Handler: utils.Rest(handlers.FollowerList, param("uid"))},
+	{Method: http.MethodPut, Path: "/users/:uid/relation", Summary: "关注用户", Handler: utils.Rest(handlers.RelationUpdate, with(param("uid"), "relation", "FOLLOWING"))},
+	{Method: http.MethodDelete, Path: "/users/:uid/relation", Summary: "取关用户", Handler: utils.Rest(handlers.RelationUpdate, with(param("uid"), "relation", "STRANGE"))},
+
+	{Method: http.MethodGet, Path: "/me", Summary: "查询当前用户信息", Cache: true, Handler: utils.Rest(handlers.Profile, nil)},
+	{Method: http.MethodGet, Path: "/me/subscriptions", Summary: "订阅列表", Query: []string{"cursor"}, Cache: true, Handler: utils.Rest(handlers.Subscription, nil)},
+	{Method: http.MethodGet, Path: "/me/subscriptions/starred", Summary: "星标订阅", Handler: utils.Rest(handlers.StarSubscription, nil)},
+	{Method: http.MethodPut, Path: "/me/subscriptions/:pid", Summary: "订阅节目", Handler: utils.Rest(handlers.SubscriptionUpdate, with(param("pid"), "mode", "ON"))},
+	{Method: http.MethodDelete, Path: "/me/subscriptions/:pid", Summary: "取消订阅节目", Handler: utils.Rest(handlers.SubscriptionUpdate, with(param("pid"), "mode", "OFF"))},
+	{Method: http.MethodPut, Path: "/me/subscriptions/:pid/star", Summary: "星标订阅", Handler: utils.Rest(handlers.UpdateStarSubscription, with(param("pid"), "withStar", true))},
+	{Method: http.MethodDelete, Path: "/me/subscriptions/:pid/star", Summary: "取消星标订阅", Handler: utils.Rest(handlers.UpdateStarSubscription, with(param("pid"), "withStar", false))},
+	{Method: http.MethodGet, Path: "/me/inbox", Summary: "订阅更新列表", Query: []string{"cursor"}, Cache: true, Handler: utils.Rest(handlers.InboxList, nil)},
+	{Method: http.MethodGet, Path: "/me/history", Summary: "收听历史", Query: []string{"cursor"}, Cache: true, Handler: utils.Rest(handlers.EpisodePlayedHistoryList, nil)},
+	{Method: http.MethodPut, Path: "/me/history/:eid", Summary: "更新收听历史", Handler: utils.Rest(handlers.UpdateEpisodePlayedHistoryList, param("eid"))},
+	{Method: http.MethodGet, Path: "/me/favorites", Summary: "收藏单集列表", Handler: utils.Rest(handlers.FavoriteEpisodeList, nil)},
+	{Method: http.MethodPut, Path: "/me/favorites/:eid", Summary: "收藏单集", Handler: utils.Rest(handlers.UpdateEpisodeFavorite, with(param("eid"), "favorited", true))},
+	{Method: http.MethodDelete, Path: "/me/favorites/:eid", Summary: "取消收藏单集", Handler: utils.Rest(handlers.UpdateEpisodeFavorite, with(param("eid"), "favorited", false))},
+	{Method: http.MethodGet, Path: "/me/comment-collections", Summary: "收藏评论列表", Handler: utils.Rest(handlers.CommentCollectList, nil)},
+	{Method: http.MethodPut, Path: "/me/comment-collections/:commentId", Summary: "收藏评论", Handler: utils.Rest(handlers.CreateCommentCollect, param("commentId"))},
+	{Method: http.MethodDelete, Path: "/me/comment-collections/:commentId", Summary: "取消收藏评论", Handler: utils.Rest(handlers.RemoveCommentCollect, param("commentId"))},
+	{Method: http.MethodPut, Path: "/me/comment-likes/:id", Summary: "点赞评论", Handler: utils.Rest(handlers.CommentLikeUpdate, with(param("id"), "liked", true))},
+	{Method: http.MethodDelete, Path: "/me/comment-likes/:id", Summary: "取消点赞评论", Handler: utils.Rest(handlers.CommentLikeUpdate, with(param("id"), "liked", false))},
+	{Method: http.MethodGet, Path: "/me/blocked-users", Summary: "黑名单列表", Handler: utils.Rest(handlers.BlockedUserLists, nil)},
+	{Method: http.MethodPut, Path: "/me/blocked-users/:uid", Summary: "将用户加入黑名单", Handler: utils.Rest(handlers.BlockedUserCreate, param("uid"))},
+	{Method: http.MethodDelete, Path: "/me/blocked-users/:uid", Summary: "将用户移出黑名单", Handler: utils.Rest(handlers.BlockedUserRemove, param("uid"))},
+	{Method: http.MethodGet, Path: "/me/preferences", Summary: "用户偏好设置", Handler: utils.Rest(handlers.UserPreferenceGet, nil)},
+	{Method: http.MethodGet, Path: "/me/mileage", Summary: "收听数据概览", Handler: utils.Rest(handlers.GetMileage, nil)},
+	{Method: http.MethodGet, Path: "/me/mileage/ranking", Summary: "收听排行", Query: []string{"all"}, Handler: utils.Rest(handlers.GetMileageList, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{"all": ctx.Query("all") == "true"}, nil
+	})},
+	{Method: http.MethodGet, Path: "/me/unread-count", Summary: "未读消息", Handler: utils.Rest(handlers.UnreadCount, nil)},
+
+	{Method: http.MethodGet, Path: "/categories", Summary: "全部分类", Handler: utils.Rest(handlers.CategoryList, nil)},
+	{Method: http.MethodGet, Path: "/categories/:categoryId/tabs", Summary: "分类下的标签", Handler: utils.Rest(handlers.CategoryListTabById, param("categoryId"))},
+	{Method: http.MethodGet, Path: "/categories/:categoryId/tabs/:tab/podcasts", Summary: "分类下的节目列表", Query: []string{"omitSubscribed", "cursor"}, Handler: utils.Rest(handlers.CategoryPodcastListByTab, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{
+			"categoryId":     ctx.Param("categoryId"),
+			"tab":            ctx.Param("tab"),
+			"omitSubscribed": ctx.Query("omitSubscribed") == "true",
+		}, nil
+	})},
+	{Method: http.MethodGet, Path: "/top-lists/:category", Summary: "榜单", Handler: utils.Rest(handlers.GetTopList, func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{"category": strings.ToUpper(ctx.Param("category"))}, nil
+	})},
+	{Method: http.MethodGet, Path: "/discovery", Summary: "首页榜单、精选节目、推荐等", Query: []string{"cursor"}, Handler: utils.Rest(handlers.Discovery, nil)},
+	{Method: http.MethodGet, Path: "/search", Summary: "搜索", Query: []string{"keyword", "type", "pid", "cursor"}, Handler: utils.Rest(handlers.Search, func(ctx *gin.Context) (map[string]any, error) {
+		p := map[string]any{
+			"keyword": ctx.Query("keyword"),
+			"type":    ctx.DefaultQuery("type", "ALL"),
+		}
+
+		if pid := ctx.Query("pid"); pid != "" {
+			p["pid"] = pid
+		}
+
+		return p, nil
+	})},
+	{Method: http.MethodGet, Path: "/search/preset", Summary: "「你可能想搜的内容」", Handler: utils.Rest(handlers.SearchPreset, nil)},
+}
+
+// param 将同名路径参数转发为请求体字段
+func param(names ...string) utils.RestBody {
+	return func(ctx *gin.Context) (map[string]any, error) {
+		p := map[string]any{}
+
+		for _, name := range names {
+			p[name] = ctx.Param(name)
+		}
+
+		return p, nil
+	}
+}
+
+// with 在 body 的基础上附加一个固定字段
+func with(body utils.RestBody, key string, value any) utils.RestBody {
+	return func(ctx *gin.Context) (map[string]any, error) {
+		p, err := body(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p[key] = value
+
+		return p, nil
+	}
+}
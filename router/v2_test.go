@@ -0,0 +1,131 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	C "github.com/ultrazg/xyz/constant"
+	"github.com/ultrazg/xyz/mock"
+)
+
+// v2Response /v2 的响应
+type v2Response struct {
+	Data   any            `json:"data"`
+	Cursor string         `json:"cursor"`
+	Error  map[string]any `json:"error"`
+}
+
+func TestV2(t *testing.T) {
+	initTestStore(t)
+	withoutRedis(t)
+
+	server := mock.NewServer()
+	upstream := httptest.NewServer(server)
+	defer upstream.Close()
+
+	baseUrl := C.BaseUrl
+	C.BaseUrl = upstream.URL
+	t.Cleanup(func() { C.BaseUrl = baseUrl })
+
+	accessToken, _ := server.Login()
+
+	engine := gin.New()
+	RegisterRouters(engine)
+
+	request := func(method, target string, token bool) (int, v2Response) {
+		req := httptest.NewRequest(method, target, nil)
+		if token {
+			req.Header.Set("x-jike-access-token", accessToken)
+		}
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		var res v2Response
+		if err := json.Unmarshal(recorder.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s %s = %d %s: %v", method, target, recorder.Code, recorder.Body.String(), err)
+		}
+
+		return recorder.Code, res
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		token  bool
+		status int
+		code   string // 失败时的错误码
+	}{
+		{"查询节目详情", http.MethodGet, "/v2/podcasts/" + fixturePid, true, http.StatusOK, ""},
+		{"查询单集的评论", http.MethodGet, "/v2/episodes/" + fixtureEid + "/comments?order=TIME", true, http.StatusOK, ""},
+		{"订阅节目", http.MethodPut, "/v2/me/subscriptions/" + fixturePid, true, http.StatusOK, ""},
+		{"取消订阅节目", http.MethodDelete, "/v2/me/subscriptions/" + fixturePid, true, http.StatusOK, ""},
+		{"路径参数格式错误", http.MethodGet, "/v2/podcasts/abc", true, http.StatusBadRequest, "VALIDATION_FAILED"},
+		{"无效的游标", http.MethodGet, "/v2/podcasts/" + fixturePid + "/episodes?cursor=!!!", true, http.StatusBadRequest, "VALIDATION_FAILED"},
+		{"缺少 x-jike-access-token", http.MethodGet, "/v2/podcasts/" + fixturePid, false, http.StatusBadRequest, "VALIDATION_FAILED"},
+		{"上游不存在的节目", http.MethodGet, "/v2/podcasts/000000000000000000000000", true, http.StatusNotFound, "UPSTREAM_NOT_FOUND"},
+		{"不存在的路由", http.MethodGet, "/v2/nothing", true, http.StatusNotFound, "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, res := request(tt.method, tt.target, tt.token)
+
+			if status != tt.status {
+				t.Fatalf("%s %s = %d %+v, want %d", tt.method, tt.target, status, res, tt.status)
+			}
+
+			if tt.code == "" {
+				if res.Error != nil || res.Data == nil {
+					t.Errorf("%s %s = %+v, want data", tt.method, tt.target, res)
+				}
+
+				return
+			}
+
+			if res.Error["code"] != tt.code || res.Data != nil {
+				t.Errorf("%s %s error = %v, want %s", tt.method, tt.target, res.Error, tt.code)
+			}
+		})
+	}
+
+	t.Run("按游标翻页", func(t *testing.T) {
+		seen := map[string]bool{}
+		target := "/v2/podcasts/" + fixturePid + "/episodes"
+		pages := 0
+
+		for {
+			status, res := request(http.MethodGet, target, true)
+			if status != http.StatusOK {
+				t.Fatalf("GET %s = %d %+v", target, status, res)
+			}
+
+			pages++
+
+			items, _ := res.Data.([]any)
+			for _, item := range items {
+				eid, _ := item.(map[string]any)["eid"].(string)
+				if seen[eid] {
+					t.Fatalf("episode %s returned twice", eid)
+				}
+
+				seen[eid] = true
+			}
+
+			if res.Cursor == "" {
+				break
+			}
+
+			target = "/v2/podcasts/" + fixturePid + "/episodes?cursor=" + res.Cursor
+		}
+
+		// 种子数据中每个节目有 25 期单集
+		if pages < 2 || len(seen) != 25 {
+			t.Errorf("paged %d episodes in %d pages, want 25 in at least 2 pages", len(seen), pages)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ultrazg/xyz/mock"
+	"github.com/ultrazg/xyz/utils"
+)
+
+// commands 子命令，如 xyz apikey create
+var commands = map[string]func(args []string) error{
+	"apikey":        apiKeyCommand,
+	"keys":          keysCommand,
+	"mock-upstream": mockUpstreamCommand,
+}
+
+// IsCommand 是否为子命令
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+
+	return ok
+}
+
+// Command 执行子命令
+func Command(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	return command(args)
+}
+
+func apiKeyCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: xyz apikey <create|list|revoke> [OPTIONS]\n\n")
+		fmt.Fprintf(os.Stderr, "  create -name <name> -scope <read|write|admin> [-quota <count>/<s|m|h|d>]  签发 API Key\n")
+		fmt.Fprintf(os.Stderr, "  list                                                                  查询 API Key\n")
+		fmt.Fprintf(os.Stderr, "  revoke <prefix>                                                       吊销 API Key\n")
+	}
+
+	if len(args) == 0 {
+		usage()
+
+		return fmt.Errorf("missing apikey subcommand")
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ExitOnError)
+	dataDir := fs.String("data", "data", "指定数据目录")
+	name := fs.String("name", "", "API Key 名称，如团队名称")
+	scope := fs.String("scope", utils.ScopeRead, "权限：read、write 或 admin")
+	quota := fs.String("quota", "", "配额，如 1000/h，为空时不限制")
+	fs.StringVar(&utils.SecretKeyFile, "key-file", "", "指定加密密钥文件，默认为数据目录中的 secret.key")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := utils.InitStore(*dataDir); err != nil {
+		return fmt.Errorf("open data dir fail (stop the running xyz first): %w", err)
+	}
+	defer utils.CloseStore()
+
+	switch args[0] {
+	case "create":
+		key, apiKey, err := utils.IssueApiKey(*name, *scope, *quota)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("API Key（只显示一次，注意保存）：\n\n%s\n\nprefix: %s\nscope:  %s\n", key, apiKey.Prefix, apiKey.Scope)
+
+		return nil
+	case "list":
+		keys, err := utils.ListApiKeys()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PREFIX\tNAME\tSCOPE\tQUOTA\tACCOUNT\tCREATED\tLAST USED")
+
+		for _, apiKey := range keys {
+			lastUsed := "-"
+			if apiKey.LastUsedAt != nil {
+				lastUsed = apiKey.LastUsedAt.Format(time.DateTime)
+			}
+
+			quota := apiKey.Quota
+			if quota == "" {
+				quota = "-"
+			}
+
+			account := apiKey.Account
+			if account == "" {
+				account = "-"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", apiKey.Prefix, apiKey.Name, apiKey.Scope, quota, account, apiKey.CreatedAt.Format(time.DateTime), lastUsed)
+		}
+
+		return w.Flush()
+	case "revoke":
+		if fs.NArg() != 1 {
+			usage()
+
+			return fmt.Errorf("missing api key prefix")
+		}
+
+		err := utils.RevokeApiKey(fs.Arg(0))
+		if errors.Is(err, utils.ErrStoreNotFound) {
+			return fmt.Errorf("api key not found: %s", fs.Arg(0))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("revoked", fs.Arg(0))
+
+		return nil
+	default:
+		usage()
+
+		return fmt.Errorf("unknown apikey subcommand %q", args[0])
+	}
+}
+
+func keysCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: xyz keys rotate [-data <dir>] [-key-file <file>] [-skip-cache]\n\n")
+		fmt.Fprintf(os.Stderr, "  rotate  生成新的加密密钥，并用新密钥重新加密本地存储和 Redis 缓存\n")
+	}
+
+	if len(args) == 0 || args[0] != "rotate" {
+		usage()
+
+		return fmt.Errorf("unknown keys subcommand")
+	}
+
+	fs := flag.NewFlagSet("keys rotate", flag.ExitOnError)
+	dataDir := fs.String("data", "data", "指定数据目录")
+	fs.StringVar(&utils.SecretKeyFile, "key-file", "", "指定加密密钥文件，默认为数据目录中的 secret.key")
+	skipCache := fs.Bool("skip-cache", false, "不重新加密 Redis 缓存，旧缓存之后视为未命中")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := utils.InitStore(*dataDir); err != nil {
+		return fmt.Errorf("open data dir fail (stop the running xyz first): %w", err)
+	}
+	defer utils.CloseStore()
+
+	id, err := utils.RotateSecret()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("current key:", id)
+
+	// 重新加密失败时旧密钥仍保留在密钥文件中，可以再次执行
+	count, err := utils.ReencryptStore()
+	if err != nil {
+		return fmt.Errorf("re-encrypt store fail after %d entries: %w", count, err)
+	}
+
+	fmt.Println("store entries re-encrypted:", count)
+
+	if *skipCache {
+		fmt.Println("skip redis cache")
+	} else {
+		utils.InitCache()
+
+		// 缓存重新加密失败时同样保留旧密钥，Redis 可用后再次执行，或传入 -skip-cache 放弃旧缓存
+		reencrypted, deleted, err := utils.ReencryptCache()
+		if err != nil {
+			return fmt.Errorf("re-encrypt redis cache fail, old keys are kept (run again, or pass -skip-cache to drop the old cache): %w", err)
+		}
+
+		fmt.Println("cache entries re-encrypted:", reencrypted)
+		fmt.Println("cache entries deleted:", deleted)
+	}
+
+	// 两步都完成后才删除旧密钥
+	if err := utils.RetireSecrets(); err != nil {
+		return err
+	}
+
+	if os.Getenv(utils.SecretKeyEnv) != "" {
+		fmt.Printf("old keys are no longer needed, remove them from %s\n", utils.SecretKeyEnv)
+	}
+
+	return nil
+}
+
+func mockUpstreamCommand(args []string) error {
+	fs := flag.NewFlagSet("mock-upstream", flag.ExitOnError)
+	port := fs.Int("p", 23021, "指定模拟上游监听的端口")
+	tokenTTL := fs.Duration("token-ttl", mock.DefaultTokenTTL, "access token 的有效期")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: xyz mock-upstream [-p <port>] [-token-ttl <duration>]\n\n")
+		fmt.Fprintf(os.Stderr, "  启动模拟的上游接口，数据保存在内存中，重启后恢复为种子数据\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := utils.CheckPort(*port); err != nil {
+		return err
+	}
+
+	server := mock.NewServer()
+	server.TokenTTL = *tokenTTL
+
+	address := ":" + strconv.Itoa(*port)
+
+	log.Printf("mock upstream start on %s, verify code %s", address, mock.VerifyCode)
+	log.Printf("run: xyz -base-url http://localhost%s", address)
+
+	return http.ListenAndServe(address, server)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ultrazg/xyz/router"
@@ -13,7 +15,17 @@ import (
 func Start() error {
 	p, d := utils.InitFlag()
 
-	err := utils.CheckPort(p)
+	err := utils.InitStore(utils.DataDir)
+	if err != nil {
+		return fmt.Errorf("open data dir fail: %w", err)
+	}
+
+	err = utils.UseFixtures(utils.FixtureMode, utils.FixtureDir)
+	if err != nil {
+		return fmt.Errorf("fixtures: %w", err)
+	}
+
+	err = utils.CheckPort(p)
 	if err != nil {
 		return err
 	}
@@ -35,6 +47,12 @@ func Start() error {
 	engine.Use(Cors())
 
 	router.RegisterRouters(engine)
+	utils.StartApiKeyUsageFlush()
+	router.StartOutbox()
+	router.StartPlayback()
+	router.StartPodcastWatch()
+	router.StartTopListArchive()
+	router.StartPopularity()
 
 	log.Printf("server start on %s", port)
 
@@ -57,15 +75,37 @@ func Start() error {
 	return nil
 }
 
+// Cors 跨域设置，指定了 -cors-origins 时只允许列表中的来源，并拒绝其它来源的请求
 func Cors() gin.HandlerFunc {
+	var origins []string
+	for _, origin := range strings.Split(utils.CorsOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimRight(origin, "/"))
+		}
+	}
+
 	return func(context *gin.Context) {
 		method := context.Request.Method
+		origin := context.GetHeader("Origin")
+
+		if len(origins) == 0 {
+			context.Header("Access-Control-Allow-Origin", "*")
+		} else if origin != "" {
+			context.Header("Vary", "Origin")
+
+			if !slices.Contains(origins, origin) {
+				utils.ReturnError(context, utils.NewError(http.StatusForbidden, utils.ErrForbidden, "origin not allowed: "+origin))
+
+				return
+			}
+
+			context.Header("Access-Control-Allow-Origin", origin)
+			context.Header("Access-Control-Allow-Credentials", "true")
+		}
 
-		context.Header("Access-Control-Allow-Origin", "*")
-		context.Header("Access-Control-Allow-Headers", "Content-Type, AccessToken, X-CSRF-Token, Authorization, Token, x-token, x-jike-access-token, x-jike-refresh-token")
+		context.Header("Access-Control-Allow-Headers", "Content-Type, AccessToken, X-CSRF-Token, Authorization, Token, x-token, x-jike-access-token, x-jike-refresh-token, x-xyz-api-key, x-xyz-account, x-xyz-outbox")
 		context.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, DELETE, PATCH, PUT")
-		context.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-		context.Header("Access-Control-Allow-Credentials", "true")
+		context.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
 
 		if method == "OPTIONS" {
 			context.AbortWithStatus(http.StatusNoContent)
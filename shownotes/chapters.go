@@ -0,0 +1,141 @@
+package shownotes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Chapter 时间线中的一个章节，时间单位为秒
+type Chapter struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Title string `json:"title"`
+}
+
+// 时间戳，如 5:30、05:30、1:02:03、90:00
+const timestampPattern = `((?:\d{1,2}:)?\d{1,3}:\d{2})`
+
+var (
+	// 行首的时间戳，前面可以有列表符号和括号，后面可以是时间范围和各种分隔符，如「[00:00] 开场」「1. 05:30 - 10:00｜正题」
+	leadingTimestamp = regexp.MustCompile(`^(?:[-*•·>]\s*|\d{1,2}[.、)]\s+)?[\[【(（]?\s*` + timestampPattern + `\s*[\]】)）]?(?:\s*[-~～–—至]\s*[\[【(（]?` + timestampPattern + `[\]】)）]?)?\s*[-–—|｜:·、.]?\s*(.*)$`)
+
+	// 行尾的时间戳，如「开场 00:00」「开场（00:00）」
+	trailingTimestamp = regexp.MustCompile(`^(?:[-*•·>]\s*|\d{1,2}[.、)]\s+)?(.*?)\s*[-–—|｜:·@]?\s*[\[【(（]?\s*` + timestampPattern + `\s*[\]】)）]?$`)
+
+	fullWidth = strings.NewReplacer("：", ":", "　", " ", "０", "0", "１", "1", "２", "2", "３", "3", "４", "4", "５", "5", "６", "6", "７", "7", "８", "8", "９", "9")
+)
+
+// Chapters 从 shownotes 的时间线中提取章节，按开始时间排列，每章在下一章开始时结束，最后一章在 duration 结束。
+//
+// 优先识别以时间戳开头的行，不足两行时识别以时间戳结尾的行；超过 duration 的时间戳被忽略，
+// 少于两个章节时认为 shownotes 中没有时间线，返回空列表。duration 为 0 时最后一章的结束时间与开始时间相同
+func Chapters(source string, duration int) []Chapter {
+	// 先按原有的换行和 <br> 分行，再合并每行中的空白
+	lines := strings.Split(fullWidth.Replace(textLines(source)), "\n")
+
+	chapters := match(lines, leadingTimestamp, 1, 3, duration)
+	if len(chapters) < 2 {
+		chapters = match(lines, trailingTimestamp, 2, 1, duration)
+	}
+
+	if len(chapters) < 2 {
+		return []Chapter{}
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+
+	// 相同开始时间的章节只保留第一个
+	result := chapters[:0]
+	for _, chapter := range chapters {
+		if len(result) > 0 && result[len(result)-1].Start == chapter.Start {
+			continue
+		}
+
+		result = append(result, chapter)
+	}
+
+	if len(result) < 2 {
+		return []Chapter{}
+	}
+
+	for i := range result {
+		if i+1 < len(result) {
+			result[i].End = result[i+1].Start
+		} else {
+			result[i].End = max(duration, result[i].Start)
+		}
+
+		if result[i].Title == "" {
+			result[i].Title = fmt.Sprintf("章节 %d", i+1)
+		}
+	}
+
+	return result
+}
+
+// Timestamp 行首的时间戳，单位为秒，如「05:30 正题」「- [1:02:03] 尾声」
+func Timestamp(line string) (int, bool) {
+	groups := leadingTimestamp.FindStringSubmatch(strings.TrimSpace(fullWidth.Replace(line)))
+	if groups == nil {
+		return 0, false
+	}
+
+	return parseTimestamp(groups[1])
+}
+
+// match 以 pattern 匹配每一行，time 和 title 为时间戳和标题所在的分组
+func match(lines []string, pattern *regexp.Regexp, time, title, duration int) []Chapter {
+	var chapters []Chapter
+	for _, line := range lines {
+		groups := pattern.FindStringSubmatch(strings.TrimSpace(line))
+		if groups == nil {
+			continue
+		}
+
+		start, ok := parseTimestamp(groups[time])
+		if !ok || (duration > 0 && start >= duration) {
+			continue
+		}
+
+		chapters = append(chapters, Chapter{Start: start, Title: strings.TrimSpace(groups[title])})
+	}
+
+	return chapters
+}
+
+// parseTimestamp 将 1:02:03、05:30、90:00 转换为秒，有小时时分钟不能超过 59
+func parseTimestamp(s string) (int, bool) {
+	parts := strings.Split(s, ":")
+
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+
+		values[i] = value
+	}
+
+	switch len(values) {
+	case 2:
+		if values[1] >= 60 {
+			return 0, false
+		}
+
+		return values[0]*60 + values[1], true
+	case 3:
+		if values[1] >= 60 || values[2] >= 60 {
+			return 0, false
+		}
+
+		return values[0]*3600 + values[1]*60 + values[2], true
+	default:
+		return 0, false
+	}
+}
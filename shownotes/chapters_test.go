@@ -0,0 +1,204 @@
+package shownotes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChapters(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		duration int
+		want     []Chapter
+	}{
+		{
+			name:     "纯文本换行",
+			source:   "00:00 开场\n03:25 话题一",
+			duration: 600,
+			want:     []Chapter{{0, 205, "开场"}, {205, 600, "话题一"}},
+		},
+		{
+			name:     "段落中的换行",
+			source:   "<p>00:00 开场\n03:25 话题一</p>",
+			duration: 600,
+			want:     []Chapter{{0, 205, "开场"}, {205, 600, "话题一"}},
+		},
+		{
+			name:     "br 分行",
+			source:   "<p>00:00 开场<br>03:25 话题一<br/>05:00 话题二</p>",
+			duration: 600,
+			want:     []Chapter{{0, 205, "开场"}, {205, 300, "话题一"}, {300, 600, "话题二"}},
+		},
+		{
+			name:     "每行一个段落",
+			source:   "<p>00:00 开场</p><p>03:25 话题一</p>",
+			duration: 600,
+			want:     []Chapter{{0, 205, "开场"}, {205, 600, "话题一"}},
+		},
+		{
+			name:     "列表",
+			source:   "<ul><li>00:00 开场</li><li>10:00 正题</li></ul>",
+			duration: 1200,
+			want:     []Chapter{{0, 600, "开场"}, {600, 1200, "正题"}},
+		},
+		{
+			name:     "带小时",
+			source:   "00:00 开场\n59:59 正题\n1:02:03 尾声",
+			duration: 4000,
+			want:     []Chapter{{0, 3599, "开场"}, {3599, 3723, "正题"}, {3723, 4000, "尾声"}},
+		},
+		{
+			name:     "方括号",
+			source:   "[00:00] 开场\n[12:00] 正题",
+			duration: 1800,
+			want:     []Chapter{{0, 720, "开场"}, {720, 1800, "正题"}},
+		},
+		{
+			name:     "全角括号和冒号",
+			source:   "【００：００】开场\n（１２：００）正题",
+			duration: 1800,
+			want:     []Chapter{{0, 720, "开场"}, {720, 1800, "正题"}},
+		},
+		{
+			name:     "全角冒号",
+			source:   "00：00 开场\n12：00 正题",
+			duration: 1800,
+			want:     []Chapter{{0, 720, "开场"}, {720, 1800, "正题"}},
+		},
+		{
+			name:     "时间范围和分隔符",
+			source:   "1. 00:00 - 05:30｜开场\n2. 05:30 - 10:00 | 正题",
+			duration: 900,
+			want:     []Chapter{{0, 330, "开场"}, {330, 900, "正题"}},
+		},
+		{
+			name:     "列表符号",
+			source:   "- 00:00 开场\n• 05:30 正题",
+			duration: 900,
+			want:     []Chapter{{0, 330, "开场"}, {330, 900, "正题"}},
+		},
+		{
+			name:     "超过 60 分钟的分钟数",
+			source:   "00:00 开场\n90:00 正题",
+			duration: 6000,
+			want:     []Chapter{{0, 5400, "开场"}, {5400, 6000, "正题"}},
+		},
+		{
+			name:     "行尾的时间戳",
+			source:   "开场 00:00\n正题（05:30）",
+			duration: 900,
+			want:     []Chapter{{0, 330, "开场"}, {330, 900, "正题"}},
+		},
+		{
+			name:     "乱序和重复的开始时间",
+			source:   "05:30 正题\n00:00 开场\n05:30 重复",
+			duration: 900,
+			want:     []Chapter{{0, 330, "开场"}, {330, 900, "正题"}},
+		},
+		{
+			name:     "没有标题",
+			source:   "00:00\n05:30",
+			duration: 900,
+			want:     []Chapter{{0, 330, "章节 1"}, {330, 900, "章节 2"}},
+		},
+		{
+			name:     "忽略超过时长的时间戳",
+			source:   "00:00 开场\n05:30 正题\n20:00 不存在",
+			duration: 900,
+			want:     []Chapter{{0, 330, "开场"}, {330, 900, "正题"}},
+		},
+		{
+			name:     "时长为 0",
+			source:   "00:00 开场\n05:30 正题",
+			duration: 0,
+			want:     []Chapter{{0, 330, "开场"}, {330, 330, "正题"}},
+		},
+		{
+			name:     "无效的时间戳",
+			source:   "00:00 开场\n05:61 正题\n1:60:00 尾声",
+			duration: 9000,
+			want:     []Chapter{},
+		},
+		{
+			name:     "只有一个时间戳",
+			source:   "<p>本期 03:25 开始讨论</p>",
+			duration: 900,
+			want:     []Chapter{},
+		},
+		{
+			name:     "没有时间线",
+			source:   "<p>本期嘉宾：某某</p>",
+			duration: 900,
+			want:     []Chapter{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Chapters(tt.source, tt.duration); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chapters(%q, %d) = %v, want %v", tt.source, tt.duration, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+		ok   bool
+	}{
+		{"05:30 正题", 330, true},
+		{"- [1:02:03] 尾声", 3723, true},
+		{"【１２：００】正题", 720, true},
+		{"正题 05:30", 0, false},
+		{"05:60 正题", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Timestamp(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Timestamp(%q) = %d, %v, want %d, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"<p>第一行\n第二行</p>", "第一行 第二行"},
+		{"<p>第一行<br>第二行</p>", "第一行\n第二行"},
+		{"<p>一</p><p>二</p>", "一\n\n二"},
+		{`<a href="https://example.com">链接</a>`, "链接"},
+		{`<a href="https://example.com"></a>`, "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := Text(tt.source); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"<h2>标题</h2><p><strong>粗体</strong>和<em>斜体</em></p>", "## 标题\n\n**粗体**和*斜体*"},
+		{`<p><a href="https://example.com">链接</a></p>`, "[链接](https://example.com)"},
+		{"<ol><li>一</li><li>二</li></ol>", "1. 一\n2. 二"},
+		{"<ul><li>一<ul><li>二</li></ul></li></ul>", "- 一\n    - 二"},
+		{"<blockquote><p>引用</p></blockquote>", "> 引用"},
+		{"<p>a_b*c</p>", `a\_b\*c`},
+	}
+
+	for _, tt := range tests {
+		if got := Markdown(tt.source); got != tt.want {
+			t.Errorf("Markdown(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
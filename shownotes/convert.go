@@ -0,0 +1,311 @@
+// Package shownotes 将单集的 HTML shownotes 转换为 Markdown 和纯文本，并从时间线中提取章节
+package shownotes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Markdown 将 HTML 转换为 Markdown，不支持的标签只保留其中的文字
+func Markdown(source string) string {
+	return render(source, &renderer{markdown: true})
+}
+
+// Text 将 HTML 转换为纯文本，链接只保留文字，没有文字时保留地址
+func Text(source string) string {
+	return render(source, &renderer{})
+}
+
+// textLines 与 Text 相同，但保留文字中原有的换行，用于按行识别时间线。纯文本或 <p> 中以换行分隔的时间线在 Text 中会被合并为一行
+func textLines(source string) string {
+	return render(source, &renderer{keepLines: true})
+}
+
+func render(source string, r *renderer) string {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return strings.TrimSpace(source)
+	}
+
+	for _, node := range nodes {
+		r.node(node)
+	}
+
+	return tidy(r.b.String(), r.markdown)
+}
+
+// renderer 按文档顺序输出节点，块级元素之间以空行分隔
+type renderer struct {
+	b         strings.Builder
+	markdown  bool
+	keepLines bool  // 保留文字中的换行，只合并行内的空白
+	lists     []int // 嵌套的列表，有序列表为下一项的序号，无序列表为 0
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+var (
+	spaces           = regexp.MustCompile(`\s+`)
+	horizontalSpaces = regexp.MustCompile(`[^\S\n]+`)
+)
+
+func (r *renderer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// 列表和表格中标签之间的空白
+		if parent := n.Parent; parent != nil && strings.TrimSpace(n.Data) == "" {
+			switch parent.DataAtom {
+			case atom.Ul, atom.Ol, atom.Table, atom.Tbody, atom.Thead, atom.Tr:
+				return
+			}
+		}
+
+		text := spaces.ReplaceAllString(n.Data, " ")
+		if r.keepLines {
+			text = horizontalSpaces.ReplaceAllString(n.Data, " ")
+		}
+
+		if r.markdown {
+			text = markdownEscaper.Replace(text)
+		}
+
+		r.b.WriteString(text)
+
+		return
+	case html.ElementNode:
+	default:
+		r.children(n)
+
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head:
+	case atom.Br:
+		if r.markdown {
+			r.b.WriteString("  ")
+		}
+
+		r.b.WriteString("\n")
+	case atom.Hr:
+		r.block()
+		r.b.WriteString("---")
+		r.block()
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.block()
+		if r.markdown {
+			r.b.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		}
+
+		r.inline(n)
+		r.block()
+	case atom.Strong, atom.B:
+		r.wrap(n, "**")
+	case atom.Em, atom.I:
+		r.wrap(n, "*")
+	case atom.Code:
+		r.wrap(n, "`")
+	case atom.Pre:
+		r.block()
+		if r.markdown {
+			r.b.WriteString("```\n" + strings.TrimRight(textOf(n), "\n") + "\n```")
+		} else {
+			r.b.WriteString(strings.TrimRight(textOf(n), "\n"))
+		}
+
+		r.block()
+	case atom.A:
+		r.link(n)
+	case atom.Img:
+		alt := attr(n, "alt")
+		if src := attr(n, "src"); r.markdown && src != "" {
+			r.b.WriteString("![" + markdownEscaper.Replace(alt) + "](" + src + ")")
+		} else {
+			r.b.WriteString(alt)
+		}
+	case atom.Ul, atom.Ol:
+		start := 0
+		if n.DataAtom == atom.Ol {
+			start = 1
+			if value, err := strconv.Atoi(attr(n, "start")); err == nil {
+				start = value
+			}
+		}
+
+		if len(r.lists) == 0 {
+			r.block()
+		}
+
+		r.lists = append(r.lists, start)
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+
+		if len(r.lists) == 0 {
+			r.block()
+		}
+	case atom.Li:
+		r.line()
+
+		depth := max(len(r.lists)-1, 0)
+		r.b.WriteString(strings.Repeat("    ", depth))
+
+		if len(r.lists) > 0 && r.lists[len(r.lists)-1] > 0 {
+			r.b.WriteString(strconv.Itoa(r.lists[len(r.lists)-1]) + ". ")
+			r.lists[len(r.lists)-1]++
+		} else {
+			r.b.WriteString("- ")
+		}
+
+		r.children(n)
+		r.line()
+	case atom.Blockquote:
+		r.block()
+
+		inner := &renderer{markdown: r.markdown, keepLines: r.keepLines}
+		inner.children(n)
+
+		text := tidy(inner.b.String(), r.markdown)
+		if r.markdown {
+			text = "> " + strings.ReplaceAll(text, "\n", "\n> ")
+		}
+
+		r.b.WriteString(text)
+		r.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Figure, atom.Figcaption, atom.Header, atom.Footer, atom.Table, atom.Tr:
+		r.block()
+		r.children(n)
+		r.block()
+	case atom.Td, atom.Th:
+		r.b.WriteString(" ")
+		r.children(n)
+		r.b.WriteString(" ")
+	default:
+		r.children(n)
+	}
+}
+
+func (r *renderer) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.node(child)
+	}
+}
+
+// inline 输出标题等只能包含一行的内容
+func (r *renderer) inline(n *html.Node) {
+	inner := &renderer{markdown: r.markdown, keepLines: r.keepLines}
+	inner.children(n)
+
+	r.b.WriteString(strings.Join(strings.Fields(inner.b.String()), " "))
+}
+
+// wrap 以 marker 包围内容，内容为空时不输出
+func (r *renderer) wrap(n *html.Node, marker string) {
+	inner := &renderer{markdown: r.markdown, keepLines: r.keepLines}
+	inner.children(n)
+
+	text := strings.TrimSpace(inner.b.String())
+	if text == "" {
+		return
+	}
+
+	if r.markdown {
+		text = marker + text + marker
+	}
+
+	r.b.WriteString(text)
+}
+
+func (r *renderer) link(n *html.Node) {
+	inner := &renderer{markdown: r.markdown, keepLines: r.keepLines}
+	inner.children(n)
+
+	text := strings.TrimSpace(inner.b.String())
+	href := attr(n, "href")
+
+	switch {
+	case !r.markdown && text == "":
+		r.b.WriteString(href)
+	case !r.markdown, href == "" || strings.HasPrefix(href, "javascript:"):
+		r.b.WriteString(text)
+	case text == "" || text == markdownEscaper.Replace(href):
+		r.b.WriteString("<" + href + ">")
+	default:
+		r.b.WriteString("[" + text + "](" + href + ")")
+	}
+}
+
+// block 开始新的块，与前面的内容以空行分隔
+func (r *renderer) block() {
+	switch s := r.b.String(); {
+	case s == "", strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		r.b.WriteString("\n")
+	default:
+		r.b.WriteString("\n\n")
+	}
+}
+
+// line 开始新的一行
+func (r *renderer) line() {
+	if s := r.b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		r.b.WriteString("\n")
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// textOf 节点中的原始文字，用于 pre
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	if n.DataAtom == atom.Br {
+		return "\n"
+	}
+
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textOf(child))
+	}
+
+	return b.String()
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// tidy 去掉每行首尾多余的空格（保留 Markdown 换行的两个空格和列表的缩进），合并连续的空行
+func tidy(s string, markdown bool) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		hardBreak := markdown && strings.HasSuffix(line, "  ") && strings.TrimSpace(line) != ""
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		line = strings.TrimSpace(line)
+		if line != "" && (strings.HasPrefix(line, "- ") || listItem.MatchString(line)) {
+			line = strings.Repeat(" ", indent) + line
+		}
+
+		if hardBreak {
+			line += "  "
+		}
+
+		lines[i] = line
+	}
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+var listItem = regexp.MustCompile(`^\d+\. `)
@@ -0,0 +1,29 @@
+package social
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// CSV 导出为边的列表，每条关注关系一行，可以在 Gephi 等工具中作为边表导入
+func CSV(w io.Writer, s *Snapshot) error {
+	b := csv.NewWriter(w)
+
+	b.Write([]string{"source", "source_nickname", "target", "target_nickname", "mutual"})
+	for _, r := range s.Relations() {
+		mutual := strconv.FormatBool(r.Mutual())
+
+		if r.Following {
+			b.Write([]string{s.Uid, s.Nickname, r.Uid, r.Nickname, mutual})
+		}
+
+		if r.Follower {
+			b.Write([]string{r.Uid, r.Nickname, s.Uid, s.Nickname, mutual})
+		}
+	}
+
+	b.Flush()
+
+	return b.Error()
+}
@@ -0,0 +1,180 @@
+// Package social 保存账号的关注和粉丝列表的快照，计算互相关注、未回关的人和两次快照之间的变化
+package social
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// User 关注或粉丝列表中的用户
+type User struct {
+	Uid      string `json:"uid"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar,omitempty"`
+}
+
+// Snapshot 一个账号在某一时刻的全部关注和粉丝，按上游返回的顺序排列
+type Snapshot struct {
+	Uid       string    `json:"uid"`
+	Nickname  string    `json:"nickname"`
+	At        time.Time `json:"at"`
+	Following []User    `json:"following"`
+	Followers []User    `json:"followers"`
+}
+
+const (
+	KindFollowing        = "following"
+	KindFollowers        = "followers"
+	KindMutual           = "mutual"
+	KindNotFollowingBack = "not_following_back" // 关注了但没有回关的人
+	KindFans             = "fans"               // 关注了「我」但「我」没有回关的人
+)
+
+// Relation 用户与账号之间的关系
+type Relation struct {
+	User
+	Following bool `json:"following"` // 账号关注了该用户
+	Follower  bool `json:"follower"`  // 该用户关注了账号
+}
+
+// Mutual 是否互相关注
+func (r Relation) Mutual() bool {
+	return r.Following && r.Follower
+}
+
+// Is 关系是否属于 kind
+func (r Relation) Is(kind string) bool {
+	switch kind {
+	case KindFollowing:
+		return r.Following
+	case KindFollowers:
+		return r.Follower
+	case KindMutual:
+		return r.Mutual()
+	case KindNotFollowingBack:
+		return r.Following && !r.Follower
+	case KindFans:
+		return r.Follower && !r.Following
+	}
+
+	return true
+}
+
+// Relations 关注和粉丝合并后的全部用户，先按关注列表的顺序，再按粉丝列表的顺序
+func (s *Snapshot) Relations() []Relation {
+	index := map[string]int{}
+	var result []Relation
+
+	add := func(u User, follower bool) {
+		i, found := index[u.Uid]
+		if !found {
+			i = len(result)
+			index[u.Uid] = i
+			result = append(result, Relation{User: u})
+		}
+
+		if follower {
+			result[i].Follower = true
+		} else {
+			result[i].Following = true
+		}
+	}
+
+	for _, u := range s.Following {
+		add(u, false)
+	}
+
+	for _, u := range s.Followers {
+		add(u, true)
+	}
+
+	return result
+}
+
+// Filter 属于 kind 且昵称或 uid 包含 keyword 的用户，keyword 不区分大小写，为空时不限制
+func (s *Snapshot) Filter(kind, keyword string) []Relation {
+	keyword = strings.ToLower(keyword)
+
+	result := []Relation{}
+	for _, r := range s.Relations() {
+		if r.Is(kind) && (keyword == "" || strings.Contains(strings.ToLower(r.Nickname+"\n"+r.Uid), keyword)) {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+// Counts 每种关系的人数
+type Counts struct {
+	Following        int `json:"following"`
+	Followers        int `json:"followers"`
+	Mutual           int `json:"mutual"`
+	NotFollowingBack int `json:"notFollowingBack"`
+	Fans             int `json:"fans"`
+}
+
+func (s *Snapshot) Counts() Counts {
+	c := Counts{Following: len(s.Following), Followers: len(s.Followers)}
+
+	for _, r := range s.Relations() {
+		switch {
+		case r.Mutual():
+			c.Mutual++
+		case r.Following:
+			c.NotFollowingBack++
+		default:
+			c.Fans++
+		}
+	}
+
+	return c
+}
+
+const (
+	ChangeFollowerAdded    = "follower_added"    // 新的粉丝
+	ChangeFollowerRemoved  = "follower_removed"  // 取消关注了账号
+	ChangeFollowingAdded   = "following_added"   // 账号新关注的人
+	ChangeFollowingRemoved = "following_removed" // 账号取消关注的人
+)
+
+// Change 两次快照之间的一个变化，At 为发现变化的快照的时间
+type Change struct {
+	Kind string    `json:"kind"`
+	User User      `json:"user"`
+	At   time.Time `json:"at"`
+}
+
+// Diff 从 previous 到 current 的变化，previous 为 nil 时没有变化
+func Diff(previous, current *Snapshot) []Change {
+	changes := []Change{}
+	if previous == nil {
+		return changes
+	}
+
+	compare := func(before, after []User, added, removed string) {
+		for _, u := range after {
+			if !contains(before, u.Uid) {
+				changes = append(changes, Change{Kind: added, User: u, At: current.At})
+			}
+		}
+
+		for _, u := range before {
+			if !contains(after, u.Uid) {
+				changes = append(changes, Change{Kind: removed, User: u, At: current.At})
+			}
+		}
+	}
+
+	compare(previous.Followers, current.Followers, ChangeFollowerAdded, ChangeFollowerRemoved)
+	compare(previous.Following, current.Following, ChangeFollowingAdded, ChangeFollowingRemoved)
+
+	return changes
+}
+
+func contains(users []User, uid string) bool {
+	return slices.ContainsFunc(users, func(u User) bool {
+		return u.Uid == uid
+	})
+}
@@ -0,0 +1,88 @@
+package social
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func users(uids ...string) []User {
+	result := make([]User, len(uids))
+	for i, uid := range uids {
+		result[i] = User{Uid: uid, Nickname: "用户" + uid}
+	}
+
+	return result
+}
+
+func uids(relations []Relation) []string {
+	result := []string{}
+	for _, r := range relations {
+		result = append(result, r.Uid)
+	}
+
+	return result
+}
+
+func TestSnapshotFilter(t *testing.T) {
+	s := &Snapshot{Following: users("a", "b", "c"), Followers: users("b", "d")}
+
+	tests := []struct {
+		name    string
+		kind    string
+		keyword string
+		want    []string
+	}{
+		{"全部用户先按关注再按粉丝的顺序", "", "", []string{"a", "b", "c", "d"}},
+		{"互相关注", KindMutual, "", []string{"b"}},
+		{"未回关", KindNotFollowingBack, "", []string{"a", "c"}},
+		{"粉丝中未回关的人", KindFans, "", []string{"d"}},
+		{"按 uid 查询", KindFollowing, "C", []string{"c"}},
+		{"按昵称查询", KindFollowers, "用户d", []string{"d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uids(s.Filter(tt.kind, tt.keyword)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter(%q, %q) = %v, want %v", tt.kind, tt.keyword, got, tt.want)
+			}
+		})
+	}
+
+	want := Counts{Following: 3, Followers: 2, Mutual: 1, NotFollowingBack: 2, Fans: 1}
+	if got := s.Counts(); got != want {
+		t.Errorf("Counts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	current := &Snapshot{At: at, Following: users("a", "c"), Followers: users("x", "y")}
+
+	tests := []struct {
+		name     string
+		previous *Snapshot
+		want     []Change
+	}{
+		{"没有上一次快照时没有变化", nil, []Change{}},
+		{"没有变化", &Snapshot{Following: users("a", "c"), Followers: users("y", "x")}, []Change{}},
+		{
+			"粉丝和关注的增减",
+			&Snapshot{Following: users("a", "b"), Followers: users("x", "z")},
+			[]Change{
+				{Kind: ChangeFollowerAdded, User: users("y")[0], At: at},
+				{Kind: ChangeFollowerRemoved, User: users("z")[0], At: at},
+				{Kind: ChangeFollowingAdded, User: users("c")[0], At: at},
+				{Kind: ChangeFollowingRemoved, User: users("b")[0], At: at},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.previous, current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
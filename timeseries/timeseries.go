@@ -0,0 +1,245 @@
+// Package timeseries 保存计数的时间序列：原始采样、按小时和按天降采样的三级数据分别保留不同的时长，
+// 支持按时间范围查询和计算增长
+package timeseries
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Missing 未采集到的值，查询时省略
+const Missing = -1
+
+const (
+	ResolutionRaw  = "raw"
+	ResolutionHour = "hour"
+	ResolutionDay  = "day"
+)
+
+// Resolutions 从细到粗的精度，与 Series.Tiers 的顺序相同
+var Resolutions = []string{ResolutionRaw, ResolutionHour, ResolutionDay}
+
+// Field 一个计数，Gauge 为 true 时表示瞬时值（如正在收听的人数），降采样时取最大值，否则为累计值，取最后一个值
+type Field struct {
+	Name  string `json:"name"`
+	Gauge bool   `json:"gauge,omitempty"`
+}
+
+// Point 一次采样或一个降采样的区间，Values 与 Series.Fields 一一对应。
+// 序列化为 [时间戳, 值...] 的数组以减少占用的空间
+type Point struct {
+	At     time.Time
+	Values []int64
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal(append([]int64{p.At.Unix()}, p.Values...))
+}
+
+func (p *Point) UnmarshalJSON(b []byte) error {
+	var a []int64
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+
+	if len(a) == 0 {
+		return fmt.Errorf("timeseries: empty point")
+	}
+
+	p.At, p.Values = time.Unix(a[0], 0), a[1:]
+
+	return nil
+}
+
+// Retention 每一级数据保留的时长，为 0 时不删除
+type Retention struct {
+	Raw  time.Duration
+	Hour time.Duration
+	Day  time.Duration
+}
+
+// DefaultRetention 原始采样保留 7 天，按小时的数据保留 90 天，按天的数据一直保留
+var DefaultRetention = Retention{Raw: 7 * 24 * time.Hour, Hour: 90 * 24 * time.Hour}
+
+// Series 一个对象的时间序列，Tiers 依次为原始采样、按小时和按天的数据，均按时间正序排列
+type Series struct {
+	Fields []Field    `json:"fields"`
+	Since  time.Time  `json:"since"` // 第一次采样的时间
+	Tiers  [3][]Point `json:"tiers"`
+}
+
+// New 创建包含 fields 的时间序列
+func New(fields []Field) *Series {
+	return &Series{Fields: fields}
+}
+
+// Add 加入一次采样，values 中没有的计数记为 Missing；同时更新 loc 中所在的小时和天的区间，并删除超出保留时长的数据
+func (s *Series) Add(at time.Time, values map[string]int64, retention Retention, loc *time.Location) {
+	point := Point{At: at.Truncate(time.Second), Values: make([]int64, len(s.Fields))}
+	for i, f := range s.Fields {
+		v, found := values[f.Name]
+		if !found {
+			v = Missing
+		}
+
+		point.Values[i] = v
+	}
+
+	if s.Since.IsZero() {
+		s.Since = point.At
+	}
+
+	s.Tiers[0] = append(s.Tiers[0], point)
+	// Truncate 按绝对时间取整，在 +05:30 等非整点时区中不是本地的整点，需要按本地时间构造
+	local := at.In(loc)
+	s.merge(1, point, time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc))
+	s.merge(2, point, time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc))
+
+	for i, keep := range []time.Duration{retention.Raw, retention.Hour, retention.Day} {
+		if keep > 0 {
+			s.Tiers[i] = s.Tiers[i][sort.Search(len(s.Tiers[i]), func(j int) bool {
+				return !s.Tiers[i][j].At.Before(at.Add(-keep))
+			}):]
+		}
+	}
+}
+
+// merge 将采样合并到 start 开始的区间，区间不存在时新建
+func (s *Series) merge(tier int, point Point, start time.Time) {
+	points := s.Tiers[tier]
+	if n := len(points); n > 0 && points[n-1].At.Equal(start) {
+		last := points[n-1].Values
+		for i, f := range s.Fields {
+			switch v := point.Values[i]; {
+			case v == Missing:
+			case f.Gauge:
+				last[i] = max(last[i], v)
+			default:
+				last[i] = v
+			}
+		}
+
+		return
+	}
+
+	values := append([]int64(nil), point.Values...)
+	s.Tiers[tier] = append(points, Point{At: start, Values: values})
+}
+
+// Value 查询结果中的一个点，没有采集到的计数不包含在 Values 中
+type Value struct {
+	At     time.Time        `json:"at"`
+	Values map[string]int64 `json:"values"`
+}
+
+// Range 时间在 [from, to) 之间的数据，零值表示不限制。resolution 为空时选择保留了 from 之后全部数据的最细的一级
+func (s *Series) Range(from, to time.Time, resolution string) (string, []Value) {
+	tier := -1
+	for i, r := range Resolutions {
+		if r == resolution {
+			tier = i
+		}
+	}
+
+	if tier < 0 {
+		tier = s.finest(from)
+	}
+
+	values := []Value{}
+	for _, p := range s.Tiers[tier] {
+		if (!from.IsZero() && p.At.Before(from)) || (!to.IsZero() && !p.At.Before(to)) {
+			continue
+		}
+
+		v := Value{At: p.At, Values: map[string]int64{}}
+		for i, f := range s.Fields {
+			if i < len(p.Values) && p.Values[i] != Missing {
+				v.Values[f.Name] = p.Values[i]
+			}
+		}
+
+		values = append(values, v)
+	}
+
+	return Resolutions[tier], values
+}
+
+// finest 保留了 from 之后全部数据的最细的一级，都不完整时为按天的数据
+func (s *Series) finest(from time.Time) int {
+	if from.Before(s.Since) {
+		from = s.Since
+	}
+
+	for i, points := range s.Tiers[:2] {
+		if len(points) > 0 && !points[0].At.After(from) {
+			return i
+		}
+	}
+
+	return 2
+}
+
+// Latest 最近一次采样，没有采样时返回 nil
+func (s *Series) Latest() *Value {
+	raw := s.Tiers[0]
+	if len(raw) == 0 {
+		return nil
+	}
+
+	_, values := s.Range(raw[len(raw)-1].At, time.Time{}, ResolutionRaw)
+
+	return &values[0]
+}
+
+// Growth 一个计数在一段时间内的变化，累计值为首尾之差，瞬时值为首尾的值和区间内的最大值
+type Growth struct {
+	Field   string    `json:"field"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	First   int64     `json:"first"`
+	Last    int64     `json:"last"`
+	Change  int64     `json:"change"`
+	Percent *float64  `json:"percent,omitempty"` // 相对于 First 的变化百分比，First 为 0 时没有
+	PerDay  float64   `json:"perDay"`            // 平均每天的变化
+	Peak    int64     `json:"peak"`
+}
+
+// Summarize 每个计数的变化，只有一个值的计数没有变化，不包含在结果中
+func (s *Series) Summarize(values []Value) []Growth {
+	result := []Growth{}
+
+	for _, f := range s.Fields {
+		var g *Growth
+
+		for _, v := range values {
+			value, found := v.Values[f.Name]
+			if !found {
+				continue
+			}
+
+			if g == nil {
+				g = &Growth{Field: f.Name, From: v.At, First: value, Peak: value}
+			}
+
+			g.To, g.Last, g.Peak = v.At, value, max(g.Peak, value)
+		}
+
+		if g == nil || !g.To.After(g.From) {
+			continue
+		}
+
+		g.Change = g.Last - g.First
+		g.PerDay = float64(g.Change) / g.To.Sub(g.From).Hours() * 24
+
+		if g.First != 0 {
+			percent := float64(g.Change) / float64(g.First) * 100
+			g.Percent = &percent
+		}
+
+		result = append(result, *g)
+	}
+
+	return result
+}
@@ -0,0 +1,134 @@
+package timeseries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesAdd(t *testing.T) {
+	utc8 := time.FixedZone("UTC+8", 8*3600)
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	fields := []Field{{Name: "subscriptions"}, {Name: "listening", Gauge: true}}
+
+	type sample struct {
+		at     time.Time
+		values map[string]int64
+	}
+
+	tests := []struct {
+		name      string
+		loc       *time.Location
+		retention Retention
+		samples   []sample
+		raw       int
+		hours     []time.Time
+		days      []time.Time
+		last      [3][]int64 // 每一级最后一个点的值
+	}{
+		{
+			name: "同一小时内合并，累计值取最后一个、瞬时值取最大值",
+			loc:  utc8,
+			samples: []sample{
+				{time.Date(2024, 1, 1, 10, 5, 0, 0, utc8), map[string]int64{"subscriptions": 10, "listening": 5}},
+				{time.Date(2024, 1, 1, 10, 35, 0, 0, utc8), map[string]int64{"subscriptions": 12, "listening": 3}},
+			},
+			raw:   2,
+			hours: []time.Time{time.Date(2024, 1, 1, 10, 0, 0, 0, utc8)},
+			days:  []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, utc8)},
+			last:  [3][]int64{{12, 3}, {12, 5}, {12, 5}},
+		},
+		{
+			name: "缺少的计数记为 Missing，合并时不覆盖",
+			loc:  utc8,
+			samples: []sample{
+				{time.Date(2024, 1, 1, 10, 5, 0, 0, utc8), map[string]int64{"subscriptions": 10, "listening": 5}},
+				{time.Date(2024, 1, 1, 10, 35, 0, 0, utc8), map[string]int64{"listening": 7}},
+			},
+			raw:   2,
+			hours: []time.Time{time.Date(2024, 1, 1, 10, 0, 0, 0, utc8)},
+			days:  []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, utc8)},
+			last:  [3][]int64{{Missing, 7}, {10, 7}, {10, 7}},
+		},
+		{
+			name: "+05:30 时区按本地的整点和零点划分区间",
+			loc:  ist,
+			samples: []sample{
+				{time.Date(2024, 1, 1, 23, 50, 0, 0, ist), map[string]int64{"subscriptions": 1, "listening": 1}},
+				{time.Date(2024, 1, 2, 0, 10, 0, 0, ist), map[string]int64{"subscriptions": 2, "listening": 2}},
+			},
+			raw: 2,
+			hours: []time.Time{
+				time.Date(2024, 1, 1, 23, 0, 0, 0, ist),
+				time.Date(2024, 1, 2, 0, 0, 0, 0, ist),
+			},
+			days: []time.Time{
+				time.Date(2024, 1, 1, 0, 0, 0, 0, ist),
+				time.Date(2024, 1, 2, 0, 0, 0, 0, ist),
+			},
+			last: [3][]int64{{2, 2}, {2, 2}, {2, 2}},
+		},
+		{
+			name:      "删除超出保留时长的原始采样",
+			loc:       utc8,
+			retention: Retention{Raw: time.Hour},
+			samples: []sample{
+				{time.Date(2024, 1, 1, 10, 0, 0, 0, utc8), map[string]int64{"subscriptions": 1}},
+				{time.Date(2024, 1, 1, 12, 0, 0, 0, utc8), map[string]int64{"subscriptions": 2}},
+			},
+			raw: 1,
+			hours: []time.Time{
+				time.Date(2024, 1, 1, 10, 0, 0, 0, utc8),
+				time.Date(2024, 1, 1, 12, 0, 0, 0, utc8),
+			},
+			days: []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, utc8)},
+			last: [3][]int64{{2, Missing}, {2, Missing}, {2, Missing}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(fields)
+			for _, sample := range tt.samples {
+				s.Add(sample.at, sample.values, tt.retention, tt.loc)
+			}
+
+			if !s.Since.Equal(tt.samples[0].at) {
+				t.Errorf("Since = %s, want %s", s.Since, tt.samples[0].at)
+			}
+
+			if len(s.Tiers[0]) != tt.raw {
+				t.Errorf("raw points = %d, want %d", len(s.Tiers[0]), tt.raw)
+			}
+
+			for tier, want := range map[int][]time.Time{1: tt.hours, 2: tt.days} {
+				if got := s.Tiers[tier]; len(got) != len(want) {
+					t.Errorf("tier %d = %d points, want %d", tier, len(got), len(want))
+				} else {
+					for i := range want {
+						if !got[i].At.Equal(want[i]) {
+							t.Errorf("tier %d point %d at %s, want %s", tier, i, got[i].At.In(tt.loc), want[i])
+						}
+					}
+				}
+			}
+
+			for tier, want := range tt.last {
+				points := s.Tiers[tier]
+				if len(points) == 0 {
+					t.Errorf("tier %d is empty", tier)
+
+					continue
+				}
+
+				got := points[len(points)-1].Values
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("tier %d last values = %v, want %v", tier, got, want)
+
+						break
+					}
+				}
+			}
+		})
+	}
+}
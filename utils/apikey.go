@@ -0,0 +1,574 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ApiKeyHeader xyz 签发的 API Key，也可以通过 Authorization: Bearer 传递
+	ApiKeyHeader = "x-xyz-api-key"
+	// SessionCookie 登录后写入的会话 Cookie，值与 API Key 相同
+	SessionCookie = "xyz_session"
+)
+
+// API Key 的权限，admin 包含 write，write 包含 read
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+const (
+	apiKeyBucket      = "api_keys"
+	apiKeyQuotaBucket = "api_key_quotas"
+	apiKeyContextKey  = "xyz.apiKey"
+)
+
+var scopeLevel = map[string]int{
+	ScopeRead:  1,
+	ScopeWrite: 2,
+	ScopeAdmin: 3,
+}
+
+// ApiKey xyz 签发的 API Key，只保存其 SHA-256
+type ApiKey struct {
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name,omitempty"`
+	Scope      string     `json:"scope"`
+	Quota      string     `json:"quota,omitempty"`
+	Account    string     `json:"account,omitempty"` // 登录时签发的 API Key 只能使用该账号，为空时可以使用所有账号
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+
+	hash string
+}
+
+// Allows 是否拥有 scope 权限
+func (k *ApiKey) Allows(scope string) bool {
+	return scopeLevel[k.Scope] >= scopeLevel[scope]
+}
+
+// Id API Key 的 SHA-256，后台任务保存它而不是明文，之后通过 LookupApiKey 读取
+func (k *ApiKey) Id() string {
+	return k.hash
+}
+
+// CanUseAccount 是否可以通过 x-xyz-account 使用账号 name
+func (k *ApiKey) CanUseAccount(name string) bool {
+	return k.Account == "" || k.Account == name
+}
+
+// CanManageAccount 是否可以创建或覆盖账号 name：该账号登录时签发的 API Key，或未绑定账号的 admin 权限的 API Key
+func (k *ApiKey) CanManageAccount(name string) bool {
+	return k.Account == name || (k.Account == "" && k.Allows(ScopeAdmin))
+}
+
+// quotaWindow 固定窗口内的请求计数，与最近使用时间一起由 FlushApiKeyUsage 定期写入存储，重启后从存储中恢复
+type quotaWindow struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+var (
+	quotaMu      sync.Mutex
+	quotaWindows = map[string]*quotaWindow{}
+	quotaChanged = map[string]bool{} // 上次写入存储后计数有变化的 API Key
+)
+
+// ApiKeyUsageFlushInterval 最近使用时间写入存储的间隔
+const ApiKeyUsageFlushInterval = time.Minute
+
+// 最近使用时间先记录在内存中，定期写入存储，避免每个请求都加密写入一次
+var (
+	apiKeyUsageMu sync.Mutex
+	apiKeyUsage   = map[string]time.Time{}
+)
+
+// 写入最近使用时间与吊销需要串行，避免写入已吊销的 API Key
+var apiKeyMu sync.Mutex
+
+// apiKeyRequestContext 请求的 context 中已校验的 API Key
+type apiKeyRequestContext struct{}
+
+// ParseQuota 解析配额，格式为 <次数>/<周期>，周期为 s、m、h、d，如 1000/h
+func ParseQuota(quota string) (int, time.Duration, error) {
+	count, unit, ok := strings.Cut(quota, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid quota %q, expected <count>/<s|m|h|d>", quota)
+	}
+
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit <= 0 {
+		return 0, 0, fmt.Errorf("invalid quota %q, count must be a positive integer", quota)
+	}
+
+	periods := map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+	}
+
+	period, ok := periods[unit]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid quota %q, period must be one of s, m, h, d", quota)
+	}
+
+	return limit, period, nil
+}
+
+// IssueApiKey 签发新的 API Key，返回明文，明文只在签发时可见
+func IssueApiKey(name, scope, quota string) (string, *ApiKey, error) {
+	return issueApiKey(name, scope, quota, "")
+}
+
+func issueApiKey(name, scope, quota, account string) (string, *ApiKey, error) {
+	if _, ok := scopeLevel[scope]; !ok {
+		return "", nil, fmt.Errorf("invalid scope %q, expected read, write or admin", scope)
+	}
+
+	if quota != "" {
+		if _, _, err := ParseQuota(quota); err != nil {
+			return "", nil, err
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", nil, err
+	}
+
+	key := "xyz_" + base64.RawURLEncoding.EncodeToString(b)
+
+	apiKey := &ApiKey{
+		Prefix:    key[:12],
+		Name:      name,
+		Scope:     scope,
+		Quota:     quota,
+		Account:   account,
+		CreatedAt: time.Now(),
+	}
+
+	if err := StorePut(apiKeyBucket, hashApiKey(key), apiKey); err != nil {
+		return "", nil, err
+	}
+
+	return key, apiKey, nil
+}
+
+// VerifyApiKey 校验 API Key 并记录最近使用时间，最近使用时间由 FlushApiKeyUsage 定期写入存储
+func VerifyApiKey(key string) (*ApiKey, error) {
+	if key == "" {
+		return nil, ErrStoreNotFound
+	}
+
+	hash := hashApiKey(key)
+
+	apiKey := &ApiKey{}
+	if err := StoreGet(apiKeyBucket, hash, apiKey); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	apiKey.LastUsedAt = &now
+	apiKey.hash = hash
+
+	apiKeyUsageMu.Lock()
+	apiKeyUsage[hash] = now
+	apiKeyUsageMu.Unlock()
+
+	return apiKey, nil
+}
+
+// LookupApiKey 按 Id 读取 API Key，不记录使用时间，已吊销时返回 ErrStoreNotFound
+func LookupApiKey(id string) (*ApiKey, error) {
+	if id == "" {
+		return nil, ErrStoreNotFound
+	}
+
+	apiKey := &ApiKey{}
+	if err := StoreGet(apiKeyBucket, id, apiKey); err != nil {
+		return nil, err
+	}
+
+	apiKey.hash = id
+
+	return apiKey, nil
+}
+
+// FlushApiKeyUsage 将内存中的最近使用时间和配额计数写入存储，已吊销的 API Key 被跳过
+func FlushApiKeyUsage() error {
+	apiKeyUsageMu.Lock()
+	usage := apiKeyUsage
+	apiKeyUsage = map[string]time.Time{}
+	apiKeyUsageMu.Unlock()
+
+	quotaMu.Lock()
+	windows := make(map[string]quotaWindow, len(quotaChanged))
+	for hash := range quotaChanged {
+		windows[hash] = *quotaWindows[hash]
+	}
+	quotaChanged = map[string]bool{}
+	quotaMu.Unlock()
+
+	apiKeyMu.Lock()
+	defer apiKeyMu.Unlock()
+
+	for hash, at := range usage {
+		apiKey := &ApiKey{}
+		err := StoreGet(apiKeyBucket, hash, apiKey)
+		if errors.Is(err, ErrStoreNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if apiKey.LastUsedAt != nil && !at.After(*apiKey.LastUsedAt) {
+			continue
+		}
+
+		apiKey.LastUsedAt = &at
+
+		if err := StorePut(apiKeyBucket, hash, apiKey); err != nil {
+			return err
+		}
+	}
+
+	for hash, window := range windows {
+		err := StoreGet(apiKeyBucket, hash, &ApiKey{})
+		if errors.Is(err, ErrStoreNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := StorePut(apiKeyQuotaBucket, hash, window); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartApiKeyUsageFlush 每隔 ApiKeyUsageFlushInterval 写入一次最近使用时间和配额计数
+func StartApiKeyUsageFlush() {
+	go func() {
+		ticker := time.NewTicker(ApiKeyUsageFlushInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := FlushApiKeyUsage(); err != nil {
+				log.Printf("api key usage: %v", err)
+			}
+		}
+	}()
+}
+
+// ListApiKeys 按签发时间返回所有 API Key
+func ListApiKeys() ([]ApiKey, error) {
+	var keys []ApiKey
+
+	err := StoreEach(apiKeyBucket, func(key string, value []byte) error {
+		var apiKey ApiKey
+		if err := json.Unmarshal(value, &apiKey); err != nil {
+			return err
+		}
+
+		apiKey.hash = key
+
+		apiKeyUsageMu.Lock()
+		if at, ok := apiKeyUsage[key]; ok {
+			apiKey.LastUsedAt = &at
+		}
+		apiKeyUsageMu.Unlock()
+
+		keys = append(keys, apiKey)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// RevokeApiKey 按前缀吊销 API Key，不存在时返回 ErrStoreNotFound
+func RevokeApiKey(prefix string) error {
+	keys, err := ListApiKeys()
+	if err != nil {
+		return err
+	}
+
+	apiKeyMu.Lock()
+	defer apiKeyMu.Unlock()
+
+	for _, apiKey := range keys {
+		if apiKey.Prefix == prefix {
+			return deleteApiKey(apiKey.hash)
+		}
+	}
+
+	return ErrStoreNotFound
+}
+
+// revokeAccountKeys 吊销账号登录时签发的全部 API Key，删除账号后同名的新账号不能被旧的 API Key 使用
+func revokeAccountKeys(account string) error {
+	keys, err := ListApiKeys()
+	if err != nil {
+		return err
+	}
+
+	apiKeyMu.Lock()
+	defer apiKeyMu.Unlock()
+
+	for _, apiKey := range keys {
+		if apiKey.Account == account {
+			if err := deleteApiKey(apiKey.hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteApiKey 删除 API Key 和保存的配额计数，调用方需持有 apiKeyMu
+func deleteApiKey(hash string) error {
+	if err := StoreDelete(apiKeyBucket, hash); err != nil {
+		return err
+	}
+
+	if err := StoreDelete(apiKeyQuotaBucket, hash); err != nil && !errors.Is(err, ErrStoreNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// RequestApiKey 依次从 x-xyz-api-key、Authorization: Bearer 和会话 Cookie 中读取 API Key
+func RequestApiKey(ctx *gin.Context) string {
+	if key := ctx.GetHeader(ApiKeyHeader); key != "" {
+		return key
+	}
+
+	if auth := ctx.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	if key, err := ctx.Cookie(SessionCookie); err == nil {
+		return key
+	}
+
+	return ""
+}
+
+// AuthorizeAccount 校验请求中的 API Key 是否可以创建或覆盖账号 name，不能时返回 401 或 403
+func AuthorizeAccount(ctx *gin.Context, name string) bool {
+	if !authenticate(ctx) {
+		return false
+	}
+
+	if apiKey := CurrentApiKey(ctx); !apiKey.CanManageAccount(name) {
+		ReturnError(ctx, NewError(http.StatusForbidden, ErrForbidden, "api key cannot create or overwrite account: "+name))
+
+		return false
+	}
+
+	return true
+}
+
+// IssueAccountKey 当前 API Key 不是账号 name 登录时签发的时，签发一个只能使用该账号的 write 权限的 API Key 并写入会话 Cookie，返回新签发的 API Key
+func IssueAccountKey(ctx *gin.Context, name string) (string, error) {
+	if apiKey := CurrentApiKey(ctx); apiKey != nil && apiKey.Account == name {
+		return "", nil
+	}
+
+	key, _, err := issueApiKey("login:"+name, ScopeWrite, "", name)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(SessionCookie, key, 0, "/", "", false, true)
+
+	return key, nil
+}
+
+// CheckApiKey 校验 xyz 签发的 API Key 或会话 Cookie
+func CheckApiKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !authenticate(ctx) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Authorize 开启 -auth 后校验 API Key 是否拥有 scope 权限，未开启时不做校验
+func Authorize(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !AuthEnabled {
+			ctx.Next()
+
+			return
+		}
+
+		if !authenticate(ctx) {
+			return
+		}
+
+		if apiKey := CurrentApiKey(ctx); !apiKey.Allows(scope) {
+			ReturnError(ctx, NewError(http.StatusForbidden, ErrForbidden, fmt.Sprintf("api key with scope %q cannot access %s scope", apiKey.Scope, scope)))
+
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// CurrentApiKey 当前请求使用的 API Key，未校验时返回 nil
+func CurrentApiKey(ctx *gin.Context) *ApiKey {
+	if v, ok := ctx.Get(apiKeyContextKey); ok {
+		return v.(*ApiKey)
+	}
+
+	return nil
+}
+
+// authenticate 校验 API Key 并扣减配额，同一请求只校验一次。
+// 校验后的 API Key 保存在请求的 context 中，/batch、/graphql、后台任务等通过 runOperation 在进程内调用的接口沿用该 API Key，
+// 不再校验和扣减配额，一次客户端请求只扣减一次配额
+func authenticate(ctx *gin.Context) bool {
+	if _, ok := ctx.Get(apiKeyContextKey); ok {
+		return true
+	}
+
+	if apiKey, ok := ctx.Request.Context().Value(apiKeyRequestContext{}).(*ApiKey); ok {
+		ctx.Set(apiKeyContextKey, apiKey)
+
+		return true
+	}
+
+	apiKey, err := VerifyApiKey(RequestApiKey(ctx))
+	if errors.Is(err, ErrStoreNotFound) {
+		ReturnError(ctx, NewError(http.StatusUnauthorized, ErrUnauthorized, "missing or invalid xyz api key"))
+
+		return false
+	}
+
+	if err != nil {
+		ReturnError(ctx, err)
+
+		return false
+	}
+
+	if !takeQuota(ctx, apiKey) {
+		return false
+	}
+
+	ctx.Set(apiKeyContextKey, apiKey)
+	ctx.Request = ctx.Request.WithContext(WithApiKey(ctx.Request.Context(), apiKey))
+
+	return true
+}
+
+// WithApiKey 将已校验的 API Key 加入 context，使用该 context 的进程内请求视为已校验
+func WithApiKey(c context.Context, apiKey *ApiKey) context.Context {
+	return context.WithValue(c, apiKeyRequestContext{}, apiKey)
+}
+
+// takeQuota 扣减一次配额并写入 X-RateLimit-* 响应头，超出配额时返回 429
+func takeQuota(ctx *gin.Context, apiKey *ApiKey) bool {
+	if apiKey.Quota == "" {
+		return true
+	}
+
+	limit, period, err := ParseQuota(apiKey.Quota)
+	if err != nil {
+		ReturnError(ctx, err)
+
+		return false
+	}
+
+	now := time.Now()
+
+	quotaMu.Lock()
+	window, ok := quotaWindows[apiKey.hash]
+	if !ok {
+		window = loadQuotaWindow(apiKey.hash)
+	}
+
+	if window == nil || now.Sub(window.Start) >= period {
+		window = &quotaWindow{Start: now.Truncate(period)}
+	}
+
+	window.Count++
+	quotaWindows[apiKey.hash] = window
+	quotaChanged[apiKey.hash] = true
+
+	count := window.Count
+	reset := window.Start.Add(period)
+	quotaMu.Unlock()
+
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	ctx.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+	ctx.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	ctx.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if count > limit {
+		ctx.Header("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+		ReturnError(ctx, NewError(http.StatusTooManyRequests, ErrQuotaExceeded, fmt.Sprintf("quota %s exceeded", apiKey.Quota)))
+
+		return false
+	}
+
+	return true
+}
+
+// loadQuotaWindow 读取上次写入存储的配额计数，进程内第一次使用该 API Key 时调用，没有保存时返回 nil
+func loadQuotaWindow(hash string) *quotaWindow {
+	window := &quotaWindow{}
+	if err := StoreGet(apiKeyQuotaBucket, hash, window); err != nil {
+		return nil
+	}
+
+	return window
+}
+
+func hashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestApiKeyAccount(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    ApiKey
+		use    bool
+		manage bool
+	}{
+		{"未绑定的 admin", ApiKey{Scope: ScopeAdmin}, true, true},
+		{"未绑定的 write", ApiKey{Scope: ScopeWrite}, true, false},
+		{"绑定到该账号", ApiKey{Scope: ScopeWrite, Account: "editorial"}, true, true},
+		{"绑定到其它账号", ApiKey{Scope: ScopeWrite, Account: "other"}, false, false},
+		{"绑定到其它账号的 admin", ApiKey{Scope: ScopeAdmin, Account: "other"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.CanUseAccount("editorial"); got != tt.use {
+				t.Errorf("CanUseAccount() = %v, want %v", got, tt.use)
+			}
+
+			if got := tt.key.CanManageAccount("editorial"); got != tt.manage {
+				t.Errorf("CanManageAccount() = %v, want %v", got, tt.manage)
+			}
+		})
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	tests := []struct {
+		quota string
+		limit int
+		ok    bool
+	}{
+		{"1000/h", 1000, true},
+		{"5/s", 5, true},
+		{"0/h", 0, false},
+		{"10/w", 0, false},
+		{"10", 0, false},
+	}
+
+	for _, tt := range tests {
+		limit, _, err := ParseQuota(tt.quota)
+		if limit != tt.limit || (err == nil) != tt.ok {
+			t.Errorf("ParseQuota(%q) = %d, %v", tt.quota, limit, err)
+		}
+	}
+}
+
+func TestAuthenticateOncePerRequest(t *testing.T) {
+	if err := InitStore(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseStore() })
+
+	key, _, err := IssueApiKey("test", ScopeRead, "10/h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/podcast_page", nil)
+	request.Header.Set(ApiKeyHeader, key)
+
+	outer, _ := gin.CreateTestContext(httptest.NewRecorder())
+	outer.Request = request
+
+	if !authenticate(outer) {
+		t.Fatal("authenticate() = false for a valid key")
+	}
+
+	// runOperation 在进程内发起的请求继承原请求的 context
+	for i := 0; i < 3; i++ {
+		inner, _ := gin.CreateTestContext(httptest.NewRecorder())
+		inner.Request = httptest.NewRequest(http.MethodPost, "/podcast_detail", nil).WithContext(outer.Request.Context())
+
+		if !authenticate(inner) || CurrentApiKey(inner) == nil {
+			t.Fatal("authenticate() = false for an in-process request")
+		}
+	}
+
+	quotaMu.Lock()
+	count := quotaWindows[hashApiKey(key)].Count
+	quotaMu.Unlock()
+
+	if count != 1 {
+		t.Errorf("quota charged %d times, want 1", count)
+	}
+
+	keys, err := ListApiKeys()
+	if err != nil || len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Fatalf("ListApiKeys() = %v, %v, want the in-memory last used time", keys, err)
+	}
+
+	if err := FlushApiKeyUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := &ApiKey{}
+	if err := StoreGet(apiKeyBucket, hashApiKey(key), stored); err != nil || stored.LastUsedAt == nil {
+		t.Errorf("LastUsedAt not flushed: %+v, %v", stored, err)
+	}
+}
+
+func TestQuotaPersisted(t *testing.T) {
+	if err := InitStore(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseStore() })
+
+	key, apiKey, err := IssueApiKey("test", ScopeRead, "2/h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/podcast_detail", nil)
+		ctx.Request.Header.Set(ApiKeyHeader, key)
+		authenticate(ctx)
+
+		return recorder
+	}
+
+	if recorder := request(); recorder.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("X-RateLimit-Remaining = %q, want 1", recorder.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	if err := FlushApiKeyUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后从存储中恢复计数
+	quotaMu.Lock()
+	delete(quotaWindows, hashApiKey(key))
+	quotaMu.Unlock()
+
+	request()
+	if recorder := request(); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("third request = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	if err := FlushApiKeyUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RevokeApiKey(apiKey.Prefix); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := StoreGet(apiKeyQuotaBucket, hashApiKey(key), &quotaWindow{}); !errors.Is(err, ErrStoreNotFound) {
+		t.Errorf("quota after revoke: %v, want ErrStoreNotFound", err)
+	}
+}
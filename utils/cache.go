@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"io"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,26 +26,91 @@ func InitCache() {
 	})
 }
 
+// cacheKeyPrefix 缓存键的前缀，重新加密时只扫描这些键，不影响 Redis 中的其它数据
+const cacheKeyPrefix = "xyz:cache:"
+
 // GetCacheKey 生成缓存键
 func GetCacheKey(uri string, token string, bodyHash string) string {
 	hash := sha256.Sum256([]byte(uri + ":" + token + ":" + bodyHash))
-	return hex.EncodeToString(hash[:])
+	return cacheKeyPrefix + hex.EncodeToString(hash[:])
 }
 
-// GetCachedResponse 获取缓存内容
+// GetCachedResponse 获取缓存内容，缓存内容加密存储
 func GetCachedResponse(key string) (string, error) {
-	return RedisClient.Get(Ctx, key).Result()
+	sealed, err := RedisClient.Get(Ctx, key).Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := Decrypt(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
 }
 
-// SetCachedResponse 设置缓存内容并存储时间戳
-// SetCachedResponse 设置缓存内容并存储时间戳
-// SetCachedResponse 设置缓存内容并存储时间戳
+// SetCachedResponse 加密缓存内容并存储时间戳
 func SetCachedResponse(key string, response string, ttl int) {
+	sealed, err := Encrypt([]byte(response))
+	if err != nil {
+		log.Printf("Cache Key: %s | Skip: %v", key, err)
+
+		return
+	}
+
 	now := time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT") // 强制使用 GMT 格式
-	RedisClient.Set(Ctx, key, response, 0)
+	RedisClient.Set(Ctx, key, sealed, 0)
 	RedisClient.Set(Ctx, key+":last_modified", now, 0)
 }
 
+// ReencryptCache 使用当前密钥重新加密 Redis 中的缓存，无法解密的缓存会被删除，返回重新加密和删除的条数
+func ReencryptCache() (int, int, error) {
+	reencrypted, deleted := 0, 0
+
+	iter := RedisClient.Scan(Ctx, 0, cacheKeyPrefix+"*", 100).Iterator()
+	for iter.Next(Ctx) {
+		key := iter.Val()
+
+		// 缓存键为前缀加 64 位十六进制的 SHA-256，:last_modified 中只有时间戳，不需要加密
+		if hash := strings.TrimPrefix(key, cacheKeyPrefix); len(hash) != sha256.Size*2 {
+			continue
+		} else if _, err := hex.DecodeString(hash); err != nil {
+			continue
+		}
+
+		sealed, err := RedisClient.Get(Ctx, key).Bytes()
+		if err != nil {
+			continue
+		}
+
+		if EncryptedWithCurrentKey(sealed) {
+			continue
+		}
+
+		b, err := Decrypt(sealed)
+		if err != nil {
+			RedisClient.Del(Ctx, key, key+":last_modified")
+			deleted++
+
+			continue
+		}
+
+		resealed, err := Encrypt(b)
+		if err != nil {
+			return reencrypted, deleted, err
+		}
+
+		if err := RedisClient.Set(Ctx, key, resealed, redis.KeepTTL).Err(); err != nil {
+			return reencrypted, deleted, err
+		}
+
+		reencrypted++
+	}
+
+	return reencrypted, deleted, iter.Err()
+}
+
 // WithConditionalGet 修改后的函数
 func WithConditionalGet(handler gin.HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -62,7 +128,7 @@ func WithConditionalGet(handler gin.HandlerFunc) gin.HandlerFunc {
 			bodyHash = hex.EncodeToString(hash[:])
 		}
 		// 构造缓存键
-		rawURI := c.Request.URL.Path
+		rawURI := c.Request.URL.RequestURI() // /v2 的 GET 接口通过查询参数传入 cursor 等参数
 		token := c.Request.Header.Get("x-jike-access-token")
 		cacheKey := GetCacheKey(rawURI, token, bodyHash)
 
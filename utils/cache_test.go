@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetCacheKey(t *testing.T) {
+	key := GetCacheKey("/episode_detail", "token", "")
+
+	if !strings.HasPrefix(key, cacheKeyPrefix) || len(key) != len(cacheKeyPrefix)+64 {
+		t.Errorf("GetCacheKey() = %q, want %q followed by a SHA-256", key, cacheKeyPrefix)
+	}
+
+	if key == GetCacheKey("/episode_detail", "other", "") {
+		t.Error("GetCacheKey() should depend on the token")
+	}
+}
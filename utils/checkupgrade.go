@@ -15,7 +15,7 @@ type ResponseBody struct {
 }
 
 func CheckUpgrade() error {
-	response, _, err := Request(constant.UpgradeUrl, http.MethodGet, nil, nil)
+	response, _, err := Request(Ctx, constant.UpgradeUrl, http.MethodGet, nil, nil)
 	if err != nil {
 		return err
 	}
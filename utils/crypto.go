@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretKeyEnv 指定加密密钥的环境变量，值为 32 字节密钥的 base64 或 hex 编码
+//
+// 多个密钥以逗号分隔，第一个用于加密，其余只用于解密，轮换密钥时使用
+const SecretKeyEnv = "XYZ_SECRET_KEY"
+
+// secretKeyFile 未指定密钥时自动生成的密钥文件，位于数据目录中
+const secretKeyFile = "secret.key"
+
+// 密文格式：版本(1) + 密钥 ID(4) + nonce(12) + 密文
+const (
+	cipherVersion = 1
+	keyIdSize     = 4
+)
+
+var (
+	// secretKeys 第一个为当前密钥，其余为轮换前的旧密钥
+	secretKeys [][]byte
+	// secretPath 密钥文件路径，密钥来自环境变量时为空
+	secretPath string
+)
+
+// InitSecret 加载用于加密本地数据的密钥
+//
+// 依次使用环境变量 XYZ_SECRET_KEY、-key-file 指定的密钥文件、数据目录中的 secret.key，密钥文件不存在时自动生成
+func InitSecret(dir string) error {
+	if v := os.Getenv(SecretKeyEnv); v != "" {
+		keys, err := parseSecretKeys(strings.Split(v, ","))
+		if err != nil {
+			return fmt.Errorf("%s: %w", SecretKeyEnv, err)
+		}
+
+		secretKeys = keys
+		secretPath = ""
+
+		return nil
+	}
+
+	path := SecretKeyFile
+	if path == "" {
+		path = filepath.Join(dir, secretKeyFile)
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := newSecretKey()
+		if err != nil {
+			return err
+		}
+
+		if err := writeSecretKeys(path, [][]byte{key}); err != nil {
+			return err
+		}
+
+		secretKeys = [][]byte{key}
+		secretPath = path
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseSecretKeys(strings.Split(string(b), "\n"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	secretKeys = keys
+	secretPath = path
+
+	return nil
+}
+
+// ParseSecretKey 解析 base64 或 hex 编码的 32 字节密钥
+func ParseSecretKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	if key, err := hex.DecodeString(s); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("secret key must be 32 bytes encoded in base64 or hex")
+}
+
+// RotateSecret 生成新密钥并将其设为当前密钥，旧密钥保留用于解密，返回新密钥的 ID
+//
+// 重新加密所有数据后调用 RetireSecrets 删除旧密钥。密钥来自环境变量时无法自动生成，
+// 需要将新密钥放在 XYZ_SECRET_KEY 的第一位
+func RotateSecret() (string, error) {
+	if secretPath == "" {
+		if len(secretKeys) < 2 {
+			return "", fmt.Errorf("keys are read from %s, put the new key first, e.g. %s=<new>,<old>", SecretKeyEnv, SecretKeyEnv)
+		}
+
+		return KeyId(secretKeys[0]), nil
+	}
+
+	key, err := newSecretKey()
+	if err != nil {
+		return "", err
+	}
+
+	keys := append([][]byte{key}, secretKeys...)
+
+	if err := writeSecretKeys(secretPath, keys); err != nil {
+		return "", err
+	}
+
+	secretKeys = keys
+
+	return KeyId(key), nil
+}
+
+// RetireSecrets 删除密钥文件中的旧密钥，只保留当前密钥
+func RetireSecrets() error {
+	if len(secretKeys) == 0 {
+		return fmt.Errorf("secret key not initialized")
+	}
+
+	if secretPath != "" {
+		if err := writeSecretKeys(secretPath, secretKeys[:1]); err != nil {
+			return err
+		}
+	}
+
+	secretKeys = secretKeys[:1]
+
+	return nil
+}
+
+// KeyId 密钥的 ID，为密钥 SHA-256 的前 4 字节，写在密文头部用于选择解密的密钥
+func KeyId(key []byte) string {
+	return hex.EncodeToString(keyId(key))
+}
+
+// Encrypt 使用当前密钥以 AES-256-GCM 加密
+func Encrypt(plaintext []byte) ([]byte, error) {
+	if len(secretKeys) == 0 {
+		return nil, fmt.Errorf("secret key not initialized")
+	}
+
+	key := secretKeys[0]
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	prefix := append([]byte{cipherVersion}, keyId(key)...)
+	out := append(append([]byte{}, prefix...), nonce...)
+
+	return aead.Seal(out, nonce, plaintext, prefix), nil
+}
+
+// Decrypt 解密 Encrypt 的结果，根据密文头部的密钥 ID 选择当前密钥或旧密钥
+func Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 1+keyIdSize || ciphertext[0] != cipherVersion {
+		return nil, fmt.Errorf("unsupported ciphertext")
+	}
+
+	id := ciphertext[1 : 1+keyIdSize]
+
+	for _, key := range secretKeys {
+		if !bytes.Equal(keyId(key), id) {
+			continue
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		rest := ciphertext[1+keyIdSize:]
+		if len(rest) < aead.NonceSize() {
+			return nil, fmt.Errorf("ciphertext too short")
+		}
+
+		return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], ciphertext[:1+keyIdSize])
+	}
+
+	return nil, fmt.Errorf("unknown secret key %s", hex.EncodeToString(id))
+}
+
+// EncryptedWithCurrentKey 密文是否已使用当前密钥加密
+func EncryptedWithCurrentKey(ciphertext []byte) bool {
+	return len(secretKeys) > 0 &&
+		len(ciphertext) >= 1+keyIdSize &&
+		ciphertext[0] == cipherVersion &&
+		bytes.Equal(ciphertext[1:1+keyIdSize], keyId(secretKeys[0]))
+}
+
+func keyId(key []byte) []byte {
+	hash := sha256.Sum256(key)
+
+	return hash[:keyIdSize]
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func newSecretKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func parseSecretKeys(lines []string) ([][]byte, error) {
+	var keys [][]byte
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, err := ParseSecretKey(line)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no secret key found")
+	}
+
+	return keys, nil
+}
+
+// writeSecretKeys 每行一个 base64 编码的密钥，先写入临时文件再替换，避免写入中断导致密钥丢失
+func writeSecretKeys(path string, keys [][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 错误码，供调用方按类型处理错误
+const (
+	ErrValidationFailed        = "VALIDATION_FAILED"
+	ErrNotFound                = "NOT_FOUND"
+	ErrInternal                = "INTERNAL_ERROR"
+	ErrUnauthorized            = "UNAUTHORIZED"
+	ErrForbidden               = "FORBIDDEN"
+	ErrQuotaExceeded           = "QUOTA_EXCEEDED"
+	ErrTimeout                 = "TIMEOUT"
+	ErrDependencyFailed        = "DEPENDENCY_FAILED"
+	ErrUpstreamBadRequest      = "UPSTREAM_BAD_REQUEST"
+	ErrUpstreamUnauthorized    = "UPSTREAM_UNAUTHORIZED"
+	ErrUpstreamForbidden       = "UPSTREAM_FORBIDDEN"
+	ErrUpstreamNotFound        = "UPSTREAM_NOT_FOUND"
+	ErrUpstreamRateLimited     = "UPSTREAM_RATE_LIMITED"
+	ErrUpstreamRejected        = "UPSTREAM_REJECTED"
+	ErrUpstreamError           = "UPSTREAM_ERROR"
+	ErrUpstreamTimeout         = "UPSTREAM_TIMEOUT"
+	ErrUpstreamUnavailable     = "UPSTREAM_UNAVAILABLE"
+	ErrUpstreamConnectionLost  = "UPSTREAM_CONNECTION_LOST"
+	ErrUpstreamInvalidResponse = "UPSTREAM_INVALID_RESPONSE"
+)
+
+// 上游错误响应体最多保留的字节数
+const upstreamErrorBodyLimit = 1 << 20
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Upstream 上游返回的错误信息
+type Upstream struct {
+	Status int `json:"status"`
+	Body   any `json:"body,omitempty"`
+}
+
+// Error 统一的错误类型，Status 为返回给调用方的 HTTP 状态码
+type Error struct {
+	Status   int          `json:"status"`
+	Code     string       `json:"code"`
+	Message  string       `json:"message"`
+	Details  []FieldError `json:"details,omitempty"`
+	Upstream *Upstream    `json:"upstream,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError 创建错误
+func NewError(status int, code, message string) *Error {
+	if message == "" {
+		message = GetMsg(status)
+	}
+
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// AsError 将任意错误转换为 *Error，无法识别的错误视为服务器内部错误
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return NewError(http.StatusInternalServerError, ErrInternal, err.Error())
+}
+
+// ReturnError 以统一格式返回错误
+func ReturnError(ctx *gin.Context, err error) {
+	e := AsError(err)
+
+	ctx.AbortWithStatusJSON(e.Status, ErrorBody(e))
+}
+
+// ErrorBody 统一格式的错误响应体
+func ErrorBody(e *Error) gin.H {
+	return gin.H{
+		"code":  e.Status,
+		"msg":   GetMsg(e.Status),
+		"error": e,
+	}
+}
+
+// upstreamRequestError 请求未能到达上游或未收到响应
+func upstreamRequestError(err error, sent bool) *Error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return NewError(http.StatusGatewayTimeout, ErrUpstreamTimeout, err.Error())
+	}
+
+	// 请求已发送后连接断开，上游可能已经处理了该请求
+	if sent {
+		return NewError(http.StatusBadGateway, ErrUpstreamConnectionLost, err.Error())
+	}
+
+	return NewError(http.StatusBadGateway, ErrUpstreamUnavailable, err.Error())
+}
+
+// upstreamStatusError 上游返回了非 2xx 状态码，保留上游的响应体
+func upstreamStatusError(response *http.Response) *Error {
+	var e *Error
+
+	switch status := response.StatusCode; {
+	case status == http.StatusUnauthorized:
+		e = NewError(http.StatusUnauthorized, ErrUpstreamUnauthorized, "")
+	case status == http.StatusForbidden:
+		e = NewError(http.StatusForbidden, ErrUpstreamForbidden, "")
+	case status == http.StatusNotFound:
+		e = NewError(http.StatusNotFound, ErrUpstreamNotFound, "")
+	case status == http.StatusTooManyRequests:
+		e = NewError(http.StatusTooManyRequests, ErrUpstreamRateLimited, "")
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		e = NewError(http.StatusBadRequest, ErrUpstreamBadRequest, "")
+	case status >= 400 && status < 500:
+		e = NewError(status, ErrUpstreamRejected, "")
+	default:
+		e = NewError(http.StatusBadGateway, ErrUpstreamError, "")
+	}
+
+	e.Upstream = &Upstream{Status: response.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, upstreamErrorBodyLimit))
+	if err != nil || len(body) == 0 {
+		return e
+	}
+
+	var data any
+	if json.Unmarshal(body, &data) == nil {
+		e.Upstream.Body = data
+
+		if m, ok := data.(map[string]any); ok {
+			if msg, ok := m["msg"].(string); ok && msg != "" {
+				e.Message = msg
+			} else if msg, ok := m["message"].(string); ok && msg != "" {
+				e.Message = msg
+			}
+		}
+	} else {
+		e.Upstream.Body = string(body)
+	}
+
+	return e
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestUpstreamStatusError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		want     int
+		code     string
+		message  string
+		upstream any // 保留的上游响应体
+	}{
+		{"上游 500 返回 502", http.StatusInternalServerError, `{"msg":"服务异常"}`, http.StatusBadGateway, ErrUpstreamError, "服务异常", map[string]any{"msg": "服务异常"}},
+		{"上游 503 返回 502", http.StatusServiceUnavailable, "maintenance", http.StatusBadGateway, ErrUpstreamError, GetMsg(http.StatusBadGateway), "maintenance"},
+		{"上游 504 返回 502", http.StatusGatewayTimeout, "", http.StatusBadGateway, ErrUpstreamError, GetMsg(http.StatusBadGateway), nil},
+		{"token 失效", http.StatusUnauthorized, `{"message":"登录已失效"}`, http.StatusUnauthorized, ErrUpstreamUnauthorized, "登录已失效", map[string]any{"message": "登录已失效"}},
+		{"无权访问", http.StatusForbidden, `{}`, http.StatusForbidden, ErrUpstreamForbidden, GetMsg(http.StatusForbidden), map[string]any{}},
+		{"资源不存在", http.StatusNotFound, `{"msg":""}`, http.StatusNotFound, ErrUpstreamNotFound, GetMsg(http.StatusNotFound), map[string]any{"msg": ""}},
+		{"被限流", http.StatusTooManyRequests, "", http.StatusTooManyRequests, ErrUpstreamRateLimited, GetMsg(http.StatusTooManyRequests), nil},
+		{"参数错误", http.StatusUnprocessableEntity, `{"msg":"参数错误"}`, http.StatusBadRequest, ErrUpstreamBadRequest, "参数错误", map[string]any{"msg": "参数错误"}},
+		{"其它 4xx 保留状态码", http.StatusConflict, `[1]`, http.StatusConflict, ErrUpstreamRejected, "Error", []any{float64(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			_, status, err := Request(context.Background(), server.URL, http.MethodPost, nil, nil)
+
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Request() error = %v, want *Error", err)
+			}
+
+			if status != tt.want || e.Status != tt.want || e.Code != tt.code || e.Message != tt.message {
+				t.Errorf("Request() = %d %s %q, want %d %s %q", e.Status, e.Code, e.Message, tt.want, tt.code, tt.message)
+			}
+
+			if e.Upstream == nil || e.Upstream.Status != tt.status || !reflect.DeepEqual(e.Upstream.Body, tt.upstream) {
+				t.Errorf("Upstream = %+v, want status %d body %v", e.Upstream, tt.status, tt.upstream)
+			}
+		})
+	}
+}
+
+func TestUpstreamRequestError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		sent   bool
+		status int
+		code   string
+	}{
+		{"超时", context.DeadlineExceeded, true, http.StatusGatewayTimeout, ErrUpstreamTimeout},
+		{"发送前失败", errors.New("connection refused"), false, http.StatusBadGateway, ErrUpstreamUnavailable},
+		{"发送后连接断开", errors.New("EOF"), true, http.StatusBadGateway, ErrUpstreamConnectionLost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e := upstreamRequestError(tt.err, tt.sent); e.Status != tt.status || e.Code != tt.code {
+				t.Errorf("upstreamRequestError() = %d %s, want %d %s", e.Status, e.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	e := NewError(http.StatusBadGateway, ErrUpstreamError, "")
+	e.Upstream = &Upstream{Status: http.StatusInternalServerError, Body: "oops"}
+
+	b, err := json.Marshal(ErrorBody(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"code":502,"error":{"status":502,"code":"UPSTREAM_ERROR","message":"网关错误","upstream":{"status":500,"body":"oops"}},"msg":"网关错误"}`
+	if string(b) != want {
+		t.Errorf("ErrorBody() = %s, want %s", b, want)
+	}
+
+	if got := AsError(errors.New("boom")); got.Status != http.StatusInternalServerError || got.Code != ErrInternal || got.Message != "boom" {
+		t.Errorf("AsError() = %+v, want an internal error", got)
+	}
+
+	if got := AsError(fmt.Errorf("wrapped: %w", e)); got != e {
+		t.Errorf("AsError() = %+v, want the wrapped *Error", got)
+	}
+}
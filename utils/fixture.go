@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 录制、回放模式
+const (
+	FixtureRecord = "record"
+	FixtureReplay = "replay"
+)
+
+// Redacted 替换敏感信息的占位符
+const Redacted = "REDACTED"
+
+// sensitiveFields 录制时需要脱敏的请求头、响应头和 JSON 字段（不区分大小写）
+var sensitiveFields = map[string]bool{
+	"x-jike-access-token":  true,
+	"x-jike-refresh-token": true,
+	"x-jike-device-id":     true,
+	"authorization":        true,
+	"cookie":               true,
+	"set-cookie":           true,
+	"mobilephonenumber":    true,
+	"verifycode":           true,
+}
+
+// volatileHeaders 每次请求都会变化或回放时需重新计算的响应头，不录制
+var volatileHeaders = map[string]bool{
+	"date":              true,
+	"content-length":    true,
+	"connection":        true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Fixture 录制的一次上游请求和响应
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+type FixtureRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+type FixtureResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+// fixtureTransport 录制或回放上游请求的 http.RoundTripper
+type fixtureTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+	mu   sync.Mutex
+}
+
+// UseFixtures 设置 Request 的录制、回放模式
+//
+// record：请求上游，并将脱敏后的请求和响应保存到 dir；replay：不请求上游，按请求方法、路径和规范化的请求体从 dir 中读取响应
+func UseFixtures(mode, dir string) error {
+	switch mode {
+	case "":
+		client.Transport = nil
+
+		return nil
+	case FixtureRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	case FixtureReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid fixture mode %q, expected record or replay", mode)
+	}
+
+	client.Transport = &fixtureTransport{mode: mode, dir: dir, next: http.DefaultTransport}
+
+	return nil
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	path := fixturePath(req)
+	normalized := normalizeBody(body)
+	file := filepath.Join(t.dir, fixtureName(req.Method, path, normalized))
+
+	if t.mode == FixtureReplay {
+		return t.replay(req, file, path)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Request: FixtureRequest{
+			Method:  req.Method,
+			Path:    path,
+			Headers: redactHeaders(req.Header),
+			Body:    normalized,
+		},
+		Response: FixtureResponse{
+			Status:  resp.StatusCode,
+			Headers: redactHeaders(resp.Header),
+			Body:    redactBody(respBody),
+		},
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(fixture); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.WriteFile(file, b.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (t *fixtureTransport) replay(req *http.Request, file, path string) (*http.Response, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("fixture not found for %s %s: %s", req.Method, path, filepath.Base(file))
+	}
+
+	var fixture Fixture
+	if err := decodeJSON(b, &fixture); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	var body []byte
+	switch v := fixture.Response.Body.(type) {
+	case nil:
+	case string:
+		body = []byte(v)
+	default:
+		body, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header := http.Header{}
+	for key, value := range fixture.Response.Headers {
+		header.Set(key, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.Status, http.StatusText(fixture.Response.Status)),
+		StatusCode:    fixture.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// fixturePath 请求路径，查询参数按名称排序
+func fixturePath(req *http.Request) string {
+	if req.URL.RawQuery == "" {
+		return req.URL.Path
+	}
+
+	return req.URL.Path + "?" + req.URL.Query().Encode()
+}
+
+// fixtureName 文件名由请求方法、路径和请求体的哈希组成，同一请求总是对应同一个文件
+func fixtureName(method, path string, body any) string {
+	b, _ := json.Marshal(body)
+	hash := sha256.Sum256([]byte(method + " " + path + "\n" + string(b)))
+
+	name := unsafeFileChars.ReplaceAllString(strings.Trim(strings.SplitN(path, "?", 2)[0], "/"), "_")
+
+	return fmt.Sprintf("%s_%s_%s.json", method, name, hex.EncodeToString(hash[:4]))
+}
+
+// normalizeBody 解析 JSON 请求体并脱敏，map 在序列化时按键排序，使相同内容的请求体得到相同的结果
+func normalizeBody(body []byte) any {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || string(body) == "null" {
+		return nil
+	}
+
+	var v any
+	if err := decodeJSON(body, &v); err != nil {
+		return string(body)
+	}
+
+	return redactValue(v)
+}
+
+func redactBody(body []byte) any {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	var v any
+	if err := decodeJSON(body, &v); err != nil {
+		return string(body)
+	}
+
+	return redactValue(v)
+}
+
+// decodeJSON 数字保留原始文本，避免回放时大整数丢失精度
+func decodeJSON(b []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+func redactValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if sensitiveFields[strings.ToLower(key)] {
+				value[key] = Redacted
+			} else {
+				value[key] = redactValue(item)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+	}
+
+	return v
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]string, len(header))
+	for key := range header {
+		if volatileHeaders[strings.ToLower(key)] {
+			continue
+		}
+
+		if sensitiveFields[strings.ToLower(key)] {
+			headers[key] = Redacted
+		} else {
+			headers[key] = header.Get(key)
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixtureRoundTrip(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-jike-access-token", "new-access-token")
+		w.Header().Set("x-jike-refresh-token", "new-refresh-token")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"data":{"pid":"`+r.URL.Query().Get("pid")+`","playCount":12345678901234567,"user":{"mobilePhoneNumber":"13800000000"}}}`)
+	}))
+
+	dir := t.TempDir()
+	t.Cleanup(func() { _ = UseFixtures("", "") })
+
+	headers := map[string]string{"x-jike-access-token": "secret-access-token", "x-jike-device-id": "secret-device-id"}
+	request := func(pid string, body map[string]any) (string, error) {
+		resp, _, err := Request(context.Background(), upstream.URL+"/v1/podcast/get?pid="+pid, http.MethodPost, body, headers)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+
+		return resp.Header.Get("x-jike-refresh-token") + " " + string(b), err
+	}
+
+	if err := UseFixtures(FixtureRecord, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, err := request("p1", map[string]any{"verifyCode": "123456", "limit": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d fixtures, want 1", len(files))
+	}
+
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"secret-access-token", "secret-device-id", "new-access-token", "new-refresh-token", "13800000000", "123456\""} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("fixture contains %q, want it redacted:\n%s", secret, b)
+		}
+	}
+
+	upstream.Close()
+
+	if err := UseFixtures(FixtureReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		pid   string
+		body  map[string]any
+		want  string
+		found bool
+	}{
+		// 请求体中的敏感字段录制时已脱敏，回放时同样脱敏后匹配；大整数保留原始的精度
+		{"相同的请求", "p1", map[string]any{"limit": 10, "verifyCode": "654321"}, Redacted + ` {"data":{"pid":"p1","playCount":12345678901234567,"user":{"mobilePhoneNumber":"` + Redacted + `"}}}`, true},
+		{"查询参数不同", "p2", map[string]any{"limit": 10, "verifyCode": "123456"}, "", false},
+		{"请求体不同", "p1", map[string]any{"limit": 20, "verifyCode": "123456"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayed, err := request(tt.pid, tt.body)
+			if (err == nil) != tt.found {
+				t.Fatalf("replay error = %v, want found %v", err, tt.found)
+			}
+
+			if replayed != tt.want {
+				t.Errorf("replay = %s, want %s", replayed, tt.want)
+			}
+		})
+	}
+
+	if !strings.HasPrefix(recorded, "new-refresh-token ") {
+		t.Errorf("record = %s, want the upstream response unchanged", recorded)
+	}
+}
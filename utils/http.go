@@ -2,10 +2,13 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,14 +16,23 @@ var client = &http.Client{
 	Timeout: time.Second * 15,
 }
 
-func Request(url, method string, body map[string]any, headers map[string]string) (*http.Response, int, error) {
+// Request 向上游发送请求，ctx 取消时（如客户端断开或 /batch 中的操作超时）请求随之取消
+func Request(ctx context.Context, url, method string, body map[string]any, headers map[string]string) (*http.Response, int, error) {
 	payload, err := json.Marshal(body)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to marshal request body: %v", err)
+		return nil, http.StatusInternalServerError, NewError(http.StatusInternalServerError, ErrInternal, fmt.Sprintf("failed to marshal request body: %v", err))
 	}
-	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	// sent 记录请求是否已完整发送，用于区分无法连接上游和发送后连接断开
+	var sent atomic.Bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			sent.Store(info.Err == nil)
+		},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %v", err)
+		return nil, http.StatusInternalServerError, NewError(http.StatusInternalServerError, ErrInternal, fmt.Sprintf("failed to create request: %v", err))
 	}
 
 	if headers != nil {
@@ -69,15 +81,18 @@ func Request(url, method string, body map[string]any, headers map[string]string)
 	//defer resp.Body.Close()
 
 	if retryErr != nil {
-		return nil, 0, fmt.Errorf("failed to send request: %v", retryErr)
-	}
+		e := upstreamRequestError(retryErr, sent.Load())
 
-	// 未登录
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, resp.StatusCode, fmt.Errorf("received 401 status code: %d", resp.StatusCode)
+		return nil, e.Status, e
 	}
+
+	// 未登录（401）等非 2xx 状态码，保留上游的错误响应
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, resp.StatusCode, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
+		defer resp.Body.Close()
+
+		e := upstreamStatusError(resp)
+
+		return nil, e.Status, e
 	}
 
 	return resp, resp.StatusCode, nil
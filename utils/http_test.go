@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestCanceledWithContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, status, err := Request(c, server.URL, http.MethodPost, nil, nil)
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("Request returned after %s, want it to stop when the context is done", elapsed)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Code != ErrUpstreamTimeout || status != http.StatusGatewayTimeout {
+		t.Errorf("Request() = %d, %v, want %d %s", status, err, http.StatusGatewayTimeout, ErrUpstreamTimeout)
+	}
+}
+
+func TestRequestConnectionErrors(t *testing.T) {
+	hangUp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		_ = conn.Close()
+	}))
+	defer hangUp.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		code string
+	}{
+		{"无法连接上游", closed.URL, ErrUpstreamUnavailable},
+		{"发送后连接断开", hangUp.URL, ErrUpstreamConnectionLost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, status, err := Request(context.Background(), tt.url, http.MethodPost, nil, nil)
+
+			var e *Error
+			if !errors.As(err, &e) || e.Code != tt.code || status != http.StatusBadGateway {
+				t.Errorf("Request() = %d, %v, want %d %s", status, err, http.StatusBadGateway, tt.code)
+			}
+		})
+	}
+}
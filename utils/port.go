@@ -6,16 +6,45 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/ultrazg/xyz/constant"
 )
 
 var (
 	port int
 	doc  bool
+
+	// DataDir 数据目录，账号等数据加密后保存在此目录中
+	DataDir string
+	// SecretKeyFile 加密密钥文件，为空时使用数据目录中的 secret.key
+	SecretKeyFile string
+	// AuthEnabled 是否要求所有接口携带 API Key
+	AuthEnabled bool
+	// CorsOrigins 允许跨域访问的来源，逗号分隔，为空时允许所有来源
+	CorsOrigins string
+	// FixtureMode 上游请求的录制、回放模式，为空时正常请求上游
+	FixtureMode string
+	// FixtureDir 录制、回放使用的目录
+	FixtureDir string
+	// WatchInterval 获取关注节目元数据快照的间隔
+	WatchInterval = 6 * time.Hour
+	// SampleInterval 采集节目和单集热度的间隔
+	SampleInterval = time.Hour
 )
 
 func InitFlag() (int, bool) {
 	flag.IntVar(&port, "p", 23020, "指定服务监听的端口")
 	flag.BoolVar(&doc, "d", false, "打开 Api 文档")
+	flag.StringVar(&DataDir, "data", "data", "指定数据目录")
+	flag.StringVar(&SecretKeyFile, "key-file", "", "指定加密密钥文件，默认为数据目录中的 secret.key")
+	flag.BoolVar(&AuthEnabled, "auth", false, "要求所有接口携带 API Key")
+	flag.StringVar(&FixtureMode, "fixture-mode", "", "录制（record）或回放（replay）上游请求")
+	flag.StringVar(&FixtureDir, "fixtures", "fixtures", "指定录制、回放上游请求的目录")
+	flag.StringVar(&constant.BaseUrl, "base-url", constant.BaseUrl, "指定上游接口地址，如 xyz mock-upstream 的地址")
+	flag.StringVar(&CorsOrigins, "cors-origins", "", "允许跨域访问的来源，多个来源以逗号分隔")
+	flag.DurationVar(&WatchInterval, "watch-interval", WatchInterval, "获取关注节目元数据快照的间隔，如 6h、30m")
+	flag.DurationVar(&SampleInterval, "sample-interval", SampleInterval, "采集节目和单集热度的间隔，如 1h、15m")
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [OPTIONS]\n", "xyz")
 		fmt.Fprintf(os.Stderr, "Options:\n")
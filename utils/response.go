@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"io"
 	"log"
@@ -10,11 +11,14 @@ import (
 
 var MsgFlag = map[int]string{
 	200: "OK",
+	202: "已接受，等待发送",
 	400: "错误请求",
 	401: "身份认证信息失效，尝试重新登录或调用 /refresh_token 接口以获取有效认证信息",
 	403: "拒绝访问",
 	404: "请求的资源不存在",
 	405: "请求方法不支持",
+	424: "依赖的请求失败",
+	429: "请求过于频繁",
 	500: "服务器内部错误",
 	502: "网关错误",
 	503: "服务不可用",
@@ -32,29 +36,31 @@ func GetMsg(code int) string {
 
 // ReturnBadRequest 错误参数
 func ReturnBadRequest(ctx *gin.Context, err error) {
+	e := NewError(http.StatusBadRequest, ErrValidationFailed, "")
+
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"code":  http.StatusBadRequest,
-			"error": err.Error(),
-			"msg":   GetMsg(http.StatusBadRequest),
-		})
-
-		log.Println(err.Error())
-	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"code": http.StatusBadRequest,
-			"msg":  GetMsg(http.StatusBadRequest),
-		})
-
-		log.Println(GetMsg(http.StatusBadRequest))
+		var typed *Error
+		if errors.As(err, &typed) {
+			e = typed
+		} else {
+			e.Message = err.Error()
+		}
 	}
+
+	log.Println(e.Message)
+
+	ReturnError(ctx, e)
 }
 
 func ReturnJson(response *http.Response, ctx *gin.Context) {
+	defer response.Body.Close()
+
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
 		log.Println("Error reading response body:", err)
 
+		ReturnError(ctx, NewError(http.StatusBadGateway, ErrUpstreamInvalidResponse, err.Error()))
+
 		return
 	}
 
@@ -63,6 +69,11 @@ func ReturnJson(response *http.Response, ctx *gin.Context) {
 	if err != nil {
 		log.Println("Error parsing response body:", err)
 
+		e := NewError(http.StatusBadGateway, ErrUpstreamInvalidResponse, err.Error())
+		e.Upstream = &Upstream{Status: response.StatusCode, Body: string(body)}
+
+		ReturnError(ctx, e)
+
 		return
 	}
 
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RestBody 根据路径参数、查询参数构造转发给原有接口的请求体
+type RestBody func(ctx *gin.Context) (map[string]any, error)
+
+// EncodeCursor 将上游返回的 loadMoreKey 编码为不透明的游标
+func EncodeCursor(loadMoreKey any) string {
+	if loadMoreKey == nil {
+		return ""
+	}
+
+	b, err := json.Marshal(loadMoreKey)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor 将游标还原为上游需要的 loadMoreKey
+func DecodeCursor(cursor string) (any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var loadMoreKey any
+	if err := json.Unmarshal(b, &loadMoreKey); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return loadMoreKey, nil
+}
+
+// Rest 将原有的 POST 接口适配为 REST 风格接口，请求体由 body 从路径和查询参数构造
+func Rest(handler gin.HandlerFunc, body RestBody) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		p := map[string]any{}
+
+		if body != nil {
+			var err error
+
+			p, err = body(ctx)
+			if err != nil {
+				ReturnBadRequest(ctx, err)
+
+				return
+			}
+		}
+
+		if cursor := ctx.Query("cursor"); cursor != "" {
+			loadMoreKey, err := DecodeCursor(cursor)
+			if err != nil {
+				ReturnBadRequest(ctx, err)
+
+				return
+			}
+
+			p["loadMoreKey"] = loadMoreKey
+		}
+
+		payload, err := json.Marshal(p)
+		if err != nil {
+			ReturnBadRequest(ctx, err)
+
+			return
+		}
+
+		// 原有接口按 JSON 请求体绑定参数，GET/DELETE 请求需转换为 POST 才能被正确绑定
+		req := ctx.Request.Clone(ctx.Request.Context())
+		req.Method = http.MethodPost
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		req.ContentLength = int64(len(payload))
+		req.Header.Set("Content-Type", "application/json")
+		ctx.Request = req
+
+		handler(ctx)
+	}
+}
+
+// RestEnvelope 将原有接口的 {code, msg, data} 响应转换为 /v2 的响应格式，没有响应体的响应（如 204）原样返回状态码
+//
+// 成功：{"data": ..., "cursor": "..."}
+// 失败：{"error": {"status": 400, "code": "VALIDATION_FAILED", "message": "...", ...}}
+func RestEnvelope() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		writer := NewBufferedWriter(ctx.Writer)
+		ctx.Writer = writer
+
+		ctx.Next()
+
+		ctx.Writer = writer.ResponseWriter
+
+		status, payload := restPayload(writer.status, writer.body.Bytes())
+		if payload == nil {
+			ctx.Status(status)
+			ctx.Writer.WriteHeaderNow()
+
+			return
+		}
+
+		ctx.JSON(status, payload)
+	}
+}
+
+// ReturnRestNotFound /v2 下不存在的路由
+func ReturnRestNotFound(ctx *gin.Context) {
+	ctx.JSON(http.StatusNotFound, gin.H{
+		"error": NewError(http.StatusNotFound, ErrNotFound, ""),
+	})
+}
+
+// restPayload 转换原有接口的响应，没有响应体时返回 nil。
+// 只有成功响应的响应体无法解析时才视为上游返回了无效的数据，错误响应保留原有的状态码
+func restPayload(status int, body []byte) (int, gin.H) {
+	var data map[string]any
+	if len(bytes.TrimSpace(body)) == 0 {
+		if status < http.StatusBadRequest {
+			return status, nil
+		}
+	} else if err := json.Unmarshal(body, &data); err != nil && status < http.StatusBadRequest {
+		return http.StatusBadGateway, gin.H{
+			"error": NewError(http.StatusBadGateway, ErrUpstreamInvalidResponse, ""),
+		}
+	}
+
+	if status >= http.StatusBadRequest {
+		if e, ok := data["error"].(map[string]any); ok {
+			return status, gin.H{"error": e}
+		}
+
+		return status, gin.H{
+			"error": NewError(status, restErrorCode(status), ""),
+		}
+	}
+
+	result := gin.H{}
+
+	upstream, ok := data["data"].(map[string]any)
+	if !ok {
+		result["data"] = data["data"]
+
+		return status, result
+	}
+
+	if loadMoreKey, ok := upstream["loadMoreKey"]; ok {
+		if cursor := EncodeCursor(loadMoreKey); cursor != "" {
+			result["cursor"] = cursor
+		}
+
+		delete(upstream, "loadMoreKey")
+	}
+
+	if inner, ok := upstream["data"]; ok {
+		result["data"] = inner
+		delete(upstream, "data")
+
+		if len(upstream) > 0 {
+			result["meta"] = upstream
+		}
+	} else {
+		result["data"] = upstream
+	}
+
+	return status, result
+}
+
+// restErrorCode 原有接口没有返回统一格式的错误时，按状态码确定错误码
+func restErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrValidationFailed
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return ErrInternal
+	}
+}
+
+// BufferedWriter 缓存 handler 的响应，由外层统一改写后再输出
+type BufferedWriter struct {
+	gin.ResponseWriter
+	body   *bytes.Buffer
+	status int
+}
+
+func NewBufferedWriter(w gin.ResponseWriter) *BufferedWriter {
+	return &BufferedWriter{ResponseWriter: w, body: &bytes.Buffer{}, status: http.StatusOK}
+}
+
+// Bytes 缓存的响应体
+func (w *BufferedWriter) Bytes() []byte {
+	return w.body.Bytes()
+}
+
+func (w *BufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *BufferedWriter) WriteHeaderNow() {}
+
+func (w *BufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *BufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *BufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *BufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+func (w *BufferedWriter) Size() int {
+	return w.body.Len()
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursor(t *testing.T) {
+	loadMoreKey := map[string]any{"id": "300000000000000000000001", "direction": "NEXT"}
+
+	cursor := EncodeCursor(loadMoreKey)
+	if cursor == "" || strings.ContainsAny(cursor, "+/=") {
+		t.Fatalf("EncodeCursor() = %q, want URL-safe base64 without padding", cursor)
+	}
+
+	if got, err := DecodeCursor(cursor); err != nil || !reflect.DeepEqual(got, loadMoreKey) {
+		t.Errorf("DecodeCursor(EncodeCursor()) = %v, %v, want %v", got, err, loadMoreKey)
+	}
+
+	if got := EncodeCursor(nil); got != "" {
+		t.Errorf("EncodeCursor(nil) = %q, want empty", got)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"不是 base64", "!!!"},
+		{"标准 base64 的填充", "eyJpZCI6MX0="},
+		{"不是 JSON", "bm90IGpzb24"},
+		{"空字符串", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeCursor(%q) = %v, want an error", tt.cursor, got)
+			}
+		})
+	}
+}
+
+func TestRest(t *testing.T) {
+	// handler 记录转发给原有接口的请求
+	var method, contentType string
+	var body map[string]any
+
+	handler := func(ctx *gin.Context) {
+		method = ctx.Request.Method
+		contentType = ctx.GetHeader("Content-Type")
+
+		b, _ := io.ReadAll(ctx.Request.Body)
+		_ = json.Unmarshal(b, &body)
+
+		ctx.Status(http.StatusNoContent)
+	}
+
+	pid := func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{"pid": ctx.Param("pid")}, nil
+	}
+
+	engine := gin.New()
+	engine.GET("/podcasts/:pid", Rest(handler, pid))
+	engine.PUT("/podcasts/:pid", Rest(handler, pid))
+	engine.DELETE("/podcasts/:pid", Rest(handler, pid))
+	engine.GET("/me", Rest(handler, nil))
+	engine.GET("/failed", Rest(handler, func(ctx *gin.Context) (map[string]any, error) {
+		return nil, errors.New("duration is required")
+	}))
+
+	cursor := EncodeCursor(map[string]any{"id": "a"})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		body   map[string]any
+	}{
+		{"GET 转换为 POST", http.MethodGet, "/podcasts/p1", http.StatusNoContent, map[string]any{"pid": "p1"}},
+		{"PUT 转换为 POST", http.MethodPut, "/podcasts/p1", http.StatusNoContent, map[string]any{"pid": "p1"}},
+		{"DELETE 转换为 POST", http.MethodDelete, "/podcasts/p1", http.StatusNoContent, map[string]any{"pid": "p1"}},
+		{"游标还原为 loadMoreKey", http.MethodGet, "/podcasts/p1?cursor=" + cursor, http.StatusNoContent, map[string]any{"pid": "p1", "loadMoreKey": map[string]any{"id": "a"}}},
+		{"没有请求体的接口", http.MethodGet, "/me", http.StatusNoContent, map[string]any{}},
+		{"无效的游标", http.MethodGet, "/podcasts/p1?cursor=!!!", http.StatusBadRequest, nil},
+		{"无法构造请求体", http.MethodGet, "/failed", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, contentType, body = "", "", nil
+
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+
+			if tt.body == nil {
+				if method != "" {
+					t.Errorf("handler called with %s %v, want no call", method, body)
+				}
+
+				return
+			}
+
+			if method != http.MethodPost || contentType != "application/json" {
+				t.Errorf("handler request = %s %s, want POST application/json", method, contentType)
+			}
+
+			if !reflect.DeepEqual(body, tt.body) {
+				t.Errorf("handler body = %v, want %v", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestRestEnvelope(t *testing.T) {
+	cursor := EncodeCursor(map[string]any{"id": "a"})
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   int
+		json   string // 为空时响应没有响应体
+	}{
+		{
+			name:   "列表的游标和其它字段",
+			status: http.StatusOK,
+			body:   `{"code":200,"msg":"OK","data":{"data":[1,2],"loadMoreKey":{"id":"a"},"total":2}}`,
+			want:   http.StatusOK,
+			json:   `{"data":[1,2],"cursor":"` + cursor + `","meta":{"total":2}}`,
+		},
+		{
+			name:   "对象",
+			status: http.StatusOK,
+			body:   `{"code":200,"msg":"OK","data":{"pid":"p1"}}`,
+			want:   http.StatusOK,
+			json:   `{"data":{"pid":"p1"}}`,
+		},
+		{
+			name:   "数组",
+			status: http.StatusOK,
+			body:   `{"code":200,"msg":"OK","data":[1]}`,
+			want:   http.StatusOK,
+			json:   `{"data":[1]}`,
+		},
+		{
+			name:   "统一格式的错误",
+			status: http.StatusNotFound,
+			body:   `{"code":404,"msg":"请求的资源不存在","error":{"status":404,"code":"UPSTREAM_NOT_FOUND","message":"请求的资源不存在"}}`,
+			want:   http.StatusNotFound,
+			json:   `{"error":{"status":404,"code":"UPSTREAM_NOT_FOUND","message":"请求的资源不存在"}}`,
+		},
+		{
+			name:   "没有响应体的成功响应",
+			status: http.StatusNoContent,
+			want:   http.StatusNoContent,
+		},
+		{
+			name:   "没有响应体的错误",
+			status: http.StatusUnauthorized,
+			want:   http.StatusUnauthorized,
+			json:   `{"error":{"status":401,"code":"UNAUTHORIZED","message":"` + GetMsg(http.StatusUnauthorized) + `"}}`,
+		},
+		{
+			name:   "不是 JSON 的错误",
+			status: http.StatusInternalServerError,
+			body:   "panic",
+			want:   http.StatusInternalServerError,
+			json:   `{"error":{"status":500,"code":"INTERNAL_ERROR","message":"服务器内部错误"}}`,
+		},
+		{
+			name:   "不是 JSON 的成功响应",
+			status: http.StatusOK,
+			body:   "<html>",
+			want:   http.StatusBadGateway,
+			json:   `{"error":{"status":502,"code":"UPSTREAM_INVALID_RESPONSE","message":"网关错误"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/", RestEnvelope(), func(ctx *gin.Context) {
+				ctx.Status(tt.status)
+				_, _ = ctx.Writer.WriteString(tt.body)
+			})
+
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+			}
+
+			if tt.json == "" {
+				if recorder.Body.Len() != 0 {
+					t.Errorf("body = %s, want empty", recorder.Body.String())
+				}
+
+				return
+			}
+
+			var got, want any
+			if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body = %s: %v", recorder.Body.String(), err)
+			}
+
+			_ = json.Unmarshal([]byte(tt.json), &want)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %s, want %s", recorder.Body.String(), tt.json)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHeader 选择使用哪个已登录的账号请求上游
+const AccountHeader = "x-xyz-account"
+
+const (
+	accountBucket     = "accounts"
+	accountContextKey = "xyz.account"
+)
+
+// 账号的读改写需要串行，避免刷新 token 与更新最近使用时间互相覆盖
+var accountMu sync.Mutex
+
+// Account 保存在服务端的小宇宙账号会话
+type Account struct {
+	Name         string     `json:"name"`
+	Uid          string     `json:"uid,omitempty"`
+	Nickname     string     `json:"nickname,omitempty"`
+	AccessToken  string     `json:"accessToken"`
+	RefreshToken string     `json:"refreshToken"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	RefreshedAt  time.Time  `json:"refreshedAt"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// AccountInfo 返回给调用方的账号信息，不包含 token
+type AccountInfo struct {
+	Name        string     `json:"name"`
+	Uid         string     `json:"uid,omitempty"`
+	Nickname    string     `json:"nickname,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	Expired     bool       `json:"expired"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RefreshedAt time.Time  `json:"refreshedAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// Info 账号信息，过期时间取自 access token 中的 exp
+func (a *Account) Info() AccountInfo {
+	return AccountInfo{
+		Name:        a.Name,
+		Uid:         a.Uid,
+		Nickname:    a.Nickname,
+		ExpiresAt:   a.ExpiresAt,
+		Expired:     a.ExpiresAt != nil && a.ExpiresAt.Before(time.Now()),
+		CreatedAt:   a.CreatedAt,
+		RefreshedAt: a.RefreshedAt,
+		LastUsedAt:  a.LastUsedAt,
+	}
+}
+
+// SaveAccount 登录成功后保存账号的 token，同名账号会被覆盖，调用前需通过 AuthorizeAccount 校验
+func SaveAccount(name, accessToken, refreshToken, uid, nickname string) (*Account, error) {
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	now := time.Now()
+
+	account := &Account{}
+	err := StoreGet(accountBucket, name, account)
+	if errors.Is(err, ErrStoreNotFound) {
+		account = &Account{Name: name, CreatedAt: now}
+	} else if err != nil {
+		return nil, err
+	}
+
+	account.Uid = uid
+	account.Nickname = nickname
+	account.AccessToken = accessToken
+	account.RefreshToken = refreshToken
+	account.ExpiresAt = tokenExpiry(accessToken)
+	account.RefreshedAt = now
+
+	if err := StorePut(accountBucket, name, account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// UpdateAccountTokens 刷新 token 后更新账号
+func UpdateAccountTokens(name, accessToken, refreshToken string) (*Account, error) {
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	account := &Account{}
+	if err := StoreGet(accountBucket, name, account); err != nil {
+		return nil, err
+	}
+
+	account.AccessToken = accessToken
+	if refreshToken != "" {
+		account.RefreshToken = refreshToken
+	}
+	account.ExpiresAt = tokenExpiry(accessToken)
+	account.RefreshedAt = time.Now()
+
+	if err := StorePut(accountBucket, name, account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetAccount 查询账号，不存在时返回 ErrStoreNotFound
+func GetAccount(name string) (*Account, error) {
+	account := &Account{}
+	if err := StoreGet(accountBucket, name, account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// ListAccounts 按名称排序返回所有账号
+func ListAccounts() ([]Account, error) {
+	var accounts []Account
+
+	err := StoreEach(accountBucket, func(key string, value []byte) error {
+		var account Account
+		if err := json.Unmarshal(value, &account); err != nil {
+			return err
+		}
+
+		accounts = append(accounts, account)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+
+	return accounts, nil
+}
+
+// RemoveAccount 删除账号并吊销该账号登录时签发的 API Key，不存在时返回 ErrStoreNotFound
+func RemoveAccount(name string) error {
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	if err := StoreDelete(accountBucket, name); err != nil {
+		return err
+	}
+
+	return revokeAccountKeys(name)
+}
+
+// SelectAccount 请求头中带有 x-xyz-account 时，校验 API Key 或会话 Cookie，并使用该账号保存的 token 请求上游
+func SelectAccount() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		name := ctx.GetHeader(AccountHeader)
+		if name == "" {
+			ctx.Next()
+
+			return
+		}
+
+		if !authenticate(ctx) {
+			return
+		}
+
+		if apiKey := CurrentApiKey(ctx); !apiKey.CanUseAccount(name) {
+			ReturnError(ctx, NewError(http.StatusForbidden, ErrForbidden, "api key cannot use account: "+name))
+
+			return
+		}
+
+		account, err := touchAccount(name)
+		if errors.Is(err, ErrStoreNotFound) {
+			ReturnError(ctx, NewError(http.StatusNotFound, ErrNotFound, "account not found: "+name))
+
+			return
+		}
+
+		if err != nil {
+			ReturnError(ctx, err)
+
+			return
+		}
+
+		ctx.Request.Header.Set("x-jike-access-token", account.AccessToken)
+		ctx.Request.Header.Set("x-jike-refresh-token", account.RefreshToken)
+		ctx.Set(accountContextKey, account)
+
+		ctx.Next()
+	}
+}
+
+// CurrentAccount 通过 x-xyz-account 选择的账号，未选择时返回 nil
+func CurrentAccount(ctx *gin.Context) *Account {
+	if v, ok := ctx.Get(accountContextKey); ok {
+		return v.(*Account)
+	}
+
+	return nil
+}
+
+func touchAccount(name string) (*Account, error) {
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	account := &Account{}
+	if err := StoreGet(accountBucket, name, account); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	account.LastUsedAt = &now
+
+	if err := StorePut(accountBucket, name, account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// tokenExpiry 读取 JWT 形式的 access token 中的 exp，无法解析时返回 nil
+func tokenExpiry(token string) *time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(b, &claims) != nil || claims.Exp == 0 {
+		return nil
+	}
+
+	exp := time.Unix(claims.Exp, 0)
+
+	return &exp
+}
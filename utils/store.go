@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nutsdb/nutsdb"
+)
+
+// ErrStoreNotFound 存储中不存在该键
+var ErrStoreNotFound = errors.New("not found")
+
+var (
+	store   *nutsdb.DB
+	buckets sync.Map
+)
+
+// InitStore 打开数据目录中的本地存储，写入的数据均经过加密
+func InitStore(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	if err := InitSecret(dir); err != nil {
+		return err
+	}
+
+	db, err := nutsdb.Open(
+		nutsdb.DefaultOptions,
+		nutsdb.WithDir(filepath.Join(dir, "store")),
+		nutsdb.WithSegmentSize(8*1024*1024),
+	)
+	if err != nil {
+		return err
+	}
+
+	store = db
+
+	return nil
+}
+
+// CloseStore 关闭本地存储
+func CloseStore() error {
+	if store == nil {
+		return nil
+	}
+
+	err := store.Close()
+	store = nil
+	buckets = sync.Map{}
+
+	return err
+}
+
+// StorePut 将 value 序列化、加密后写入 bucket
+func StorePut(bucket, key string, value any) error {
+	if err := ensureBucket(bucket); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := Encrypt(b)
+	if err != nil {
+		return err
+	}
+
+	return store.Update(func(tx *nutsdb.Tx) error {
+		return tx.Put(bucket, []byte(key), sealed, nutsdb.Persistent)
+	})
+}
+
+// StoreGet 读取并解密 bucket 中的 key，不存在时返回 ErrStoreNotFound
+func StoreGet(bucket, key string, value any) error {
+	if store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	var sealed []byte
+
+	err := store.View(func(tx *nutsdb.Tx) error {
+		v, err := tx.Get(bucket, []byte(key))
+		if err != nil {
+			return err
+		}
+
+		sealed = v
+
+		return nil
+	})
+	if isNotFound(err) {
+		return ErrStoreNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return open(sealed, value)
+}
+
+// StoreDelete 删除 bucket 中的 key，不存在时返回 ErrStoreNotFound
+func StoreDelete(bucket, key string) error {
+	if store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	err := store.Update(func(tx *nutsdb.Tx) error {
+		return tx.Delete(bucket, []byte(key))
+	})
+	if isNotFound(err) {
+		return ErrStoreNotFound
+	}
+
+	return err
+}
+
+// StoreEach 按键的顺序遍历 bucket，fn 接收键和解密后的 JSON
+func StoreEach(bucket string, fn func(key string, value []byte) error) error {
+	if store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	var keys, values [][]byte
+
+	err := store.View(func(tx *nutsdb.Tx) error {
+		var err error
+
+		keys, values, err = tx.GetAll(bucket)
+
+		return err
+	})
+	if isNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		b, err := Decrypt(values[i])
+		if err != nil {
+			return fmt.Errorf("%s/%s: %w", bucket, key, err)
+		}
+
+		if err := fn(string(key), b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StoreKeys 按顺序返回 bucket 中以 prefix 开头的键，不读取和解密值
+func StoreKeys(bucket, prefix string) ([]string, error) {
+	if store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	var keys [][]byte
+
+	err := store.View(func(tx *nutsdb.Tx) error {
+		var err error
+
+		keys, err = tx.GetKeys(bucket)
+
+		return err
+	})
+	if isNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, key := range keys {
+		if strings.HasPrefix(string(key), prefix) {
+			result = append(result, string(key))
+		}
+	}
+
+	return result, nil
+}
+
+// ReencryptStore 使用当前密钥重新加密存储中的所有数据，返回重新加密的条数
+func ReencryptStore() (int, error) {
+	if store == nil {
+		return 0, fmt.Errorf("store not initialized")
+	}
+
+	var names []string
+
+	err := store.View(func(tx *nutsdb.Tx) error {
+		return tx.IterateBuckets(nutsdb.DataStructureBTree, "*", func(bucket string) bool {
+			names = append(names, bucket)
+
+			return true
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, bucket := range names {
+		err := store.Update(func(tx *nutsdb.Tx) error {
+			keys, values, err := tx.GetAll(bucket)
+			if err != nil {
+				return err
+			}
+
+			for i, key := range keys {
+				if EncryptedWithCurrentKey(values[i]) {
+					continue
+				}
+
+				b, err := Decrypt(values[i])
+				if err != nil {
+					return fmt.Errorf("%s/%s: %w", bucket, key, err)
+				}
+
+				sealed, err := Encrypt(b)
+				if err != nil {
+					return err
+				}
+
+				if err := tx.Put(bucket, key, sealed, nutsdb.Persistent); err != nil {
+					return err
+				}
+
+				count++
+			}
+
+			return nil
+		})
+		if err != nil && !isNotFound(err) {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+func open(sealed []byte, value any) error {
+	b, err := Decrypt(sealed)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, value)
+}
+
+// isNotFound nutsdb 在不同操作中对不存在的 bucket、key 返回不同的错误
+func isNotFound(err error) bool {
+	return errors.Is(err, nutsdb.ErrKeyNotFound) ||
+		errors.Is(err, nutsdb.ErrBucketNotFound) ||
+		errors.Is(err, nutsdb.ErrBucketNotExist) ||
+		errors.Is(err, nutsdb.ErrNotFoundBucket)
+}
+
+// ensureBucket nutsdb 需要先创建 bucket 才能写入
+func ensureBucket(bucket string) error {
+	if store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+
+	if _, ok := buckets.Load(bucket); ok {
+		return nil
+	}
+
+	err := store.Update(func(tx *nutsdb.Tx) error {
+		if tx.ExistBucket(nutsdb.DataStructureBTree, bucket) {
+			return nil
+		}
+
+		return tx.NewBucket(nutsdb.DataStructureBTree, bucket)
+	})
+	if err != nil {
+		return err
+	}
+
+	buckets.Store(bucket, struct{}{})
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStoreKeys(t *testing.T) {
+	if err := InitStore(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = CloseStore() })
+
+	for _, key := range []string{"b/2", "a/1", "b/1", "c"} {
+		if err := StorePut("keys", key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		bucket string
+		prefix string
+		want   []string
+	}{
+		{"全部按顺序", "keys", "", []string{"a/1", "b/1", "b/2", "c"}},
+		{"按前缀", "keys", "b/", []string{"b/1", "b/2"}},
+		{"没有匹配的键", "keys", "d", nil},
+		{"bucket 不存在", "missing", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StoreKeys(tt.bucket, tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StoreKeys(%q, %q) = %v, want %v", tt.bucket, tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
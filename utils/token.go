@@ -1,6 +1,10 @@
 package utils
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 // CheckAccessToken 检查 token
 var CheckAccessToken = func() gin.HandlerFunc {
@@ -10,7 +14,10 @@ var CheckAccessToken = func() gin.HandlerFunc {
 		token := h.Get("x-jike-access-token")
 
 		if token == "" {
-			ReturnBadRequest(ctx, nil)
+			e := NewError(http.StatusBadRequest, ErrValidationFailed, "缺少请求头 x-jike-access-token")
+			e.Details = []FieldError{{Field: "x-jike-access-token", Rule: "required", Message: "必填"}}
+
+			ReturnBadRequest(ctx, e)
 			ctx.Abort()
 			return
 		}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// XyzIdPattern 小宇宙的 eid、pid 均为 24 位十六进制字符串
+const XyzIdPattern = `^[0-9a-f]{24}$`
+
+var xyzIdPattern = regexp.MustCompile(XyzIdPattern)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// 校验错误中的字段名与请求体中的字段名保持一致
+	v.RegisterTagNameFunc(FieldName)
+
+	_ = v.RegisterValidation("xyzid", func(fl validator.FieldLevel) bool {
+		return xyzIdPattern.MatchString(fl.Field().String())
+	})
+}
+
+// FieldName 字段在请求体中的名称，依次取 json、form 标签，最后使用首字母小写的字段名
+func FieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		tag := strings.Split(field.Tag.Get(key), ",")[0]
+		if tag == "-" {
+			return ""
+		}
+
+		if tag != "" {
+			return tag
+		}
+	}
+
+	return strings.ToLower(field.Name[:1]) + field.Name[1:]
+}
+
+// Bind 绑定并按结构体上的 binding 标签校验请求参数，失败时返回逐字段的错误并返回 false
+//
+// 规则示例：
+//
+//	Pid   string `json:"pid" binding:"required,xyzid"`
+//	Order string `json:"order" binding:"required,oneof=desc asc"`
+func Bind(ctx *gin.Context, params any) bool {
+	err := ctx.ShouldBind(params)
+
+	// 空请求体按零值继续校验，以便返回具体缺少哪些字段
+	if errors.Is(err, io.EOF) {
+		err = binding.Validator.ValidateStruct(params)
+	}
+
+	if err == nil {
+		return true
+	}
+
+	ReturnBadRequest(ctx, ValidationError(err))
+
+	return false
+}
+
+// ValidationError 将绑定、校验过程中的错误转换为带逐字段详情的 *Error
+func ValidationError(err error) *Error {
+	e := NewError(http.StatusBadRequest, ErrValidationFailed, "")
+
+	var validationErrors validator.ValidationErrors
+	var typeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrors):
+		for _, fe := range validationErrors {
+			e.Details = append(e.Details, FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Message: ruleMessage(fe),
+			})
+		}
+	case errors.As(err, &typeError):
+		e.Details = append(e.Details, FieldError{
+			Field:   typeError.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("类型应为 %s", typeError.Type.String()),
+		})
+	default:
+		e.Message = err.Error()
+	}
+
+	return e
+}
+
+// fieldPath 去掉命名空间中的结构体名，如 UpdateMileageRequestBody.tracking[0].eid -> tracking[0].eid
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+
+	return namespace
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "必填"
+	case "oneof":
+		return "必须是以下值之一：" + fe.Param()
+	case "xyzid":
+		return "格式错误，应为 24 位十六进制字符串"
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return "至少包含 " + fe.Param() + " 项"
+		}
+
+		return "不能小于 " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return "最多包含 " + fe.Param() + " 项"
+		}
+
+		return "不能大于 " + fe.Param()
+	case "unique":
+		return lowerFirst(fe.Param()) + " 不能重复"
+	case "gt":
+		return "必须大于 " + fe.Param()
+	case "gte":
+		return "不能小于 " + fe.Param()
+	case "lte":
+		return "不能大于 " + fe.Param()
+	case "gtefield", "gtfield":
+		return "不能小于 " + lowerFirst(fe.Param())
+	case "ltefield", "ltfield":
+		return "不能大于 " + lowerFirst(fe.Param())
+	case "numeric":
+		return "必须是数字"
+	case "datetime":
+		return "格式错误，应为 " + fe.Param()
+	case "timezone":
+		return "不是有效的时区，如 Asia/Shanghai"
+	default:
+		return "不满足规则 " + fe.Tag()
+	}
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const validId = "5e280fab418a84a0461fa8a0"
+
+type bindBody struct {
+	Pid      string   `json:"pid" binding:"required,xyzid"`
+	Order    string   `json:"order" binding:"required,oneof=desc asc"`
+	Eids     []string `json:"eids" binding:"omitempty,min=1,dive,xyzid"`
+	Tracking []struct {
+		Start float64 `json:"start" binding:"required,gt=0"`
+		End   float64 `json:"end" binding:"gtefield=Start"`
+	} `json:"tracking" binding:"dive"`
+	Limit int `form:"limit" binding:"max=50"`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		details []FieldError // 为 nil 时绑定成功
+	}{
+		{
+			name: "合法的请求体",
+			body: `{"pid":"` + validId + `","order":"desc","eids":["` + validId + `"],"tracking":[{"start":1,"end":2}],"limit":50}`,
+		},
+		{
+			name: "空请求体返回所有缺少的字段",
+			details: []FieldError{
+				{Field: "pid", Rule: "required", Message: "必填"},
+				{Field: "order", Rule: "required", Message: "必填"},
+			},
+		},
+		{
+			name: "格式和枚举值",
+			body: `{"pid":"abc","order":"hot","limit":51}`,
+			details: []FieldError{
+				{Field: "pid", Rule: "xyzid", Message: "格式错误，应为 24 位十六进制字符串"},
+				{Field: "order", Rule: "oneof", Message: "必须是以下值之一：desc asc"},
+				{Field: "limit", Rule: "max", Message: "不能大于 50"},
+			},
+		},
+		{
+			name: "数组元素和嵌套字段的路径",
+			body: `{"pid":"` + validId + `","order":"asc","eids":["` + validId + `","x"],"tracking":[{"start":2,"end":1}]}`,
+			details: []FieldError{
+				{Field: "eids[1]", Rule: "xyzid", Message: "格式错误，应为 24 位十六进制字符串"},
+				{Field: "tracking[0].end", Rule: "gtefield", Message: "不能小于 start"},
+			},
+		},
+		{
+			name:    "类型错误",
+			body:    `{"pid":"` + validId + `","order":"asc","limit":"ten"}`,
+			details: []FieldError{{Field: "limit", Rule: "type", Message: "类型应为 int"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			var params bindBody
+			ok := Bind(ctx, &params)
+
+			if tt.details == nil {
+				if !ok {
+					t.Fatalf("Bind() = false: %s", recorder.Body.String())
+				}
+
+				return
+			}
+
+			if ok || recorder.Code != http.StatusBadRequest {
+				t.Fatalf("Bind() = %v, status %d, want false and 400", ok, recorder.Code)
+			}
+
+			var res struct {
+				Error Error `json:"error"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Error.Code != ErrValidationFailed || !reflect.DeepEqual(res.Error.Details, tt.details) {
+				t.Errorf("error = %s %+v, want %s %+v", res.Error.Code, res.Error.Details, ErrValidationFailed, tt.details)
+			}
+		})
+	}
+}
+
+func TestXyzId(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		ok   bool
+	}{
+		{"24 位小写十六进制", validId, true},
+		{"大写字母", strings.ToUpper(validId), false},
+		{"23 位", validId[:23], false},
+		{"25 位", validId + "0", false},
+		{"非十六进制字符", "5e280fab418a84a0461fa8ag", false},
+		{"空字符串", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := binding.Validator.ValidateStruct(&struct {
+				Id string `binding:"xyzid"`
+			}{tt.id})
+
+			if (err == nil) != tt.ok {
+				t.Errorf("xyzid(%q) error = %v, want ok %v", tt.id, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	type body struct {
+		Json    string `json:"json_name,omitempty" form:"form_name"`
+		Form    string `form:"form_name"`
+		Skipped string `json:"-"`
+		Default string
+	}
+
+	typ := reflect.TypeOf(body{})
+	want := []string{"json_name", "form_name", "", "default"}
+
+	for i, name := range want {
+		if got := FieldName(typ.Field(i)); got != name {
+			t.Errorf("FieldName(%s) = %q, want %q", typ.Field(i).Name, got, name)
+		}
+	}
+}